@@ -1,8 +1,18 @@
 package api
 
 import (
+	"context"
+	"log"
+	"net"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/clock"
 	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/filter"
+	"github.com/EgorSkurihin/Hokku/scheduler"
+	"github.com/EgorSkurihin/Hokku/similar"
 	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/EgorSkurihin/Hokku/stream"
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -10,24 +20,65 @@ import (
 )
 
 type APIServer struct {
-	Echo         *echo.Echo
-	addr         string
-	logLevel     int
-	store        store.Store
-	sessionStore *sessions.CookieStore
+	Echo  *echo.Echo
+	Clock clock.Clock
+	// Views counts hokku views if set
+	Views *scheduler.ViewCounter
+	// Similar recommends similar hokkus if set
+	Similar *similar.Index
+	// Filter screens user texts if set
+	Filter *filter.Pipeline
+	// Hub streams updates to clients if set
+	Hub *stream.Hub
+	// LookupIP resolves the hosts of webhooks
+	LookupIP func(ctx context.Context, network, host string) ([]net.IP, error)
+
+	addr              string
+	logLevel          int
+	store             store.Store
+	sessionStore      *sessions.CookieStore
+	trashRetention    time.Duration
+	dailyRepeat       int
+	duplicateAction   string
+	duplicateDistance int
 }
 
 func New(conf *config.Server, store store.Store) *APIServer {
 	api := &APIServer{
-		Echo:         echo.New(),
-		addr:         conf.Addr,
-		logLevel:     conf.LogLevel,
-		sessionStore: sessions.NewCookieStore([]byte(conf.SessionKey)),
+		Echo:              echo.New(),
+		Clock:             clock.Real{},
+		LookupIP:          net.DefaultResolver.LookupIP,
+		addr:              conf.Addr,
+		logLevel:          conf.LogLevel,
+		sessionStore:      sessions.NewCookieStore([]byte(conf.SessionKey)),
+		trashRetention:    time.Duration(conf.TrashRetentionDays) * 24 * time.Hour,
+		dailyRepeat:       conf.DailyRepeatDays,
+		duplicateAction:   conf.DuplicateAction,
+		duplicateDistance: conf.DuplicateDistance,
 	}
 	api.store = store
+	api.Echo.IPExtractor = ipExtractor(conf.TrustedProxies)
 	return api
 }
 
+// ipExtractor trusts X-Forwarded-For only from the given ranges of proxies.
+// Invalid ranges are logged and skipped.
+func ipExtractor(proxies []string) echo.IPExtractor {
+	if len(proxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, p := range proxies {
+		_, ipNet, err := net.ParseCIDR(p)
+		if err != nil {
+			log.Printf("trusted proxies: %v", err)
+			continue
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
 func (api *APIServer) Start() error {
 	api.setupRoutes()
 	return api.Echo.Start(api.addr)
@@ -42,24 +93,103 @@ func (api *APIServer) setupRoutes() {
 	api.Echo.GET("/hokkus", api.GetHokkus)
 	api.Echo.GET("/hokkus/byTheme/:themeId", api.GetHokkusByTheme)
 	api.Echo.GET("/hokkus/byAuthor/:authorId", api.GetHokkusByAuthor)
+	api.Echo.GET("/hokkus/byTag/:tag", api.GetHokkusByTag)
+	api.Echo.GET("/hokkus/bySeason/:season", api.GetHokkusBySeason)
+	api.Echo.GET("/hokkus/trending", api.GetTrendingHokkus)
+	api.Echo.GET("/hokkus/top", api.GetTopHokkus)
+	api.Echo.GET("/hokku/random", api.GetRandomHokku)
+	api.Echo.GET("/hokku/daily", api.GetDailyHokku)
 	api.Echo.GET("/hokku/:id", api.GetHokku)
+	api.Echo.GET("/hokku/:id/comments", api.GetComments)
+	api.Echo.GET("/hokku/:id/similar", api.GetSimilarHokkus)
+	api.Echo.GET("/stream", api.Stream)
 	api.Echo.GET("/user/:id", api.GetUser)
 	api.Echo.GET("/themes", api.GetThemes)
+	api.Echo.GET("/themes/:id", api.GetTheme)
+	api.Echo.GET("/kigo", api.GetKigo)
+	api.Echo.GET("/chain/:id", api.GetChain)
+	api.Echo.GET("/contests", api.GetContests)
+	api.Echo.GET("/contest/:id", api.GetContest)
+	api.Echo.POST("/analyze", api.Analyze)
 	api.Echo.POST("/user", api.PostUser)
 	api.Echo.POST("/login", api.Login)
 
 	restricted := api.Echo.Group("/restricted")
 	//restricted.Use(session.Middleware(api.sessionStore))
 	restricted.Use(api.authMiddleware)
-	restricted.POST("/hokku", api.PostHokku)
+	restricted.POST("/hokku", api.PostHokku, api.postingMiddleware)
 	restricted.DELETE("/hokku/:id", api.DeleteHokku)
-	restricted.PUT("/hokku/:id", api.PutHokku)
+	restricted.PUT("/hokku/:id", api.PutHokku, api.postingMiddleware)
+	restricted.GET("/hokku/:id/views", api.GetViews)
+	restricted.GET("/me/stats", api.GetMyStats)
+	restricted.GET("/drafts", api.GetDrafts)
+	restricted.GET("/trash", api.GetTrash)
+	restricted.POST("/hokku/:id/restore", api.RestoreHokku)
+	restricted.POST("/hokku/:id/like", api.Like)
+	restricted.DELETE("/hokku/:id/like", api.Unlike)
+	restricted.POST("/hokku/:id/bookmark", api.Bookmark)
+	restricted.DELETE("/hokku/:id/bookmark", api.Unbookmark)
+	restricted.GET("/bookmarks", api.GetBookmarks)
+	restricted.POST("/hokku/:id/comment", api.PostComment, api.postingMiddleware)
+	restricted.POST("/hokku/:id/report", api.ReportHokku)
+	restricted.DELETE("/comment/:id", api.DeleteComment)
 	restricted.DELETE("/user/:id", api.DeleteUser)
 	restricted.PUT("/user/:id", api.PutUser)
+	restricted.POST("/chain", api.PostChain, api.postingMiddleware)
+	restricted.POST("/chain/:id/stanza", api.PostStanza, api.postingMiddleware)
+	restricted.POST("/contest/:id/entry", api.PostContestEntry, api.postingMiddleware)
+	restricted.POST("/contest/:id/vote", api.PostBallot)
+	restricted.POST("/user/:id/follow", api.Follow)
+	restricted.DELETE("/user/:id/follow", api.Unfollow)
+	restricted.POST("/user/:id/block", api.Block)
+	restricted.DELETE("/user/:id/block", api.Unblock)
+	restricted.GET("/blocks", api.GetBlocks)
+	restricted.POST("/user/:id/mute", api.Mute)
+	restricted.DELETE("/user/:id/mute", api.Unmute)
+	restricted.GET("/mutes", api.GetMutes)
+	restricted.POST("/user/:id/report", api.ReportUser)
+	restricted.GET("/notifications", api.GetNotifications)
+	restricted.GET("/notifications/unread", api.CountUnreadNotifications)
+	restricted.POST("/notifications/read", api.ReadNotifications)
+	restricted.POST("/notification/:id/read", api.ReadNotification)
+	restricted.GET("/notifications/preferences", api.GetNotificationPreferences)
+	restricted.PUT("/notifications/preferences", api.PutNotificationPreferences)
+	restricted.POST("/webhook", api.PostWebhook)
+	restricted.GET("/webhooks", api.GetWebhooks)
+	restricted.DELETE("/webhook/:id", api.DeleteWebhook)
+	restricted.GET("/webhook/:id/deliveries", api.GetWebhookDeliveries)
+
+	moderation := api.Echo.Group("/moderation")
+	moderation.Use(api.authMiddleware, api.moderatorMiddleware)
+	moderation.GET("/reports", api.GetReports)
+	moderation.GET("/report/:id", api.GetReport)
+	moderation.POST("/report/:id/claim", api.ClaimReport)
+	moderation.POST("/report/:id/resolve", api.ResolveReport)
+	moderation.POST("/hokku/:id/unhide", api.UnhideHokku)
+	moderation.POST("/user/:id/unhide", api.UnhideUser)
+	moderation.POST("/user/:id/restriction", api.PostRestriction)
+	moderation.GET("/user/:id/restrictions", api.GetRestrictions)
+	moderation.POST("/restriction/:id/lift", api.LiftRestriction)
 
-	/* admin := api.Echo.Group("/admin")
+	admin := api.Echo.Group("/admin")
+	admin.Use(api.authMiddleware, api.adminMiddleware)
+	admin.POST("/kigo", api.PostKigo)
+	admin.PUT("/kigo/:id", api.PutKigo)
+	admin.DELETE("/kigo/:id", api.DeleteKigo)
 	admin.POST("/theme", api.PostTheme)
-	admin.DELETE("/theme/:id", api.DeleteTheme) */
+	admin.PUT("/theme/:id", api.PutTheme)
+	admin.DELETE("/theme/:id", api.DeleteTheme)
+	admin.POST("/contest", api.PostContest)
+	admin.GET("/classics", api.GetClassics)
+	admin.POST("/classic", api.PostClassic)
+	admin.DELETE("/classic/:id", api.DeleteClassic)
+	admin.GET("/flags", api.GetFlags)
+	admin.PUT("/user/:id/role", api.PutUserRole)
+	admin.POST("/user/:id/restore", api.RestoreUser)
+	admin.POST("/filter/reload", api.ReloadFilter)
+	admin.GET("/audit", api.GetAuditLog)
+	admin.GET("/audit/export", api.ExportAuditLog)
+	admin.GET("/webhooks", api.GetAllWebhooks)
 
 	//swagger
 	api.Echo.GET("/swagger/*", echoSwagger.WrapHandler)
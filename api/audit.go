@@ -0,0 +1,187 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/labstack/echo/v4"
+)
+
+// maxAuditExport limits the entries exported at once.
+const maxAuditExport = 10000
+
+// Lengths in characters of the audit log columns, longer values are cut.
+const (
+	maxAuditIP      = 45
+	maxUserAgent    = 512
+	maxAuditDetails = 512
+)
+
+// audit records the action of the current user, or of the actor when it is
+// set, with the snapshots of the target before and after it. Nil snapshots
+// are skipped. Failures are only logged, the action is already done.
+func (api *APIServer) audit(c echo.Context, entry *models.AuditEntry, before, after interface{}) {
+	if entry.ActorId == 0 {
+		entry.ActorId, _ = api.currentUserId(c)
+	}
+	entry.IP = truncate(c.RealIP(), maxAuditIP)
+	entry.UserAgent = truncate(c.Request().UserAgent(), maxUserAgent)
+	entry.Details = truncate(entry.Details, maxAuditDetails)
+	entry.Before, entry.After = snapshot(c, before), snapshot(c, after)
+	if _, err := api.store.CreateAuditEntry(entry); err != nil {
+		c.Logger().Errorf("audit %s of %s %d: %v", entry.Action, entry.TargetType, entry.TargetId, err)
+	}
+}
+
+// truncate cuts the text to at most n characters.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+func snapshot(c echo.Context, v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		c.Logger().Errorf("audit snapshot: %v", err)
+		return nil
+	}
+	if string(raw) == "null" {
+		return nil
+	}
+	return raw
+}
+
+// userSnapshot returns a copy of the user for the audit log, nil when it
+// can't be read.
+func (api *APIServer) userSnapshot(id int) *models.User {
+	u, err := api.store.GetUser(id)
+	if err != nil {
+		return nil
+	}
+	cp := *u
+	return &cp
+}
+
+// auditQuery reads the filters of the audit log from the query string.
+func auditQuery(c echo.Context) (*models.AuditQuery, error) {
+	q := &models.AuditQuery{TargetType: c.QueryParam("targetType")}
+	var err error
+	for name, id := range map[string]*int{"actor": &q.ActorId, "target": &q.TargetId} {
+		if v := c.QueryParam(name); v != "" {
+			if *id, err = strconv.Atoi(v); err != nil {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Bad query parameters")
+			}
+		}
+	}
+	for name, t := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		if v := c.QueryParam(name); v != "" {
+			if *t, err = time.Parse(time.RFC3339, v); err != nil {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Bad query parameters")
+			}
+		}
+	}
+	return q, nil
+}
+
+// @Summary Get audit log
+// @Security cookieAuth
+// @Description Get the audit log from the newest entry filtered by the actor, the target and the time range
+// @Tags Admin routes
+// @Accept json
+// @Produce json
+// @Param actor query int false "id of the acting user"
+// @Param targetType query string false "user, hokku, comment, theme, kigo, classic, report or restriction"
+// @Param target query int false "id of the target"
+// @Param since query string false "RFC 3339 time, inclusive"
+// @Param until query string false "RFC 3339 time, exclusive"
+// @Param limit query int false "Sample size"
+// @Param offset query int false "Number of items to skip"
+// @Success 200 {array} models.AuditEntry
+// @Failure 400 {object} echo.HTTPError "Bad query parameters"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /admin/audit [get]
+func (api *APIServer) GetAuditLog(c echo.Context) error {
+	q, err := auditQuery(c)
+	if err != nil {
+		return err
+	}
+	if q.Limit, q.Offset, err = paginationParams(c); err != nil {
+		return err
+	}
+	entries, err := api.store.GetAuditLog(q)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, entries)
+}
+
+// @Summary Export audit log
+// @Security cookieAuth
+// @Description Export the filtered audit log as CSV from the newest entry, at most 10000 entries
+// @Tags Admin routes
+// @Produce text/csv
+// @Param actor query int false "id of the acting user"
+// @Param targetType query string false "user, hokku, comment, theme, kigo, classic, report or restriction"
+// @Param target query int false "id of the target"
+// @Param since query string false "RFC 3339 time, inclusive"
+// @Param until query string false "RFC 3339 time, exclusive"
+// @Success 200 {string} string "CSV file"
+// @Failure 400 {object} echo.HTTPError "Bad query parameters"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /admin/audit/export [get]
+func (api *APIServer) ExportAuditLog(c echo.Context) error {
+	q, err := auditQuery(c)
+	if err != nil {
+		return err
+	}
+	q.Limit = maxAuditExport
+	entries, err := api.store.GetAuditLog(q)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	c.Response().Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="audit.csv"`)
+	c.Response().WriteHeader(http.StatusOK)
+	w := csv.NewWriter(c.Response())
+	w.Write([]string{"id", "created", "actor", "action", "target_type", "target", "ip", "user_agent", "details", "before", "after"})
+	for _, e := range entries {
+		w.Write([]string{
+			strconv.Itoa(e.Id),
+			e.Created.UTC().Format(time.RFC3339),
+			strconv.Itoa(e.ActorId),
+			e.Action,
+			e.TargetType,
+			strconv.Itoa(e.TargetId),
+			csvText(e.IP),
+			csvText(e.UserAgent),
+			csvText(e.Details),
+			csvText(string(e.Before)),
+			csvText(string(e.After)),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// csvText keeps spreadsheets from running user-supplied text as formulas.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,171 @@
+package api_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLogin(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	for _, body := range []string{
+		`{"email":"example1@email.com","password":"Admin"}`,
+		`{"email":"example1@email.com","password":"wrong"}`,
+		`{"email":"nobody@email.com","password":"Admin"}`,
+	} {
+		req := httptest.NewRequest(echo.POST, "/login", strings.NewReader(body))
+		req.Header.Set("User-Agent", "test-agent")
+		req.RemoteAddr = "10.0.0.1:1234"
+		api.Login(api.Echo.NewContext(req, httptest.NewRecorder()))
+	}
+	if assert.Len(t, s.AuditLog, 3) {
+		assert.Equal(t, &models.AuditEntry{Id: 1, ActorId: 1, Action: models.AuditLogin, TargetType: models.TargetUser,
+			TargetId: 1, IP: "10.0.0.1", UserAgent: "test-agent", Created: s.AuditLog[0].Created}, s.AuditLog[0])
+		assert.Equal(t, models.AuditLoginFailed, s.AuditLog[1].Action)
+		assert.Equal(t, 0, s.AuditLog[1].ActorId)
+		assert.Equal(t, 1, s.AuditLog[1].TargetId)
+		assert.Equal(t, "Wrong password", s.AuditLog[1].Details)
+		assert.Equal(t, 0, s.AuditLog[2].TargetId)
+		assert.Contains(t, s.AuditLog[2].Details, "nobody@email.com")
+	}
+}
+
+func TestAuditSnapshots(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	req := httptest.NewRequest(echo.PUT, "/admin/user/", strings.NewReader(`{"role":"moderator"}`))
+	c := api.Echo.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	c.Set("userId", 3)
+	assert.NoError(t, api.PutUserRole(c))
+
+	req = httptest.NewRequest(echo.DELETE, "/admin/theme/", nil)
+	c = api.Echo.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues("2")
+	c.Set("userId", 3)
+	assert.NoError(t, api.DeleteTheme(c))
+
+	if !assert.Len(t, s.AuditLog, 2) {
+		return
+	}
+	role := s.AuditLog[0]
+	assert.Equal(t, 3, role.ActorId)
+	assert.Equal(t, models.AuditRoleChange, role.Action)
+	before, after := &models.User{}, &models.User{}
+	assert.NoError(t, json.Unmarshal(role.Before, before))
+	assert.NoError(t, json.Unmarshal(role.After, after))
+	assert.Equal(t, models.RoleUser, before.Role)
+	assert.Equal(t, models.RoleModerator, after.Role)
+
+	theme := s.AuditLog[1]
+	assert.Equal(t, models.AuditThemeDelete, theme.Action)
+	assert.Equal(t, 2, theme.TargetId)
+	assert.NotEmpty(t, theme.Before)
+	assert.Empty(t, theme.After)
+}
+
+func TestGetAuditLog(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	s.CreateAuditEntry(&models.AuditEntry{ActorId: 1, Action: models.AuditLogin, TargetType: models.TargetUser, TargetId: 1})
+	s.CreateAuditEntry(&models.AuditEntry{ActorId: 3, Action: models.AuditUserDelete, TargetType: models.TargetUser, TargetId: 1,
+		UserAgent: "=HYPERLINK(\"http://evil\")"})
+	s.CreateAuditEntry(&models.AuditEntry{ActorId: 3, Action: models.AuditThemeDelete, TargetType: models.TargetTheme, TargetId: 1})
+	cases := []struct {
+		name    string
+		query   string
+		ids     []int
+		isValid bool
+	}{
+		{name: "all", query: "", ids: []int{3, 2, 1}, isValid: true},
+		{name: "actor", query: "actor=3", ids: []int{3, 2}, isValid: true},
+		{name: "target", query: "targetType=user&target=1", ids: []int{2, 1}, isValid: true},
+		{name: "time range", query: "since=2000-01-01T00:00:00Z&until=2001-01-01T00:00:00Z", ids: []int{}, isValid: true},
+		{name: "page", query: "limit=1&offset=1", ids: []int{2}, isValid: true},
+		{name: "bad actor", query: "actor=me", isValid: false},
+		{name: "bad time", query: "since=yesterday", isValid: false},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/admin/audit?"+cs.query, nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			err := api.GetAuditLog(c)
+			if !cs.isValid {
+				httpErr, ok := err.(*echo.HTTPError)
+				if assert.True(t, ok) {
+					assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+				}
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			entries := []*models.AuditEntry{}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+			ids := []int{}
+			for _, e := range entries {
+				ids = append(ids, e.Id)
+			}
+			assert.Equal(t, cs.ids, ids)
+		})
+	}
+
+	req := httptest.NewRequest(echo.GET, "/admin/audit/export?actor=3", nil)
+	rec := httptest.NewRecorder()
+	if !assert.NoError(t, api.ExportAuditLog(api.Echo.NewContext(req, rec))) {
+		return
+	}
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	assert.NoError(t, err)
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, "id", records[0][0])
+		assert.Equal(t, []string{"3", models.AuditThemeDelete}, []string{records[1][0], records[1][3]})
+		assert.Equal(t, `'=HYPERLINK("http://evil")`, records[2][7])
+	}
+}
+
+func TestAuditClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		remote  string
+		ip      string
+	}{
+		{name: "No trusted proxies", remote: "10.0.0.1:1234", ip: "10.0.0.1"},
+		{name: "Trusted proxy", proxies: []string{"10.0.0.0/8"}, remote: "10.0.0.1:1234", ip: "203.0.113.5"},
+		{name: "Untrusted proxy", proxies: []string{"10.0.0.0/8"}, remote: "198.51.100.1:1234", ip: "198.51.100.1"},
+	}
+	for _, test := range tests {
+		s := test_store.New()
+		api := api.New(&config.Server{TrustedProxies: test.proxies}, s)
+		email := strings.Repeat("я", 600) + "@email.com"
+		req := httptest.NewRequest(echo.POST, "/login", strings.NewReader(`{"email":"`+email+`","password":"Admin"}`))
+		req.RemoteAddr = test.remote
+		req.Header.Set(echo.HeaderXForwardedFor, "203.0.113.5")
+		req.Header.Set("User-Agent", strings.Repeat("ё", 600))
+		api.Login(api.Echo.NewContext(req, httptest.NewRecorder()))
+		if assert.Len(t, s.AuditLog, 1, test.name) {
+			e := s.AuditLog[0]
+			assert.Equal(t, test.ip, e.IP, test.name)
+			assert.Equal(t, 512, utf8.RuneCountInString(e.UserAgent), test.name)
+			assert.Equal(t, 512, utf8.RuneCountInString(e.Details), test.name)
+			assert.True(t, utf8.ValidString(e.Details), test.name)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/labstack/echo/v4"
+)
+
+// relate adds or removes a relation of the current user to the user from
+// the path.
+func (api *APIServer) relate(c echo.Context, action func(userId, otherId int) error) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	if userId == id {
+		return echo.NewHTTPError(http.StatusBadRequest, "Users can`t block or mute themselves")
+	}
+	if err := action(userId, id); err != nil {
+		if errors.Is(err, store.ErrForeignKeyConstraint) {
+			return echo.NewHTTPError(http.StatusNotFound, "A user with the specified ID was not found")
+		}
+		if errors.Is(err, store.ErrAlreadyExist) {
+			return echo.NewHTTPError(http.StatusConflict, "The relation is already added")
+		}
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "The relation was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Block user
+// @Security cookieAuth
+// @Description Block the user. Blocked users can't follow, like or comment the current user and don't see their followers-only hokkus. Blocking ends their following
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of user"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Bad request. User ID must be an integer and not the current user"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 404 {object} echo.HTTPError "A user with the specified ID was not found"
+// @Failure 409 {object} echo.HTTPError "The user is already blocked"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/user/{id}/block [post]
+func (api *APIServer) Block(c echo.Context) error {
+	return api.relate(c, api.store.Block)
+}
+
+// @Summary Unblock user
+// @Security cookieAuth
+// @Description Remove the block of the user
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of user"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Bad request. User ID must be an integer and not the current user"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 404 {object} echo.HTTPError "The user is not blocked"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/user/{id}/block [delete]
+func (api *APIServer) Unblock(c echo.Context) error {
+	return api.relate(c, api.store.Unblock)
+}
+
+// @Summary Get blocked users
+// @Security cookieAuth
+// @Description Get the users blocked by the current user from the newest block
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Success 200 {object} []models.Block
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/blocks [get]
+func (api *APIServer) GetBlocks(c echo.Context) error {
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	blocks, err := api.store.GetBlocks(userId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, blocks)
+}
+
+// @Summary Mute user
+// @Security cookieAuth
+// @Description Mute the user. Hokkus and comments of muted users are left out of the feeds and the comments of the current user
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of user"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Bad request. User ID must be an integer and not the current user"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 404 {object} echo.HTTPError "A user with the specified ID was not found"
+// @Failure 409 {object} echo.HTTPError "The user is already muted"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/user/{id}/mute [post]
+func (api *APIServer) Mute(c echo.Context) error {
+	return api.relate(c, api.store.Mute)
+}
+
+// @Summary Unmute user
+// @Security cookieAuth
+// @Description Remove the mute of the user
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of user"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Bad request. User ID must be an integer and not the current user"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 404 {object} echo.HTTPError "The user is not muted"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/user/{id}/mute [delete]
+func (api *APIServer) Unmute(c echo.Context) error {
+	return api.relate(c, api.store.Unmute)
+}
+
+// @Summary Get muted users
+// @Security cookieAuth
+// @Description Get the users muted by the current user from the newest mute
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Success 200 {object} []models.Mute
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/mutes [get]
+func (api *APIServer) GetMutes(c echo.Context) error {
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	mutes, err := api.store.GetMutes(userId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, mutes)
+}
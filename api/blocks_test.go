@@ -0,0 +1,150 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlock(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	cases := []struct {
+		name    string
+		id      string
+		block   bool
+		isValid bool
+	}{
+		{name: "block", id: "1", block: true, isValid: true},
+		{name: "already blocked", id: "1", block: true, isValid: false},
+		{name: "themselves", id: "2", block: true, isValid: false},
+		{name: "unknown user", id: "100", block: true, isValid: false},
+		{name: "invalid id", id: "id", block: true, isValid: false},
+		{name: "unblock", id: "1", isValid: true},
+		{name: "not blocked", id: "1", isValid: false},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/restricted/user/", nil)
+			c := api.Echo.NewContext(req, httptest.NewRecorder())
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", 2)
+			var err error
+			if cs.block {
+				err = api.Block(c)
+			} else {
+				err = api.Unblock(c)
+			}
+			if cs.isValid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestBlockedUser(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	req := httptest.NewRequest(echo.POST, "/restricted/user/", nil)
+	c := api.Echo.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	c.Set("userId", 2)
+	assert.NoError(t, api.Block(c))
+	assert.Empty(t, s.Follows)
+
+	// The block is kept when the following comes back in another way
+	s.Follows = append(s.Follows, &models.Follow{FollowerId: 1, FolloweeId: 2})
+	cases := []struct {
+		name    string
+		id      string
+		handler func(echo.Context) error
+		body    string
+		code    int
+	}{
+		{name: "followers-only hokku", id: "9", handler: api.GetHokku, code: http.StatusNotFound},
+		{name: "like", id: "2", handler: api.Like, code: http.StatusForbidden},
+		{name: "comment", id: "2", handler: api.PostComment, body: `{"content":"Hi"}`, code: http.StatusForbidden},
+		{name: "follow", id: "2", handler: api.Follow, code: http.StatusForbidden},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/restricted/", strings.NewReader(cs.body))
+			c := api.Echo.NewContext(req, httptest.NewRecorder())
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", 1)
+			httpErr, ok := cs.handler(c).(*echo.HTTPError)
+			if assert.True(t, ok) {
+				assert.Equal(t, cs.code, httpErr.Code)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	c = api.Echo.NewContext(httptest.NewRequest(echo.GET, "/restricted/blocks", nil), rec)
+	c.Set("userId", 2)
+	assert.NoError(t, api.GetBlocks(c))
+	blocks := []*models.Block{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &blocks))
+	if assert.Len(t, blocks, 1) {
+		assert.Equal(t, 1, blocks[0].BlockedId)
+	}
+}
+
+func TestMute(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	req := httptest.NewRequest(echo.POST, "/restricted/user/", nil)
+	c := api.Echo.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues("3")
+	c.Set("userId", 1)
+	assert.NoError(t, api.Mute(c))
+	assert.Error(t, api.Mute(c))
+
+	rec := httptest.NewRecorder()
+	c = api.Echo.NewContext(httptest.NewRequest(echo.GET, "/hokkus", nil), rec)
+	c.Set("userId", 1)
+	assert.NoError(t, api.GetHokkus(c))
+	var hs []*models.Hokku
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &hs))
+	assert.NotEmpty(t, hs)
+	for _, h := range hs {
+		assert.NotEqual(t, 3, h.OwnerId)
+	}
+
+	rec = httptest.NewRecorder()
+	c = api.Echo.NewContext(httptest.NewRequest(echo.GET, "/hokku/", nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	c.Set("userId", 1)
+	assert.NoError(t, api.GetComments(c))
+	var comments []*models.Comment
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &comments))
+	if assert.Len(t, comments, 1) {
+		assert.Equal(t, 2, comments[0].OwnerId)
+	}
+
+	c = api.Echo.NewContext(httptest.NewRequest(echo.DELETE, "/restricted/user/", nil), httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues("3")
+	c.Set("userId", 1)
+	assert.NoError(t, api.Unmute(c))
+	assert.Error(t, api.Unmute(c))
+	mutes, err := s.GetMutes(1)
+	assert.NoError(t, err)
+	assert.Empty(t, mutes)
+}
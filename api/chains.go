@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/labstack/echo/v4"
+)
+
+// prepareStanza makes the hokku the stanza of the chain at the position
+// written by the user and checks the theme of the chain is open and the
+// stanza follows the form of the position. It returns why the stanza must
+// be sent to moderation, see screen.
+func (api *APIServer) prepareStanza(chain *models.Chain, h *models.Hokku, userId, position int) ([]string, error) {
+	theme, err := api.store.GetTheme(chain.ThemeId)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return nil, echo.NewHTTPError(http.StatusConflict, "Foreign key constraint fails")
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	if theme.PostingClosed {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "Posting to the theme is closed")
+	}
+	h.OwnerId = userId
+	h.ThemeId = chain.ThemeId
+	h.ChainId = chain.Id
+	h.Position = position
+	h.Status, h.Visibility, h.PublishAt = "", "", nil
+	h.Normalize()
+	if h.Title == "" {
+		h.Title = fmt.Sprintf("%s #%d", chain.Title, position)
+	}
+	if err := h.Validate(); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
+	}
+	if err := checkPattern(h, models.StanzaPattern(position)); err != nil {
+		return nil, err
+	}
+	if err := api.attachSeasons(h); err != nil {
+		return nil, err
+	}
+	moderation, err := api.screenHokku(h)
+	if err != nil {
+		return nil, err
+	}
+	h.BeforeSave()
+	return moderation, nil
+}
+
+// @Summary Start chain
+// @Security cookieAuth
+// @Description Start a renga with its first stanza in the 5-7-5 form. The current user goes first, the other participants follow in the given order
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param chain body models.ChainStart true "New chain with the first stanza"
+// @Success 201 "Created"
+// @Failure 400 {object} echo.HTTPError "Dont pass validation or the form check"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "Posting to the theme is closed or posting is suspended"
+// @Failure 409 {object} echo.HTTPError "Foreign key constraint fails"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/chain [post]
+func (api *APIServer) PostChain(c echo.Context) error {
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	req := &models.ChainStart{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&req); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	chain := &req.Chain
+	chain.OwnerId = userId
+	if err := chain.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
+	}
+	chain.BeforeSave()
+	moderation, err := api.prepareStanza(chain, &req.Stanza, userId, 1)
+	if err != nil {
+		return err
+	}
+	id, err := api.store.CreateChain(chain, &req.Stanza)
+	if err != nil {
+		if errors.Is(err, store.ErrForeignKeyConstraint) {
+			return echo.NewHTTPError(http.StatusConflict, "Foreign key constraint fails")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	api.reportFiltered(c, &models.Report{HokkuId: req.Stanza.Id, AuthorId: userId}, moderation)
+	c.Response().Header().Set("Location", fmt.Sprintf("/chain/%d", id))
+	return c.NoContent(http.StatusCreated)
+}
+
+// @Summary Append stanza
+// @Security cookieAuth
+// @Description Add the next stanza to the chain. Participants take turns, odd stanzas follow the 5-7-5 form and even ones the 7-7 form
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of chain"
+// @Param stanza body models.Hokku true "The stanza object can only contain title and content or lines"
+// @Success 201 "Created"
+// @Failure 400 {object} echo.HTTPError "Dont pass validation or the form check"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The user doesn't participate in the chain, it is not their turn, posting to the theme is closed or posting is suspended"
+// @Failure 404 {object} echo.HTTPError "A chain with the specified ID was not found"
+// @Failure 409 {object} echo.HTTPError "Another stanza was added first"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/chain/{id}/stanza [post]
+func (api *APIServer) PostStanza(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	chain, err := api.store.GetChain(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A chain with the specified ID was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	if !chain.IsParticipant(userId) {
+		return echo.NewHTTPError(http.StatusForbidden, "The user doesn`t participate in the chain")
+	}
+	position := chain.Length + 1
+	if chain.Poet(position) != userId {
+		return echo.NewHTTPError(http.StatusForbidden, "It is another participant`s turn")
+	}
+	h := &models.Hokku{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&h); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	moderation, err := api.prepareStanza(chain, h, userId, position)
+	if err != nil {
+		return err
+	}
+	hokkuId, err := api.store.AppendStanza(h)
+	if err != nil {
+		if errors.Is(err, store.ErrAlreadyExist) {
+			return echo.NewHTTPError(http.StatusConflict, "Another stanza was added first")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	api.reportFiltered(c, &models.Report{HokkuId: hokkuId, AuthorId: userId}, moderation)
+	c.Response().Header().Set("Location", fmt.Sprintf("/hokku/%d", hokkuId))
+	return c.NoContent(http.StatusCreated)
+}
+
+// @Summary Get chain
+// @Description Get the chain with its stanzas in order
+// @Tags Open routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of chain"
+// @Success 200 {object} models.ChainDetails
+// @Failure 400 {object} echo.HTTPError "Bad request. Chain ID must be an integer"
+// @Failure 404 {object} echo.HTTPError "A chain with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /chain/{id} [get]
+func (api *APIServer) GetChain(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	chain, err := api.store.GetChain(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A chain with the specified ID was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	stanzas, err := api.store.GetChainStanzas(id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, &models.ChainDetails{Chain: chain, Stanzas: stanzas})
+}
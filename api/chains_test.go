@@ -0,0 +1,214 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetChain(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name         string
+		id           string
+		expectedBody []byte
+		isValid      bool
+	}{
+		{
+			name:         "valid",
+			id:           "1",
+			expectedBody: test_store.MockChain(1),
+			isValid:      true,
+		},
+		{
+			name:    "not found",
+			id:      "100",
+			isValid: false,
+		},
+		{
+			name:    "invalid id",
+			id:      "id",
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/chain/", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			if cs.isValid {
+				assert.NoError(t, api.GetChain(c))
+				assert.Equal(t, string(cs.expectedBody), rec.Body.String())
+			}
+			if !cs.isValid {
+				assert.Error(t, api.GetChain(c))
+			}
+		})
+	}
+}
+
+func TestPostChain(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		reqBody string
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			reqBody: `{"title":"Chain","themeId":1,"participants":[2],"stanza":{"content":"An old silent pond\nA frog jumps into the pond\nSplash! Silence again"}}`,
+			isValid: true,
+		},
+		{
+			name:    "first stanza is not 5-7-5",
+			reqBody: `{"title":"Chain","themeId":1,"participants":[2],"stanza":{"content":"Content"}}`,
+			isValid: false,
+		},
+		{
+			name:    "no title",
+			reqBody: `{"themeId":1,"participants":[2],"stanza":{"content":"An old silent pond\nA frog jumps into the pond\nSplash! Silence again"}}`,
+			isValid: false,
+		},
+		{
+			name:    "closed theme",
+			reqBody: `{"title":"Chain","themeId":5,"participants":[2],"stanza":{"content":"An old silent pond\nA frog jumps into the pond\nSplash! Silence again"}}`,
+			isValid: false,
+		},
+		{
+			name:    "unknown participant",
+			reqBody: `{"title":"Chain","themeId":1,"participants":[100],"stanza":{"content":"An old silent pond\nA frog jumps into the pond\nSplash! Silence again"}}`,
+			isValid: false,
+		},
+		{
+			name:    "bad body params",
+			reqBody: `{Error}`,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/restricted/chain", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.Set("userId", 1)
+			if cs.isValid {
+				assert.NoError(t, api.PostChain(c))
+				assert.Equal(t, "/chain/2", rec.Header().Get("Location"))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.PostChain(c))
+			}
+		})
+	}
+}
+
+func TestPostStanza(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		id      string
+		userId  int
+		reqBody string
+		isValid bool
+	}{
+		{
+			name:    "not a participant",
+			id:      "1",
+			userId:  2,
+			reqBody: `{"content":"An old silent pond\nA frog jumps into the pond\nSplash! Silence again"}`,
+			isValid: false,
+		},
+		{
+			name:    "not the turn of the user",
+			id:      "1",
+			userId:  3,
+			reqBody: `{"content":"An old silent pond\nA frog jumps into the pond\nSplash! Silence again"}`,
+			isValid: false,
+		},
+		{
+			name:    "third stanza is not 5-7-5",
+			id:      "1",
+			userId:  1,
+			reqBody: `{"content":"The light of a candle\nis transferred to another candle"}`,
+			isValid: false,
+		},
+		{
+			name:    "third stanza",
+			id:      "1",
+			userId:  1,
+			reqBody: `{"content":"An old silent pond\nA frog jumps into the pond\nSplash! Silence again"}`,
+			isValid: true,
+		},
+		{
+			name:    "fourth stanza is not 7-7",
+			id:      "1",
+			userId:  3,
+			reqBody: `{"content":"An old silent pond\nA frog jumps into the pond\nSplash! Silence again"}`,
+			isValid: false,
+		},
+		{
+			name:    "fourth stanza",
+			id:      "1",
+			userId:  3,
+			reqBody: `{"content":"Circles widen on the water\nand the reeds are still again"}`,
+			isValid: true,
+		},
+		{
+			name:    "not found",
+			id:      "100",
+			userId:  1,
+			reqBody: `{"content":"An old silent pond\nA frog jumps into the pond\nSplash! Silence again"}`,
+			isValid: false,
+		},
+		{
+			name:    "invalid id",
+			id:      "id",
+			userId:  1,
+			reqBody: `{"content":"An old silent pond\nA frog jumps into the pond\nSplash! Silence again"}`,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/restricted/chain/", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", cs.userId)
+			if cs.isValid {
+				assert.NoError(t, api.PostStanza(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.PostStanza(c))
+			}
+		})
+	}
+}
+
+func TestPostStanzaClosedTheme(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	theme, err := s.GetTheme(2)
+	assert.NoError(t, err)
+	theme.PostingClosed = true
+	req := httptest.NewRequest(echo.POST, "/restricted/chain/", strings.NewReader(
+		`{"content":"An old silent pond\nA frog jumps into the pond\nSplash! Silence again"}`))
+	c := api.Echo.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	c.Set("userId", 1)
+	err = api.PostStanza(c)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusForbidden, err.(*echo.HTTPError).Code)
+	}
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/labstack/echo/v4"
+)
+
+// contest returns the contest with the id from the path.
+func (api *APIServer) contest(c echo.Context) (*models.Contest, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	contest, err := api.store.GetContest(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "A contest with the specified ID was not found")
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return contest, nil
+}
+
+// @Summary Get contests
+// @Description Get all contests from the latest one
+// @Tags Open routes
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.Contest
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /contests [get]
+func (api *APIServer) GetContests(c echo.Context) error {
+	result, err := api.store.GetContests()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Get contest
+// @Description Get the contest with its current phase and entries. Results are added when the voting is over
+// @Tags Open routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of contest"
+// @Success 200 {object} models.ContestDetails
+// @Failure 400 {object} echo.HTTPError "Bad request. Contest ID must be an integer"
+// @Failure 404 {object} echo.HTTPError "A contest with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /contest/{id} [get]
+func (api *APIServer) GetContest(c echo.Context) error {
+	contest, err := api.contest(c)
+	if err != nil {
+		return err
+	}
+	entries, err := api.store.GetContestEntries(contest.Id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, &models.ContestDetails{
+		Contest: contest,
+		Phase:   contest.Phase(api.Clock.Now()),
+		Entries: entries,
+	})
+}
+
+// @Summary Post contest
+// @Security cookieAuth
+// @Description Create a contest on a theme. Submissions must close before the voting opens
+// @Tags Admin routes
+// @Accept json
+// @Produce json
+// @Param contest body models.Contest true "New contest"
+// @Success 201 "Created"
+// @Failure 400 {object} echo.HTTPError "Dont pass validation"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
+// @Failure 409 {object} echo.HTTPError "Foreign key constraint fails"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /admin/contest [post]
+func (api *APIServer) PostContest(c echo.Context) error {
+	contest := &models.Contest{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&contest); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	if err := contest.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
+	}
+	contest.ResultsPublishedAt, contest.Results = nil, nil
+	id, err := api.store.CreateContest(contest)
+	if err != nil {
+		if errors.Is(err, store.ErrForeignKeyConstraint) {
+			return echo.NewHTTPError(http.StatusConflict, "Foreign key constraint fails")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	c.Response().Header().Set("Location", fmt.Sprintf("/contest/%d", id))
+	return c.NoContent(http.StatusCreated)
+}
+
+// @Summary Submit contest entry
+// @Security cookieAuth
+// @Description Submit a public hokku of the current user written on the theme of the contest while the contest is open
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of contest"
+// @Param entry body models.ContestEntry true "The entry object can only contain hokkuId"
+// @Success 201 "Created"
+// @Failure 400 {object} echo.HTTPError "The hokku is not public or is written on another theme"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The contest is not open, the hokku belongs to another user or posting is suspended"
+// @Failure 404 {object} echo.HTTPError "A contest or a hokku with the specified ID was not found"
+// @Failure 409 {object} echo.HTTPError "The hokku is already submitted"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/contest/{id}/entry [post]
+func (api *APIServer) PostContestEntry(c echo.Context) error {
+	contest, err := api.contest(c)
+	if err != nil {
+		return err
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	if contest.Phase(api.Clock.Now()) != models.ContestOpen {
+		return echo.NewHTTPError(http.StatusForbidden, "The contest doesn`t take entries now")
+	}
+	entry := &models.ContestEntry{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&entry); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	h, err := api.store.GetHokku(userId, entry.HokkuId)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A hokku with the specified ID was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	if h.OwnerId != userId {
+		return echo.NewHTTPError(http.StatusForbidden, "Only own hokkus can be submitted")
+	}
+	if !h.Listed(0, false) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Only public hokkus can be submitted")
+	}
+	if h.ThemeId != contest.ThemeId {
+		return echo.NewHTTPError(http.StatusBadRequest, "The hokku is written on another theme")
+	}
+	entry.ContestId = contest.Id
+	if err := api.store.CreateContestEntry(entry); err != nil {
+		if errors.Is(err, store.ErrAlreadyExist) {
+			return echo.NewHTTPError(http.StatusConflict, "The hokku is already submitted")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	c.Response().Header().Set("Location", fmt.Sprintf("/contest/%d", contest.Id))
+	return c.NoContent(http.StatusCreated)
+}
+
+// @Summary Vote in contest
+// @Security cookieAuth
+// @Description Vote for the entries during the voting window. A single vote lists one hokku, a ranked vote lists up to three hokkus from the best one. Every user votes once and not for own hokkus
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of contest"
+// @Param ballot body models.Ballot true "The ballot object can only contain hokkuIds"
+// @Success 201 "Created"
+// @Failure 400 {object} echo.HTTPError "Dont pass validation or the hokku is not an entry"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The voting is not open or the user votes for own hokku"
+// @Failure 404 {object} echo.HTTPError "A contest with the specified ID was not found"
+// @Failure 409 {object} echo.HTTPError "The user has already voted"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/contest/{id}/vote [post]
+func (api *APIServer) PostBallot(c echo.Context) error {
+	contest, err := api.contest(c)
+	if err != nil {
+		return err
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	if contest.Phase(api.Clock.Now()) != models.ContestVoting {
+		return echo.NewHTTPError(http.StatusForbidden, "The voting is not open")
+	}
+	b := &models.Ballot{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&b); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	if err := b.Validate(contest); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
+	}
+	entries, err := api.store.GetContestEntries(contest.Id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	owners := map[int]int{}
+	for _, h := range entries {
+		owners[h.Id] = h.OwnerId
+	}
+	for _, id := range b.HokkuIds {
+		owner, ok := owners[id]
+		if !ok {
+			return echo.NewHTTPError(http.StatusBadRequest, "The hokku is not an entry of the contest")
+		}
+		if owner == userId {
+			return echo.NewHTTPError(http.StatusForbidden, "Users can`t vote for their own hokkus")
+		}
+	}
+	b.ContestId, b.VoterId = contest.Id, userId
+	if err := api.store.CreateBallot(b); err != nil {
+		if errors.Is(err, store.ErrAlreadyExist) {
+			return echo.NewHTTPError(http.StatusConflict, "The user has already voted")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.NoContent(http.StatusCreated)
+}
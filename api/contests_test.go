@@ -0,0 +1,339 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetContests(t *testing.T) {
+	api := testAPIServer()
+	req := httptest.NewRequest(echo.GET, "/contests", nil)
+	rec := httptest.NewRecorder()
+	c := api.Echo.NewContext(req, rec)
+	assert.NoError(t, api.GetContests(c))
+	contests := []*models.Contest{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &contests))
+	assert.Len(t, contests, len(test_store.Contests))
+}
+
+func TestGetContest(t *testing.T) {
+	api := testAPIServer()
+	api.Clock = clock.NewMock(test_store.ContestStart.Add(time.Hour))
+	cases := []struct {
+		name         string
+		id           string
+		expectedBody []byte
+		isValid      bool
+	}{
+		{
+			name:         "valid",
+			id:           "1",
+			expectedBody: test_store.MockContest(1, models.ContestOpen),
+			isValid:      true,
+		},
+		{
+			name:    "not found",
+			id:      "100",
+			isValid: false,
+		},
+		{
+			name:    "invalid id",
+			id:      "id",
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/contest/", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			if cs.isValid {
+				assert.NoError(t, api.GetContest(c))
+				assert.Equal(t, string(cs.expectedBody), rec.Body.String())
+			}
+			if !cs.isValid {
+				assert.Error(t, api.GetContest(c))
+			}
+		})
+	}
+}
+
+func TestPostContest(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		reqBody string
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			reqBody: `{"title":"Contest","themeId":1,"votingMode":"ranked","opensAt":"2030-03-01T00:00:00Z","closesAt":"2030-03-08T00:00:00Z","votingOpensAt":"2030-03-08T00:00:00Z","votingClosesAt":"2030-03-15T00:00:00Z"}`,
+			isValid: true,
+		},
+		{
+			name:    "voting before submissions close",
+			reqBody: `{"title":"Contest","themeId":1,"votingMode":"ranked","opensAt":"2030-03-01T00:00:00Z","closesAt":"2030-03-08T00:00:00Z","votingOpensAt":"2030-03-07T00:00:00Z","votingClosesAt":"2030-03-15T00:00:00Z"}`,
+			isValid: false,
+		},
+		{
+			name:    "closes before opening",
+			reqBody: `{"title":"Contest","themeId":1,"votingMode":"ranked","opensAt":"2030-03-08T00:00:00Z","closesAt":"2030-03-01T00:00:00Z","votingOpensAt":"2030-03-08T00:00:00Z","votingClosesAt":"2030-03-15T00:00:00Z"}`,
+			isValid: false,
+		},
+		{
+			name:    "unknown voting mode",
+			reqBody: `{"title":"Contest","themeId":1,"votingMode":"approval","opensAt":"2030-03-01T00:00:00Z","closesAt":"2030-03-08T00:00:00Z","votingOpensAt":"2030-03-08T00:00:00Z","votingClosesAt":"2030-03-15T00:00:00Z"}`,
+			isValid: false,
+		},
+		{
+			name:    "unknown theme",
+			reqBody: `{"title":"Contest","themeId":100,"votingMode":"single","opensAt":"2030-03-01T00:00:00Z","closesAt":"2030-03-08T00:00:00Z","votingOpensAt":"2030-03-08T00:00:00Z","votingClosesAt":"2030-03-15T00:00:00Z"}`,
+			isValid: false,
+		},
+		{
+			name:    "bad body params",
+			reqBody: `{Error}`,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/admin/contest", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			if cs.isValid {
+				assert.NoError(t, api.PostContest(c))
+				assert.Equal(t, "/contest/3", rec.Header().Get("Location"))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.PostContest(c))
+			}
+		})
+	}
+}
+
+func TestPostContestEntry(t *testing.T) {
+	api := testAPIServer()
+	open := test_store.ContestStart.Add(time.Hour)
+	cases := []struct {
+		name    string
+		now     time.Time
+		userId  int
+		reqBody string
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			now:     open,
+			userId:  3,
+			reqBody: `{"hokkuId":3}`,
+			isValid: true,
+		},
+		{
+			name:    "already submitted",
+			now:     open,
+			userId:  3,
+			reqBody: `{"hokkuId":3}`,
+			isValid: false,
+		},
+		{
+			name:    "hokku of another user",
+			now:     open,
+			userId:  1,
+			reqBody: `{"hokkuId":5}`,
+			isValid: false,
+		},
+		{
+			name:    "draft",
+			now:     open,
+			userId:  1,
+			reqBody: `{"hokkuId":6}`,
+			isValid: false,
+		},
+		{
+			name:    "another theme",
+			now:     open,
+			userId:  1,
+			reqBody: `{"hokkuId":4}`,
+			isValid: false,
+		},
+		{
+			name:    "not found",
+			now:     open,
+			userId:  1,
+			reqBody: `{"hokkuId":100}`,
+			isValid: false,
+		},
+		{
+			name:    "contest is not open yet",
+			now:     test_store.ContestStart.Add(-time.Hour),
+			userId:  2,
+			reqBody: `{"hokkuId":10}`,
+			isValid: false,
+		},
+		{
+			name:    "submissions are closed",
+			now:     test_store.ContestStart.AddDate(0, 0, 7),
+			userId:  2,
+			reqBody: `{"hokkuId":10}`,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			api.Clock = clock.NewMock(cs.now)
+			req := httptest.NewRequest(echo.POST, "/restricted/contest/", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+			c.Set("userId", cs.userId)
+			if cs.isValid {
+				assert.NoError(t, api.PostContestEntry(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.PostContestEntry(c))
+			}
+		})
+	}
+}
+
+func TestPostBallot(t *testing.T) {
+	api := testAPIServer()
+	voting := test_store.ContestStart.AddDate(0, 0, 8)
+	cases := []struct {
+		name    string
+		now     time.Time
+		id      string
+		userId  int
+		reqBody string
+		isValid bool
+	}{
+		{
+			name:    "single vote",
+			now:     voting,
+			id:      "1",
+			userId:  2,
+			reqBody: `{"hokkuIds":[1]}`,
+			isValid: true,
+		},
+		{
+			name:    "second vote",
+			now:     voting,
+			id:      "1",
+			userId:  2,
+			reqBody: `{"hokkuIds":[1]}`,
+			isValid: false,
+		},
+		{
+			name:    "own hokku",
+			now:     voting,
+			id:      "1",
+			userId:  1,
+			reqBody: `{"hokkuIds":[1]}`,
+			isValid: false,
+		},
+		{
+			name:    "not an entry",
+			now:     voting,
+			id:      "1",
+			userId:  1,
+			reqBody: `{"hokkuIds":[3]}`,
+			isValid: false,
+		},
+		{
+			name:    "ranked vote in a single vote contest",
+			now:     voting,
+			id:      "1",
+			userId:  1,
+			reqBody: `{"hokkuIds":[5,1]}`,
+			isValid: false,
+		},
+		{
+			name:    "repeated choice",
+			now:     voting,
+			id:      "2",
+			userId:  1,
+			reqBody: `{"hokkuIds":[2,2]}`,
+			isValid: false,
+		},
+		{
+			name:    "voting is not open",
+			now:     test_store.ContestStart.Add(time.Hour),
+			id:      "2",
+			userId:  1,
+			reqBody: `{"hokkuIds":[2]}`,
+			isValid: false,
+		},
+		{
+			name:    "ranked vote",
+			now:     voting,
+			id:      "2",
+			userId:  1,
+			reqBody: `{"hokkuIds":[2]}`,
+			isValid: true,
+		},
+		{
+			name:    "not found",
+			now:     voting,
+			id:      "100",
+			userId:  1,
+			reqBody: `{"hokkuIds":[2]}`,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			api.Clock = clock.NewMock(cs.now)
+			req := httptest.NewRequest(echo.POST, "/restricted/contest/", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", cs.userId)
+			if cs.isValid {
+				assert.NoError(t, api.PostBallot(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.PostBallot(c))
+			}
+		})
+	}
+}
+
+func TestContestEntriesHidden(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	api.Clock = clock.NewMock(test_store.ContestStart.Add(time.Hour))
+	// An entry made private after it was submitted leaves the contest
+	req := httptest.NewRequest(echo.PUT, "/hokku", strings.NewReader(`{"title":"Title5","content":"Content","visibility":"private"}`))
+	c := api.Echo.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues("5")
+	c.Set("userId", 2)
+	assert.NoError(t, api.PutHokku(c))
+
+	rec := httptest.NewRecorder()
+	c = api.Echo.NewContext(httptest.NewRequest(echo.GET, "/contest/", nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	assert.NoError(t, api.GetContest(c))
+	details := &models.ContestDetails{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), details))
+	if assert.Len(t, details.Entries, 1) {
+		assert.Equal(t, 1, details.Entries[0].Id)
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Get random hokku
+// @Description Get a random hokku, optionally of the theme
+// @Tags Open routes
+// @Accept json
+// @Produce json
+// @Param themeId query int false "Theme id"
+// @Success 200 {object} models.Hokku
+// @Failure 400 {object} echo.HTTPError "Bad request. Theme ID must be an integer"
+// @Failure 404 {object} echo.HTTPError "No hokkus found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /hokku/random [get]
+func (api *APIServer) GetRandomHokku(c echo.Context) error {
+	themeId := 0
+	if t := c.QueryParam("themeId"); t != "" {
+		var err error
+		themeId, err = strconv.Atoi(t)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Bad request. ThemeID must be an integer")
+		}
+	}
+	viewerId, _ := api.currentUserId(c)
+	h, err := api.store.GetRandomHokku(viewerId, themeId)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "No hokkus found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, h)
+}
+
+// dailyHokku returns the hokku of the current day in the location. The
+// first request of the day picks a hokku not picked within the repeat
+// window and saves the pick, later requests get the saved one. When the
+// picked hokku is no longer public a replacement is picked the same way but
+// not saved.
+func (api *APIServer) dailyHokku(loc *time.Location) (*models.Hokku, error) {
+	now := api.Clock.Now().In(loc)
+	day := now.Format(models.DayLayout)
+	since := now.AddDate(0, 0, -api.dailyRepeat).Format(models.DayLayout)
+	for retried := false; ; retried = true {
+		picks, err := api.store.GetDailyPicks(loc.String(), since)
+		if err != nil {
+			return nil, err
+		}
+		excluded := map[int]bool{}
+		saved := false
+		for _, p := range picks {
+			if p.Day == day {
+				h, err := api.store.GetHokku(0, p.HokkuId)
+				if err == nil && h.Listed(0, false) {
+					return h, nil
+				}
+				if err != nil && !errors.Is(err, store.ErrNoRecord) {
+					return nil, err
+				}
+				saved = true
+			}
+			excluded[p.HokkuId] = true
+		}
+		ids, err := api.store.GetPublicHokkuIds()
+		if err != nil {
+			return nil, err
+		}
+		id, ok := models.PickDaily(day, loc.String(), ids, excluded)
+		if !ok {
+			return nil, store.ErrNoRecord
+		}
+		if !saved {
+			err = api.store.CreateDailyPick(&models.DailyPick{Day: day, Timezone: loc.String(), HokkuId: id})
+			if errors.Is(err, store.ErrAlreadyExist) && !retried {
+				// Another server has just picked the hokku of the day
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return api.store.GetHokku(0, id)
+	}
+}
+
+// @Summary Get hokku of the day
+// @Description Get the hokku of the current day in the timezone. Every day gets one hokku that is not repeated within the configured number of days
+// @Tags Open routes
+// @Accept json
+// @Produce json
+// @Param tz query string false "IANA timezone, UTC by default"
+// @Success 200 {object} models.Hokku
+// @Failure 400 {object} echo.HTTPError "Unknown timezone"
+// @Failure 404 {object} echo.HTTPError "No hokkus found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /hokku/daily [get]
+func (api *APIServer) GetDailyHokku(c echo.Context) error {
+	tz := c.QueryParam("tz")
+	if tz == "" {
+		tz = "UTC"
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown timezone")
+	}
+	h, err := api.dailyHokku(loc)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "No hokkus found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, h)
+}
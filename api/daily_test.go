@@ -0,0 +1,117 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRandomHokku(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		themeId string
+		isValid bool
+	}{
+		{
+			name:    "any theme",
+			isValid: true,
+		},
+		{
+			name:    "theme",
+			themeId: "2",
+			isValid: true,
+		},
+		{
+			name:    "theme without hokkus",
+			themeId: "3",
+			isValid: false,
+		},
+		{
+			name:    "invalid theme id",
+			themeId: "id",
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/hokku/random?themeId="+cs.themeId, nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			if !cs.isValid {
+				assert.Error(t, api.GetRandomHokku(c))
+				return
+			}
+			assert.NoError(t, api.GetRandomHokku(c))
+			h := &models.Hokku{}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), h))
+			assert.True(t, h.Listed(0, false))
+			if cs.themeId != "" {
+				assert.Equal(t, 2, h.ThemeId)
+			}
+		})
+	}
+}
+
+func getDailyHokku(t *testing.T, api *api.APIServer, tz string) *models.Hokku {
+	req := httptest.NewRequest(echo.GET, "/hokku/daily?tz="+tz, nil)
+	rec := httptest.NewRecorder()
+	c := api.Echo.NewContext(req, rec)
+	assert.NoError(t, api.GetDailyHokku(c))
+	h := &models.Hokku{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), h))
+	return h
+}
+
+func TestGetDailyHokku(t *testing.T) {
+	s := test_store.New()
+	conf := &config.Server{DailyRepeatDays: 30}
+	clk := clock.NewMock(time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC))
+	first, second := api.New(conf, s), api.New(conf, s)
+	first.Clock, second.Clock = clk, clk
+
+	// The pick is stable within the day and shared by the servers
+	h := getDailyHokku(t, first, "")
+	assert.Equal(t, h.Id, getDailyHokku(t, first, "UTC").Id)
+	assert.Equal(t, h.Id, getDailyHokku(t, second, "UTC").Id)
+	assert.Len(t, s.DailyPicks, 1)
+
+	// Timezones get their own picks
+	getDailyHokku(t, first, "Asia/Tokyo")
+	assert.Len(t, s.DailyPicks, 2)
+
+	// Hokkus are not repeated while there are other ones
+	ids, _ := s.GetPublicHokkuIds()
+	seen := map[int]bool{h.Id: true}
+	for i := 1; i < len(ids); i++ {
+		clk.Add(24 * time.Hour)
+		h = getDailyHokku(t, second, "UTC")
+		assert.False(t, seen[h.Id])
+		seen[h.Id] = true
+	}
+	clk.Add(24 * time.Hour)
+	getDailyHokku(t, second, "UTC")
+
+	// A removed pick is replaced
+	assert.NoError(t, s.DeleteHokku(h.Id))
+	clk.Add(-24 * time.Hour)
+	assert.NotEqual(t, h.Id, getDailyHokku(t, first, "UTC").Id)
+
+	// So is a hidden one
+	h = getDailyHokku(t, first, "Asia/Tokyo")
+	assert.NoError(t, s.HideHokku(h.Id))
+	assert.NotEqual(t, h.Id, getDailyHokku(t, first, "Asia/Tokyo").Id)
+
+	req := httptest.NewRequest(echo.GET, "/hokku/daily?tz=Mars/Olympus", nil)
+	c := first.Echo.NewContext(req, httptest.NewRecorder())
+	assert.Error(t, first.GetDailyHokku(c))
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/labstack/echo/v4"
+)
+
+// checkDuplicates looks for the poems the hokku nearly duplicates and
+// rejects the hokku or returns the duplicates to be flagged depending on
+// the configured action. It returns nil when the hokku is accepted as is.
+func (api *APIServer) checkDuplicates(c echo.Context, h *models.Hokku) (*models.Duplicates, error) {
+	if api.duplicateAction == "" || api.duplicateAction == models.DuplicateAccept {
+		return nil, nil
+	}
+	userId, _ := api.currentUserId(c)
+	d, err := api.store.FindDuplicates(userId, models.Fingerprint(h.Content), api.duplicateDistance)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	if d.Empty() {
+		return nil, nil
+	}
+	if api.duplicateAction == models.DuplicateReject {
+		return nil, echo.NewHTTPError(http.StatusConflict, echo.Map{
+			"message":    "The hokku duplicates existing poems",
+			"hokkuIds":   d.HokkuIds,
+			"classicIds": d.ClassicIds,
+		})
+	}
+	return d, nil
+}
+
+// flagDuplicates sends the saved hokku to moderation if it has duplicates.
+// The hokku is already saved, so a failure is only logged.
+func (api *APIServer) flagDuplicates(c echo.Context, hokkuId int, d *models.Duplicates) {
+	if d == nil {
+		return
+	}
+	flag := &models.Flag{HokkuId: hokkuId, Reason: models.FlagDuplicate, Matches: d}
+	if _, err := api.store.CreateFlag(flag); err != nil {
+		c.Logger().Errorf("flag hokku %d: %v", hokkuId, err)
+	}
+}
+
+// @Summary Get classics
+// @Security cookieAuth
+// @Description Get the classic poems new hokkus are compared with
+// @Tags Admin routes
+// @Accept json
+// @Produce json
+// @Param limit query int false "Sample size"
+// @Param offset query int false "Number of items to skip"
+// @Success 200 {array} models.Classic
+// @Failure 400 {object} echo.HTTPError "Bad query parameters"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /admin/classics [get]
+func (api *APIServer) GetClassics(c echo.Context) error {
+	limit, offset, err := paginationParams(c)
+	if err != nil {
+		return err
+	}
+	result, err := api.store.GetClassics(limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Post classic
+// @Security cookieAuth
+// @Description Add a classic poem new hokkus are compared with
+// @Tags Admin routes
+// @Accept json
+// @Produce json
+// @Param classic body models.Classic true "New classic poem"
+// @Success 201 "Created"
+// @Failure 400 {object} echo.HTTPError "Dont pass validation"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /admin/classic [post]
+func (api *APIServer) PostClassic(c echo.Context) error {
+	classic := &models.Classic{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&classic); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	if err := classic.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
+	}
+	id, err := api.store.CreateClassic(classic)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	c.Response().Header().Set("Location", fmt.Sprintf("/admin/classic/%d", id))
+	return c.NoContent(http.StatusCreated)
+}
+
+// @Summary Delete classic
+// @Security cookieAuth
+// @Description Delete a classic poem from the corpus
+// @Tags Admin routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of classic"
+// @Success 204 "Deleted succesfuly"
+// @Failure 400 {object} echo.HTTPError "Bad request. Id must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
+// @Failure 404 {object} echo.HTTPError "A classic with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /admin/classic/{id} [delete]
+func (api *APIServer) DeleteClassic(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	if err := api.store.DeleteClassic(id); err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A classic with the specified ID was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	api.audit(c, &models.AuditEntry{Action: models.AuditClassicDelete, TargetType: models.TargetClassic, TargetId: id}, nil, nil)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Get flags
+// @Security cookieAuth
+// @Description Get the hokkus flagged for moderation from the newest flag
+// @Tags Admin routes
+// @Accept json
+// @Produce json
+// @Param limit query int false "Sample size"
+// @Param offset query int false "Number of items to skip"
+// @Success 200 {array} models.Flag
+// @Failure 400 {object} echo.HTTPError "Bad query parameters"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /admin/flags [get]
+func (api *APIServer) GetFlags(c echo.Context) error {
+	limit, offset, err := paginationParams(c)
+	if err != nil {
+		return err
+	}
+	result, err := api.store.GetFlags(limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, result)
+}
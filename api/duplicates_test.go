@@ -0,0 +1,161 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+const bashoCopy = `{"title":"Pond","content":"An old silent pond,\na frog jumps into the pond.\nSplash! Silence again.","ownerId":1,"themeId":1}`
+
+func TestPostHokkuDuplicates(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{DuplicateAction: models.DuplicateReject, DuplicateDistance: 8}, s)
+	cases := []struct {
+		name       string
+		userId     int
+		reqBody    string
+		hokkuIds   []int
+		classicIds []int
+		isValid    bool
+	}{
+		{
+			name:       "classic",
+			userId:     1,
+			reqBody:    bashoCopy,
+			classicIds: []int{1},
+			isValid:    false,
+		},
+		{
+			name:     "hokku of another user",
+			userId:   1,
+			reqBody:  `{"title":"Snow","content":"First snow!","ownerId":1,"themeId":1}`,
+			hokkuIds: []int{2},
+			isValid:  false,
+		},
+		{
+			name:    "own hokku",
+			userId:  2,
+			reqBody: `{"title":"Snow","content":"First snow!","ownerId":2,"themeId":1}`,
+			isValid: true,
+		},
+		{
+			name:    "original",
+			userId:  1,
+			reqBody: `{"title":"Crow","content":"On a withered branch\na crow has alighted","ownerId":1,"themeId":1}`,
+			isValid: true,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/restricted/hokku", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.Set("userId", cs.userId)
+			err := api.PostHokku(c)
+			if cs.isValid {
+				assert.NoError(t, err)
+				return
+			}
+			httpErr, ok := err.(*echo.HTTPError)
+			if assert.True(t, ok) {
+				assert.Equal(t, http.StatusConflict, httpErr.Code)
+				body, _ := json.Marshal(httpErr.Message)
+				d := &models.Duplicates{}
+				assert.NoError(t, json.Unmarshal(body, d))
+				assert.ElementsMatch(t, cs.hokkuIds, d.HokkuIds)
+				assert.ElementsMatch(t, cs.classicIds, d.ClassicIds)
+			}
+		})
+	}
+	assert.Empty(t, s.Flags)
+}
+
+func TestFlagDuplicates(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{DuplicateAction: models.DuplicateFlag, DuplicateDistance: 8}, s)
+	req := httptest.NewRequest(echo.POST, "/restricted/hokku", strings.NewReader(bashoCopy))
+	rec := httptest.NewRecorder()
+	c := api.Echo.NewContext(req, rec)
+	c.Set("userId", 1)
+	assert.NoError(t, api.PostHokku(c))
+
+	req = httptest.NewRequest(echo.GET, "/admin/flags", nil)
+	rec = httptest.NewRecorder()
+	c = api.Echo.NewContext(req, rec)
+	assert.NoError(t, api.GetFlags(c))
+	flags := []*models.Flag{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &flags))
+	if assert.Len(t, flags, 1) {
+		assert.Equal(t, len(test_store.Hokkus)+1, flags[0].HokkuId)
+		assert.Equal(t, models.FlagDuplicate, flags[0].Reason)
+		assert.Equal(t, []int{1}, flags[0].Matches.ClassicIds)
+	}
+}
+
+func TestPostClassic(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		reqBody string
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			reqBody: `{"title":"Crow","author":"Matsuo Basho","content":"On a withered branch\na crow has alighted"}`,
+			isValid: true,
+		},
+		{
+			name:    "no content",
+			reqBody: `{"title":"Crow","author":"Matsuo Basho"}`,
+			isValid: false,
+		},
+		{
+			name:    "bad body params",
+			reqBody: `{Error}`,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/admin/classic", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			if cs.isValid {
+				assert.NoError(t, api.PostClassic(c))
+				assert.Equal(t, "/admin/classic/2", rec.Header().Get("Location"))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.PostClassic(c))
+			}
+		})
+	}
+}
+
+func TestDeleteClassic(t *testing.T) {
+	api := testAPIServer()
+	for _, cs := range []struct {
+		id      string
+		isValid bool
+	}{{"1", true}, {"1", false}, {"id", false}} {
+		req := httptest.NewRequest(echo.DELETE, "/admin/classic/", nil)
+		c := api.Echo.NewContext(req, httptest.NewRecorder())
+		c.SetParamNames("id")
+		c.SetParamValues(cs.id)
+		if cs.isValid {
+			assert.NoError(t, api.DeleteClassic(c))
+		}
+		if !cs.isValid {
+			assert.Error(t, api.DeleteClassic(c))
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/labstack/echo/v4"
+)
+
+// textField is a user text screened by the content filter.
+type textField struct {
+	name string
+	text *string
+}
+
+// screen runs the texts through the content filter and replaces them with
+// the masked texts. It rejects the request or returns why the content must
+// be sent to moderation, nil when it needn't.
+func (api *APIServer) screen(fields ...textField) ([]string, error) {
+	if api.Filter == nil {
+		return nil, nil
+	}
+	var moderation []string
+	for _, f := range fields {
+		res := api.Filter.Check(*f.text)
+		if res.Rejected {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, echo.Map{
+				"message": "The text is rejected by the content filter",
+				"field":   f.name,
+				"reasons": res.Reasons,
+			})
+		}
+		*f.text = res.Text
+		if res.Moderated {
+			moderation = append(moderation, f.name+": "+strings.Join(res.Reasons, ", "))
+		}
+	}
+	return moderation, nil
+}
+
+// screenHokku screens the title and the content of a normalized hokku.
+// Masking keeps the lines, so they are split again from the content.
+func (api *APIServer) screenHokku(h *models.Hokku) ([]string, error) {
+	moderation, err := api.screen(textField{"title", &h.Title}, textField{"content", &h.Content})
+	if err != nil {
+		return nil, err
+	}
+	h.Lines = strings.Split(h.Content, "\n")
+	return moderation, nil
+}
+
+// reportFiltered sends the saved content to moderation on behalf of the
+// content filter. A report already waiting in the queue is kept, failures
+// are only logged.
+func (api *APIServer) reportFiltered(c echo.Context, report *models.Report, moderation []string) {
+	if len(moderation) == 0 {
+		return
+	}
+	report.ReporterId, report.Reason = 0, models.ReasonFilter
+	report.Comment = strings.Join(moderation, "; ")
+	if _, err := api.store.CreateReport(report); err != nil && !errors.Is(err, store.ErrAlreadyExist) {
+		c.Logger().Errorf("report filtered content of user %d: %v", report.AuthorId, err)
+	}
+}
+
+// @Summary Reload content filter
+// @Security cookieAuth
+// @Description Read the content filter rules and wordlists again
+// @Tags Admin routes
+// @Accept json
+// @Produce json
+// @Success 204 "OK"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
+// @Failure 500 {object} echo.HTTPError "The rules can't be read, the previous rules are kept"
+// @Router /admin/filter/reload [post]
+func (api *APIServer) ReloadFilter(c echo.Context) error {
+	if api.Filter == nil {
+		return c.NoContent(http.StatusNoContent)
+	}
+	if err := api.Filter.Reload(); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, echo.Map{
+			"message": "The rules can't be read, the previous rules are kept",
+			"error":   err.Error(),
+		})
+	}
+	api.audit(c, &models.AuditEntry{Action: models.AuditFilterReload}, nil, nil)
+	return c.NoContent(http.StatusNoContent)
+}
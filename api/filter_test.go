@@ -0,0 +1,121 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/filter"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func testFilter() *filter.Pipeline {
+	return filter.New(
+		filter.Step{Name: "profanity", Action: filter.ActionMask, Rule: filter.NewWordlist([]string{"shit"})},
+		filter.Step{Name: "links", Action: filter.ActionModerate, Rule: &filter.Links{Max: 0}},
+		filter.Step{Name: "repeats", Action: filter.ActionReject, Rule: &filter.Repeats{Max: 4}},
+	)
+}
+
+func TestPostHokkuFilter(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	api.Filter = testFilter()
+	cases := []struct {
+		name     string
+		reqBody  string
+		content  string
+		reported bool
+		isValid  bool
+	}{
+		{
+			name:    "masked",
+			reqBody: `{"title":"Rain","content":"Shit, the rain\nagain","ownerId":1,"themeId":1}`,
+			content: "****, the rain\nagain",
+			isValid: true,
+		},
+		{
+			name:     "sent to moderation",
+			reqBody:  `{"title":"Sale","content":"Cheap haiku\nat spam.com","ownerId":1,"themeId":1}`,
+			content:  "Cheap haiku\nat spam.com",
+			reported: true,
+			isValid:  true,
+		},
+		{
+			name:    "rejected",
+			reqBody: `{"title":"Wow","content":"Snow!!!!!!","ownerId":1,"themeId":1}`,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			reports := len(s.Reports)
+			req := httptest.NewRequest(echo.POST, "/restricted/hokku", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.Set("userId", 1)
+			err := api.PostHokku(c)
+			if !cs.isValid {
+				httpErr, ok := err.(*echo.HTTPError)
+				if assert.True(t, ok) {
+					assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+				}
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			h := s.Hokkus[len(s.Hokkus)-1]
+			assert.Equal(t, cs.content, h.Content)
+			assert.Equal(t, strings.Split(cs.content, "\n"), h.Lines)
+			if !cs.reported {
+				assert.Len(t, s.Reports, reports)
+				return
+			}
+			if assert.Len(t, s.Reports, reports+1) {
+				r := s.Reports[reports]
+				assert.Equal(t, 0, r.ReporterId)
+				assert.Equal(t, h.Id, r.HokkuId)
+				assert.Equal(t, 1, r.AuthorId)
+				assert.Equal(t, models.ReasonFilter, r.Reason)
+				assert.Equal(t, "content: links", r.Comment)
+			}
+		})
+	}
+}
+
+func TestPostCommentFilter(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	api.Filter = testFilter()
+	req := httptest.NewRequest(echo.POST, "/restricted/hokku/", strings.NewReader(`{"content":"Follow me at www.example.com"}`))
+	rec := httptest.NewRecorder()
+	c := api.Echo.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	c.Set("userId", 2)
+	if !assert.NoError(t, api.PostComment(c)) {
+		return
+	}
+	if assert.Len(t, s.Reports, 1) {
+		r := s.Reports[0]
+		assert.Equal(t, 1, r.HokkuId)
+		assert.Equal(t, s.Comments[len(s.Comments)-1].Id, r.CommentId)
+		assert.Equal(t, 2, r.AuthorId)
+	}
+}
+
+func TestReloadFilter(t *testing.T) {
+	api := testAPIServer()
+	req := httptest.NewRequest(echo.POST, "/admin/filter/reload", nil)
+	rec := httptest.NewRecorder()
+	c := api.Echo.NewContext(req, rec)
+	assert.NoError(t, api.ReloadFilter(c))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/prosody"
 	"github.com/EgorSkurihin/Hokku/store"
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo/v4"
@@ -28,25 +31,16 @@ func (api *APIServer) HealthCheck(c echo.Context) error {
 	})
 }
 
-// @Summary Get all hokkus
-// @Description Get all hokkus
-// @Tags Open routes
-// @Accept json
-// @Produce json
-// @Param limit query int false "Sample size"
-// @Param offset query int false "Number of items to skip"
-// @Success 200 {array} models.Hokku
-// @Failure 400 {object} echo.HTTPError "Bad query parameters"
-// @Failure 500 {object} echo.HTTPError "Unexpected error"
-// @Router /hokkus [get]
-func (api *APIServer) GetHokkus(c echo.Context) error {
+// paginationParams reads the limit and offset query parameters.
+// Limit defaults to 10.
+func paginationParams(c echo.Context) (int, int, error) {
 	var limit, offset int
 	var err error
 	l := c.QueryParam("limit")
 	if l != "" {
 		limit, err = strconv.Atoi(l)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "Limit must be a number")
+			return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Limit must be a number")
 		}
 	}
 	if limit == 0 {
@@ -54,12 +48,32 @@ func (api *APIServer) GetHokkus(c echo.Context) error {
 	}
 	o := c.QueryParam("offset")
 	if o != "" {
-		offset, err = strconv.Atoi(c.QueryParam("offset"))
+		offset, err = strconv.Atoi(o)
 		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "Offset must be a number")
+			return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "Offset must be a number")
 		}
 	}
-	result, err := api.store.GetHokkus(limit, offset)
+	return limit, offset, nil
+}
+
+// @Summary Get all hokkus
+// @Description Get all hokkus
+// @Tags Open routes
+// @Accept json
+// @Produce json
+// @Param limit query int false "Sample size"
+// @Param offset query int false "Number of items to skip"
+// @Success 200 {array} models.Hokku
+// @Failure 400 {object} echo.HTTPError "Bad query parameters"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /hokkus [get]
+func (api *APIServer) GetHokkus(c echo.Context) error {
+	limit, offset, err := paginationParams(c)
+	if err != nil {
+		return err
+	}
+	viewerId, _ := api.currentUserId(c)
+	result, err := api.store.GetHokkus(viewerId, limit, offset)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
 	}
@@ -79,30 +93,16 @@ func (api *APIServer) GetHokkus(c echo.Context) error {
 // @Failure 500 {object} echo.HTTPError "Unexpected error"
 // @Router /hokkus/byAuthor/{authorId} [get]
 func (api *APIServer) GetHokkusByAuthor(c echo.Context) error {
-	var limit, offset int
-	var err error
-	l := c.QueryParam("limit")
-	if l != "" {
-		limit, err = strconv.Atoi(l)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "Limit must be a number")
-		}
-	}
-	if limit == 0 {
-		limit = 10
-	}
-	o := c.QueryParam("offset")
-	if o != "" {
-		offset, err = strconv.Atoi(c.QueryParam("offset"))
-		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "Offset must be a number")
-		}
+	limit, offset, err := paginationParams(c)
+	if err != nil {
+		return err
 	}
 	authorId, err := strconv.Atoi(c.Param("authorId"))
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. ThemeID must be an integer")
 	}
-	result, err := api.store.GetHokkusByAuthor(authorId, limit, offset)
+	viewerId, _ := api.currentUserId(c)
+	result, err := api.store.GetHokkusByAuthor(viewerId, authorId, limit, offset)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
 	}
@@ -117,35 +117,29 @@ func (api *APIServer) GetHokkusByAuthor(c echo.Context) error {
 // @Param limit query int false "Sample size"
 // @Param offset query int false "Number of items to skip"
 // @Param themeId path int true "thme Id"
+// @Param descendants query bool false "Include hokkus of the subthemes"
 // @Success 200 {array} models.Hokku
 // @Failure 400 {object} echo.HTTPError "Bad query parameters"
 // @Failure 500 {object} echo.HTTPError "Unexpected error"
 // @Router /hokkus/byTheme/{themeId} [get]
 func (api *APIServer) GetHokkusByTheme(c echo.Context) error {
-	var limit, offset int
-	var err error
-	l := c.QueryParam("limit")
-	if l != "" {
-		limit, err = strconv.Atoi(l)
-		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "Limit must be a number")
-		}
-	}
-	if limit == 0 {
-		limit = 10
-	}
-	o := c.QueryParam("offset")
-	if o != "" {
-		offset, err = strconv.Atoi(c.QueryParam("offset"))
-		if err != nil {
-			return echo.NewHTTPError(http.StatusBadRequest, "Offset must be a number")
-		}
+	limit, offset, err := paginationParams(c)
+	if err != nil {
+		return err
 	}
 	themeId, err := strconv.Atoi(c.Param("themeId"))
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. ThemeID must be an integer")
 	}
-	result, err := api.store.GetHokkusByTheme(themeId, limit, offset)
+	descendants := false
+	if d := c.QueryParam("descendants"); d != "" {
+		descendants, err = strconv.ParseBool(d)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Descendants must be a boolean")
+		}
+	}
+	viewerId, _ := api.currentUserId(c)
+	result, err := api.store.GetHokkusByTheme(viewerId, themeId, descendants, limit, offset)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
 	}
@@ -153,7 +147,7 @@ func (api *APIServer) GetHokkusByTheme(c echo.Context) error {
 }
 
 // @Summary Get hokku
-// @Description Get hokku by ID
+// @Description Get hokku by ID. Unlisted hokkus are available by ID only, followers-only and private ones require an authorized viewer
 // @Tags Open routes
 // @Accept json
 // @Produce json
@@ -168,38 +162,68 @@ func (api *APIServer) GetHokku(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
 	}
-	hokku, err := api.store.GetHokku(id)
+	viewerId, _ := api.currentUserId(c)
+	hokku, err := api.store.GetHokku(viewerId, id)
 	if err != nil {
 		if errors.Is(err, store.ErrNoRecord) {
 			return echo.NewHTTPError(http.StatusNotFound, "A hokku with the specified ID was not found")
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
 	}
+	api.countView(c, viewerId, hokku)
 	return c.JSON(http.StatusOK, hokku)
 }
 
 // @Summary Post hokku
 // @Security cookieAuth
-// @Description Create new hokku in Store. Reurn location of new object in header
+// @Description Create new hokku of the current user in Store. Reurn location of new object in header
 // @Tags Restricted routes
 // @Accept json
 // @Produce json
 // @Param hokku body models.Hokku true "New Hokku"
+// @Param strict query bool false "Require the 5-7-5 form even if the theme does not"
 // @Success 201 "Created"
-// @Failure 400 {object} echo.HTTPError "Dont pass validation"
+// @Failure 400 {object} echo.HTTPError "Dont pass validation or the 5-7-5 form check, or the publication time has passed"
 // @Failure 401 {object} echo.HTTPError "The request requires user authentication"
-// @Failure 409 {object} echo.HTTPError "Foreign key constraint fails"
+// @Failure 403 {object} echo.HTTPError "Posting to the theme is closed or posting is suspended"
+// @Failure 409 {object} echo.HTTPError "Foreign key constraint fails or the hokku duplicates existing poems, their ids are listed"
 // @Failure 500 {object} echo.HTTPError "Unexpected error"
 // @Router /restricted/hokku [post]
 func (api *APIServer) PostHokku(c echo.Context) error {
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
 	h := &models.Hokku{}
 	decoder := json.NewDecoder(c.Request().Body)
 	if err := decoder.Decode(&h); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
 	}
+	h.OwnerId = userId
+	// Stanzas are added to chains through their own route
+	h.ChainId, h.ParentId, h.Position = 0, 0, 0
+	h.Normalize()
 	if err := h.Validate(); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
 	}
+	if err := api.checkSchedule(h); err != nil {
+		return err
+	}
+	if err := api.checkTheme(c, h); err != nil {
+		return err
+	}
+	if err := api.attachSeasons(h); err != nil {
+		return err
+	}
+	moderation, err := api.screenHokku(h)
+	if err != nil {
+		return err
+	}
+	duplicates, err := api.checkDuplicates(c, h)
+	if err != nil {
+		return err
+	}
+	h.BeforeSave()
 	id, err := api.store.CreateHokku(h)
 	if err != nil {
 		if errors.Is(err, store.ErrForeignKeyConstraint) {
@@ -207,13 +231,15 @@ func (api *APIServer) PostHokku(c echo.Context) error {
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
 	}
+	api.flagDuplicates(c, id, duplicates)
+	api.reportFiltered(c, &models.Report{HokkuId: id, AuthorId: h.OwnerId}, moderation)
 	c.Response().Header().Set("Location", fmt.Sprintf("/hokku/%d", id))
 	return c.NoContent(http.StatusCreated)
 }
 
 // @Summary Delete hokku
 // @Security cookieAuth
-// @Description Delete hokku from Store
+// @Description Move hokku to the trash. It can be restored during the retention period
 // @Tags Restricted routes
 // @Accept json
 // @Produce json
@@ -221,6 +247,7 @@ func (api *APIServer) PostHokku(c echo.Context) error {
 // @Success 204 "Deleted succesfuly"
 // @Failure 400 {object} echo.HTTPError "Bad request. Hokku ID must be an integer and larger than 0"
 // @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The hokku belongs to another user"
 // @Failure 404 {object} echo.HTTPError "A hokku with the specified ID was not found"
 // @Failure 500 {object} echo.HTTPError "Unexpected error"
 // @Router /restricted/hokku/{id} [delete]
@@ -229,26 +256,39 @@ func (api *APIServer) DeleteHokku(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
 	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	before, err := api.ownHokku(userId, id)
+	if err != nil {
+		return err
+	}
 	if err = api.store.DeleteHokku(id); err != nil {
 		if errors.Is(err, store.ErrNoRecord) {
 			return echo.NewHTTPError(http.StatusNotFound, "A hokku with the specified ID was not exist")
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
 	}
+	api.audit(c, &models.AuditEntry{Action: models.AuditHokkuDelete, TargetType: models.TargetHokku, TargetId: id}, before, nil)
 	return c.NoContent(http.StatusNoContent)
 }
 
 // @Summary Put hokku
 // @Security cookieAuth
-// @Description Update hokku in store
+// @Description Update hokku of the current user in store. The owner and the theme are kept. Omitted status and visibility keep their values. Scheduled hokkus must be published in the future. Stanzas of a chain keep their status and the form of their position
 // @Tags Restricted routes
 // @Accept json
 // @Produce json
 // @Param id path  int  true  "id of hokku"
 // @Param hokku body models.Hokku true "Put Hokku"
+// @Param strict query bool false "Require the 5-7-5 form even if the theme does not"
 // @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Dont pass validation, the 5-7-5 form check or the form of the stanza, or the publication time has passed"
 // @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "Posting to the theme is closed, posting is suspended or the hokku belongs to another user"
 // @Failure 404 {object} echo.HTTPError "Not Found"
+// @Failure 409 {object} echo.HTTPError "The hokku duplicates existing poems, their ids are listed"
 // @Failure 500 {object} echo.HTTPError "Unexpected error"
 // @Router /restricted/hokku [put]
 func (api *APIServer) PutHokku(c echo.Context) error {
@@ -257,25 +297,263 @@ func (api *APIServer) PutHokku(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest)
 	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
 	decoder := json.NewDecoder(c.Request().Body)
 	if err := decoder.Decode(&h); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
 	}
+	stored, err := api.ownHokku(userId, id)
+	if err != nil {
+		return err
+	}
+	// The owner and the theme of a hokku don't change, nor do the place and
+	// the status of a stanza
+	h.OwnerId, h.ThemeId = stored.OwnerId, stored.ThemeId
+	h.ChainId, h.Position = stored.ChainId, stored.Position
+	if h.ChainId != 0 {
+		h.Status, h.Visibility, h.PublishAt = "", "", nil
+	}
+	// Omitted status and visibility keep their stored values
+	rescheduled := h.Status != ""
+	if !rescheduled {
+		h.Status, h.PublishAt = stored.Status, stored.PublishAt
+	}
+	if h.Visibility == "" {
+		h.Visibility = stored.Visibility
+	}
+	h.Normalize()
 	if err := h.Validate(); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
 	}
+	if rescheduled {
+		if err := api.checkSchedule(h); err != nil {
+			return err
+		}
+	}
+	if h.ChainId != 0 {
+		if err := checkPattern(h, models.StanzaPattern(h.Position)); err != nil {
+			return err
+		}
+	}
+	if err := api.checkTheme(c, h); err != nil {
+		return err
+	}
+	if err := api.attachSeasons(h); err != nil {
+		return err
+	}
+	moderation, err := api.screenHokku(h)
+	if err != nil {
+		return err
+	}
+	duplicates, err := api.checkDuplicates(c, h)
+	if err != nil {
+		return err
+	}
 	h.Id = id
+	h.BeforeSave()
 	if err := api.store.UpdateHokku(h); err != nil {
 		if errors.Is(err, store.ErrNoRecord) {
 			return echo.NewHTTPError(http.StatusNotFound, "A hokku with the specified ID was not found")
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
 	}
+	api.flagDuplicates(c, id, duplicates)
+	api.reportFiltered(c, &models.Report{HokkuId: id, AuthorId: h.OwnerId}, moderation)
 	return c.NoContent(http.StatusNoContent)
 }
 
+// ownHokku returns the hokku if it belongs to the user.
+func (api *APIServer) ownHokku(userId, id int) (*models.Hokku, error) {
+	h, err := api.store.GetHokku(userId, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "A hokku with the specified ID was not found")
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	if h.OwnerId != userId {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "The hokku belongs to another user")
+	}
+	return h, nil
+}
+
+// checkSchedule rejects hokkus scheduled for a time that has passed.
+func (api *APIServer) checkSchedule(h *models.Hokku) error {
+	if h.Status == models.StatusScheduled && h.PublishAt != nil && !h.PublishAt.After(api.Clock.Now()) {
+		return echo.NewHTTPError(http.StatusBadRequest, "The publication time must be in the future")
+	}
+	return nil
+}
+
+// checkTheme rejects hokkus posted to a closed theme and hokkus that don't
+// follow the 5-7-5 form when the client asks for strict mode or the theme
+// of the hokku is strict. The form error lists the syllables counted in
+// every line.
+func (api *APIServer) checkTheme(c echo.Context, h *models.Hokku) error {
+	strict := false
+	if s := c.QueryParam("strict"); s != "" {
+		var err error
+		strict, err = strconv.ParseBool(s)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Strict must be a boolean")
+		}
+	}
+	if h.ThemeId != 0 {
+		theme, err := api.store.GetTheme(h.ThemeId)
+		if err != nil && !errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+		}
+		if err == nil && theme.PostingClosed {
+			return echo.NewHTTPError(http.StatusForbidden, "Posting to the theme is closed")
+		}
+		strict = strict || err == nil && theme.Strict
+	}
+	if !strict {
+		return nil
+	}
+	return checkPattern(h, prosody.Pattern)
+}
+
+// checkPattern rejects hokkus whose lines don't follow the syllable
+// pattern. The error lists the syllables counted in every line.
+func checkPattern(h *models.Hokku, pattern []int) error {
+	var formErr *prosody.FormError
+	if err := prosody.CheckPattern(h.Content, pattern); errors.As(err, &formErr) {
+		return echo.NewHTTPError(http.StatusBadRequest, echo.Map{
+			"message": fmt.Sprintf("The hokku doesn`t follow the %s form: %s", patternName(pattern), formErr.Error()),
+			"lines":   formErr.Lines,
+		})
+	}
+	return nil
+}
+
+func patternName(pattern []int) string {
+	parts := make([]string, len(pattern))
+	for i, n := range pattern {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, "-")
+}
+
+// @Summary Analyze hokku
+// @Description Split the hokku content into lines and count syllables without saving it
+// @Tags Open routes
+// @Accept json
+// @Produce json
+// @Param hokku body models.Hokku true "The hokku object can only contain content or lines"
+// @Success 200 {object} prosody.Analysis
+// @Failure 400 {object} echo.HTTPError "Bad request params"
+// @Router /analyze [post]
+func (api *APIServer) Analyze(c echo.Context) error {
+	h := &models.Hokku{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&h); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	h.Normalize()
+	return c.JSON(http.StatusOK, prosody.Analyze(h.Content))
+}
+
+// @Summary Get drafts
+// @Security cookieAuth
+// @Description Get drafts and scheduled hokkus of the current user
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param limit query int false "Sample size"
+// @Param offset query int false "Number of items to skip"
+// @Success 200 {array} models.Hokku
+// @Failure 400 {object} echo.HTTPError "Bad query parameters"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/drafts [get]
+func (api *APIServer) GetDrafts(c echo.Context) error {
+	limit, offset, err := paginationParams(c)
+	if err != nil {
+		return err
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	result, err := api.store.GetDrafts(userId, limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Get trash
+// @Security cookieAuth
+// @Description Get deleted hokkus of the current user that can still be restored
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param limit query int false "Sample size"
+// @Param offset query int false "Number of items to skip"
+// @Success 200 {array} models.Hokku
+// @Failure 400 {object} echo.HTTPError "Bad query parameters"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/trash [get]
+func (api *APIServer) GetTrash(c echo.Context) error {
+	limit, offset, err := paginationParams(c)
+	if err != nil {
+		return err
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	result, err := api.store.GetTrash(userId, api.restorableSince(), limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Restore hokku
+// @Security cookieAuth
+// @Description Restore deleted hokku of the current user from the trash
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path  int  true  "id of hokku"
+// @Success 204 "Restored succesfuly"
+// @Failure 400 {object} echo.HTTPError "Bad request. Hokku ID must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 404 {object} echo.HTTPError "A hokku with the specified ID was not found in the trash"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/hokku/{id}/restore [post]
+func (api *APIServer) RestoreHokku(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	if err := api.store.RestoreHokku(userId, id, api.restorableSince()); err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A hokku with the specified ID was not found in the trash")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// restorableSince returns the earliest deletion time of items that can
+// still be restored from the trash.
+func (api *APIServer) restorableSince() time.Time {
+	return api.Clock.Now().Add(-api.trashRetention)
+}
+
 // @Summary Get user
-// @Description Get user by ID
+// @Description Get user by ID. The role is shown only to the user and to moderators, the hidden flag only to moderators
 // @Tags Open routes
 // @Accept json
 // @Produce json
@@ -297,7 +575,21 @@ func (api *APIServer) GetUser(c echo.Context) error {
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
 	}
-	return c.JSON(http.StatusOK, user)
+	view := *user
+	viewerId, _ := api.currentUserId(c)
+	viewer, err := api.store.GetUser(viewerId)
+	if err != nil && !errors.Is(err, store.ErrNoRecord) {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	if viewer == nil || !viewer.IsModerator() {
+		// Users don't learn that moderators hid them, and only they
+		// see their own role.
+		view.Hidden = false
+		if viewerId != id {
+			view.Role = ""
+		}
+	}
+	return c.JSON(http.StatusOK, &view)
 }
 
 // @Summary Post user
@@ -320,6 +612,11 @@ func (api *APIServer) PostUser(c echo.Context) error {
 	if err := u.Validate(); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
 	}
+	moderation, err := api.screen(textField{"name", &u.Name})
+	if err != nil {
+		return err
+	}
+	u.Role = models.RoleUser
 	if err := u.BeforeCreate(); err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
 	}
@@ -330,13 +627,14 @@ func (api *APIServer) PostUser(c echo.Context) error {
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
 	}
+	api.reportFiltered(c, &models.Report{AuthorId: id}, moderation)
 	c.Response().Header().Set("Location", fmt.Sprintf("/user/%d", id))
 	return c.NoContent(http.StatusCreated)
 }
 
 // @Summary Put user
 // @Security cookieAuth
-// @Description Update user in store
+// @Description Update user in store. Users update only themselves unless they are admins, only admins send a changed role
 // @Tags Restricted routes
 // @Accept json
 // @Produce json
@@ -344,6 +642,7 @@ func (api *APIServer) PostUser(c echo.Context) error {
 // @Param user body models.User true "Put User"
 // @Success 204 "OK"
 // @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The user is another one or the role is changed by a non-admin"
 // @Failure 404 {object} echo.HTTPError "A user with the specified ID was not found"
 // @Failure 500 {object} echo.HTTPError "Unexpected error"
 // @Router /restricted/user [put]
@@ -353,6 +652,10 @@ func (api *APIServer) PutUser(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest)
 	}
+	caller, err := api.selfOrAdmin(c, id)
+	if err != nil {
+		return err
+	}
 	decoder := json.NewDecoder(c.Request().Body)
 	if err := decoder.Decode(&u); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
@@ -360,6 +663,14 @@ func (api *APIServer) PutUser(c echo.Context) error {
 	if err := u.Validate(); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
 	}
+	before := api.userSnapshot(id)
+	if before != nil && u.Role != "" && u.Role != before.Role && !caller.IsAdmin() {
+		return echo.NewHTTPError(http.StatusForbidden, "Only admins change roles")
+	}
+	moderation, err := api.screen(textField{"name", &u.Name})
+	if err != nil {
+		return err
+	}
 	u.Id = id
 	if err := api.store.UpdateUser(u); err != nil {
 		if errors.Is(err, store.ErrNoRecord) {
@@ -367,12 +678,15 @@ func (api *APIServer) PutUser(c echo.Context) error {
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
 	}
+	api.audit(c, &models.AuditEntry{Action: models.AuditUserUpdate, TargetType: models.TargetUser, TargetId: id},
+		before, api.userSnapshot(id))
+	api.reportFiltered(c, &models.Report{AuthorId: id}, moderation)
 	return c.NoContent(http.StatusNoContent)
 }
 
 // @Summary Delete user
 // @Security cookieAuth
-// @Description Delete user from Store
+// @Description Move user and their hokkus to the trash. They can be restored during the retention period. The webhooks of the user are removed. Users delete only themselves unless they are admins
 // @Tags Restricted routes
 // @Accept json
 // @Produce json
@@ -380,6 +694,7 @@ func (api *APIServer) PutUser(c echo.Context) error {
 // @Success 204 "Deleted succesfuly"
 // @Failure 400 {object} echo.HTTPError "Bad request. User ID must be an integer and larger than 0"
 // @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The user is another one"
 // @Failure 404 {object} echo.HTTPError "A user with the specified ID was not found"
 // @Failure 500 {object} echo.HTTPError "Unexpected error"
 // @Router /restricted/user/{id} [delete]
@@ -388,12 +703,161 @@ func (api *APIServer) DeleteUser(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest)
 	}
-	if err = api.store.DeleteUser(id); err != nil {
+	if _, err := api.selfOrAdmin(c, id); err != nil {
+		return err
+	}
+	before := api.userSnapshot(id)
+	err = api.store.WithTx(c.Request().Context(), func(tx store.Store) error {
+		return deleteUser(tx, id)
+	})
+	if err != nil {
 		if errors.Is(err, store.ErrNoRecord) {
 			return echo.NewHTTPError(http.StatusNotFound)
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError)
 	}
+	api.audit(c, &models.AuditEntry{Action: models.AuditUserDelete, TargetType: models.TargetUser, TargetId: id}, before, nil)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// deleteUser deletes the user along with their webhooks, since a deleted
+// user can't manage them. Run it in a transaction.
+func deleteUser(s store.Store, id int) error {
+	if err := s.DeleteUser(id); err != nil {
+		return err
+	}
+	hooks, err := s.GetWebhooks(id)
+	if err != nil {
+		return err
+	}
+	for _, w := range hooks {
+		if err := s.DeleteWebhook(w.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selfOrAdmin returns the current user if they are the user with the id or
+// an admin.
+func (api *APIServer) selfOrAdmin(c echo.Context, id int) (*models.User, error) {
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	caller, err := api.store.GetUser(userId)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return nil, echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	if userId != id && !caller.IsAdmin() {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "Users can change only themselves")
+	}
+	return caller, nil
+}
+
+// @Summary Follow user
+// @Security cookieAuth
+// @Description Follow user. Followers can read followers-only hokkus of the user
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path  int  true  "id of user"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Bad request. User ID must be an integer and not the current user"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The user has blocked you"
+// @Failure 404 {object} echo.HTTPError "A user with the specified ID was not found"
+// @Failure 409 {object} echo.HTTPError "User is already followed"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/user/{id}/follow [post]
+func (api *APIServer) Follow(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	if userId == id {
+		return echo.NewHTTPError(http.StatusBadRequest, "Users can`t follow themselves")
+	}
+	if err := api.store.Follow(userId, id); err != nil {
+		if errors.Is(err, store.ErrForeignKeyConstraint) {
+			return echo.NewHTTPError(http.StatusNotFound, "A user with the specified ID was not found")
+		}
+		if errors.Is(err, store.ErrAlreadyExist) {
+			return echo.NewHTTPError(http.StatusConflict, "User is already followed")
+		}
+		if errors.Is(err, store.ErrBlocked) {
+			return echo.NewHTTPError(http.StatusForbidden, "The user has blocked you")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	api.notify(c, &models.Notification{UserId: id, Type: models.NotificationFollow, ActorId: userId})
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Unfollow user
+// @Security cookieAuth
+// @Description Stop following user
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path  int  true  "id of user"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Bad request. User ID must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 404 {object} echo.HTTPError "User is not followed"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/user/{id}/follow [delete]
+func (api *APIServer) Unfollow(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	if err := api.store.Unfollow(userId, id); err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "User is not followed")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Restore user
+// @Security cookieAuth
+// @Description Restore the deleted user together with the hokkus deleted along with them. Users restore themselves by logging in with restore set
+// @Tags Admin routes
+// @Accept json
+// @Produce json
+// @Param id path  int  true  "id of user"
+// @Success 204 "Restored succesfuly"
+// @Failure 400 {object} echo.HTTPError "Bad request. User ID must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
+// @Failure 404 {object} echo.HTTPError "A user with the specified ID was not found in the trash"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /admin/user/{id}/restore [post]
+func (api *APIServer) RestoreUser(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	if err := api.store.RestoreUser(id, api.restorableSince()); err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A user with the specified ID was not found in the trash")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	api.audit(c, &models.AuditEntry{Action: models.AuditUserRestore, TargetType: models.TargetUser, TargetId: id}, nil, api.userSnapshot(id))
 	return c.NoContent(http.StatusNoContent)
 }
 
@@ -414,34 +878,98 @@ func (api *APIServer) GetThemes(c echo.Context) error {
 	return c.JSON(http.StatusOK, result)
 }
 
+// @Summary Get theme
+// @Description Get theme by ID or slug with its subthemes and numbers of public hokkus, subthemes included
+// @Tags Open routes
+// @Accept json
+// @Produce json
+// @Param id path string true "id or slug of theme"
+// @Success 200 {object} models.ThemeDetails
+// @Failure 404 {object} echo.HTTPError "A theme with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /themes/{id} [get]
+func (api *APIServer) GetTheme(c echo.Context) error {
+	var theme *models.Theme
+	var err error
+	if id, convErr := strconv.Atoi(c.Param("id")); convErr == nil {
+		theme, err = api.store.GetTheme(id)
+	} else {
+		theme, err = api.store.GetThemeBySlug(c.Param("id"))
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A theme with the specified ID was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	themes, err := api.store.GetThemes()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	counts, err := api.store.CountHokkusByTheme()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, models.NewThemeDetails(theme, themes, counts))
+}
+
 // @Summary Authenticate
-// @Description Login
+// @Description Login. Deleted users are restored with their hokkus when they log in with restore set during the retention
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param user body models.User true "The user object can only contain email and password"
+// @Param restore query bool false "Restore the deleted user"
 // @Success 200
 // @Failure 400 {object} echo.HTTPError "Wrong email or passowrd"
+// @Failure 403 {object} echo.HTTPError "The user is banned, the reason and the expiry are given"
 // @Failure 404 {object} echo.HTTPError "A user with the specified Email was not found"
 // @Failure 500 {object} echo.HTTPError "Unexpected error"
 // @Router /login [post]
 func (api *APIServer) Login(c echo.Context) error {
+	restore := false
+	if r := c.QueryParam("restore"); r != "" {
+		var err error
+		restore, err = strconv.ParseBool(r)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Restore must be a boolean")
+		}
+	}
 	formUser := &models.User{}
 	decoder := json.NewDecoder(c.Request().Body)
 	if err := decoder.Decode(&formUser); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
 	}
 	dbUser, err := api.store.GetUserByEmail(formUser.Email)
+	if errors.Is(err, store.ErrNoRecord) && restore {
+		dbUser, err = api.store.GetDeletedUserByEmail(formUser.Email, api.restorableSince())
+	}
 	if err != nil {
 		if errors.Is(err, store.ErrNoRecord) {
+			api.audit(c, &models.AuditEntry{Action: models.AuditLoginFailed, Details: "Unknown email " + formUser.Email}, nil, nil)
 			return echo.NewHTTPError(http.StatusNotFound)
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError)
 	}
+	failed := &models.AuditEntry{Action: models.AuditLoginFailed, TargetType: models.TargetUser, TargetId: dbUser.Id}
 	err = bcrypt.CompareHashAndPassword([]byte(dbUser.HashedPassword), []byte(formUser.OpenPassword))
 	if err != nil {
+		failed.Details = "Wrong password"
+		api.audit(c, failed, nil, nil)
 		return echo.NewHTTPError(http.StatusBadRequest, "Wrong email or passowrd")
 	}
+	if err := api.checkRestriction(dbUser.Id, models.RestrictionBan, "The user is banned"); err != nil {
+		failed.Details = "The user is banned"
+		api.audit(c, failed, nil, nil)
+		return err
+	}
+	if dbUser.DeletedAt != nil {
+		if err := api.store.RestoreUser(dbUser.Id, api.restorableSince()); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+		}
+		api.audit(c, &models.AuditEntry{ActorId: dbUser.Id, Action: models.AuditUserRestore, TargetType: models.TargetUser, TargetId: dbUser.Id}, nil, nil)
+	}
+	api.audit(c, &models.AuditEntry{ActorId: dbUser.Id, Action: models.AuditLogin, TargetType: models.TargetUser, TargetId: dbUser.Id}, nil, nil)
 	session, _ := api.sessionStore.Get(c.Request(), "session")
 	session.Options = &sessions.Options{
 		Path:     "/",
@@ -453,16 +981,18 @@ func (api *APIServer) Login(c echo.Context) error {
 	return c.NoContent(http.StatusOK)
 }
 
-/*
 // @Summary Post theme
-// @Description Create new user in Store. Return location of new user in header
-// @Tags theme
+// @Security cookieAuth
+// @Description Create new theme in Store. The slug is derived from the title when omitted. Return location of new theme in header
+// @Tags Admin routes
 // @Accept json
 // @Produce json
 // @Param theme body models.Theme true "New theme"
 // @Success 201 "Created"
-// @Failure 400 {object} echo.HTTPError "Dont pass validation"
-// @Failure 409 {object} echo.HTTPError "Theme with this title already exists"
+// @Failure 400 {object} echo.HTTPError "Dont pass validation or the parent theme was not found"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
+// @Failure 409 {object} echo.HTTPError "Theme with this title or slug already exists"
 // @Failure 500 {object} echo.HTTPError "Unexpected error"
 // @Router /admin/theme [post]
 func (api *APIServer) PostTheme(c echo.Context) error {
@@ -474,25 +1004,93 @@ func (api *APIServer) PostTheme(c echo.Context) error {
 	if err := t.Validate(); err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
 	}
+	t.BeforeSave()
 	id, err := api.store.CreateTheme(t)
 	if err != nil {
-		if errors.Is(err, store.ErrAlreadyExist) {
-			return echo.NewHTTPError(http.StatusConflict, "Theme with this title already exists")
-		}
-		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+		return themeError(err)
 	}
-	c.Response().Header().Set("Location", fmt.Sprintf("/theme/%d", id))
+	t.Id = id
+	api.audit(c, &models.AuditEntry{Action: models.AuditThemeCreate, TargetType: models.TargetTheme, TargetId: id}, nil, t)
+	c.Response().Header().Set("Location", fmt.Sprintf("/themes/%d", id))
 	return c.NoContent(http.StatusCreated)
 }
 
+// @Summary Put theme
+// @Security cookieAuth
+// @Description Update theme in Store
+// @Tags Admin routes
+// @Accept json
+// @Produce json
+// @Param id path  int  true  "id of theme"
+// @Param theme body models.Theme true "Put theme"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Dont pass validation or the parent theme is wrong"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
+// @Failure 404 {object} echo.HTTPError "A theme with the specified ID was not found"
+// @Failure 409 {object} echo.HTTPError "Theme with this title or slug already exists"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /admin/theme/{id} [put]
+func (api *APIServer) PutTheme(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	t := &models.Theme{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&t); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	if err := t.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
+	}
+	t.Id = id
+	t.BeforeSave()
+	if t.ParentId != 0 {
+		themes, err := api.store.GetThemes()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+		}
+		for _, d := range models.DescendantIds(themes, id) {
+			if d == t.ParentId {
+				return echo.NewHTTPError(http.StatusBadRequest, "A theme can't be nested in itself or its subthemes")
+			}
+		}
+	}
+	before, _ := api.store.GetTheme(id)
+	if err := api.store.UpdateTheme(t); err != nil {
+		return themeError(err)
+	}
+	after, _ := api.store.GetTheme(id)
+	api.audit(c, &models.AuditEntry{Action: models.AuditThemeUpdate, TargetType: models.TargetTheme, TargetId: id}, before, after)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// themeError maps store errors of theme writes to responses.
+func themeError(err error) error {
+	switch {
+	case errors.Is(err, store.ErrNoRecord):
+		return echo.NewHTTPError(http.StatusNotFound, "A theme with the specified ID was not found")
+	case errors.Is(err, store.ErrAlreadyExist):
+		return echo.NewHTTPError(http.StatusConflict, "Theme with this title or slug already exists")
+	case errors.Is(err, store.ErrForeignKeyConstraint):
+		return echo.NewHTTPError(http.StatusBadRequest, "The parent theme was not found")
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+}
+
 // @Summary Delete theme
-// @Description Delete theme from Store
-// @Tags theme
+// @Security cookieAuth
+// @Description Delete theme with its hokkus from Store, or move the hokkus and chains to another theme first. Subthemes become root themes
+// @Tags Admin routes
 // @Accept json
 // @Produce json
 // @Param id path  int  true  "id of theme"
+// @Param moveTo query int false "id of the theme the hokkus are moved to"
 // @Success 204 "Deleted succesfuly"
-// @Failure 400 {object} echo.HTTPError "Bad request. Theme ID must be an integer and larger than 0"
+// @Failure 400 {object} echo.HTTPError "Bad request. Theme ID must be an integer and larger than 0, the theme to move hokkus to must exist and differ from the deleted one"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
 // @Failure 404 {object} echo.HTTPError "A theme with the specified ID was not found"
 // @Failure 500 {object} echo.HTTPError "Unexpected error"
 // @Router /admin/theme/{id} [delete]
@@ -501,11 +1099,35 @@ func (api *APIServer) DeleteTheme(c echo.Context) error {
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest)
 	}
-	if err = api.store.DeleteTheme(id); err != nil {
+	moveTo := 0
+	if v := c.QueryParam("moveTo"); v != "" {
+		if moveTo, err = strconv.Atoi(v); err != nil || moveTo == id {
+			return echo.NewHTTPError(http.StatusBadRequest, "Bad request. MoveTo must be the id of another theme")
+		}
+	}
+	before, _ := api.store.GetTheme(id)
+	// The hokkus are moved only along with deleting the theme
+	err = api.store.WithTx(c.Request().Context(), func(tx store.Store) error {
+		if moveTo != 0 {
+			if _, err := tx.MoveHokkus(id, moveTo); err != nil {
+				return err
+			}
+		}
+		return tx.DeleteTheme(id)
+	})
+	if err != nil {
 		if errors.Is(err, store.ErrNoRecord) {
 			return echo.NewHTTPError(http.StatusNotFound)
 		}
+		if errors.Is(err, store.ErrForeignKeyConstraint) {
+			return echo.NewHTTPError(http.StatusBadRequest, "The theme to move hokkus to was not found")
+		}
 		return echo.NewHTTPError(http.StatusInternalServerError)
 	}
+	entry := &models.AuditEntry{Action: models.AuditThemeDelete, TargetType: models.TargetTheme, TargetId: id}
+	if moveTo != 0 {
+		entry.Details = fmt.Sprintf("Hokkus moved to theme %d", moveTo)
+	}
+	api.audit(c, entry, before, nil)
 	return c.NoContent(http.StatusNoContent)
-} */
+}
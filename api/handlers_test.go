@@ -1,14 +1,21 @@
 package api_test
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/clock"
 	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/prosody"
+	"github.com/EgorSkurihin/Hokku/store"
 	"github.com/EgorSkurihin/Hokku/store/test_store"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -17,8 +24,10 @@ import (
 func testAPIServer() *api.APIServer {
 	store := test_store.New()
 	conf := &config.Server{
-		Addr:  ":1323",
-		Debug: true,
+		Addr:               ":1323",
+		Debug:              true,
+		TrashRetentionDays: 30,
+		DailyRepeatDays:    30,
 	}
 	return api.New(conf, store)
 }
@@ -47,7 +56,7 @@ func TestGetHokkus(t *testing.T) {
 			name:         "without params",
 			url:          "/hokkus",
 			expectedCode: http.StatusOK,
-			expectedBody: test_store.MockHokkus(0, len(test_store.Hokkus)),
+			expectedBody: test_store.MockHokkus(0, -1),
 			isValid:      true,
 		},
 		{
@@ -61,7 +70,7 @@ func TestGetHokkus(t *testing.T) {
 			name:         "correct offset",
 			url:          "/hokkus?offset=2",
 			expectedCode: http.StatusOK,
-			expectedBody: test_store.MockHokkus(2, len(test_store.Hokkus)),
+			expectedBody: test_store.MockHokkus(2, -1),
 			isValid:      true,
 		},
 		{
@@ -103,6 +112,7 @@ func TestGetHokkusByTheme(t *testing.T) {
 		themeId      string
 		limit        string
 		offset       string
+		descendants  string
 		expectedCode int
 		expectedBody []byte
 		isValid      bool
@@ -137,6 +147,21 @@ func TestGetHokkusByTheme(t *testing.T) {
 			expectedBody: test_store.MockHokkusByTheme(1, 2, -1),
 			isValid:      true,
 		},
+		{
+			name:         "with descendants",
+			themeId:      "1",
+			descendants:  "true",
+			expectedCode: http.StatusOK,
+			expectedBody: test_store.MockHokkusByThemeTree(1),
+			isValid:      true,
+		},
+		{
+			name:         "wrong descendants",
+			themeId:      "1",
+			descendants:  "qwe",
+			expectedCode: http.StatusBadRequest,
+			isValid:      false,
+		},
 		{
 			name:         "wrong limit",
 			themeId:      "1",
@@ -157,6 +182,7 @@ func TestGetHokkusByTheme(t *testing.T) {
 			q := make(url.Values)
 			q.Set("limit", cs.limit)
 			q.Set("offset", cs.offset)
+			q.Set("descendants", cs.descendants)
 			req := httptest.NewRequest(echo.GET, "/hokkus/byTheme?"+q.Encode(), nil)
 			rec := httptest.NewRecorder()
 			c := api.Echo.NewContext(req, rec)
@@ -277,6 +303,27 @@ func TestGetHokku(t *testing.T) {
 			id:      "id",
 			isValid: false,
 		},
+		{
+			name:    "draft of another user",
+			id:      "6",
+			isValid: false,
+		},
+		{
+			name:         "unlisted",
+			id:           "8",
+			expectedBody: test_store.MockHokku(8),
+			isValid:      true,
+		},
+		{
+			name:    "followers-only",
+			id:      "9",
+			isValid: false,
+		},
+		{
+			name:    "private",
+			id:      "10",
+			isValid: false,
+		},
 	}
 	for _, cs := range cases {
 		t.Run(cs.name, func(t *testing.T) {
@@ -322,7 +369,57 @@ func TestPostHokku(t *testing.T) {
 		},
 		{
 			name:    "bad foreign key constraint",
-			reqBody: `{"title":"Example","content":"1","ownerId":100,"themeId":1}`,
+			reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":100}`,
+			isValid: false,
+		},
+		{
+			name:    "scheduled",
+			reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":1,"status":"scheduled","publishAt":"2030-01-01T12:00:00Z"}`,
+			isValid: true,
+		},
+		{
+			name:    "scheduled in the past",
+			reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":1,"status":"scheduled","publishAt":"2020-01-01T12:00:00Z"}`,
+			isValid: false,
+		},
+		{
+			name:    "scheduled without time",
+			reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":1,"status":"scheduled"}`,
+			isValid: false,
+		},
+		{
+			name:    "unknown status",
+			reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":1,"status":"hidden"}`,
+			isValid: false,
+		},
+		{
+			name:    "followers-only",
+			reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":1,"visibility":"followers"}`,
+			isValid: true,
+		},
+		{
+			name:    "unknown visibility",
+			reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":1,"visibility":"friends"}`,
+			isValid: false,
+		},
+		{
+			name:    "closed theme",
+			reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":5}`,
+			isValid: false,
+		},
+		{
+			name:    "lines instead of content",
+			reqBody: `{"title":"Example","lines":["1","2","3"],"ownerId":1,"themeId":1}`,
+			isValid: true,
+		},
+		{
+			name:    "too many lines",
+			reqBody: `{"title":"Example","lines":["1","2","3","4","5","6"],"ownerId":1,"themeId":1}`,
+			isValid: false,
+		},
+		{
+			name:    "too long line",
+			reqBody: `{"title":"Example","content":"` + strings.Repeat("a", 101) + `","ownerId":1,"themeId":1}`,
 			isValid: false,
 		},
 	}
@@ -331,6 +428,7 @@ func TestPostHokku(t *testing.T) {
 			req := httptest.NewRequest(echo.POST, "/hokku", strings.NewReader(cs.reqBody))
 			rec := httptest.NewRecorder()
 			c := api.Echo.NewContext(req, rec)
+			c.Set("userId", 1)
 			if cs.isValid {
 				assert.NoError(t, api.PostHokku(c))
 			}
@@ -341,323 +439,1243 @@ func TestPostHokku(t *testing.T) {
 	}
 }
 
-func TestDeleteHokku(t *testing.T) {
+func TestPostHokkuOwner(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	req := httptest.NewRequest(echo.POST, "/hokku", strings.NewReader(`{"title":"Example","content":"1","ownerId":2,"themeId":1}`))
+	rec := httptest.NewRecorder()
+	c := api.Echo.NewContext(req, rec)
+	c.Set("userId", 1)
+	assert.NoError(t, api.PostHokku(c))
+	h := s.Hokkus[len(s.Hokkus)-1]
+	assert.Equal(t, 1, h.OwnerId)
+
+	// Anonymous users can't post
+	req = httptest.NewRequest(echo.POST, "/hokku", strings.NewReader(`{"title":"Example","content":"1","ownerId":2,"themeId":1}`))
+	c = api.Echo.NewContext(req, httptest.NewRecorder())
+	assert.Error(t, api.PostHokku(c))
+}
+
+func TestPostHokkuLines(t *testing.T) {
 	api := testAPIServer()
 	cases := []struct {
 		name    string
-		id      string
-		isValid bool
+		reqBody string
+		content string
+		lines   []string
 	}{
 		{
-			name:    "valid",
-			id:      "1",
-			isValid: true,
-		},
-		{
-			name:    "bad params",
-			id:      "qwe",
-			isValid: false,
+			name:    "content",
+			reqBody: `{"title":"Example","content":"  An old  pond\r\n\r\nA frog\tjumps in\rSplash! ","ownerId":1,"themeId":1}`,
+			content: "An old pond\nA frog jumps in\nSplash!",
+			lines:   []string{"An old pond", "A frog jumps in", "Splash!"},
 		},
 		{
-			name:    "validation error",
-			id:      "1000",
-			isValid: false,
+			name:    "lines",
+			reqBody: `{"title":"Example","content":"ignored","lines":[" An old pond","","A frog jumps in\nSplash!"],"ownerId":1,"themeId":1}`,
+			content: "An old pond\nA frog jumps in\nSplash!",
+			lines:   []string{"An old pond", "A frog jumps in", "Splash!"},
 		},
 	}
 	for _, cs := range cases {
 		t.Run(cs.name, func(t *testing.T) {
-			req := httptest.NewRequest(echo.DELETE, "/hokku", nil)
+			req := httptest.NewRequest(echo.POST, "/hokku", strings.NewReader(cs.reqBody))
 			rec := httptest.NewRecorder()
 			c := api.Echo.NewContext(req, rec)
+			c.Set("userId", 1)
+			assert.NoError(t, api.PostHokku(c))
+
+			req = httptest.NewRequest(echo.GET, rec.Header().Get("Location"), nil)
+			rec = httptest.NewRecorder()
+			c = api.Echo.NewContext(req, rec)
 			c.SetParamNames("id")
-			c.SetParamValues(cs.id)
-			if cs.isValid {
-				assert.NoError(t, api.DeleteHokku(c))
-			}
-			if !cs.isValid {
-				assert.Error(t, api.DeleteHokku(c))
-			}
+			c.SetParamValues(strings.TrimPrefix(req.URL.Path, "/hokku/"))
+			assert.NoError(t, api.GetHokku(c))
+			h := &models.Hokku{}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), h))
+			assert.Equal(t, cs.content, h.Content)
+			assert.Equal(t, cs.lines, h.Lines)
 		})
 	}
 }
 
-func TestPutHokku(t *testing.T) {
+func TestPostHokkuStrict(t *testing.T) {
 	api := testAPIServer()
+	const (
+		conforming = `An old silent pond\nA frog jumps into the pond—\nSplash! Silence again.`
+		free       = `An old pond\nA frog jumps in\nSplash!`
+	)
 	cases := []struct {
 		name    string
+		url     string
 		reqBody string
-		id      string
 		isValid bool
 	}{
 		{
-			name:    "valid",
-			reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":1}`,
-			id:      "1",
+			name:    "free form",
+			url:     "/hokku",
+			reqBody: `{"title":"Example","content":"` + free + `","ownerId":1,"themeId":1}`,
 			isValid: true,
 		},
 		{
-			name:    "bad body params",
-			reqBody: `{Error}`,
-			id:      "1",
+			name:    "free form in strict mode",
+			url:     "/hokku?strict=true",
+			reqBody: `{"title":"Example","content":"` + free + `","ownerId":1,"themeId":1}`,
 			isValid: false,
 		},
 		{
-			name:    "not found",
-			reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":1}`,
-			id:      "1000",
+			name:    "free form in strict theme",
+			url:     "/hokku",
+			reqBody: `{"title":"Example","content":"` + free + `","ownerId":1,"themeId":3}`,
 			isValid: false,
 		},
 		{
-			name:    "id not valid",
-			reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":1}`,
-			id:      "qwe",
-			isValid: false,
+			name:    "5-7-5 in strict theme",
+			url:     "/hokku",
+			reqBody: `{"title":"Example","content":"` + conforming + `","ownerId":1,"themeId":3}`,
+			isValid: true,
 		},
 		{
-			name:    "validation error",
-			reqBody: `{"title":"","content":"1","ownerId":1,"themeId":0}`,
+			name:    "bad strict param",
+			url:     "/hokku?strict=qwe",
+			reqBody: `{"title":"Example","content":"` + conforming + `","ownerId":1,"themeId":1}`,
 			isValid: false,
 		},
 	}
 	for _, cs := range cases {
 		t.Run(cs.name, func(t *testing.T) {
-			req := httptest.NewRequest(echo.PUT, "/hokku", strings.NewReader(cs.reqBody))
+			req := httptest.NewRequest(echo.POST, cs.url, strings.NewReader(cs.reqBody))
 			rec := httptest.NewRecorder()
 			c := api.Echo.NewContext(req, rec)
-			c.SetParamNames("id")
-			c.SetParamValues(cs.id)
+			c.Set("userId", 1)
 			if cs.isValid {
-				assert.NoError(t, api.PutHokku(c))
+				assert.NoError(t, api.PostHokku(c))
 			}
 			if !cs.isValid {
-				assert.Error(t, api.PutHokku(c))
+				assert.Error(t, api.PostHokku(c))
 			}
 		})
 	}
 }
 
-func TestGetUser(t *testing.T) {
+func TestAnalyze(t *testing.T) {
 	api := testAPIServer()
 	cases := []struct {
-		name         string
-		id           string
-		expectedBody []byte
-		isValid      bool
+		name      string
+		reqBody   string
+		syllables []int
+		conforms  bool
+		isValid   bool
 	}{
 		{
-			name:         "valid",
-			id:           "1",
-			expectedBody: test_store.MockUser(1),
-			isValid:      true,
+			name:      "5-7-5",
+			reqBody:   `{"content":"An old silent pond\nA frog jumps into the pond—\nSplash! Silence again."}`,
+			syllables: []int{5, 7, 5},
+			conforms:  true,
+			isValid:   true,
 		},
 		{
-			name:    "id not found",
-			id:      "1000",
-			isValid: false,
+			name:      "free form",
+			reqBody:   `{"content":"Старый пруд.\nПрыгнула в воду лягушка.\nВсплеск в тишине."}`,
+			syllables: []int{3, 8, 4},
+			conforms:  false,
+			isValid:   true,
 		},
 		{
-			name:    "invalid id",
-			id:      "id",
+			name:    "bad params",
+			reqBody: `{Error}`,
 			isValid: false,
 		},
 	}
 	for _, cs := range cases {
 		t.Run(cs.name, func(t *testing.T) {
-			req := httptest.NewRequest(echo.GET, "/user/", nil)
+			req := httptest.NewRequest(echo.POST, "/analyze", strings.NewReader(cs.reqBody))
 			rec := httptest.NewRecorder()
 			c := api.Echo.NewContext(req, rec)
-			c.SetParamNames("id")
-			c.SetParamValues(cs.id)
 			if cs.isValid {
-				assert.NoError(t, api.GetUser(c))
-				assert.Equal(t, cs.expectedBody, rec.Body.Bytes())
-
+				assert.NoError(t, api.Analyze(c))
+				a := &prosody.Analysis{}
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), a))
+				syllables := []int{}
+				for _, l := range a.Lines {
+					syllables = append(syllables, l.Syllables)
+				}
+				assert.Equal(t, cs.syllables, syllables)
+				assert.Equal(t, cs.conforms, a.Conforms)
 			}
 			if !cs.isValid {
-				assert.Error(t, api.GetUser(c))
+				assert.Error(t, api.Analyze(c))
 			}
 		})
 	}
 }
 
-func TestPostUser(t *testing.T) {
+func TestDeleteHokku(t *testing.T) {
 	api := testAPIServer()
 	cases := []struct {
 		name    string
-		reqBody string
+		id      string
 		isValid bool
 	}{
 		{
 			name:    "valid",
-			reqBody: `{"email":"example@email.com","password":"123123231","name":"test"}`,
+			id:      "1",
 			isValid: true,
 		},
 		{
 			name:    "bad params",
-			reqBody: `{Error}`,
+			id:      "qwe",
 			isValid: false,
 		},
 		{
 			name:    "validation error",
-			reqBody: `{"email":"example.com","password":"1231","name":"test"}`,
+			id:      "1000",
 			isValid: false,
 		},
 		{
-			name:    "already exists",
-			reqBody: `{"email":"example1@email.com","password":"123123231","name":"test"}`,
+			name:    "another user's hokku",
+			id:      "2",
 			isValid: false,
 		},
 	}
 	for _, cs := range cases {
 		t.Run(cs.name, func(t *testing.T) {
-			req := httptest.NewRequest(echo.POST, "/user", strings.NewReader(cs.reqBody))
+			req := httptest.NewRequest(echo.DELETE, "/hokku", nil)
 			rec := httptest.NewRecorder()
 			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", 1)
 			if cs.isValid {
-				assert.NoError(t, api.PostUser(c))
+				assert.NoError(t, api.DeleteHokku(c))
 			}
 			if !cs.isValid {
-				assert.Error(t, api.PostUser(c))
+				assert.Error(t, api.DeleteHokku(c))
 			}
 		})
 	}
 }
 
-func TestDeleteUser(t *testing.T) {
+func TestPutHokku(t *testing.T) {
 	api := testAPIServer()
 	cases := []struct {
 		name    string
+		reqBody string
 		id      string
 		isValid bool
 	}{
 		{
 			name:    "valid",
+			reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":1}`,
 			id:      "1",
 			isValid: true,
 		},
 		{
-			name:    "bad params",
+			name:    "bad body params",
+			reqBody: `{Error}`,
+			id:      "1",
+			isValid: false,
+		},
+		{
+			name:    "not found",
+			reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":1}`,
+			id:      "1000",
+			isValid: false,
+		},
+		{
+			name:    "id not valid",
+			reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":1}`,
 			id:      "qwe",
 			isValid: false,
 		},
 		{
 			name:    "validation error",
-			id:      "1000",
+			reqBody: `{"title":"","content":"1","ownerId":1,"themeId":0}`,
+			isValid: false,
+		},
+		{
+			name:    "another user's hokku",
+			reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":1}`,
+			id:      "2",
 			isValid: false,
 		},
 	}
 	for _, cs := range cases {
 		t.Run(cs.name, func(t *testing.T) {
-			req := httptest.NewRequest(echo.DELETE, "/user", nil)
+			req := httptest.NewRequest(echo.PUT, "/hokku", strings.NewReader(cs.reqBody))
 			rec := httptest.NewRecorder()
 			c := api.Echo.NewContext(req, rec)
 			c.SetParamNames("id")
 			c.SetParamValues(cs.id)
+			c.Set("userId", 1)
 			if cs.isValid {
-				assert.NoError(t, api.DeleteUser(c))
+				assert.NoError(t, api.PutHokku(c))
 			}
 			if !cs.isValid {
-				assert.Error(t, api.DeleteUser(c))
+				assert.Error(t, api.PutHokku(c))
 			}
 		})
 	}
 }
 
-func TestPutUser(t *testing.T) {
+func TestPutHokkuKeepsStatus(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	cases := []struct {
+		name       string
+		reqBody    string
+		id         int
+		userId     int
+		status     string
+		visibility string
+		isValid    bool
+	}{
+		{name: "draft", reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":1}`, id: 6, userId: 1,
+			status: models.StatusDraft, visibility: models.VisibilityPublic, isValid: true},
+		{name: "scheduled", reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":2}`, id: 7, userId: 1,
+			status: models.StatusScheduled, visibility: models.VisibilityPublic, isValid: true},
+		{name: "followers-only", reqBody: `{"title":"Example","content":"1","ownerId":2,"themeId":2}`, id: 9, userId: 2,
+			status: models.StatusPublished, visibility: models.VisibilityFollowers, isValid: true},
+		{name: "published", reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":1,"status":"published","visibility":"private"}`, id: 6, userId: 1,
+			status: models.StatusPublished, visibility: models.VisibilityPrivate, isValid: true},
+		{name: "scheduled in the past", reqBody: `{"title":"Example","content":"1","ownerId":1,"themeId":1,"status":"scheduled","publishAt":"2020-01-01T12:00:00Z"}`, id: 4, userId: 1,
+			isValid: false},
+		{name: "stanza", reqBody: `{"title":"Example","content":"An old silent pond\nA frog jumps into the pond\nSplash! Silence again","status":"draft","visibility":"private"}`, id: 12, userId: 1,
+			status: models.StatusPublished, visibility: models.VisibilityPublic, isValid: true},
+		{name: "stanza out of form", reqBody: `{"title":"Example","content":"The light of a candle\nis transferred to another candle"}`, id: 12, userId: 1,
+			isValid: false},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.PUT, "/hokku", strings.NewReader(cs.reqBody))
+			c := api.Echo.NewContext(req, httptest.NewRecorder())
+			c.SetParamNames("id")
+			c.SetParamValues(strconv.Itoa(cs.id))
+			c.Set("userId", cs.userId)
+			if !cs.isValid {
+				assert.Error(t, api.PutHokku(c))
+				return
+			}
+			assert.NoError(t, api.PutHokku(c))
+			h, err := s.GetHokku(cs.userId, cs.id)
+			assert.NoError(t, err)
+			assert.Equal(t, cs.status, h.Status)
+			assert.Equal(t, cs.visibility, h.Visibility)
+			if cs.status == models.StatusScheduled {
+				assert.Equal(t, test_store.ScheduledAt, *h.PublishAt)
+			}
+		})
+	}
+}
+
+func TestGetHokkuVisibility(t *testing.T) {
 	api := testAPIServer()
 	cases := []struct {
 		name    string
-		reqBody string
 		id      string
+		userId  int
 		isValid bool
 	}{
 		{
-			name:    "valid",
-			reqBody: `{"email":"example@email.com","password":"123123231","name":"test"}`,
-			id:      "1",
+			name:    "followers-only by follower",
+			id:      "9",
+			userId:  1,
 			isValid: true,
 		},
 		{
-			name:    "bad body params",
-			reqBody: `{Error}`,
-			id:      "1",
-			isValid: false,
-		},
-		{
-			name:    "not found",
-			reqBody: `{"email":"example@email.com","password":"123123231","name":"test"}`,
-			id:      "1000",
+			name:    "followers-only by other user",
+			id:      "9",
+			userId:  3,
 			isValid: false,
 		},
 		{
-			name:    "id not valid",
-			reqBody: `{"email":"example@email.com","password":"123123231","name":"test"}`,
-			id:      "qwe",
+			name:    "private by other user",
+			id:      "10",
+			userId:  1,
 			isValid: false,
 		},
 		{
-			name:    "validation error",
-			reqBody: `{"email":"example.com","password":"1231","name":"test"}`,
-			id:      "1",
-			isValid: false,
+			name:    "private by owner",
+			id:      "10",
+			userId:  2,
+			isValid: true,
 		},
 	}
 	for _, cs := range cases {
 		t.Run(cs.name, func(t *testing.T) {
-			req := httptest.NewRequest(echo.PUT, "/user", strings.NewReader(cs.reqBody))
+			req := httptest.NewRequest(echo.GET, "/hokku/", nil)
 			rec := httptest.NewRecorder()
 			c := api.Echo.NewContext(req, rec)
 			c.SetParamNames("id")
 			c.SetParamValues(cs.id)
+			c.Set("userId", cs.userId)
 			if cs.isValid {
-				assert.NoError(t, api.PutUser(c))
+				assert.NoError(t, api.GetHokku(c))
 			}
 			if !cs.isValid {
-				assert.Error(t, api.PutUser(c))
+				assert.Error(t, api.GetHokku(c))
 			}
 		})
 	}
 }
 
-func TestGetThemes(t *testing.T) {
+func TestGetHokkusByAuthorFollower(t *testing.T) {
+	api := testAPIServer()
+	req := httptest.NewRequest(echo.GET, "/hokkus/byAuthor", nil)
+	rec := httptest.NewRecorder()
+	c := api.Echo.NewContext(req, rec)
+	c.SetParamNames("authorId")
+	c.SetParamValues("2")
+	c.Set("userId", 1)
+	assert.NoError(t, api.GetHokkusByAuthor(c))
+	var hs []*models.Hokku
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &hs))
+	ids := []int{}
+	for _, h := range hs {
+		ids = append(ids, h.Id)
+	}
+	assert.Equal(t, []int{2, 5, 9}, ids)
+}
+
+func TestGetDraft(t *testing.T) {
+	api := testAPIServer()
+	req := httptest.NewRequest(echo.GET, "/hokku/", nil)
+	rec := httptest.NewRecorder()
+	c := api.Echo.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("6")
+	c.Set("userId", 1)
+	assert.NoError(t, api.GetHokku(c))
+	assert.Equal(t, test_store.MockHokku(6), rec.Body.Bytes())
+}
+
+func TestGetDrafts(t *testing.T) {
 	api := testAPIServer()
 	cases := []struct {
 		name         string
+		userId       interface{}
 		expectedBody []byte
 		isValid      bool
 	}{
 		{
 			name:         "valid",
-			expectedBody: test_store.MockThemes(),
+			userId:       1,
+			expectedBody: test_store.MockDrafts(1),
+			isValid:      true,
+		},
+		{
+			name:         "no drafts",
+			userId:       2,
+			expectedBody: test_store.MockDrafts(2),
 			isValid:      true,
 		},
+		{
+			name:    "not authenticated",
+			isValid: false,
+		},
 	}
 	for _, cs := range cases {
 		t.Run(cs.name, func(t *testing.T) {
-			req := httptest.NewRequest(echo.GET, "/themes", nil)
+			req := httptest.NewRequest(echo.GET, "/restricted/drafts", nil)
 			rec := httptest.NewRecorder()
 			c := api.Echo.NewContext(req, rec)
+			if cs.userId != nil {
+				c.Set("userId", cs.userId)
+			}
 			if cs.isValid {
-				t.Log(cs.name)
-				assert.NoError(t, api.GetThemes(c))
+				assert.NoError(t, api.GetDrafts(c))
 				assert.Equal(t, cs.expectedBody, rec.Body.Bytes())
-
 			}
 			if !cs.isValid {
-				assert.Error(t, api.GetThemes(c))
+				assert.Error(t, api.GetDrafts(c))
 			}
 		})
 	}
 }
 
-func TestLogin(t *testing.T) {
+func TestTrash(t *testing.T) {
 	api := testAPIServer()
-	cases := []struct {
-		name    string
-		reqBody string
-		isValid bool
-	}{
-		{
+	newContext := func(method, id string) (echo.Context, *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(method, "/restricted/", nil)
+		rec := httptest.NewRecorder()
+		c := api.Echo.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		c.Set("userId", 1)
+		return c, rec
+	}
+
+	c, _ := newContext(echo.DELETE, "1")
+	assert.NoError(t, api.DeleteHokku(c))
+	c, _ = newContext(echo.GET, "1")
+	assert.Error(t, api.GetHokku(c))
+
+	c, rec := newContext(echo.GET, "")
+	assert.NoError(t, api.GetTrash(c))
+	var hs []*models.Hokku
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &hs))
+	if assert.Len(t, hs, 1) {
+		assert.Equal(t, 1, hs[0].Id)
+		assert.NotNil(t, hs[0].DeletedAt)
+	}
+
+	c, _ = newContext(echo.POST, "1")
+	assert.NoError(t, api.RestoreHokku(c))
+	c, _ = newContext(echo.POST, "1")
+	assert.Error(t, api.RestoreHokku(c))
+	c, _ = newContext(echo.GET, "1")
+	assert.NoError(t, api.GetHokku(c))
+
+	// Hokkus of other users can't be restored
+	c, _ = newContext(echo.DELETE, "2")
+	c.Set("userId", 2)
+	assert.NoError(t, api.DeleteHokku(c))
+	c, _ = newContext(echo.POST, "2")
+	assert.Error(t, api.RestoreHokku(c))
+}
+
+func TestTrashRetention(t *testing.T) {
+	api := testAPIServer()
+	clk := clock.NewMock(time.Now())
+	api.Clock = clk
+	req := httptest.NewRequest(echo.DELETE, "/restricted/hokku", nil)
+	c := api.Echo.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	c.Set("userId", 1)
+	assert.NoError(t, api.DeleteHokku(c))
+
+	clk.Add(31 * 24 * time.Hour)
+	req = httptest.NewRequest(echo.POST, "/restricted/hokku/restore", nil)
+	c = api.Echo.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	c.Set("userId", 1)
+	assert.Error(t, api.RestoreHokku(c))
+}
+
+func TestRestoreUser(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		id      string
+		userId  int
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			id:      "1",
+			userId:  3,
+			isValid: true,
+		},
+		{
+			name:    "not deleted",
+			id:      "1",
+			userId:  3,
+			isValid: false,
+		},
+		{
+			name:    "bad params",
+			id:      "qwe",
+			userId:  3,
+			isValid: false,
+		},
+	}
+	req := httptest.NewRequest(echo.DELETE, "/restricted/user", nil)
+	c := api.Echo.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	c.Set("userId", 1)
+	assert.NoError(t, api.DeleteUser(c))
+	req = httptest.NewRequest(echo.GET, "/hokkus/byAuthor", nil)
+	rec := httptest.NewRecorder()
+	c = api.Echo.NewContext(req, rec)
+	c.SetParamNames("authorId")
+	c.SetParamValues("1")
+	assert.NoError(t, api.GetHokkusByAuthor(c))
+	assert.Equal(t, "[]\n", rec.Body.String())
+
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/admin/user/restore", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", cs.userId)
+			if cs.isValid {
+				assert.NoError(t, api.RestoreUser(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.RestoreUser(c))
+			}
+		})
+	}
+
+	req = httptest.NewRequest(echo.GET, "/hokkus/byAuthor", nil)
+	rec = httptest.NewRecorder()
+	c = api.Echo.NewContext(req, rec)
+	c.SetParamNames("authorId")
+	c.SetParamValues("1")
+	assert.NoError(t, api.GetHokkusByAuthor(c))
+	assert.Equal(t, test_store.MockHokkusByUser(1, 0, -1), rec.Body.Bytes())
+}
+
+func TestGetUser(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name         string
+		id           string
+		expectedBody []byte
+		isValid      bool
+	}{
+		{
+			name:         "valid",
+			id:           "1",
+			expectedBody: test_store.MockUser(1),
+			isValid:      true,
+		},
+		{
+			name:    "id not found",
+			id:      "1000",
+			isValid: false,
+		},
+		{
+			name:    "invalid id",
+			id:      "id",
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/user/", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.Set("userId", 1)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			if cs.isValid {
+				assert.NoError(t, api.GetUser(c))
+				assert.Equal(t, cs.expectedBody, rec.Body.Bytes())
+
+			}
+			if !cs.isValid {
+				assert.Error(t, api.GetUser(c))
+			}
+		})
+	}
+}
+
+func TestGetUserModeration(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	assert.NoError(t, s.HideUser(2))
+	cases := []struct {
+		name       string
+		viewerId   int
+		showRole   bool
+		showHidden bool
+	}{
+		{name: "anonymous", viewerId: 0},
+		{name: "the user", viewerId: 2, showRole: true},
+		{name: "another user", viewerId: 1},
+		{name: "admin", viewerId: 3, showRole: true, showHidden: true},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/user/", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			if cs.viewerId != 0 {
+				c.Set("userId", cs.viewerId)
+			}
+			c.SetParamNames("id")
+			c.SetParamValues("2")
+			assert.NoError(t, api.GetUser(c))
+			var u models.User
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &u))
+			assert.Equal(t, cs.showRole, u.Role == models.RoleUser)
+			assert.Equal(t, cs.showHidden, u.Hidden)
+		})
+	}
+	u, err := s.GetUser(2)
+	assert.NoError(t, err)
+	assert.True(t, u.Hidden)
+	assert.Equal(t, models.RoleUser, u.Role)
+}
+
+func TestPostUser(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		reqBody string
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			reqBody: `{"email":"example@email.com","password":"123123231","name":"test"}`,
+			isValid: true,
+		},
+		{
+			name:    "bad params",
+			reqBody: `{Error}`,
+			isValid: false,
+		},
+		{
+			name:    "validation error",
+			reqBody: `{"email":"example.com","password":"1231","name":"test"}`,
+			isValid: false,
+		},
+		{
+			name:    "already exists",
+			reqBody: `{"email":"example1@email.com","password":"123123231","name":"test"}`,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/user", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			if cs.isValid {
+				assert.NoError(t, api.PostUser(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.PostUser(c))
+			}
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		id      string
+		userId  int
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			id:      "1",
+			userId:  1,
+			isValid: true,
+		},
+		{
+			name:    "another user",
+			id:      "3",
+			userId:  2,
+			isValid: false,
+		},
+		{
+			name:    "admin",
+			id:      "2",
+			userId:  3,
+			isValid: true,
+		},
+		{
+			name:    "bad params",
+			id:      "qwe",
+			userId:  3,
+			isValid: false,
+		},
+		{
+			name:    "validation error",
+			id:      "1000",
+			userId:  3,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.DELETE, "/user", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", cs.userId)
+			if cs.isValid {
+				assert.NoError(t, api.DeleteUser(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.DeleteUser(c))
+			}
+		})
+	}
+}
+
+func TestDeleteUserWebhooks(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	for _, owner := range []int{2, 3} {
+		_, err := s.CreateWebhook(&models.Webhook{OwnerId: owner, URL: "https://example.com", Events: models.WebhookEvents})
+		assert.NoError(t, err)
+	}
+	req := httptest.NewRequest(echo.DELETE, "/user", nil)
+	c := api.Echo.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues("2")
+	c.Set("userId", 2)
+	assert.NoError(t, api.DeleteUser(c))
+	hooks, err := s.GetWebhooks(0)
+	assert.NoError(t, err)
+	if assert.Len(t, hooks, 1) {
+		assert.Equal(t, 3, hooks[0].OwnerId)
+	}
+}
+
+func TestPutUser(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		reqBody string
+		id      string
+		userId  int
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			reqBody: `{"email":"example@email.com","password":"123123231","name":"test"}`,
+			id:      "1",
+			userId:  1,
+			isValid: true,
+		},
+		{
+			name:    "another user",
+			reqBody: `{"email":"example@email.com","password":"123123231","name":"test"}`,
+			id:      "3",
+			userId:  2,
+			isValid: false,
+		},
+		{
+			name:    "role change",
+			reqBody: `{"email":"example2@email.com","password":"123123231","name":"test","role":"admin"}`,
+			id:      "2",
+			userId:  2,
+			isValid: false,
+		},
+		{
+			name:    "admin",
+			reqBody: `{"email":"example2@email.com","password":"123123231","name":"test","role":"user"}`,
+			id:      "2",
+			userId:  3,
+			isValid: true,
+		},
+		{
+			name:    "bad body params",
+			reqBody: `{Error}`,
+			id:      "1",
+			userId:  1,
+			isValid: false,
+		},
+		{
+			name:    "not found",
+			reqBody: `{"email":"example@email.com","password":"123123231","name":"test"}`,
+			id:      "1000",
+			userId:  3,
+			isValid: false,
+		},
+		{
+			name:    "id not valid",
+			reqBody: `{"email":"example@email.com","password":"123123231","name":"test"}`,
+			id:      "qwe",
+			userId:  1,
+			isValid: false,
+		},
+		{
+			name:    "validation error",
+			reqBody: `{"email":"example.com","password":"1231","name":"test"}`,
+			id:      "1",
+			userId:  1,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.PUT, "/user", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", cs.userId)
+			if cs.isValid {
+				assert.NoError(t, api.PutUser(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.PutUser(c))
+			}
+		})
+	}
+}
+
+func TestFollow(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		id      string
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			id:      "3",
+			isValid: true,
+		},
+		{
+			name:    "already followed",
+			id:      "2",
+			isValid: false,
+		},
+		{
+			name:    "self",
+			id:      "1",
+			isValid: false,
+		},
+		{
+			name:    "not found",
+			id:      "1000",
+			isValid: false,
+		},
+		{
+			name:    "bad params",
+			id:      "qwe",
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/restricted/user/follow", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", 1)
+			if cs.isValid {
+				assert.NoError(t, api.Follow(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.Follow(c))
+			}
+		})
+	}
+}
+
+func TestUnfollow(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		id      string
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			id:      "2",
+			isValid: true,
+		},
+		{
+			name:    "not followed",
+			id:      "3",
+			isValid: false,
+		},
+		{
+			name:    "bad params",
+			id:      "qwe",
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.DELETE, "/restricted/user/follow", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", 1)
+			if cs.isValid {
+				assert.NoError(t, api.Unfollow(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.Unfollow(c))
+			}
+		})
+	}
+}
+
+func TestGetThemes(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name         string
+		expectedBody []byte
+		isValid      bool
+	}{
+		{
+			name:         "valid",
+			expectedBody: test_store.MockThemes(),
+			isValid:      true,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/themes", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			if cs.isValid {
+				t.Log(cs.name)
+				assert.NoError(t, api.GetThemes(c))
+				assert.Equal(t, cs.expectedBody, rec.Body.Bytes())
+
+			}
+			if !cs.isValid {
+				assert.Error(t, api.GetThemes(c))
+			}
+		})
+	}
+}
+
+func TestGetTheme(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name      string
+		id        string
+		count     int
+		subthemes []int
+		subCounts []int
+		isValid   bool
+	}{
+		{
+			name:      "with subthemes",
+			id:        "1",
+			count:     4,
+			subthemes: []int{4},
+			subCounts: []int{1},
+			isValid:   true,
+		},
+		{
+			name:      "by slug",
+			id:        "example-theme-2",
+			count:     4,
+			subthemes: []int{},
+			subCounts: []int{},
+			isValid:   true,
+		},
+		{
+			name:    "not found",
+			id:      "1000",
+			isValid: false,
+		},
+		{
+			name:    "unknown slug",
+			id:      "unknown",
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/themes/", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			if !cs.isValid {
+				assert.Error(t, api.GetTheme(c))
+				return
+			}
+			assert.NoError(t, api.GetTheme(c))
+			d := &models.ThemeDetails{}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), d))
+			assert.Equal(t, cs.count, d.HokkuCount)
+			subthemes, subCounts := []int{}, []int{}
+			for _, sub := range d.Subthemes {
+				subthemes = append(subthemes, sub.Id)
+				subCounts = append(subCounts, sub.HokkuCount)
+			}
+			assert.Equal(t, cs.subthemes, subthemes)
+			assert.Equal(t, cs.subCounts, subCounts)
+		})
+	}
+}
+
+func TestPostTheme(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		reqBody string
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			reqBody: `{"title":"Осенние листья","parentId":1,"description":"Description"}`,
+			isValid: true,
+		},
+		{
+			name:    "bad body params",
+			reqBody: `{Error}`,
+			isValid: false,
+		},
+		{
+			name:    "bad slug",
+			reqBody: `{"title":"New theme","slug":"New Theme"}`,
+			isValid: false,
+		},
+		{
+			name:    "duplicate slug",
+			reqBody: `{"title":"New theme","slug":"example-theme-1"}`,
+			isValid: false,
+		},
+		{
+			name:    "parent not found",
+			reqBody: `{"title":"New theme","parentId":1000}`,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/admin/theme", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			if cs.isValid {
+				assert.NoError(t, api.PostTheme(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.PostTheme(c))
+			}
+		})
+	}
+}
+
+func TestPutTheme(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		id      string
+		reqBody string
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			id:      "2",
+			reqBody: `{"title":"exampleTheme2","slug":"example-theme-2","parentId":1,"postingClosed":true}`,
+			isValid: true,
+		},
+		{
+			name:    "nested in itself",
+			id:      "1",
+			reqBody: `{"title":"exampleTheme1","slug":"example-theme-1","parentId":1}`,
+			isValid: false,
+		},
+		{
+			name:    "nested in subtheme",
+			id:      "1",
+			reqBody: `{"title":"exampleTheme1","slug":"example-theme-1","parentId":4}`,
+			isValid: false,
+		},
+		{
+			name:    "not found",
+			id:      "1000",
+			reqBody: `{"title":"New theme"}`,
+			isValid: false,
+		},
+		{
+			name:    "invalid id",
+			id:      "id",
+			reqBody: `{"title":"New theme"}`,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.PUT, "/admin/theme/", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			if cs.isValid {
+				assert.NoError(t, api.PutTheme(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.PutTheme(c))
+			}
+		})
+	}
+}
+
+func TestDeleteTheme(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		id      string
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			id:      "1",
+			isValid: true,
+		},
+		{
+			name:    "not found",
+			id:      "1",
+			isValid: false,
+		},
+		{
+			name:    "invalid id",
+			id:      "id",
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.DELETE, "/admin/theme/", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			if cs.isValid {
+				assert.NoError(t, api.DeleteTheme(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.DeleteTheme(c))
+			}
+		})
+	}
+}
+
+func TestDeleteThemeMoveTo(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	deleteTheme := func(id, moveTo string) error {
+		req := httptest.NewRequest(echo.DELETE, "/admin/theme/?moveTo="+moveTo, nil)
+		c := api.Echo.NewContext(req, httptest.NewRecorder())
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		c.Set("userId", 3)
+		return api.DeleteTheme(c)
+	}
+	assert.Error(t, deleteTheme("1", "1"))
+	assert.Error(t, deleteTheme("1", "qwe"))
+
+	// A failed move keeps the theme and its hokkus
+	assert.Error(t, deleteTheme("1", "100"))
+	_, err := s.GetTheme(1)
+	assert.NoError(t, err)
+	h, err := s.GetHokku(1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, h.ThemeId)
+
+	assert.NoError(t, deleteTheme("1", "2"))
+	_, err = s.GetTheme(1)
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+	h, err = s.GetHokku(1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, h.ThemeId)
+}
+
+func TestLogin(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		reqBody string
+		isValid bool
+	}{
+		{
 			name:    "valid",
 			reqBody: `{"email":"example1@email.com","password":"Admin"}`,
 			isValid: true,
@@ -683,3 +1701,49 @@ func TestLogin(t *testing.T) {
 		})
 	}
 }
+
+func TestLoginRestore(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{TrashRetentionDays: 30}, s)
+	req := httptest.NewRequest(echo.DELETE, "/restricted/user", nil)
+	c := api.Echo.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	c.Set("userId", 1)
+	assert.NoError(t, api.DeleteUser(c))
+
+	cases := []struct {
+		name     string
+		query    string
+		password string
+		isValid  bool
+	}{
+		{name: "deleted", query: "", password: "Admin", isValid: false},
+		{name: "bad restore", query: "?restore=yes", password: "Admin", isValid: false},
+		{name: "wrong password", query: "?restore=true", password: "qweqweqwe", isValid: false},
+		{name: "restore", query: "?restore=true", password: "Admin", isValid: true},
+		{name: "restored", query: "", password: "Admin", isValid: true},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			body := `{"email":"example1@email.com","password":"` + cs.password + `"}`
+			req := httptest.NewRequest(echo.POST, "/login"+cs.query, strings.NewReader(body))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			if !cs.isValid {
+				assert.Error(t, api.Login(c))
+				return
+			}
+			assert.NoError(t, api.Login(c))
+			assert.NotEmpty(t, rec.Result().Cookies())
+		})
+	}
+	u, err := s.GetUser(1)
+	assert.NoError(t, err)
+	assert.Nil(t, u.DeletedAt)
+	for _, h := range s.Hokkus {
+		if h.OwnerId == 1 {
+			assert.Nil(t, h.DeletedAt)
+		}
+	}
+}
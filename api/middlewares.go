@@ -1,18 +1,92 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
 	"github.com/labstack/echo/v4"
 )
 
 func (srv *APIServer) authMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		session, _ := srv.sessionStore.Get(c.Request(), "session")
-		_, ok := session.Values["userId"]
+		userId, ok := session.Values["userId"].(int)
 		if !ok {
 			return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
 		}
+		// Sessions of deleted users are refused, they are restored by
+		// logging in again
+		if _, err := srv.store.GetUser(userId); err != nil {
+			if errors.Is(err, store.ErrNoRecord) {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+		}
+		// Sessions opened before the ban are refused too
+		if err := srv.checkRestriction(userId, models.RestrictionBan, "The user is banned"); err != nil {
+			return err
+		}
+		c.Set("userId", userId)
+		return next(c)
+	}
+}
+
+// postingMiddleware refuses suspended users. It must run after
+// authMiddleware.
+func (srv *APIServer) postingMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userId, _ := c.Get("userId").(int)
+		if err := srv.checkRestriction(userId, models.RestrictionSuspension, "Posting is suspended"); err != nil {
+			return err
+		}
 		return next(c)
 	}
 }
+
+// adminMiddleware lets through only admins. It must run after
+// authMiddleware.
+func (srv *APIServer) adminMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return srv.roleMiddleware(next, (*models.User).IsAdmin, "The action is allowed only to admins")
+}
+
+// moderatorMiddleware lets through only moderators and admins. It must run
+// after authMiddleware.
+func (srv *APIServer) moderatorMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return srv.roleMiddleware(next, (*models.User).IsModerator, "The action is allowed only to moderators")
+}
+
+// roleMiddleware lets through only the users the role check passes and
+// answers the others with the message.
+func (srv *APIServer) roleMiddleware(next echo.HandlerFunc, allowed func(*models.User) bool, message string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userId, _ := c.Get("userId").(int)
+		u, err := srv.store.GetUser(userId)
+		if err != nil {
+			if errors.Is(err, store.ErrNoRecord) {
+				return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+			}
+			return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+		}
+		if !allowed(u) {
+			return echo.NewHTTPError(http.StatusForbidden, message)
+		}
+		return next(c)
+	}
+}
+
+// currentUserId returns the id of the authenticated user. Open routes
+// don't pass through authMiddleware, so the session is read directly
+// when the id wasn't set on the context.
+func (srv *APIServer) currentUserId(c echo.Context) (int, bool) {
+	if userId, ok := c.Get("userId").(int); ok {
+		return userId, true
+	}
+	session, err := srv.sessionStore.Get(c.Request(), "session")
+	if err != nil {
+		return 0, false
+	}
+	userId, ok := session.Values["userId"].(int)
+	return userId, ok
+}
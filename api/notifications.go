@@ -0,0 +1,228 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/EgorSkurihin/Hokku/stream"
+	"github.com/labstack/echo/v4"
+)
+
+// notify sends the notification unless it is about the own action of the
+// user, the user turned the type off or muted the actor. The action is
+// already done, so failures are only logged.
+func (api *APIServer) notify(c echo.Context, n *models.Notification) {
+	if n.ActorId != 0 && n.ActorId == n.UserId {
+		return
+	}
+	prefs, err := api.store.GetNotificationPreferences(n.UserId)
+	if err != nil {
+		c.Logger().Errorf("notify user %d: %v", n.UserId, err)
+		return
+	}
+	if enabled, ok := prefs[n.Type]; ok && !enabled {
+		return
+	}
+	if n.ActorId != 0 {
+		mutes, err := api.store.GetMutes(n.UserId)
+		if err != nil {
+			c.Logger().Errorf("notify user %d: %v", n.UserId, err)
+			return
+		}
+		for _, m := range mutes {
+			if m.MutedId == n.ActorId {
+				return
+			}
+		}
+	}
+	if _, err := api.store.CreateNotification(n); err != nil {
+		c.Logger().Errorf("notify user %d: %v", n.UserId, err)
+		return
+	}
+	n.Message = n.Describe()
+	api.publish(c, eventNotification, n, stream.UserTopic(n.UserId))
+}
+
+// @Summary Get notifications
+// @Security cookieAuth
+// @Description Get the notifications of the current user from the latest activity. Likes, comments and follows are gathered into one notification until it is read. The X-Unread-Count header holds the number of unread notifications
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param limit query int false "Sample size"
+// @Param offset query int false "Number of items to skip"
+// @Success 200 {array} models.Notification
+// @Header 200 {integer} X-Unread-Count "Number of unread notifications"
+// @Failure 400 {object} echo.HTTPError "Bad query parameters"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/notifications [get]
+func (api *APIServer) GetNotifications(c echo.Context) error {
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	limit, offset, err := paginationParams(c)
+	if err != nil {
+		return err
+	}
+	result, err := api.store.GetNotifications(userId, limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	unread, err := api.store.CountUnreadNotifications(userId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	for _, n := range result {
+		n.Message = n.Describe()
+	}
+	c.Response().Header().Set("X-Unread-Count", strconv.Itoa(total(unread)))
+	return c.JSON(http.StatusOK, result)
+}
+
+func total(counts map[string]int) int {
+	res := 0
+	for _, n := range counts {
+		res += n
+	}
+	return res
+}
+
+// @Summary Count unread notifications
+// @Security cookieAuth
+// @Description Count the unread notifications of the current user in total and by type
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/notifications/unread [get]
+func (api *APIServer) CountUnreadNotifications(c echo.Context) error {
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	unread, err := api.store.CountUnreadNotifications(userId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, echo.Map{"total": total(unread), "byType": unread})
+}
+
+// @Summary Read notification
+// @Security cookieAuth
+// @Description Mark the notification of the current user as read
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of notification"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Bad request. Id must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 404 {object} echo.HTTPError "A notification with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/notification/{id}/read [post]
+func (api *APIServer) ReadNotification(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	if err := api.store.ReadNotification(userId, id); err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A notification with the specified ID was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Read all notifications
+// @Security cookieAuth
+// @Description Mark all notifications of the current user as read
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Success 204 "OK"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/notifications/read [post]
+func (api *APIServer) ReadNotifications(c echo.Context) error {
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	if err := api.store.ReadNotifications(userId); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Get notification preferences
+// @Security cookieAuth
+// @Description Get which types of notifications the current user receives
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]bool
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/notifications/preferences [get]
+func (api *APIServer) GetNotificationPreferences(c echo.Context) error {
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	saved, err := api.store.GetNotificationPreferences(userId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	prefs := map[string]bool{}
+	for _, t := range models.NotificationTypes {
+		enabled, ok := saved[t]
+		prefs[t] = !ok || enabled
+	}
+	return c.JSON(http.StatusOK, prefs)
+}
+
+// @Summary Put notification preferences
+// @Security cookieAuth
+// @Description Turn types of notifications on or off for the current user. Types left out are kept as they are
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param preferences body map[string]bool true "Types of notifications and whether they are on"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Bad request params or an unknown type of notifications"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/notifications/preferences [put]
+func (api *APIServer) PutNotificationPreferences(c echo.Context) error {
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	prefs := map[string]bool{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&prefs); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	for t := range prefs {
+		if !models.ValidNotificationType(t) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Unknown type of notifications: "+t)
+		}
+	}
+	if err := api.store.SetNotificationPreferences(userId, prefs); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
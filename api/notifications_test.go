@@ -0,0 +1,102 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifications(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	call := func(handler func(echo.Context) error, userId int, id, body string) error {
+		req := httptest.NewRequest(echo.POST, "/restricted/", strings.NewReader(body))
+		c := api.Echo.NewContext(req, httptest.NewRecorder())
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		c.Set("userId", userId)
+		return handler(c)
+	}
+	inbox := func(userId int) ([]*models.Notification, string) {
+		rec := httptest.NewRecorder()
+		c := api.Echo.NewContext(httptest.NewRequest(echo.GET, "/restricted/notifications", nil), rec)
+		c.Set("userId", userId)
+		assert.NoError(t, api.GetNotifications(c))
+		notifications := []*models.Notification{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &notifications))
+		return notifications, rec.Header().Get("X-Unread-Count")
+	}
+
+	assert.NoError(t, call(api.Like, 2, "4", ""))
+	assert.NoError(t, call(api.Like, 3, "4", ""))
+	assert.NoError(t, call(api.Like, 1, "4", ""))
+	assert.NoError(t, call(api.PostComment, 2, "1", `{"content":"Lovely"}`))
+	assert.NoError(t, call(api.Follow, 2, "1", ""))
+
+	notifications, unread := inbox(1)
+	assert.Equal(t, "3", unread)
+	if !assert.Len(t, notifications, 3) {
+		return
+	}
+	follow, comment, like := notifications[0], notifications[1], notifications[2]
+	assert.Equal(t, models.NotificationFollow, follow.Type)
+	assert.Equal(t, "Someone followed you", follow.Message)
+	assert.Equal(t, models.NotificationComment, comment.Type)
+	assert.NotZero(t, comment.CommentId)
+	assert.Equal(t, models.NotificationLike, like.Type)
+	assert.Equal(t, 3, like.ActorId)
+	assert.Equal(t, 2, like.Actors)
+	assert.Equal(t, "2 people liked your hokku", like.Message)
+
+	// Read notifications gather no more events
+	assert.NoError(t, call(api.ReadNotification, 1, "1", ""))
+	httpErr, ok := call(api.ReadNotification, 2, "1", "").(*echo.HTTPError)
+	if assert.True(t, ok) {
+		assert.Equal(t, http.StatusNotFound, httpErr.Code)
+	}
+	assert.NoError(t, call(api.Unlike, 2, "4", ""))
+	assert.NoError(t, call(api.Like, 2, "4", ""))
+	notifications, unread = inbox(1)
+	assert.Equal(t, "3", unread)
+	if assert.Len(t, notifications, 4) {
+		assert.Equal(t, models.NotificationLike, notifications[0].Type)
+		assert.Equal(t, 1, notifications[0].Actors)
+		assert.False(t, notifications[0].Read)
+		assert.True(t, notifications[3].Read)
+	}
+
+	// Turned off types and muted users send nothing
+	assert.Error(t, call(api.PutNotificationPreferences, 1, "", `{"hokku.shared":false}`))
+	assert.NoError(t, call(api.PutNotificationPreferences, 1, "", `{"hokku.liked":false}`))
+	assert.NoError(t, call(api.Mute, 1, "2", ""))
+	assert.NoError(t, call(api.Like, 3, "12", ""))
+	assert.NoError(t, call(api.PostComment, 2, "12", `{"content":"Lovely"}`))
+	notifications, _ = inbox(1)
+	assert.Len(t, notifications, 4)
+
+	rec := httptest.NewRecorder()
+	c := api.Echo.NewContext(httptest.NewRequest(echo.GET, "/restricted/notifications/preferences", nil), rec)
+	c.Set("userId", 1)
+	assert.NoError(t, api.GetNotificationPreferences(c))
+	prefs := map[string]bool{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &prefs))
+	assert.False(t, prefs[models.NotificationLike])
+	assert.True(t, prefs[models.NotificationComment])
+	assert.Len(t, prefs, len(models.NotificationTypes))
+
+	assert.NoError(t, call(api.ReadNotifications, 1, "", ""))
+	rec = httptest.NewRecorder()
+	c = api.Echo.NewContext(httptest.NewRequest(echo.GET, "/restricted/notifications/unread", nil), rec)
+	c.Set("userId", 1)
+	assert.NoError(t, api.CountUnreadNotifications(c))
+	assert.JSONEq(t, `{"total":0,"byType":{}}`, rec.Body.String())
+}
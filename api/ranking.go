@@ -0,0 +1,67 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// @Summary Get trending hokkus
+// @Description Get hokkus ordered by their reactions, recent reactions weigh more. Scores are refreshed periodically
+// @Tags Open routes
+// @Accept json
+// @Produce json
+// @Param limit query int false "Sample size"
+// @Param offset query int false "Number of items to skip"
+// @Success 200 {array} models.Hokku
+// @Failure 400 {object} echo.HTTPError "Bad query parameters"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /hokkus/trending [get]
+func (api *APIServer) GetTrendingHokkus(c echo.Context) error {
+	limit, offset, err := paginationParams(c)
+	if err != nil {
+		return err
+	}
+	viewerId, _ := api.currentUserId(c)
+	result, err := api.store.GetTrendingHokkus(viewerId, limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Get top hokkus
+// @Description Get hokkus published during the period ordered by their reactions. Scores are refreshed periodically
+// @Tags Open routes
+// @Accept json
+// @Produce json
+// @Param period query string false "week, month or all, all by default"
+// @Param limit query int false "Sample size"
+// @Param offset query int false "Number of items to skip"
+// @Success 200 {array} models.Hokku
+// @Failure 400 {object} echo.HTTPError "Bad query parameters or unknown period"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /hokkus/top [get]
+func (api *APIServer) GetTopHokkus(c echo.Context) error {
+	limit, offset, err := paginationParams(c)
+	if err != nil {
+		return err
+	}
+	var since time.Time
+	switch c.QueryParam("period") {
+	case "week":
+		since = api.Clock.Now().AddDate(0, 0, -7)
+	case "month":
+		since = api.Clock.Now().AddDate(0, -1, 0)
+	case "all", "":
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "Period must be week, month or all")
+	}
+	viewerId, _ := api.currentUserId(c)
+	result, err := api.store.GetTopHokkus(viewerId, since, limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,100 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func hokkuIds(t *testing.T, body []byte) []int {
+	hs := []*models.Hokku{}
+	assert.NoError(t, json.Unmarshal(body, &hs))
+	ids := []int{}
+	for _, h := range hs {
+		ids = append(ids, h.Id)
+	}
+	return ids
+}
+
+func TestGetTrendingHokkus(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	assert.NoError(t, s.SaveScores([]*models.HokkuScore{
+		{HokkuId: 3, Score: 10, Trending: 5},
+		{HokkuId: 2, Score: 1, Trending: 7},
+	}))
+	req := httptest.NewRequest(echo.GET, "/hokkus/trending?limit=3", nil)
+	rec := httptest.NewRecorder()
+	c := api.Echo.NewContext(req, rec)
+	assert.NoError(t, api.GetTrendingHokkus(c))
+	// Hokkus without score go from the newest one
+	assert.Equal(t, []int{2, 3, 13}, hokkuIds(t, rec.Body.Bytes()))
+}
+
+func TestGetTopHokkus(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	now := time.Date(2030, time.March, 1, 0, 0, 0, 0, time.UTC)
+	api.Clock = clock.NewMock(now)
+	recent := &models.Hokku{Title: "Recent", Content: "Content", OwnerId: 1, ThemeId: 1, Created: now.AddDate(0, 0, -10),
+		Status: models.StatusPublished, Visibility: models.VisibilityPublic}
+	recentId, _ := s.CreateHokku(recent)
+	assert.NoError(t, s.SaveScores([]*models.HokkuScore{
+		{HokkuId: 3, Score: 10, Trending: 5},
+		{HokkuId: 2, Score: 1, Trending: 7},
+		{HokkuId: recentId, Score: 2},
+	}))
+	cases := []struct {
+		name     string
+		period   string
+		expected []int
+		isValid  bool
+	}{
+		{
+			name:     "all",
+			period:   "all",
+			expected: []int{3, recentId, 2},
+			isValid:  true,
+		},
+		{
+			name:     "month",
+			period:   "month",
+			expected: []int{recentId},
+			isValid:  true,
+		},
+		{
+			name:     "week",
+			period:   "week",
+			expected: []int{},
+			isValid:  true,
+		},
+		{
+			name:    "unknown period",
+			period:  "year",
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/hokkus/top?limit=3&period="+cs.period, nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			if cs.isValid {
+				assert.NoError(t, api.GetTopHokkus(c))
+				assert.Equal(t, cs.expected, hokkuIds(t, rec.Body.Bytes()))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.GetTopHokkus(c))
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/labstack/echo/v4"
+)
+
+// hokku returns the hokku with the id from the path if it is visible to the
+// viewer.
+func (api *APIServer) hokku(c echo.Context, viewerId int) (*models.Hokku, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	h, err := api.store.GetHokku(viewerId, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "A hokku with the specified ID was not found")
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return h, nil
+}
+
+// react adds or removes a reaction of the current user to the hokku from
+// the path and then calls done unless it is nil.
+func (api *APIServer) react(c echo.Context, action func(userId, hokkuId int) error,
+	done func(c echo.Context, userId int, h *models.Hokku)) error {
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	h, err := api.hokku(c, userId)
+	if err != nil {
+		return err
+	}
+	if err := action(userId, h.Id); err != nil {
+		if errors.Is(err, store.ErrAlreadyExist) {
+			return echo.NewHTTPError(http.StatusConflict, "The reaction is already added")
+		}
+		if errors.Is(err, store.ErrBlocked) {
+			return echo.NewHTTPError(http.StatusForbidden, "The user has blocked you")
+		}
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "The reaction was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	if done != nil {
+		done(c, userId, h)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Like hokku
+// @Security cookieAuth
+// @Description Like the hokku
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of hokku"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Bad request. Hokku ID must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The user has blocked you"
+// @Failure 404 {object} echo.HTTPError "A hokku with the specified ID was not found"
+// @Failure 409 {object} echo.HTTPError "The hokku is already liked"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/hokku/{id}/like [post]
+func (api *APIServer) Like(c echo.Context) error {
+	return api.react(c, api.store.Like, func(c echo.Context, userId int, h *models.Hokku) {
+		api.notify(c, &models.Notification{UserId: h.OwnerId, Type: models.NotificationLike, ActorId: userId, HokkuId: h.Id})
+	})
+}
+
+// @Summary Unlike hokku
+// @Security cookieAuth
+// @Description Remove the like of the hokku
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of hokku"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Bad request. Hokku ID must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 404 {object} echo.HTTPError "The hokku or the like was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/hokku/{id}/like [delete]
+func (api *APIServer) Unlike(c echo.Context) error {
+	return api.react(c, api.store.Unlike, nil)
+}
+
+// @Summary Bookmark hokku
+// @Security cookieAuth
+// @Description Add the hokku to the bookmarks
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of hokku"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Bad request. Hokku ID must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 404 {object} echo.HTTPError "A hokku with the specified ID was not found"
+// @Failure 409 {object} echo.HTTPError "The hokku is already bookmarked"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/hokku/{id}/bookmark [post]
+func (api *APIServer) Bookmark(c echo.Context) error {
+	return api.react(c, api.store.Bookmark, nil)
+}
+
+// @Summary Remove bookmark
+// @Security cookieAuth
+// @Description Remove the hokku from the bookmarks
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of hokku"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Bad request. Hokku ID must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 404 {object} echo.HTTPError "The hokku or the bookmark was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/hokku/{id}/bookmark [delete]
+func (api *APIServer) Unbookmark(c echo.Context) error {
+	return api.react(c, api.store.Unbookmark, nil)
+}
+
+// @Summary Get bookmarks
+// @Security cookieAuth
+// @Description Get the hokkus bookmarked by the current user
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param limit query int false "Sample size"
+// @Param offset query int false "Number of items to skip"
+// @Success 200 {array} models.Hokku
+// @Failure 400 {object} echo.HTTPError "Bad query parameters"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/bookmarks [get]
+func (api *APIServer) GetBookmarks(c echo.Context) error {
+	limit, offset, err := paginationParams(c)
+	if err != nil {
+		return err
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	result, err := api.store.GetBookmarks(userId, limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Get comments
+// @Description Get comments of the hokku from the first one
+// @Tags Open routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of hokku"
+// @Param limit query int false "Sample size"
+// @Param offset query int false "Number of items to skip"
+// @Success 200 {array} models.Comment
+// @Failure 400 {object} echo.HTTPError "Bad query parameters"
+// @Failure 404 {object} echo.HTTPError "A hokku with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /hokku/{id}/comments [get]
+func (api *APIServer) GetComments(c echo.Context) error {
+	limit, offset, err := paginationParams(c)
+	if err != nil {
+		return err
+	}
+	viewerId, _ := api.currentUserId(c)
+	h, err := api.hokku(c, viewerId)
+	if err != nil {
+		return err
+	}
+	result, err := api.store.GetComments(viewerId, h.Id, limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Post comment
+// @Security cookieAuth
+// @Description Comment the hokku
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of hokku"
+// @Param comment body models.Comment true "The comment object can only contain content"
+// @Success 201 "Created"
+// @Failure 400 {object} echo.HTTPError "Dont pass validation"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "Posting is suspended or the user has blocked you"
+// @Failure 404 {object} echo.HTTPError "A hokku with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/hokku/{id}/comment [post]
+func (api *APIServer) PostComment(c echo.Context) error {
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	h, err := api.hokku(c, userId)
+	if err != nil {
+		return err
+	}
+	comment := &models.Comment{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&comment); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	if err := comment.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
+	}
+	moderation, err := api.screen(textField{"content", &comment.Content})
+	if err != nil {
+		return err
+	}
+	comment.HokkuId, comment.OwnerId = h.Id, userId
+	id, err := api.store.CreateComment(comment)
+	if err != nil {
+		if errors.Is(err, store.ErrBlocked) {
+			return echo.NewHTTPError(http.StatusForbidden, "The user has blocked you")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	api.reportFiltered(c, &models.Report{HokkuId: h.Id, CommentId: id, AuthorId: userId}, moderation)
+	api.notify(c, &models.Notification{UserId: h.OwnerId, Type: models.NotificationComment, ActorId: userId,
+		HokkuId: h.Id, CommentId: id})
+	c.Response().Header().Set("Location", fmt.Sprintf("/comment/%d", id))
+	return c.NoContent(http.StatusCreated)
+}
+
+// @Summary Delete comment
+// @Security cookieAuth
+// @Description Delete a comment of the current user
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of comment"
+// @Success 204 "Deleted succesfuly"
+// @Failure 400 {object} echo.HTTPError "Bad request. Comment ID must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The comment belongs to another user"
+// @Failure 404 {object} echo.HTTPError "A comment with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/comment/{id} [delete]
+func (api *APIServer) DeleteComment(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	comment, err := api.store.GetComment(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A comment with the specified ID was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	if comment.OwnerId != userId {
+		return echo.NewHTTPError(http.StatusForbidden, "The comment belongs to another user")
+	}
+	if err := api.store.DeleteComment(id); err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A comment with the specified ID was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	api.audit(c, &models.AuditEntry{Action: models.AuditCommentDelete, TargetType: models.TargetComment, TargetId: id}, comment, nil)
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,253 @@
+package api_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLike(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		id      string
+		like    bool
+		isValid bool
+	}{
+		{
+			name:    "like",
+			id:      "3",
+			like:    true,
+			isValid: true,
+		},
+		{
+			name:    "already liked",
+			id:      "3",
+			like:    true,
+			isValid: false,
+		},
+		{
+			name:    "unlike",
+			id:      "3",
+			isValid: true,
+		},
+		{
+			name:    "not liked",
+			id:      "3",
+			isValid: false,
+		},
+		{
+			name:    "private hokku of another user",
+			id:      "10",
+			like:    true,
+			isValid: false,
+		},
+		{
+			name:    "invalid id",
+			id:      "id",
+			like:    true,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/restricted/hokku/", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", 1)
+			action := api.Unlike
+			if cs.like {
+				action = api.Like
+			}
+			if cs.isValid {
+				assert.NoError(t, action(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, action(c))
+			}
+		})
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	api := testAPIServer()
+	newContext := func(id string) echo.Context {
+		req := httptest.NewRequest(echo.POST, "/restricted/hokku/", nil)
+		c := api.Echo.NewContext(req, httptest.NewRecorder())
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		c.Set("userId", 1)
+		return c
+	}
+	assert.NoError(t, api.Bookmark(newContext("5")))
+	assert.Error(t, api.Bookmark(newContext("5")))
+	assert.Error(t, api.Bookmark(newContext("100")))
+	assert.NoError(t, api.Unbookmark(newContext("3")))
+	assert.Error(t, api.Unbookmark(newContext("3")))
+
+	req := httptest.NewRequest(echo.GET, "/restricted/bookmarks", nil)
+	rec := httptest.NewRecorder()
+	c := api.Echo.NewContext(req, rec)
+	c.Set("userId", 1)
+	assert.NoError(t, api.GetBookmarks(c))
+	// Unlisted hokkus stay in the bookmarks
+	assert.Equal(t, []int{8, 5}, hokkuIds(t, rec.Body.Bytes()))
+}
+
+func TestGetComments(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name         string
+		id           string
+		expectedBody []byte
+		isValid      bool
+	}{
+		{
+			name:         "valid",
+			id:           "1",
+			expectedBody: test_store.MockComments(1),
+			isValid:      true,
+		},
+		{
+			name:         "no comments",
+			id:           "2",
+			expectedBody: []byte("[]\n"),
+			isValid:      true,
+		},
+		{
+			name:    "private hokku",
+			id:      "10",
+			isValid: false,
+		},
+		{
+			name:    "invalid id",
+			id:      "id",
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/hokku/", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			if cs.isValid {
+				assert.NoError(t, api.GetComments(c))
+				assert.Equal(t, string(cs.expectedBody), rec.Body.String())
+			}
+			if !cs.isValid {
+				assert.Error(t, api.GetComments(c))
+			}
+		})
+	}
+}
+
+func TestPostComment(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		id      string
+		reqBody string
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			id:      "2",
+			reqBody: `{"content":"Lovely"}`,
+			isValid: true,
+		},
+		{
+			name:    "empty",
+			id:      "2",
+			reqBody: `{"content":""}`,
+			isValid: false,
+		},
+		{
+			name:    "bad body params",
+			id:      "2",
+			reqBody: `{Error}`,
+			isValid: false,
+		},
+		{
+			name:    "not found",
+			id:      "100",
+			reqBody: `{"content":"Lovely"}`,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/restricted/hokku/", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", 1)
+			if cs.isValid {
+				assert.NoError(t, api.PostComment(c))
+				assert.Equal(t, "/comment/3", rec.Header().Get("Location"))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.PostComment(c))
+			}
+		})
+	}
+}
+
+func TestDeleteComment(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		id      string
+		userId  int
+		isValid bool
+	}{
+		{
+			name:    "comment of another user",
+			id:      "1",
+			userId:  1,
+			isValid: false,
+		},
+		{
+			name:    "valid",
+			id:      "1",
+			userId:  2,
+			isValid: true,
+		},
+		{
+			name:    "already deleted",
+			id:      "1",
+			userId:  2,
+			isValid: false,
+		},
+		{
+			name:    "invalid id",
+			id:      "id",
+			userId:  2,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.DELETE, "/restricted/comment/", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", cs.userId)
+			if cs.isValid {
+				assert.NoError(t, api.DeleteComment(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.DeleteComment(c))
+			}
+		})
+	}
+}
@@ -0,0 +1,456 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/labstack/echo/v4"
+)
+
+// decodeReport reads the reason of a report from the request body.
+func decodeReport(c echo.Context) (*models.Report, error) {
+	report := &models.Report{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&report); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	if err := report.Validate(); err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
+	}
+	return report, nil
+}
+
+func (api *APIServer) saveReport(c echo.Context, report *models.Report) error {
+	id, err := api.store.CreateReport(report)
+	if err != nil {
+		if errors.Is(err, store.ErrAlreadyExist) {
+			return echo.NewHTTPError(http.StatusConflict, "The report is already sent")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	c.Response().Header().Set("Location", fmt.Sprintf("/moderation/report/%d", id))
+	return c.NoContent(http.StatusCreated)
+}
+
+// @Summary Report hokku
+// @Security cookieAuth
+// @Description Send the hokku to the moderation queue
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of hokku"
+// @Param report body models.Report true "Reason of the report: spam, abuse, plagiarism or other"
+// @Success 201 "Created"
+// @Failure 400 {object} echo.HTTPError "Dont pass validation or the hokku is written by the current user"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 404 {object} echo.HTTPError "A hokku with the specified ID was not found"
+// @Failure 409 {object} echo.HTTPError "The report is already sent"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/hokku/{id}/report [post]
+func (api *APIServer) ReportHokku(c echo.Context) error {
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	h, err := api.hokku(c, userId)
+	if err != nil {
+		return err
+	}
+	if h.OwnerId == userId {
+		return echo.NewHTTPError(http.StatusBadRequest, "Users can`t report themselves")
+	}
+	report, err := decodeReport(c)
+	if err != nil {
+		return err
+	}
+	report.ReporterId, report.HokkuId, report.AuthorId = userId, h.Id, h.OwnerId
+	return api.saveReport(c, report)
+}
+
+// @Summary Report user
+// @Security cookieAuth
+// @Description Send the user to the moderation queue
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of user"
+// @Param report body models.Report true "Reason of the report: spam, abuse, plagiarism or other"
+// @Success 201 "Created"
+// @Failure 400 {object} echo.HTTPError "Dont pass validation or the user is the current user"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 404 {object} echo.HTTPError "A user with the specified ID was not found"
+// @Failure 409 {object} echo.HTTPError "The report is already sent"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/user/{id}/report [post]
+func (api *APIServer) ReportUser(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	if userId == id {
+		return echo.NewHTTPError(http.StatusBadRequest, "Users can`t report themselves")
+	}
+	if _, err := api.store.GetUser(id); err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A user with the specified ID was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	report, err := decodeReport(c)
+	if err != nil {
+		return err
+	}
+	report.ReporterId, report.HokkuId, report.AuthorId = userId, 0, id
+	return api.saveReport(c, report)
+}
+
+// @Summary Get reports
+// @Security cookieAuth
+// @Description Get the moderation queue from the oldest report
+// @Tags Moderation routes
+// @Accept json
+// @Produce json
+// @Param status query string false "open, claimed or resolved, open and claimed reports by default"
+// @Param limit query int false "Sample size"
+// @Param offset query int false "Number of items to skip"
+// @Success 200 {array} models.Report
+// @Failure 400 {object} echo.HTTPError "Bad query parameters"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to moderators"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /moderation/reports [get]
+func (api *APIServer) GetReports(c echo.Context) error {
+	status := c.QueryParam("status")
+	switch status {
+	case "", models.ReportOpen, models.ReportClaimed, models.ReportResolved:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "Status must be open, claimed or resolved")
+	}
+	limit, offset, err := paginationParams(c)
+	if err != nil {
+		return err
+	}
+	result, err := api.store.GetReports(status, limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Get report
+// @Security cookieAuth
+// @Description Get the report
+// @Tags Moderation routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of report"
+// @Success 200 {object} models.Report
+// @Failure 400 {object} echo.HTTPError "Bad request. Id must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to moderators"
+// @Failure 404 {object} echo.HTTPError "A report with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /moderation/report/{id} [get]
+func (api *APIServer) GetReport(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	report, err := api.store.GetReport(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A report with the specified ID was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// reportError maps the errors of claiming and resolving reports.
+func reportError(err error) error {
+	if errors.Is(err, store.ErrNoRecord) {
+		return echo.NewHTTPError(http.StatusNotFound, "A report with the specified ID was not found")
+	}
+	if errors.Is(err, store.ErrAlreadyExist) {
+		return echo.NewHTTPError(http.StatusConflict, "The report is resolved or claimed by another moderator")
+	}
+	return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+}
+
+// @Summary Claim report
+// @Security cookieAuth
+// @Description Take the report so other moderators don't handle it
+// @Tags Moderation routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of report"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Bad request. Id must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to moderators"
+// @Failure 404 {object} echo.HTTPError "A report with the specified ID was not found"
+// @Failure 409 {object} echo.HTTPError "The report is resolved or claimed by another moderator"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /moderation/report/{id}/claim [post]
+func (api *APIServer) ClaimReport(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	if err := api.store.ClaimReport(id, userId); err != nil {
+		return reportError(err)
+	}
+	api.audit(c, &models.AuditEntry{Action: models.AuditReportClaim, TargetType: models.TargetReport, TargetId: id}, nil, nil)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Resolve report
+// @Security cookieAuth
+// @Description Hide or delete the reported hokku or user, or dismiss the report. All unresolved reports
+// @Description on the same hokku or user are resolved, their reporters and the author are notified
+// @Tags Moderation routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of report"
+// @Param resolution body models.Resolution true "Action: hide, delete or dismiss"
+// @Success 200 {array} models.Report
+// @Failure 400 {object} echo.HTTPError "Dont pass validation"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to moderators, only admins can moderate moderators"
+// @Failure 404 {object} echo.HTTPError "A report with the specified ID was not found"
+// @Failure 409 {object} echo.HTTPError "The report is resolved or claimed by another moderator"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /moderation/report/{id}/resolve [post]
+func (api *APIServer) ResolveReport(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	resolution := &models.Resolution{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&resolution); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	if err := resolution.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
+	}
+	report, err := api.store.GetReport(id)
+	if err != nil {
+		return reportError(err)
+	}
+	if err := api.checkModeration(userId, report, resolution.Action); err != nil {
+		return err
+	}
+	// Claiming first keeps other moderators from acting on the report
+	// while the content is moderated
+	if err := api.store.ClaimReport(id, userId); err != nil {
+		return reportError(err)
+	}
+	// The content is moderated only along with resolving the report
+	var resolved []*models.Report
+	err = api.store.WithTx(c.Request().Context(), func(tx store.Store) error {
+		if err := moderate(tx, report, resolution.Action); err != nil {
+			return err
+		}
+		reports, err := tx.ResolveReport(id, userId, resolution.Action)
+		resolved = reports
+		return err
+	})
+	if err != nil {
+		return reportError(err)
+	}
+	api.audit(c, &models.AuditEntry{Action: models.AuditReportResolve, TargetType: models.TargetReport, TargetId: id,
+		Details: resolution.Action}, report, resolved)
+	api.notifyResolved(c, resolved, resolution.Action)
+	return c.JSON(http.StatusOK, resolved)
+}
+
+// moderate hides or deletes the reported hokku, comment or user. Content
+// removed in the meantime is left as is.
+func moderate(s store.Store, report *models.Report, action string) error {
+	var err error
+	switch {
+	case action == models.ResolutionDismiss:
+	case report.CommentId != 0:
+		err = s.DeleteComment(report.CommentId)
+	default:
+		err = moderateContent(s, report, action)
+	}
+	if errors.Is(err, store.ErrNoRecord) {
+		return nil
+	}
+	return err
+}
+
+// checkModeration refuses moderators hiding or deleting the accounts of
+// moderators and admins.
+func (api *APIServer) checkModeration(moderatorId int, report *models.Report, action string) error {
+	if action == models.ResolutionDismiss || report.HokkuId != 0 || report.CommentId != 0 {
+		return nil
+	}
+	author, err := api.store.GetUser(report.AuthorId)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return nil
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return api.checkOutranks(moderatorId, author, "Only admins can moderate moderators")
+}
+
+func moderateContent(s store.Store, report *models.Report, action string) error {
+	var err error
+	switch action {
+	case models.ResolutionHide:
+		if report.HokkuId != 0 {
+			err = s.HideHokku(report.HokkuId)
+		} else {
+			err = s.HideUser(report.AuthorId)
+		}
+	case models.ResolutionDelete:
+		if report.HokkuId != 0 {
+			err = s.DeleteHokku(report.HokkuId)
+		} else {
+			err = deleteUser(s, report.AuthorId)
+		}
+	}
+	return err
+}
+
+// @Summary Unhide hokku
+// @Security cookieAuth
+// @Description Show the hokku hidden by a moderator again
+// @Tags Moderation routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of hokku"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Bad request. Id must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to moderators"
+// @Failure 404 {object} echo.HTTPError "A hokku with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /moderation/hokku/{id}/unhide [post]
+func (api *APIServer) UnhideHokku(c echo.Context) error {
+	return api.unhide(c, api.store.UnhideHokku, models.AuditHokkuUnhide, models.TargetHokku)
+}
+
+// @Summary Unhide user
+// @Security cookieAuth
+// @Description Show the hokkus of the user hidden by a moderator again. Hokkus hidden one by one stay hidden
+// @Tags Moderation routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of user"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Bad request. Id must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to moderators"
+// @Failure 404 {object} echo.HTTPError "A user with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /moderation/user/{id}/unhide [post]
+func (api *APIServer) UnhideUser(c echo.Context) error {
+	return api.unhide(c, api.store.UnhideUser, models.AuditUserUnhide, models.TargetUser)
+}
+
+func (api *APIServer) unhide(c echo.Context, unhide func(int) error, action, target string) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	if err := unhide(id); err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A "+target+" with the specified ID was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	api.audit(c, &models.AuditEntry{Action: action, TargetType: target, TargetId: id}, nil, nil)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// notifyResolved tells the reporters how their reports were resolved and
+// the author that their content was hidden or deleted. The reports are
+// already resolved, so failures are only logged.
+func (api *APIServer) notifyResolved(c echo.Context, reports []*models.Report, action string) {
+	notifications := []*models.Notification{}
+	for _, r := range reports {
+		if r.ReporterId == 0 {
+			continue
+		}
+		notifications = append(notifications, &models.Notification{
+			UserId:     r.ReporterId,
+			Type:       models.NotificationReportResolved,
+			ReportId:   r.Id,
+			HokkuId:    r.HokkuId,
+			Resolution: action,
+		})
+	}
+	if len(reports) > 0 && action != models.ResolutionDismiss {
+		notifications = append(notifications, &models.Notification{
+			UserId:     reports[0].AuthorId,
+			Type:       models.NotificationContentModerated,
+			HokkuId:    reports[0].HokkuId,
+			Resolution: action,
+		})
+	}
+	for _, n := range notifications {
+		api.notify(c, n)
+	}
+}
+
+// @Summary Put user role
+// @Security cookieAuth
+// @Description Appoint the user a moderator or an admin or take the role back
+// @Tags Admin routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of user"
+// @Param role body models.RoleChange true "user, moderator or admin"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Dont pass validation"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
+// @Failure 404 {object} echo.HTTPError "A user with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /admin/user/{id}/role [put]
+func (api *APIServer) PutUserRole(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	change := &models.RoleChange{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&change); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	if err := change.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
+	}
+	before := api.userSnapshot(id)
+	if err := api.store.SetRole(id, change.Role); err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A user with the specified ID was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	api.audit(c, &models.AuditEntry{Action: models.AuditRoleChange, TargetType: models.TargetUser, TargetId: id},
+		before, api.userSnapshot(id))
+	return c.NoContent(http.StatusNoContent)
+}
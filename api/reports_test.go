@@ -0,0 +1,276 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		user    bool
+		id      string
+		reqBody string
+		isValid bool
+	}{
+		{
+			name:    "hokku",
+			id:      "2",
+			reqBody: `{"reason":"spam","comment":"An advert"}`,
+			isValid: true,
+		},
+		{
+			name:    "hokku again",
+			id:      "2",
+			reqBody: `{"reason":"abuse"}`,
+			isValid: false,
+		},
+		{
+			name:    "own hokku",
+			id:      "1",
+			reqBody: `{"reason":"spam"}`,
+			isValid: false,
+		},
+		{
+			name:    "private hokku of another user",
+			id:      "10",
+			reqBody: `{"reason":"spam"}`,
+			isValid: false,
+		},
+		{
+			name:    "unknown reason",
+			id:      "3",
+			reqBody: `{"reason":"boring"}`,
+			isValid: false,
+		},
+		{
+			name:    "user",
+			user:    true,
+			id:      "2",
+			reqBody: `{"reason":"abuse"}`,
+			isValid: true,
+		},
+		{
+			name:    "self",
+			user:    true,
+			id:      "1",
+			reqBody: `{"reason":"abuse"}`,
+			isValid: false,
+		},
+		{
+			name:    "unknown user",
+			user:    true,
+			id:      "100",
+			reqBody: `{"reason":"abuse"}`,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/restricted/", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", 1)
+			action := api.ReportHokku
+			if cs.user {
+				action = api.ReportUser
+			}
+			if cs.isValid {
+				assert.NoError(t, action(c))
+				assert.Equal(t, http.StatusCreated, rec.Code)
+			}
+			if !cs.isValid {
+				assert.Error(t, action(c))
+			}
+		})
+	}
+}
+
+func TestResolveReport(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	report := func(reporterId, hokkuId, authorId int) int {
+		id, err := s.CreateReport(&models.Report{
+			ReporterId: reporterId, HokkuId: hokkuId, AuthorId: authorId, Reason: models.ReasonSpam,
+		})
+		assert.NoError(t, err)
+		return id
+	}
+	resolve := func(moderatorId, id int, action string) error {
+		req := httptest.NewRequest(echo.POST, "/moderation/report/", strings.NewReader(`{"action":"`+action+`"}`))
+		rec := httptest.NewRecorder()
+		c := api.Echo.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues(strconv.Itoa(id))
+		c.Set("userId", moderatorId)
+		return api.ResolveReport(c)
+	}
+	assert.NoError(t, s.SetRole(2, models.RoleModerator))
+
+	// Hiding a hokku resolves every report on it
+	first, second := report(1, 3, 3), report(2, 3, 3)
+	assert.NoError(t, resolve(2, first, models.ResolutionHide))
+	r, _ := s.GetReport(second)
+	assert.Equal(t, models.ReportResolved, r.Status)
+	_, err := s.GetHokku(1, 3)
+	assert.Error(t, err)
+	_, err = s.GetHokku(3, 3)
+	assert.NoError(t, err)
+	assert.Error(t, resolve(2, first, models.ResolutionDismiss))
+
+	// A claimed report is left to its moderator
+	claimed := report(1, 2, 2)
+	assert.NoError(t, s.ClaimReport(claimed, 3))
+	assert.Error(t, resolve(2, claimed, models.ResolutionDelete))
+	assert.NoError(t, resolve(3, claimed, models.ResolutionDelete))
+	_, err = s.GetHokku(0, 2)
+	assert.Error(t, err)
+
+	// Only admins delete moderators and admins
+	admin := report(1, 0, 3)
+	assert.Error(t, resolve(2, admin, models.ResolutionDelete))
+	r, _ = s.GetReport(admin)
+	assert.Equal(t, models.ReportOpen, r.Status)
+	_, err = s.GetUser(3)
+	assert.NoError(t, err)
+
+	// Dismissed reports change nothing
+	dismissed := report(2, 4, 1)
+	assert.Error(t, resolve(2, dismissed, "ban"))
+	assert.NoError(t, resolve(2, dismissed, models.ResolutionDismiss))
+	_, err = s.GetHokku(0, 4)
+	assert.NoError(t, err)
+
+	notifications := map[int][]string{}
+	for _, n := range s.Notifications {
+		notifications[n.UserId] = append(notifications[n.UserId], n.Type+" "+n.Resolution)
+	}
+	assert.Equal(t, map[int][]string{
+		1: {"report.resolved hide", "report.resolved delete"},
+		2: {"report.resolved hide", "content.moderated delete", "report.resolved dismiss"},
+		3: {"content.moderated hide"},
+	}, notifications)
+}
+
+func TestResolveReportDeletesWebhooks(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	for _, owner := range []int{1, 2} {
+		_, err := s.CreateWebhook(&models.Webhook{OwnerId: owner, URL: "https://example.com", Events: models.WebhookEvents})
+		assert.NoError(t, err)
+	}
+	id, err := s.CreateReport(&models.Report{ReporterId: 1, AuthorId: 2, Reason: models.ReasonAbuse})
+	assert.NoError(t, err)
+	req := httptest.NewRequest(echo.POST, "/moderation/report/", strings.NewReader(`{"action":"delete"}`))
+	c := api.Echo.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues(strconv.Itoa(id))
+	c.Set("userId", 3)
+	assert.NoError(t, api.ResolveReport(c))
+	_, err = s.GetUser(2)
+	assert.Error(t, err)
+	hooks, err := s.GetWebhooks(0)
+	assert.NoError(t, err)
+	if assert.Len(t, hooks, 1) {
+		assert.Equal(t, 1, hooks[0].OwnerId)
+	}
+}
+
+func TestGetReports(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	for _, hokkuId := range []int{2, 3} {
+		_, err := s.CreateReport(&models.Report{ReporterId: 1, HokkuId: hokkuId, AuthorId: 2, Reason: models.ReasonSpam})
+		assert.NoError(t, err)
+	}
+	_, err := s.ResolveReport(1, 3, models.ResolutionDismiss)
+	assert.NoError(t, err)
+	cases := []struct {
+		name    string
+		query   string
+		ids     []int
+		isValid bool
+	}{
+		{name: "unresolved", ids: []int{2}, isValid: true},
+		{name: "resolved", query: "?status=resolved", ids: []int{1}, isValid: true},
+		{name: "unknown status", query: "?status=lost", isValid: false},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/moderation/reports"+cs.query, nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.Set("userId", 3)
+			err := api.GetReports(c)
+			if !cs.isValid {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			reports := []*models.Report{}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &reports))
+			ids := []int{}
+			for _, r := range reports {
+				ids = append(ids, r.Id)
+			}
+			assert.Equal(t, cs.ids, ids)
+		})
+	}
+}
+
+func TestHideUser(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	assert.NoError(t, s.SetRole(2, models.RoleModerator))
+	call := func(handler func(echo.Context) error, userId, id int, body string) error {
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(body))
+		c := api.Echo.NewContext(req, httptest.NewRecorder())
+		c.SetParamNames("id")
+		c.SetParamValues(strconv.Itoa(id))
+		c.Set("userId", userId)
+		return handler(c)
+	}
+	id, err := s.CreateReport(&models.Report{ReporterId: 3, AuthorId: 1, Reason: models.ReasonSpam})
+	assert.NoError(t, err)
+	assert.NoError(t, call(api.ResolveReport, 2, id, `{"action":"hide"}`))
+
+	// Hokkus posted after hiding the user are hidden too
+	assert.NoError(t, call(api.PostHokku, 1, 0, `{"title":"Example","content":"1","themeId":1}`))
+	posted := s.Hokkus[len(s.Hokkus)-1].Id
+	for _, hokkuId := range []int{1, posted} {
+		_, err = s.GetHokku(3, hokkuId)
+		assert.ErrorIs(t, err, store.ErrNoRecord)
+		_, err = s.GetHokku(1, hokkuId)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, call(api.UnhideUser, 2, 1, ""))
+	assert.Error(t, call(api.UnhideUser, 2, 100, ""))
+	for _, hokkuId := range []int{1, posted} {
+		_, err = s.GetHokku(3, hokkuId)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, s.HideHokku(posted))
+	_, err = s.GetHokku(3, posted)
+	assert.Error(t, err)
+	assert.NoError(t, call(api.UnhideHokku, 2, posted, ""))
+	_, err = s.GetHokku(3, posted)
+	assert.NoError(t, err)
+	assert.Error(t, call(api.UnhideHokku, 2, 1000, ""))
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/EgorSkurihin/Hokku/stream"
+	"github.com/labstack/echo/v4"
+)
+
+// activeRestriction returns the newest active restriction of the kind
+// placed on the user, nil when there is none.
+func (api *APIServer) activeRestriction(userId int, kind string) (*models.Restriction, error) {
+	restrictions, err := api.store.GetActiveRestrictions(userId, api.Clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range restrictions {
+		if r.Kind == kind {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkRestriction answers with 403 when the user is under an active
+// restriction of the kind, telling the reason and the expiry.
+func (api *APIServer) checkRestriction(userId int, kind, message string) error {
+	r, err := api.activeRestriction(userId, kind)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	if r == nil {
+		return nil
+	}
+	return echo.NewHTTPError(http.StatusForbidden, echo.Map{
+		"message": message,
+		"reason":  r.Reason,
+		"expires": r.Expires,
+	})
+}
+
+// @Summary Restrict user
+// @Security cookieAuth
+// @Description Ban the user, suspend their posting or shadow-ban them so their hokkus are shown only to themselves. Restrictions without the expiry are permanent. Only admins restrict moderators
+// @Tags Moderation routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of user"
+// @Param restriction body models.Restriction true "Kind, reason and expiry"
+// @Success 201 "Created"
+// @Failure 400 {object} echo.HTTPError "Dont pass validation, the expiry is past or users can`t restrict themselves"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to moderators"
+// @Failure 404 {object} echo.HTTPError "A user with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /moderation/user/{id}/restriction [post]
+func (api *APIServer) PostRestriction(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	moderatorId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	r := &models.Restriction{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&r); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	if err := r.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
+	}
+	if r.Expires != nil && !r.Expires.After(api.Clock.Now()) {
+		return echo.NewHTTPError(http.StatusBadRequest, "The expiry must be in the future")
+	}
+	if id == moderatorId {
+		return echo.NewHTTPError(http.StatusBadRequest, "Users can`t restrict themselves")
+	}
+	u, err := api.store.GetUser(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A user with the specified ID was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	if err := api.checkOutranks(moderatorId, u, "Only admins can restrict moderators"); err != nil {
+		return err
+	}
+	r.UserId, r.ModeratorId = id, moderatorId
+	if _, err := api.store.CreateRestriction(r); err != nil {
+		if errors.Is(err, store.ErrForeignKeyConstraint) {
+			return echo.NewHTTPError(http.StatusNotFound, "A user with the specified ID was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	api.audit(c, &models.AuditEntry{Action: models.AuditRestrict, TargetType: models.TargetUser, TargetId: id}, nil, r)
+	// Banned users stop listening at once, other servers drop them on the
+	// next heartbeat
+	if r.Kind == models.RestrictionBan && api.Hub != nil {
+		api.Hub.CloseTopic(stream.UserTopic(id))
+	}
+	c.Response().Header().Set("Location", fmt.Sprintf("/moderation/user/%d/restrictions", id))
+	return c.NoContent(http.StatusCreated)
+}
+
+// @Summary Get user restrictions
+// @Security cookieAuth
+// @Description Get all restrictions ever placed on the user from the newest one, lifted and expired ones too
+// @Tags Moderation routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of user"
+// @Success 200 {object} []models.Restriction
+// @Failure 400 {object} echo.HTTPError "Bad request. Id must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to moderators"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /moderation/user/{id}/restrictions [get]
+func (api *APIServer) GetRestrictions(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	restrictions, err := api.store.GetRestrictions(id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, restrictions)
+}
+
+// @Summary Lift restriction
+// @Security cookieAuth
+// @Description Lift the restriction before it expires. Only admins lift restrictions of moderators
+// @Tags Moderation routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of restriction"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Bad request. Id must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to moderators"
+// @Failure 404 {object} echo.HTTPError "The restriction was not found or is already lifted"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /moderation/restriction/{id}/lift [post]
+func (api *APIServer) LiftRestriction(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	moderatorId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	r, err := api.store.GetRestriction(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "The restriction was not found or is already lifted")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	u, err := api.store.GetUser(r.UserId)
+	if err != nil && !errors.Is(err, store.ErrNoRecord) {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	if u != nil {
+		if err := api.checkOutranks(moderatorId, u, "Only admins can lift restrictions of moderators"); err != nil {
+			return err
+		}
+	}
+	if err := api.store.LiftRestriction(id, moderatorId); err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "The restriction was not found or is already lifted")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	api.audit(c, &models.AuditEntry{Action: models.AuditRestrictionLift, TargetType: models.TargetRestriction, TargetId: id}, nil, nil)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// checkOutranks refuses moderators acting on the user when the user is a
+// moderator or an admin too. Only admins act on them.
+func (api *APIServer) checkOutranks(moderatorId int, u *models.User, message string) error {
+	if !u.IsModerator() {
+		return nil
+	}
+	moderator, err := api.store.GetUser(moderatorId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	if !moderator.IsAdmin() {
+		return echo.NewHTTPError(http.StatusForbidden, message)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostRestriction(t *testing.T) {
+	s := test_store.New()
+	s.Users[1].Role = models.RoleModerator
+	api := api.New(&config.Server{}, s)
+	now := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+	api.Clock = clock.NewMock(now)
+	cases := []struct {
+		name        string
+		id          string
+		moderatorId int
+		reqBody     string
+		code        int
+	}{
+		{
+			name:        "permanent ban",
+			id:          "1",
+			moderatorId: 2,
+			reqBody:     `{"kind":"ban","reason":"Spam"}`,
+			code:        http.StatusCreated,
+		},
+		{
+			name:        "temporary suspension",
+			id:          "1",
+			moderatorId: 2,
+			reqBody:     `{"kind":"suspension","reason":"Flood","expires":"2030-03-08T12:00:00Z"}`,
+			code:        http.StatusCreated,
+		},
+		{
+			name:        "past expiry",
+			id:          "1",
+			moderatorId: 2,
+			reqBody:     `{"kind":"shadowban","reason":"Flood","expires":"2030-02-01T12:00:00Z"}`,
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "unknown kind",
+			id:          "1",
+			moderatorId: 2,
+			reqBody:     `{"kind":"exile","reason":"Spam"}`,
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "no reason",
+			id:          "1",
+			moderatorId: 2,
+			reqBody:     `{"kind":"ban"}`,
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "themselves",
+			id:          "2",
+			moderatorId: 2,
+			reqBody:     `{"kind":"ban","reason":"Spam"}`,
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "admin by moderator",
+			id:          "3",
+			moderatorId: 2,
+			reqBody:     `{"kind":"ban","reason":"Spam"}`,
+			code:        http.StatusForbidden,
+		},
+		{
+			name:        "moderator by admin",
+			id:          "2",
+			moderatorId: 3,
+			reqBody:     `{"kind":"shadowban","reason":"Spam"}`,
+			code:        http.StatusCreated,
+		},
+		{
+			name:        "unknown user",
+			id:          "100",
+			moderatorId: 2,
+			reqBody:     `{"kind":"ban","reason":"Spam"}`,
+			code:        http.StatusNotFound,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/moderation/user/", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", cs.moderatorId)
+			err := api.PostRestriction(c)
+			if cs.code == http.StatusCreated {
+				assert.NoError(t, err)
+				assert.Equal(t, cs.code, rec.Code)
+				return
+			}
+			httpErr, ok := err.(*echo.HTTPError)
+			if assert.True(t, ok) {
+				assert.Equal(t, cs.code, httpErr.Code)
+			}
+		})
+	}
+	if assert.Len(t, s.Restrictions, 3) {
+		assert.Equal(t, 2, s.Restrictions[0].ModeratorId)
+		assert.Nil(t, s.Restrictions[0].Expires)
+		assert.Equal(t, now.Add(7*24*time.Hour), *s.Restrictions[1].Expires)
+		assert.Equal(t, 3, s.Restrictions[2].ModeratorId)
+	}
+
+	req := httptest.NewRequest(echo.GET, "/moderation/user/", nil)
+	rec := httptest.NewRecorder()
+	c := api.Echo.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	if assert.NoError(t, api.GetRestrictions(c)) {
+		restrictions := []*models.Restriction{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &restrictions))
+		if assert.Len(t, restrictions, 2) {
+			assert.Equal(t, models.RestrictionSuspension, restrictions[0].Kind)
+			assert.Equal(t, models.RestrictionBan, restrictions[1].Kind)
+		}
+	}
+}
+
+func TestLoginBanned(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	now := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewMock(now)
+	api.Clock = clk
+	expires := now.Add(24 * time.Hour)
+	banId, err := s.CreateRestriction(&models.Restriction{UserId: 1, Kind: models.RestrictionBan, Reason: "Spam",
+		Expires: &expires, ModeratorId: 3})
+	assert.NoError(t, err)
+	_, err = s.CreateRestriction(&models.Restriction{UserId: 1, Kind: models.RestrictionSuspension, Reason: "Flood",
+		ModeratorId: 3})
+	assert.NoError(t, err)
+	login := func() error {
+		req := httptest.NewRequest(echo.POST, "/login", strings.NewReader(`{"email":"example1@email.com","password":"Admin"}`))
+		rec := httptest.NewRecorder()
+		return api.Login(api.Echo.NewContext(req, rec))
+	}
+
+	err = login()
+	httpErr, ok := err.(*echo.HTTPError)
+	if assert.True(t, ok) {
+		assert.Equal(t, http.StatusForbidden, httpErr.Code)
+		assert.Equal(t, "Spam", httpErr.Message.(echo.Map)["reason"])
+	}
+	// Suspended users can log in
+	clk.Add(25 * time.Hour)
+	assert.NoError(t, login())
+
+	clk.Set(now)
+	req := httptest.NewRequest(echo.POST, "/moderation/restriction/", nil)
+	rec := httptest.NewRecorder()
+	c := api.Echo.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	c.Set("userId", 3)
+	assert.NoError(t, api.LiftRestriction(c))
+	assert.NoError(t, login())
+	assert.Equal(t, 3, s.Restrictions[banId-1].LiftedBy)
+
+	rec = httptest.NewRecorder()
+	c = api.Echo.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	c.Set("userId", 3)
+	httpErr, ok = api.LiftRestriction(c).(*echo.HTTPError)
+	if assert.True(t, ok) {
+		assert.Equal(t, http.StatusNotFound, httpErr.Code)
+	}
+}
+
+func TestLiftRestrictionOfModerator(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	assert.NoError(t, s.SetRole(1, models.RoleModerator))
+	assert.NoError(t, s.SetRole(2, models.RoleModerator))
+	id, err := s.CreateRestriction(&models.Restriction{UserId: 1, Kind: models.RestrictionBan, Reason: "Spam", ModeratorId: 3})
+	assert.NoError(t, err)
+	lift := func(moderatorId int) error {
+		req := httptest.NewRequest(echo.POST, "/moderation/restriction/", nil)
+		c := api.Echo.NewContext(req, httptest.NewRecorder())
+		c.SetParamNames("id")
+		c.SetParamValues(strconv.Itoa(id))
+		c.Set("userId", moderatorId)
+		return api.LiftRestriction(c)
+	}
+
+	httpErr, ok := lift(2).(*echo.HTTPError)
+	if assert.True(t, ok) {
+		assert.Equal(t, http.StatusForbidden, httpErr.Code)
+	}
+	assert.Nil(t, s.Restrictions[id-1].Lifted)
+	assert.NoError(t, lift(3))
+	assert.Equal(t, 3, s.Restrictions[id-1].LiftedBy)
+}
+
+func TestShadowBan(t *testing.T) {
+	s := test_store.New()
+	_, err := s.CreateRestriction(&models.Restriction{UserId: 1, Kind: models.RestrictionShadowBan, Reason: "Spam",
+		ModeratorId: 3})
+	assert.NoError(t, err)
+	own := s.Hokkus[0]
+	_, err = s.GetHokku(own.OwnerId, own.Id)
+	assert.NoError(t, err)
+	_, err = s.GetHokku(2, own.Id)
+	assert.Error(t, err)
+	hs, err := s.GetHokkusByAuthor(0, own.OwnerId, 100, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, hs)
+	hs, err = s.GetHokkusByAuthor(own.OwnerId, own.OwnerId, 100, 0)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, hs)
+}
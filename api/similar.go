@@ -0,0 +1,67 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/labstack/echo/v4"
+)
+
+// maxSimilar limits the number of similar hokkus.
+const maxSimilar = 50
+
+// @Summary Get similar hokkus
+// @Description Get public hokkus similar to the hokku by their words, theme and tags, from the most similar one. Hokkus of the users muted by the viewer are left out
+// @Tags Open routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of hokku"
+// @Param limit query int false "Sample size, 10 by default and 50 at most"
+// @Success 200 {array} models.Hokku
+// @Failure 400 {object} echo.HTTPError "Bad request. Hokku ID and limit must be integers"
+// @Failure 404 {object} echo.HTTPError "A hokku with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /hokku/{id}/similar [get]
+func (api *APIServer) GetSimilarHokkus(c echo.Context) error {
+	limit, _, err := paginationParams(c)
+	if err != nil {
+		return err
+	}
+	if limit < 1 || limit > maxSimilar {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad query parameters")
+	}
+	viewerId, _ := api.currentUserId(c)
+	h, err := api.hokku(c, viewerId)
+	if err != nil {
+		return err
+	}
+	result := []*models.Hokku{}
+	if api.Similar == nil {
+		return c.JSON(http.StatusOK, result)
+	}
+	muted, err := api.mutedUsers(viewerId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	// The index knows nothing of the viewer, so the matches are cut to the
+	// limit only after the ones the viewer doesn't get are skipped
+	for _, m := range api.Similar.Similar(h, 0) {
+		if len(result) == limit {
+			break
+		}
+		match, err := api.store.GetHokku(viewerId, m.HokkuId)
+		if errors.Is(err, store.ErrNoRecord) {
+			continue
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+		}
+		if !match.Listed(viewerId, false) || muted[match.OwnerId] {
+			continue
+		}
+		result = append(result, match)
+	}
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,103 @@
+package api_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/similar"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSimilarHokkus(t *testing.T) {
+	index := similar.NewIndex()
+	s := similar.NewStore(test_store.New(), index)
+	assert.NoError(t, s.Rebuild())
+	api := api.New(&config.Server{}, s)
+	api.Similar = index
+	// Shares the words and the tag with hokku 2 only
+	id, err := s.CreateHokku(&models.Hokku{Title: "Snow", Content: "First snow falls", Tags: []string{"season:winter"},
+		OwnerId: 3, ThemeId: 3, Status: models.StatusPublished, Visibility: models.VisibilityPublic})
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name     string
+		id       string
+		limit    string
+		expected []int
+		isValid  bool
+	}{
+		{
+			name:     "valid",
+			id:       "2",
+			limit:    "1",
+			expected: []int{id},
+			isValid:  true,
+		},
+		{
+			name:    "private hokku",
+			id:      "10",
+			isValid: false,
+		},
+		{
+			name:    "too many",
+			id:      "2",
+			limit:   "100",
+			isValid: false,
+		},
+		{
+			name:    "invalid id",
+			id:      "id",
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/hokku/?limit="+cs.limit, nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			if cs.isValid {
+				assert.NoError(t, api.GetSimilarHokkus(c))
+				assert.Equal(t, cs.expected, hokkuIds(t, rec.Body.Bytes()))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.GetSimilarHokkus(c))
+			}
+		})
+	}
+}
+
+func TestGetSimilarHokkusMuted(t *testing.T) {
+	index := similar.NewIndex()
+	s := similar.NewStore(test_store.New(), index)
+	assert.NoError(t, s.Rebuild())
+	api := api.New(&config.Server{}, s)
+	api.Similar = index
+	muted, err := s.CreateHokku(&models.Hokku{Title: "Snow", Content: "First snow falls", Tags: []string{"season:winter"},
+		OwnerId: 3, ThemeId: 2, Status: models.StatusPublished, Visibility: models.VisibilityPublic})
+	assert.NoError(t, err)
+	other, err := s.CreateHokku(&models.Hokku{Title: "Melting", Content: "First snow melts", Tags: []string{"nature"},
+		OwnerId: 1, ThemeId: 2, Status: models.StatusPublished, Visibility: models.VisibilityPublic})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Mute(2, 3))
+
+	similar := func(viewerId int) []int {
+		req := httptest.NewRequest(echo.GET, "/hokku/?limit=1", nil)
+		rec := httptest.NewRecorder()
+		c := api.Echo.NewContext(req, rec)
+		c.SetParamNames("id")
+		c.SetParamValues("2")
+		c.Set("userId", viewerId)
+		assert.NoError(t, api.GetSimilarHokkus(c))
+		return hokkuIds(t, rec.Body.Bytes())
+	}
+	assert.Equal(t, []int{muted}, similar(1))
+	// The muted match doesn't take the place of the next one
+	assert.Equal(t, []int{other}, similar(2))
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/labstack/echo/v4"
+)
+
+// popularHokkus is the number of the most popular hokkus in the stats.
+const popularHokkus = 5
+
+// @Summary Get author statistics
+// @Security cookieAuth
+// @Description Get totals of the published hokkus of the current user and the reactions to them, the followers, the most popular hokkus, posting streaks, a breakdown by theme and the activity series for the last days, today included
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param interval query string false "day or week, day by default"
+// @Param days query int false "Number of days in the series, 30 by default and 365 at most"
+// @Success 200 {object} models.AuthorStats
+// @Failure 400 {object} echo.HTTPError "Bad query parameters"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/me/stats [get]
+func (api *APIServer) GetMyStats(c echo.Context) error {
+	interval := c.QueryParam("interval")
+	switch interval {
+	case "":
+		interval = models.IntervalDay
+	case models.IntervalDay, models.IntervalWeek:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "Interval must be day or week")
+	}
+	days := 30
+	if d := c.QueryParam("days"); d != "" {
+		var err error
+		days, err = strconv.Atoi(d)
+		if err != nil || days < 1 || days > maxViewDays {
+			return echo.NewHTTPError(http.StatusBadRequest, "Bad query parameters")
+		}
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+
+	today := api.Clock.Now().UTC()
+	first := today.AddDate(0, 0, 1-days)
+	since := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, time.UTC)
+	stats := &models.AuthorStats{Interval: interval}
+	totals, err := api.store.GetAuthorTotals(userId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	stats.AuthorTotals = *totals
+	if stats.Popular, err = api.store.GetPopularHokkus(userId, popularHokkus); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	if stats.Themes, err = api.store.GetThemeStats(userId); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	postingDays, err := api.store.GetPostingDays(userId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	stats.Streaks = models.PostingStreaks(postingDays, today)
+	activity, err := api.store.GetActivity(userId, since)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	stats.Series = models.ActivitySeries(activity, since, today, interval)
+	return c.JSON(http.StatusOK, stats)
+}
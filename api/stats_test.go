@@ -0,0 +1,90 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMyStats(t *testing.T) {
+	api := testAPIServer()
+	// Monday
+	api.Clock = clock.NewMock(time.Date(2030, time.January, 7, 12, 0, 0, 0, time.UTC))
+	cases := []struct {
+		name     string
+		query    string
+		userId   int
+		expected []*models.Activity
+		isValid  bool
+	}{
+		{
+			name:   "daily",
+			query:  "?days=7",
+			userId: 1,
+			expected: []*models.Activity{
+				{Day: "2030-01-01", Views: 10},
+				{Day: "2030-01-02", Views: 5},
+				{Day: "2030-01-03"},
+				{Day: "2030-01-04"},
+				{Day: "2030-01-05"},
+				{Day: "2030-01-06"},
+				{Day: "2030-01-07"},
+			},
+			isValid: true,
+		},
+		{
+			name:   "weekly",
+			query:  "?days=7&interval=week",
+			userId: 1,
+			expected: []*models.Activity{
+				{Day: "2030-01-01", Views: 15},
+				{Day: "2030-01-07"},
+			},
+			isValid: true,
+		},
+		{
+			name:    "unknown interval",
+			query:   "?interval=year",
+			userId:  1,
+			isValid: false,
+		},
+		{
+			name:    "too many days",
+			query:   "?days=366",
+			userId:  1,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/restricted/me/stats"+cs.query, nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.Set("userId", cs.userId)
+			if !cs.isValid {
+				assert.Error(t, api.GetMyStats(c))
+				return
+			}
+			assert.NoError(t, api.GetMyStats(c))
+			stats := &models.AuthorStats{}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), stats))
+			assert.Equal(t, models.AuthorTotals{Hokkus: 3, Likes: 2, Comments: 2, Views: 15}, stats.AuthorTotals)
+			popular := []int{}
+			for _, p := range stats.Popular {
+				popular = append(popular, p.HokkuId)
+			}
+			assert.Equal(t, []int{1, 4, 12}, popular)
+			if assert.Len(t, stats.Themes, 2) {
+				assert.Equal(t, 2, stats.Themes[0].ThemeId)
+				assert.Equal(t, 2, stats.Themes[0].Hokkus)
+			}
+			assert.Equal(t, cs.expected, stats.Series)
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/EgorSkurihin/Hokku/stream"
+	"github.com/labstack/echo/v4"
+)
+
+// Types of streamed events besides the hokku events.
+const (
+	eventNotification = "notification"
+	// eventReset tells the client that events were lost and the state has
+	// to be loaded again
+	eventReset = "reset"
+)
+
+// streamRetry is how long clients wait before reconnecting, in
+// milliseconds.
+const streamRetry = 3000
+
+// publish pushes the event to the subscribers of the topics if the hub is
+// set. The action is already done, so failures are only logged.
+func (api *APIServer) publish(c echo.Context, typ string, data interface{}, topics ...string) {
+	if api.Hub == nil {
+		return
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		c.Logger().Errorf("publish %s: %v", typ, err)
+		return
+	}
+	api.Hub.Publish(typ, raw, topics...)
+}
+
+// StreamEvent streams the hokku events relayed from the outbox if the hub
+// is set. Events repeated by the relay are skipped by the bus it is
+// subscribed to.
+func (api *APIServer) StreamEvent(e *models.OutboxEvent) error {
+	if api.Hub == nil {
+		return nil
+	}
+	a, err := api.announce(e)
+	if err != nil || a == nil || a.themeId == 0 {
+		return err
+	}
+	raw, err := json.Marshal(a.data)
+	if err != nil {
+		return err
+	}
+	api.Hub.Publish(a.typ, raw, stream.TopicHokkus, stream.ThemeTopic(a.themeId))
+	return nil
+}
+
+// announcement is the public side of an outbox event.
+type announcement struct {
+	typ     string
+	data    interface{}
+	themeId int
+}
+
+// announce turns the outbox event into what clients and integrations are
+// told. Only hokkus listed publicly are announced: hokkus that are no
+// longer listed are announced as deleted and the ones listed for the first
+// time, like published drafts, as created. It returns nil when there is
+// nothing to tell.
+func (api *APIServer) announce(e *models.OutboxEvent) (*announcement, error) {
+	if e.Type == models.EventUserCreated {
+		u := &models.UserEvent{}
+		if err := json.Unmarshal(e.Payload, u); err != nil {
+			return nil, err
+		}
+		return &announcement{typ: e.Type, data: u}, nil
+	}
+	p := &models.HokkuEvent{}
+	if err := json.Unmarshal(e.Payload, p); err != nil {
+		return nil, err
+	}
+	switch {
+	case p.Listed:
+		// The hokku is sent as it is now, later changes come with their own
+		// events
+		h, err := api.store.GetHokku(0, p.Id)
+		if errors.Is(err, store.ErrNoRecord) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !h.Listed(0, false) {
+			return nil, nil
+		}
+		// Hokkus that weren't listed before, like published drafts, are
+		// new to clients
+		typ := e.Type
+		if !p.WasListed {
+			typ = models.EventHokkuCreated
+		}
+		return &announcement{typ: typ, data: h, themeId: h.ThemeId}, nil
+	case p.WasListed:
+		return &announcement{typ: models.EventHokkuDeleted, data: echo.Map{"id": p.Id}, themeId: p.ThemeId}, nil
+	}
+	return nil, nil
+}
+
+// @Summary Stream updates
+// @Description Push new, updated and deleted public hokkus and the notifications of the current user as Server-Sent Events. Hokkus of the users muted by the current user are left out and the stream of a banned user is closed. The events are hokku.created, hokku.updated, hokku.deleted and notification. Comments are sent as heartbeats. Reconnecting clients send the Last-Event-ID header to get the events they missed, a reset event tells them to load the state again when the events are lost
+// @Tags Open routes
+// @Produce text/event-stream
+// @Param feed query bool false "Stream the hokkus of all themes, true by default"
+// @Param theme query []int false "Stream the hokkus of the themes" collectionFormat(multi)
+// @Param Last-Event-ID header int false "Id of the last received event"
+// @Success 200 {string} string "Event stream"
+// @Failure 400 {object} echo.HTTPError "Bad query parameters"
+// @Failure 403 {object} echo.HTTPError "The user is banned"
+// @Failure 503 {object} echo.HTTPError "Streaming is turned off"
+// @Router /stream [get]
+func (api *APIServer) Stream(c echo.Context) error {
+	if api.Hub == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Streaming is turned off")
+	}
+	topics, err := streamTopics(c)
+	if err != nil {
+		return err
+	}
+	muted := map[int]bool{}
+	userId, loggedIn := api.currentUserId(c)
+	if loggedIn {
+		if err := api.checkRestriction(userId, models.RestrictionBan, "The user is banned"); err != nil {
+			return err
+		}
+		topics = append(topics, stream.UserTopic(userId))
+		if muted, err = api.mutedUsers(userId); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+		}
+	}
+	var lastId uint64
+	if v := c.Request().Header.Get("Last-Event-ID"); v != "" {
+		if lastId, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "Bad query parameters")
+		}
+	}
+
+	sub, replay, complete := api.Hub.Subscribe(lastId, topics...)
+	defer sub.Close()
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set(echo.HeaderCacheControl, "no-cache")
+	w.Header().Set(echo.HeaderConnection, "keep-alive")
+	// Proxies must not buffer the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+	if !complete {
+		fmt.Fprintf(w, "event: %s\ndata: {}\n\n", eventReset)
+	}
+	for _, e := range replay {
+		if !fromMuted(e, muted) {
+			writeEvent(w, e)
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(api.Hub.Heartbeat())
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case e, ok := <-sub.Events():
+			if !ok {
+				// The client fell behind and catches up after reconnecting,
+				// or was banned
+				return nil
+			}
+			if fromMuted(e, muted) {
+				continue
+			}
+			writeEvent(w, e)
+			w.Flush()
+		case <-heartbeat.C:
+			// Bans and mutes made since the client connected apply too
+			if loggedIn {
+				if api.checkRestriction(userId, models.RestrictionBan, "The user is banned") != nil {
+					return nil
+				}
+				if m, err := api.mutedUsers(userId); err == nil {
+					muted = m
+				}
+			}
+			fmt.Fprint(w, ": heartbeat\n\n")
+			w.Flush()
+		}
+	}
+}
+
+// streamTopics reads the topics the client subscribes to from the query
+// string.
+func streamTopics(c echo.Context) ([]string, error) {
+	topics := []string{}
+	if v := c.QueryParam("feed"); v == "" || v == "true" {
+		topics = append(topics, stream.TopicHokkus)
+	} else if v != "false" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Bad query parameters")
+	}
+	for _, v := range c.QueryParams()["theme"] {
+		for _, s := range strings.Split(v, ",") {
+			id, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, echo.NewHTTPError(http.StatusBadRequest, "Bad query parameters")
+			}
+			topics = append(topics, stream.ThemeTopic(id))
+		}
+	}
+	return topics, nil
+}
+
+// mutedUsers returns the ids of the users muted by the user.
+func (api *APIServer) mutedUsers(userId int) (map[int]bool, error) {
+	mutes, err := api.store.GetMutes(userId)
+	if err != nil {
+		return nil, err
+	}
+	muted := map[int]bool{}
+	for _, m := range mutes {
+		muted[m.MutedId] = true
+	}
+	return muted, nil
+}
+
+// fromMuted reports whether the event is about a hokku of a muted user.
+// Deleted hokkus carry no owner and are always sent.
+func fromMuted(e *stream.Event, muted map[int]bool) bool {
+	if len(muted) == 0 || !strings.HasPrefix(e.Type, "hokku.") {
+		return false
+	}
+	h := struct {
+		OwnerId int `json:"ownerId"`
+	}{}
+	if err := json.Unmarshal(e.Data, &h); err != nil {
+		return false
+	}
+	return muted[h.OwnerId]
+}
+
+func writeEvent(w *echo.Response, e *stream.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.Id, e.Type, e.Data)
+}
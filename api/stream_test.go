@@ -0,0 +1,172 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/outbox"
+	"github.com/EgorSkurihin/Hokku/scheduler"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/EgorSkurihin/Hokku/stream"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamPublish(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	api.Hub = stream.NewHub(10, 10, time.Second)
+	sub, _, _ := api.Hub.Subscribe(0, stream.TopicHokkus, stream.UserTopic(1))
+	defer sub.Close()
+	call := func(handler func(echo.Context) error, userId int, id, body string) {
+		req := httptest.NewRequest(echo.POST, "/restricted/", strings.NewReader(body))
+		c := api.Echo.NewContext(req, httptest.NewRecorder())
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		c.Set("userId", userId)
+		assert.NoError(t, handler(c))
+	}
+	call(api.PostHokku, 1, "", `{"title":"Example","content":"1","ownerId":1,"themeId":1}`)
+	call(api.PostHokku, 1, "", `{"title":"Example","content":"1","ownerId":1,"themeId":1,"visibility":"private"}`)
+	call(api.PutHokku, 1, "4", `{"title":"Example","content":"1","ownerId":1,"themeId":2,"visibility":"private"}`)
+	call(api.DeleteHokku, 1, "1", "")
+	call(api.Like, 2, "12", "")
+	bus := outbox.NewBus(10)
+	bus.Subscribe(api.StreamEvent)
+	_, err := scheduler.NewOutboxRelay(s, time.Minute, 3, bus).Relay()
+	assert.NoError(t, err)
+
+	types := []string{}
+	for len(sub.Events()) > 0 {
+		e := <-sub.Events()
+		types = append(types, e.Type)
+		if e.Type == "notification" {
+			n := &models.Notification{}
+			assert.NoError(t, json.Unmarshal(e.Data, n))
+			assert.Equal(t, "Someone liked your hokku", n.Message)
+		}
+	}
+	assert.Equal(t, []string{"notification", "hokku.created", "hokku.deleted", "hokku.deleted"}, types)
+}
+
+func TestStream(t *testing.T) {
+	api := api.New(&config.Server{}, test_store.New())
+	api.Hub = stream.NewHub(3, 10, time.Second)
+	for _, topic := range []string{stream.ThemeTopic(1), stream.ThemeTopic(2), stream.TopicHokkus, stream.UserTopic(1)} {
+		api.Hub.Publish("hokku.created", []byte(`{}`), topic)
+	}
+	cases := []struct {
+		name      string
+		query     string
+		lastId    string
+		userId    int
+		ids       []string
+		reset     bool
+		errorCode int
+	}{
+		{name: "feed", query: "", lastId: "2", ids: []string{"3"}},
+		{name: "themes", query: "?feed=false&theme=2&theme=3,4", lastId: "1", ids: []string{"2"}},
+		{name: "notifications", query: "?feed=false", lastId: "2", userId: 1, ids: []string{"4"}},
+		{name: "new connection", query: "?theme=1", lastId: "", ids: []string{}},
+		{name: "restarted hub", query: "?theme=1", lastId: "9", ids: []string{}, reset: true},
+		{name: "bad theme", query: "?theme=one", errorCode: http.StatusBadRequest},
+		{name: "bad feed", query: "?feed=yes", errorCode: http.StatusBadRequest},
+		{name: "bad last event", query: "", lastId: "x", errorCode: http.StatusBadRequest},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			// The stream ends as soon as the replay is written
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			req := httptest.NewRequest(echo.GET, "/stream"+cs.query, nil).WithContext(ctx)
+			req.Header.Set("Last-Event-ID", cs.lastId)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			if cs.userId != 0 {
+				c.Set("userId", cs.userId)
+			}
+			err := api.Stream(c)
+			if cs.errorCode != 0 {
+				httpErr, ok := err.(*echo.HTTPError)
+				if assert.True(t, ok) {
+					assert.Equal(t, cs.errorCode, httpErr.Code)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "text/event-stream", rec.Header().Get(echo.HeaderContentType))
+			body := rec.Body.String()
+			assert.True(t, strings.HasPrefix(body, "retry: 3000\n\n"))
+			assert.Equal(t, cs.reset, strings.Contains(body, "event: reset\n"))
+			ids := []string{}
+			for _, line := range strings.Split(body, "\n") {
+				if strings.HasPrefix(line, "id: ") {
+					ids = append(ids, strings.TrimPrefix(line, "id: "))
+				}
+			}
+			assert.Equal(t, cs.ids, ids)
+		})
+	}
+	assert.Equal(t, 0, api.Hub.Subscribers())
+
+	api.Hub = nil
+	c := api.Echo.NewContext(httptest.NewRequest(echo.GET, "/stream", nil), httptest.NewRecorder())
+	httpErr, ok := api.Stream(c).(*echo.HTTPError)
+	if assert.True(t, ok) {
+		assert.Equal(t, http.StatusServiceUnavailable, httpErr.Code)
+	}
+}
+
+func TestStreamMutes(t *testing.T) {
+	s := test_store.New()
+	assert.NoError(t, s.Mute(2, 1))
+	api := api.New(&config.Server{}, s)
+	api.Hub = stream.NewHub(10, 10, time.Second)
+	api.Hub.Publish("hokku.created", []byte(`{"id":4,"ownerId":1}`), stream.TopicHokkus)
+	api.Hub.Publish("hokku.created", []byte(`{"id":1,"ownerId":1}`), stream.TopicHokkus)
+	api.Hub.Publish("hokku.created", []byte(`{"id":3,"ownerId":3}`), stream.TopicHokkus)
+	api.Hub.Publish("hokku.deleted", []byte(`{"id":4}`), stream.TopicHokkus)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(echo.GET, "/stream", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", "1")
+	rec := httptest.NewRecorder()
+	c := api.Echo.NewContext(req, rec)
+	c.Set("userId", 2)
+	assert.NoError(t, api.Stream(c))
+	ids := []string{}
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if strings.HasPrefix(line, "id: ") {
+			ids = append(ids, strings.TrimPrefix(line, "id: "))
+		}
+	}
+	assert.Equal(t, []string{"3", "4"}, ids)
+}
+
+func TestStreamBan(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	api.Hub = stream.NewHub(10, 10, time.Second)
+	banned, _, _ := api.Hub.Subscribe(0, stream.TopicHokkus, stream.UserTopic(1))
+	other, _, _ := api.Hub.Subscribe(0, stream.TopicHokkus, stream.UserTopic(2))
+
+	req := httptest.NewRequest(echo.POST, "/moderation/user/", strings.NewReader(`{"kind":"ban","reason":"Spam"}`))
+	c := api.Echo.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	c.Set("userId", 3)
+	assert.NoError(t, api.PostRestriction(c))
+	_, open := <-banned.Events()
+	assert.False(t, open)
+	assert.Equal(t, 1, api.Hub.Subscribers())
+	other.Close()
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/labstack/echo/v4"
+)
+
+// attachSeasons tags the hokku with the seasons of the kigo found in its
+// content.
+func (api *APIServer) attachSeasons(h *models.Hokku) error {
+	dict, err := api.store.GetKigo()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	h.AttachSeasons(dict)
+	return nil
+}
+
+// @Summary Get hokkus by tag
+// @Description Get hokkus with the tag. Season tags look like season:winter
+// @Tags Open routes
+// @Accept json
+// @Produce json
+// @Param limit query int false "Sample size"
+// @Param offset query int false "Number of items to skip"
+// @Param tag path string true "Tag"
+// @Success 200 {array} models.Hokku
+// @Failure 400 {object} echo.HTTPError "Bad query parameters"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /hokkus/byTag/{tag} [get]
+func (api *APIServer) GetHokkusByTag(c echo.Context) error {
+	limit, offset, err := paginationParams(c)
+	if err != nil {
+		return err
+	}
+	tags := models.NormalizeTags([]string{c.Param("tag")})
+	if len(tags) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Tag must not be empty")
+	}
+	viewerId, _ := api.currentUserId(c)
+	result, err := api.store.GetHokkusByTag(viewerId, tags[0], limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Get hokkus by season
+// @Description Get hokkus with a kigo of the season
+// @Tags Open routes
+// @Accept json
+// @Produce json
+// @Param limit query int false "Sample size"
+// @Param offset query int false "Number of items to skip"
+// @Param season path string true "Season: spring, summer, autumn, winter or new-year"
+// @Success 200 {array} models.Hokku
+// @Failure 400 {object} echo.HTTPError "Bad query parameters or unknown season"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /hokkus/bySeason/{season} [get]
+func (api *APIServer) GetHokkusBySeason(c echo.Context) error {
+	limit, offset, err := paginationParams(c)
+	if err != nil {
+		return err
+	}
+	season := c.Param("season")
+	if !models.IsSeason(season) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Unknown season")
+	}
+	viewerId, _ := api.currentUserId(c)
+	result, err := api.store.GetHokkusByTag(viewerId, models.SeasonTag(season), limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Get kigo dictionary
+// @Description Get seasonal words used to tag hokkus with seasons
+// @Tags Open routes
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.Kigo
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /kigo [get]
+func (api *APIServer) GetKigo(c echo.Context) error {
+	result, err := api.store.GetKigo()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Post kigo
+// @Security cookieAuth
+// @Description Add a seasonal word to the dictionary. Already saved hokkus are not retagged
+// @Tags Admin routes
+// @Accept json
+// @Produce json
+// @Param kigo body models.Kigo true "New kigo"
+// @Success 201 "Created"
+// @Failure 400 {object} echo.HTTPError "Dont pass validation"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
+// @Failure 409 {object} echo.HTTPError "The word is already in the dictionary"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /admin/kigo [post]
+func (api *APIServer) PostKigo(c echo.Context) error {
+	k := &models.Kigo{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&k); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	if err := k.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
+	}
+	id, err := api.store.CreateKigo(k)
+	if err != nil {
+		if errors.Is(err, store.ErrAlreadyExist) {
+			return echo.NewHTTPError(http.StatusConflict, "The word is already in the dictionary")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	c.Response().Header().Set("Location", fmt.Sprintf("/kigo/%d", id))
+	return c.NoContent(http.StatusCreated)
+}
+
+// @Summary Put kigo
+// @Security cookieAuth
+// @Description Update a seasonal word of the dictionary
+// @Tags Admin routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of kigo"
+// @Param kigo body models.Kigo true "Put kigo"
+// @Success 204 "OK"
+// @Failure 400 {object} echo.HTTPError "Dont pass validation"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
+// @Failure 404 {object} echo.HTTPError "A kigo with the specified ID was not found"
+// @Failure 409 {object} echo.HTTPError "The word is already in the dictionary"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /admin/kigo/{id} [put]
+func (api *APIServer) PutKigo(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	k := &models.Kigo{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&k); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	if err := k.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
+	}
+	k.Id = id
+	before := api.kigoSnapshot(id)
+	if err := api.store.UpdateKigo(k); err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A kigo with the specified ID was not found")
+		}
+		if errors.Is(err, store.ErrAlreadyExist) {
+			return echo.NewHTTPError(http.StatusConflict, "The word is already in the dictionary")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	api.audit(c, &models.AuditEntry{Action: models.AuditKigoUpdate, TargetType: models.TargetKigo, TargetId: id},
+		before, api.kigoSnapshot(id))
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Delete kigo
+// @Security cookieAuth
+// @Description Remove a seasonal word from the dictionary
+// @Tags Admin routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of kigo"
+// @Success 204 "Deleted succesfuly"
+// @Failure 400 {object} echo.HTTPError "Bad request. Kigo ID must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
+// @Failure 404 {object} echo.HTTPError "A kigo with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /admin/kigo/{id} [delete]
+func (api *APIServer) DeleteKigo(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	before := api.kigoSnapshot(id)
+	if err := api.store.DeleteKigo(id); err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A kigo with the specified ID was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	api.audit(c, &models.AuditEntry{Action: models.AuditKigoDelete, TargetType: models.TargetKigo, TargetId: id}, before, nil)
+	return c.NoContent(http.StatusNoContent)
+}
+
+// kigoSnapshot returns a copy of the kigo for the audit log, nil when it
+// can't be read.
+func (api *APIServer) kigoSnapshot(id int) *models.Kigo {
+	kigo, err := api.store.GetKigo()
+	if err != nil {
+		return nil
+	}
+	for _, k := range kigo {
+		if k.Id == id {
+			cp := *k
+			return &cp
+		}
+	}
+	return nil
+}
@@ -0,0 +1,317 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetHokkusByTag(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name         string
+		tag          string
+		expectedBody []byte
+		isValid      bool
+	}{
+		{
+			name:         "user tag",
+			tag:          "nature",
+			expectedBody: test_store.MockHokkusByTag("nature"),
+			isValid:      true,
+		},
+		{
+			name:         "not normalized tag",
+			tag:          "#Nature",
+			expectedBody: test_store.MockHokkusByTag("nature"),
+			isValid:      true,
+		},
+		{
+			name:         "season tag",
+			tag:          "season:winter",
+			expectedBody: test_store.MockHokkusByTag("season:winter"),
+			isValid:      true,
+		},
+		{
+			name:    "empty tag",
+			tag:     "#",
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/hokkus/byTag/", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("tag")
+			c.SetParamValues(cs.tag)
+			if cs.isValid {
+				assert.NoError(t, api.GetHokkusByTag(c))
+				assert.Equal(t, string(cs.expectedBody), rec.Body.String())
+			}
+			if !cs.isValid {
+				assert.Error(t, api.GetHokkusByTag(c))
+			}
+		})
+	}
+}
+
+func TestGetHokkusBySeason(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name         string
+		season       string
+		expectedBody []byte
+		isValid      bool
+	}{
+		{
+			name:         "winter",
+			season:       models.SeasonWinter,
+			expectedBody: test_store.MockHokkusByTag("season:winter"),
+			isValid:      true,
+		},
+		{
+			name:         "no hokkus",
+			season:       models.SeasonSpring,
+			expectedBody: []byte("[]\n"),
+			isValid:      true,
+		},
+		{
+			name:    "unknown season",
+			season:  "monsoon",
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/hokkus/bySeason/", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("season")
+			c.SetParamValues(cs.season)
+			if cs.isValid {
+				assert.NoError(t, api.GetHokkusBySeason(c))
+				assert.Equal(t, string(cs.expectedBody), rec.Body.String())
+			}
+			if !cs.isValid {
+				assert.Error(t, api.GetHokkusBySeason(c))
+			}
+		})
+	}
+}
+
+func TestPostHokkuTags(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		reqBody string
+		tags    []string
+		isValid bool
+	}{
+		{
+			name:    "kigo",
+			reqBody: `{"title":"Example","content":"The first snow\nis falling on the cherry blossoms","ownerId":1,"themeId":1}`,
+			tags:    []string{"season:spring", "season:winter"},
+			isValid: true,
+		},
+		{
+			name:    "russian kigo",
+			reqBody: `{"title":"Example","content":"Первый снег","ownerId":1,"themeId":1}`,
+			tags:    []string{"season:winter"},
+			isValid: true,
+		},
+		{
+			name:    "user tags",
+			reqBody: `{"title":"Example","content":"Content","tags":["#Nature"," night","nature"],"ownerId":1,"themeId":1}`,
+			tags:    []string{"nature", "night"},
+			isValid: true,
+		},
+		{
+			name:    "season tags are detected only",
+			reqBody: `{"title":"Example","content":"Content","tags":["season:summer"],"ownerId":1,"themeId":1}`,
+			tags:    []string{},
+			isValid: true,
+		},
+		{
+			name:    "bad tag",
+			reqBody: `{"title":"Example","content":"Content","tags":["two words"],"ownerId":1,"themeId":1}`,
+			isValid: false,
+		},
+		{
+			name:    "too many tags",
+			reqBody: `{"title":"Example","content":"Content","tags":["1","2","3","4","5","6","7","8","9","10","11"],"ownerId":1,"themeId":1}`,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/hokku", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.Set("userId", 1)
+			if !cs.isValid {
+				assert.Error(t, api.PostHokku(c))
+				return
+			}
+			assert.NoError(t, api.PostHokku(c))
+
+			req = httptest.NewRequest(echo.GET, rec.Header().Get("Location"), nil)
+			rec = httptest.NewRecorder()
+			c = api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(strings.TrimPrefix(req.URL.Path, "/hokku/"))
+			assert.NoError(t, api.GetHokku(c))
+			h := &models.Hokku{}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), h))
+			assert.Equal(t, cs.tags, h.Tags)
+		})
+	}
+}
+
+func TestGetKigo(t *testing.T) {
+	api := testAPIServer()
+	req := httptest.NewRequest(echo.GET, "/kigo", nil)
+	rec := httptest.NewRecorder()
+	c := api.Echo.NewContext(req, rec)
+	assert.NoError(t, api.GetKigo(c))
+	assert.Equal(t, string(test_store.MockKigo()), rec.Body.String())
+}
+
+func TestPostKigo(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		reqBody string
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			reqBody: `{"word":"harvest moon","season":"autumn"}`,
+			isValid: true,
+		},
+		{
+			name:    "bad body params",
+			reqBody: `{Error}`,
+			isValid: false,
+		},
+		{
+			name:    "unknown season",
+			reqBody: `{"word":"monsoon","season":"monsoon"}`,
+			isValid: false,
+		},
+		{
+			name:    "duplicate",
+			reqBody: `{"word":"first snow","season":"winter"}`,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/admin/kigo", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			if cs.isValid {
+				assert.NoError(t, api.PostKigo(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.PostKigo(c))
+			}
+		})
+	}
+}
+
+func TestPutKigo(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		id      string
+		reqBody string
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			id:      "2",
+			reqBody: `{"word":"autumn wind","season":"autumn"}`,
+			isValid: true,
+		},
+		{
+			name:    "not found",
+			id:      "100",
+			reqBody: `{"word":"autumn wind","season":"autumn"}`,
+			isValid: false,
+		},
+		{
+			name:    "word of another kigo",
+			id:      "2",
+			reqBody: `{"word":"first snow","season":"winter"}`,
+			isValid: false,
+		},
+		{
+			name:    "invalid id",
+			id:      "id",
+			reqBody: `{"word":"autumn wind","season":"autumn"}`,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.PUT, "/admin/kigo/", strings.NewReader(cs.reqBody))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			if cs.isValid {
+				assert.NoError(t, api.PutKigo(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.PutKigo(c))
+			}
+		})
+	}
+}
+
+func TestDeleteKigo(t *testing.T) {
+	api := testAPIServer()
+	cases := []struct {
+		name    string
+		id      string
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			id:      "1",
+			isValid: true,
+		},
+		{
+			name:    "already deleted",
+			id:      "1",
+			isValid: false,
+		},
+		{
+			name:    "invalid id",
+			id:      "id",
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.DELETE, "/admin/kigo/", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			if cs.isValid {
+				assert.NoError(t, api.DeleteKigo(c))
+			}
+			if !cs.isValid {
+				assert.Error(t, api.DeleteKigo(c))
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/labstack/echo/v4"
+)
+
+// maxViewDays limits the daily views series.
+const maxViewDays = 365
+
+// countView counts the view of the hokku by a user or, for anonymous
+// viewers, by the address. Authors don't count their own views.
+func (api *APIServer) countView(c echo.Context, viewerId int, h *models.Hokku) {
+	if api.Views == nil || viewerId == h.OwnerId {
+		return
+	}
+	viewer := "ip:" + c.RealIP()
+	if viewerId != 0 {
+		viewer = fmt.Sprintf("user:%d", viewerId)
+	}
+	api.Views.Count(h.Id, viewer)
+}
+
+// @Summary Get hokku views
+// @Security cookieAuth
+// @Description Get the total number of views of a hokku of the current user and its daily views for the last days, today included. Views are counted once per user or address within a window and saved with a small delay
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of hokku"
+// @Param days query int false "Number of days in the series, 30 by default and 365 at most"
+// @Success 200 {object} models.ViewStats
+// @Failure 400 {object} echo.HTTPError "Bad request. Hokku ID and days must be integers"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The hokku belongs to another user"
+// @Failure 404 {object} echo.HTTPError "A hokku with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/hokku/{id}/views [get]
+func (api *APIServer) GetViews(c echo.Context) error {
+	days := 30
+	if d := c.QueryParam("days"); d != "" {
+		var err error
+		days, err = strconv.Atoi(d)
+		if err != nil || days < 1 || days > maxViewDays {
+			return echo.NewHTTPError(http.StatusBadRequest, "Bad query parameters")
+		}
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	h, err := api.hokku(c, userId)
+	if err != nil {
+		return err
+	}
+	if h.OwnerId != userId {
+		return echo.NewHTTPError(http.StatusForbidden, "The hokku belongs to another user")
+	}
+	views, err := api.store.GetViews(h.Id)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	today := api.Clock.Now().UTC()
+	return c.JSON(http.StatusOK, models.NewViewStats(h.Id, views, today.AddDate(0, 0, 1-days), today))
+}
@@ -0,0 +1,105 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/scheduler"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountViews(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	clk := clock.NewMock(time.Date(2030, time.January, 3, 12, 0, 0, 0, time.UTC))
+	api.Clock = clk
+	api.Views = scheduler.NewViewCounter(s, clk, time.Minute, time.Hour, 100)
+	view := func(userId int, ip string) {
+		req := httptest.NewRequest(echo.GET, "/hokku/", nil)
+		req.RemoteAddr = ip + ":1234"
+		c := api.Echo.NewContext(req, httptest.NewRecorder())
+		c.SetParamNames("id")
+		c.SetParamValues("4")
+		if userId != 0 {
+			c.Set("userId", userId)
+		}
+		assert.NoError(t, api.GetHokku(c))
+	}
+	view(2, "10.0.0.1")
+	view(2, "10.0.0.2")
+	view(0, "10.0.0.1")
+	view(0, "10.0.0.1")
+	view(0, "10.0.0.3")
+	// The author's views are not counted
+	view(1, "10.0.0.1")
+	n, err := api.Views.Flush()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	cases := []struct {
+		name    string
+		id      string
+		days    string
+		userId  int
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			id:      "4",
+			days:    "3",
+			userId:  1,
+			isValid: true,
+		},
+		{
+			name:    "hokku of another user",
+			id:      "4",
+			userId:  2,
+			isValid: false,
+		},
+		{
+			name:    "too many days",
+			id:      "4",
+			days:    "1000",
+			userId:  1,
+			isValid: false,
+		},
+		{
+			name:    "not found",
+			id:      "100",
+			userId:  1,
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/restricted/hokku/?days="+cs.days, nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", cs.userId)
+			if cs.isValid {
+				assert.NoError(t, api.GetViews(c))
+				stats := &models.ViewStats{}
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), stats))
+				assert.Equal(t, 18, stats.Total)
+				assert.Equal(t, []*models.DailyViews{
+					{HokkuId: 4, Day: "2030-01-01", Views: 10},
+					{HokkuId: 4, Day: "2030-01-02", Views: 5},
+					{HokkuId: 4, Day: "2030-01-03", Views: 3},
+				}, stats.Daily)
+			}
+			if !cs.isValid {
+				assert.Error(t, api.GetViews(c))
+			}
+		})
+	}
+}
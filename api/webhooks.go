@@ -0,0 +1,217 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/labstack/echo/v4"
+)
+
+// webhookPayload is the body posted to webhooks.
+type webhookPayload struct {
+	Event   string      `json:"event"`
+	Data    interface{} `json:"data"`
+	Created time.Time   `json:"created"`
+}
+
+// DispatchWebhooks queues the events relayed from the outbox for the
+// webhooks subscribed to them. Webhooks get every event once even when the
+// relay repeats it.
+func (api *APIServer) DispatchWebhooks(e *models.OutboxEvent) error {
+	a, err := api.announce(e)
+	if err != nil || a == nil {
+		return err
+	}
+	payload, err := json.Marshal(webhookPayload{Event: a.typ, Data: a.data, Created: e.Created})
+	if err != nil {
+		return err
+	}
+	_, err = api.store.EnqueueWebhookDeliveries(e.Key, a.typ, payload, api.Clock.Now())
+	return err
+}
+
+// @Summary Post webhook
+// @Security cookieAuth
+// @Description Register an endpoint that receives the events it subscribes to: hokku.created, hokku.updated, hokku.deleted and user.created. Only public hokkus are sent. Endpoints must resolve to public addresses and redirects are not followed. Payloads are signed with the secret, which is returned only once: the X-Hokku-Signature header holds sha256= and the hex HMAC-SHA256 of the X-Hokku-Timestamp header, a dot and the body. Failed deliveries are retried with growing delays
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param webhook body models.Webhook true "Post webhook"
+// @Success 201 {object} models.Webhook
+// @Failure 400 {object} echo.HTTPError "Dont pass validation"
+// @Failure 400 {object} echo.HTTPError "The webhook must point to a public address"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/webhook [post]
+func (api *APIServer) PostWebhook(c echo.Context) error {
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	w := &models.Webhook{}
+	decoder := json.NewDecoder(c.Request().Body)
+	if err := decoder.Decode(&w); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Bad request params")
+	}
+	if err := w.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Don`t pass the validation")
+	}
+	if err := w.CheckHost(c.Request().Context(), api.LookupIP); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "The webhook must point to a public address")
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	w.Id, w.OwnerId, w.Secret = 0, userId, hex.EncodeToString(secret)
+	id, err := api.store.CreateWebhook(w)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	w.Id = id
+	c.Response().Header().Set("Location", fmt.Sprintf("/restricted/webhook/%d/deliveries", id))
+	return c.JSON(http.StatusCreated, w)
+}
+
+// @Summary Get webhooks
+// @Security cookieAuth
+// @Description Get the webhooks of the current user from the newest one. Secrets are not shown
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.Webhook
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/webhooks [get]
+func (api *APIServer) GetWebhooks(c echo.Context) error {
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	return api.listWebhooks(c, userId)
+}
+
+// @Summary Get all webhooks
+// @Security cookieAuth
+// @Description Get the webhooks of all users from the newest one. Secrets are not shown
+// @Tags Admin routes
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.Webhook
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The action is allowed only to admins"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /admin/webhooks [get]
+func (api *APIServer) GetAllWebhooks(c echo.Context) error {
+	return api.listWebhooks(c, 0)
+}
+
+func (api *APIServer) listWebhooks(c echo.Context, ownerId int) error {
+	hooks, err := api.store.GetWebhooks(ownerId)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	for _, w := range hooks {
+		w.Secret = ""
+	}
+	return c.JSON(http.StatusOK, hooks)
+}
+
+// @Summary Delete webhook
+// @Security cookieAuth
+// @Description Delete the webhook with its delivery log. Admins can delete the webhooks of other users
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of webhook"
+// @Success 204 "Deleted succesfuly"
+// @Failure 400 {object} echo.HTTPError "Bad request. Id must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The webhook belongs to another user"
+// @Failure 404 {object} echo.HTTPError "A webhook with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/webhook/{id} [delete]
+func (api *APIServer) DeleteWebhook(c echo.Context) error {
+	w, err := api.ownWebhook(c)
+	if err != nil {
+		return err
+	}
+	if err := api.store.DeleteWebhook(w.Id); err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return echo.NewHTTPError(http.StatusNotFound, "A webhook with the specified ID was not found")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Get webhook deliveries
+// @Security cookieAuth
+// @Description Get the delivery log of the webhook from the newest delivery with the outcome of the latest attempt. Admins can see the logs of other users
+// @Tags Restricted routes
+// @Accept json
+// @Produce json
+// @Param id path int true "id of webhook"
+// @Param limit query int false "Sample size"
+// @Param offset query int false "Number of items to skip"
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 400 {object} echo.HTTPError "Bad request. Id must be an integer"
+// @Failure 401 {object} echo.HTTPError "The request requires user authentication"
+// @Failure 403 {object} echo.HTTPError "The webhook belongs to another user"
+// @Failure 404 {object} echo.HTTPError "A webhook with the specified ID was not found"
+// @Failure 500 {object} echo.HTTPError "Unexpected error"
+// @Router /restricted/webhook/{id}/deliveries [get]
+func (api *APIServer) GetWebhookDeliveries(c echo.Context) error {
+	w, err := api.ownWebhook(c)
+	if err != nil {
+		return err
+	}
+	limit, offset, err := paginationParams(c)
+	if err != nil {
+		return err
+	}
+	deliveries, err := api.store.GetWebhookDeliveries(w.Id, limit, offset)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+// ownWebhook returns the webhook from the path if it belongs to the
+// current user or the user is an admin.
+func (api *APIServer) ownWebhook(c echo.Context) (*models.Webhook, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Bad request. Id must be an integer")
+	}
+	userId, ok := api.currentUserId(c)
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, "Wrong session")
+	}
+	w, err := api.store.GetWebhook(id)
+	if err != nil {
+		if errors.Is(err, store.ErrNoRecord) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "A webhook with the specified ID was not found")
+		}
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	if w.OwnerId == userId {
+		return w, nil
+	}
+	u, err := api.store.GetUser(userId)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Unexpected error")
+	}
+	if !u.IsAdmin() {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "The webhook belongs to another user")
+	}
+	return w, nil
+}
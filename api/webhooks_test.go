@@ -0,0 +1,233 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/config"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/outbox"
+	"github.com/EgorSkurihin/Hokku/scheduler"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostWebhook(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	api.LookupIP = func(ctx context.Context, network, host string) ([]net.IP, error) {
+		if host == "intranet.example.com" {
+			return []net.IP{net.ParseIP("10.0.0.5")}, nil
+		}
+		return []net.IP{net.ParseIP("93.184.216.34")}, nil
+	}
+	cases := []struct {
+		name    string
+		body    string
+		isValid bool
+	}{
+		{name: "valid", body: `{"url":"https://example.com/hook","events":["hokku.created","user.created"]}`, isValid: true},
+		{name: "no events", body: `{"url":"https://example.com/hook","events":[]}`, isValid: false},
+		{name: "unknown event", body: `{"url":"https://example.com/hook","events":["hokku.liked"]}`, isValid: false},
+		{name: "not http", body: `{"url":"ftp://example.com/hook","events":["hokku.created"]}`, isValid: false},
+		{name: "internal host", body: `{"url":"https://intranet.example.com/hook","events":["hokku.created"]}`, isValid: false},
+		{name: "loopback", body: `{"url":"http://127.0.0.1:8080/hook","events":["hokku.created"]}`, isValid: false},
+		{name: "metadata", body: `{"url":"http://169.254.169.254/latest","events":["hokku.created"]}`, isValid: false},
+		{name: "no url", body: `{"events":["hokku.created"]}`, isValid: false},
+		{name: "bad json", body: `{"url":`, isValid: false},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.POST, "/restricted/webhook", strings.NewReader(cs.body))
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.Set("userId", 1)
+			err := api.PostWebhook(c)
+			if !cs.isValid {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			w := &models.Webhook{}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), w))
+			assert.Equal(t, 1, w.OwnerId)
+			assert.Len(t, w.Secret, 64)
+		})
+	}
+
+	// Secrets are shown only once
+	req := httptest.NewRequest(echo.GET, "/restricted/webhooks", nil)
+	rec := httptest.NewRecorder()
+	c := api.Echo.NewContext(req, rec)
+	c.Set("userId", 1)
+	assert.NoError(t, api.GetWebhooks(c))
+	hooks := []*models.Webhook{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &hooks))
+	if assert.Len(t, hooks, 1) {
+		assert.Empty(t, hooks[0].Secret)
+	}
+}
+
+func TestWebhookAccess(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	id, err := s.CreateWebhook(&models.Webhook{OwnerId: 1, URL: "https://example.com", Events: []string{models.WebhookHokkuCreated}})
+	assert.NoError(t, err)
+	_, err = s.EnqueueWebhookDeliveries("key", models.WebhookHokkuCreated, []byte(`{}`), api.Clock.Now())
+	assert.NoError(t, err)
+	cases := []struct {
+		name    string
+		id      string
+		userId  int
+		delete  bool
+		isValid bool
+	}{
+		{name: "owner", id: "1", userId: 1, isValid: true},
+		{name: "admin", id: "1", userId: 3, isValid: true},
+		{name: "another user", id: "1", userId: 2, isValid: false},
+		{name: "another user deletes", id: "1", userId: 2, delete: true, isValid: false},
+		{name: "unknown webhook", id: "100", userId: 1, isValid: false},
+		{name: "invalid id", id: "id", userId: 1, isValid: false},
+		{name: "owner deletes", id: "1", userId: 1, delete: true, isValid: true},
+		{name: "deleted", id: "1", userId: 1, isValid: false},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			req := httptest.NewRequest(echo.GET, "/restricted/webhook/", nil)
+			rec := httptest.NewRecorder()
+			c := api.Echo.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(cs.id)
+			c.Set("userId", cs.userId)
+			if cs.delete {
+				err = api.DeleteWebhook(c)
+			} else {
+				err = api.GetWebhookDeliveries(c)
+			}
+			if !cs.isValid {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			if !cs.delete {
+				deliveries := []*models.WebhookDelivery{}
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &deliveries))
+				if assert.Len(t, deliveries, 1) {
+					assert.Equal(t, id, deliveries[0].WebhookId)
+				}
+			}
+		})
+	}
+	assert.Empty(t, s.WebhookDeliveries)
+}
+
+func TestDispatchWebhooks(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	_, err := s.CreateWebhook(&models.Webhook{OwnerId: 2, URL: "https://example.com", Events: models.WebhookEvents})
+	assert.NoError(t, err)
+	call := func(handler func(echo.Context) error, id, body string) {
+		req := httptest.NewRequest(echo.POST, "/", strings.NewReader(body))
+		c := api.Echo.NewContext(req, httptest.NewRecorder())
+		c.SetParamNames("id")
+		c.SetParamValues(id)
+		c.Set("userId", 1)
+		assert.NoError(t, handler(c))
+	}
+	call(api.PostHokku, "", `{"title":"Example","content":"1","ownerId":1,"themeId":1}`)
+	call(api.PostHokku, "", `{"title":"Example","content":"1","ownerId":1,"themeId":1,"visibility":"private"}`)
+	call(api.PutHokku, "4", `{"title":"Example","content":"1","ownerId":1,"themeId":2,"visibility":"private"}`)
+	call(api.DeleteHokku, "12", "")
+	call(api.PostUser, "", `{"email":"hook@mail.ru","name":"Hook","password":"password"}`)
+	assert.Empty(t, s.WebhookDeliveries)
+
+	events := append([]*models.OutboxEvent{}, s.Outbox...)
+	relay := scheduler.NewOutboxRelay(s, time.Minute, 3, outbox.SinkFunc(api.DispatchWebhooks))
+	n, err := relay.Relay()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Empty(t, s.Outbox)
+
+	// Repeated events are queued once
+	for _, e := range events {
+		assert.NoError(t, api.DispatchWebhooks(e))
+	}
+	types := []string{}
+	for _, d := range s.WebhookDeliveries {
+		types = append(types, d.Event)
+		payload := map[string]interface{}{}
+		assert.NoError(t, json.Unmarshal(d.Payload, &payload))
+		assert.Equal(t, d.Event, payload["event"])
+		if d.Event == models.WebhookUserCreated {
+			data := payload["data"].(map[string]interface{})
+			assert.Equal(t, "Hook", data["name"])
+			assert.NotContains(t, data, "email")
+		}
+	}
+	assert.Equal(t, []string{"hokku.created", "hokku.deleted", "hokku.deleted", "user.created"}, types)
+}
+
+func TestDispatchListingChanges(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	_, err := s.CreateWebhook(&models.Webhook{OwnerId: 3, URL: "https://example.com", Events: models.WebhookEvents})
+	assert.NoError(t, err)
+	req := httptest.NewRequest(echo.PUT, "/", strings.NewReader(`{"title":"Draft","content":"1","status":"published"}`))
+	c := api.Echo.NewContext(req, httptest.NewRecorder())
+	c.SetParamNames("id")
+	c.SetParamValues("6")
+	c.Set("userId", 1)
+	assert.NoError(t, api.PutHokku(c))
+	assert.NoError(t, s.HideHokku(4))
+	assert.NoError(t, s.UnhideHokku(4))
+	assert.NoError(t, s.DeleteUser(2))
+	assert.NoError(t, s.RestoreUser(2, time.Now().Add(-time.Hour)))
+	_, err = s.PublishScheduled(test_store.ScheduledAt)
+	assert.NoError(t, err)
+
+	_, err = scheduler.NewOutboxRelay(s, time.Minute, 3, outbox.SinkFunc(api.DispatchWebhooks)).Relay()
+	assert.NoError(t, err)
+	events := []string{}
+	for _, d := range s.WebhookDeliveries {
+		payload := struct {
+			Data struct {
+				Id int `json:"id"`
+			} `json:"data"`
+		}{}
+		assert.NoError(t, json.Unmarshal(d.Payload, &payload))
+		events = append(events, fmt.Sprintf("%s %d", d.Event, payload.Data.Id))
+	}
+	assert.Equal(t, []string{
+		"hokku.created 6",
+		"hokku.deleted 4", "hokku.created 4",
+		"hokku.deleted 2", "hokku.deleted 5", "hokku.created 2", "hokku.created 5",
+		"hokku.created 7",
+	}, events)
+}
+
+func TestDispatchShadowBanned(t *testing.T) {
+	s := test_store.New()
+	api := api.New(&config.Server{}, s)
+	_, err := s.CreateWebhook(&models.Webhook{OwnerId: 3, URL: "https://example.com", Events: models.WebhookEvents})
+	assert.NoError(t, err)
+	_, err = s.CreateRestriction(&models.Restriction{UserId: 2, ModeratorId: 3, Kind: models.RestrictionShadowBan, Reason: "Spam"})
+	assert.NoError(t, err)
+	h, err := s.GetHokku(2, 2)
+	assert.NoError(t, err)
+	assert.NoError(t, s.UpdateHokku(h))
+	assert.NoError(t, s.DeleteHokku(2))
+
+	_, err = scheduler.NewOutboxRelay(s, time.Minute, 3, outbox.SinkFunc(api.DispatchWebhooks)).Relay()
+	assert.NoError(t, err)
+	assert.Empty(t, s.WebhookDeliveries)
+}
@@ -0,0 +1,46 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a source of the current time. Background jobs take a Clock
+// instead of calling time.Now directly so tests can control time.
+type Clock interface {
+	Now() time.Time
+}
+
+type Real struct{}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Mock is a manually driven clock for tests.
+type Mock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewMock(now time.Time) *Mock {
+	return &Mock{now: now}
+}
+
+func (m *Mock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+func (m *Mock) Set(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = now
+}
+
+func (m *Mock) Add(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = m.now.Add(d)
+}
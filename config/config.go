@@ -3,8 +3,12 @@ package config
 import "github.com/BurntSushi/toml"
 
 type Config struct {
-	Server Server `toml:"server"`
-	Store  Store  `toml:"database"`
+	Server  Server  `toml:"server"`
+	Store   Store   `toml:"database"`
+	Jobs    Jobs    `toml:"jobs"`
+	Ranking Ranking `toml:"ranking"`
+	Filter  Filter  `toml:"filter"`
+	Stream  Stream  `toml:"stream"`
 }
 
 type Server struct {
@@ -12,6 +16,22 @@ type Server struct {
 	LogLevel   int    `toml:"loglevel"`
 	SessionKey string `toml:"session_key"`
 	Debug      bool   `toml:"debug"`
+	// Deleted users and hokkus can be restored during this many days
+	TrashRetentionDays int `toml:"trash_retention_days"`
+	// The hokku of the day isn't repeated within this many days
+	DailyRepeatDays int `toml:"daily_repeat_days"`
+	// Views of a hokku by the same user or address are counted once
+	// during this many minutes
+	ViewWindowMinutes int `toml:"view_window_minutes"`
+	// Hokkus whose fingerprints differ from existing poems in at most
+	// DuplicateDistance bits are accepted, flagged for moderation or
+	// rejected. Duplicates are not looked for when the action is empty.
+	DuplicateAction   string `toml:"duplicate_action"`
+	DuplicateDistance int    `toml:"duplicate_distance"`
+	// Client addresses are read from X-Forwarded-For only on requests from
+	// these ranges of proxies, otherwise the address of the connection is
+	// used
+	TrustedProxies []string `toml:"trusted_proxies"`
 }
 
 type Store struct {
@@ -22,6 +42,58 @@ type Store struct {
 	DBName   string `toml:"dbname"`
 }
 
+// Background jobs settings. Intervals are in seconds.
+type Jobs struct {
+	PublishInterval int `toml:"publish_interval"`
+	PurgeInterval   int `toml:"purge_interval"`
+	JudgeInterval   int `toml:"judge_interval"`
+	RankInterval    int `toml:"rank_interval"`
+	// Counted views are flushed every interval or when views of this many
+	// hokkus and days are pending
+	ViewFlushInterval int `toml:"view_flush_interval"`
+	ViewBufferSize    int `toml:"view_buffer_size"`
+	// Webhook deliveries are sent every interval and given up after this
+	// many attempts. The wait between attempts starts at WebhookBackoff
+	// seconds and doubles, requests time out after WebhookTimeout seconds.
+	WebhookInterval int `toml:"webhook_interval"`
+	WebhookAttempts int `toml:"webhook_attempts"`
+	WebhookBackoff  int `toml:"webhook_backoff"`
+	WebhookTimeout  int `toml:"webhook_timeout"`
+	// Domain events are relayed from the outbox every interval, parked
+	// after this many failed attempts and also written to the log when
+	// OutboxLog is set
+	OutboxInterval int  `toml:"outbox_interval"`
+	OutboxAttempts int  `toml:"outbox_attempts"`
+	OutboxLog      bool `toml:"outbox_log"`
+}
+
+// Points given for every reaction to a hokku. Trending scores halve every
+// HalfLifeHours after the publication.
+type Ranking struct {
+	LikeWeight     float64 `toml:"like_weight"`
+	CommentWeight  float64 `toml:"comment_weight"`
+	BookmarkWeight float64 `toml:"bookmark_weight"`
+	ViewWeight     float64 `toml:"view_weight"`
+	HalfLifeHours  float64 `toml:"half_life_hours"`
+}
+
+// Content filter settings. User texts are not screened when no rules file
+// is set.
+type Filter struct {
+	Rules string `toml:"rules"`
+}
+
+// Real-time stream settings. Idle connections get a heartbeat every
+// HeartbeatInterval seconds. A subscriber queues at most BufferSize events
+// and is dropped when it falls behind, the last HistorySize events are
+// replayed to reconnecting subscribers. Streaming is turned off when the
+// heartbeat interval or the buffer size is 0.
+type Stream struct {
+	HeartbeatInterval int `toml:"heartbeat_interval"`
+	BufferSize        int `toml:"buffer_size"`
+	HistorySize       int `toml:"history_size"`
+}
+
 // New Config from toml file
 func New(configFile string) (*Config, error) {
 	config := &Config{}
@@ -23,33 +23,14 @@ var doc = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/health": {
+        "/admin/audit": {
             "get": {
-                "description": "get the status of server.",
-                "consumes": [
-                    "*/*"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "root"
-                ],
-                "summary": "Show the status of server.",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
+                "security": [
+                    {
+                        "cookieAuth": []
                     }
-                }
-            }
-        },
-        "/hokku/{id}": {
-            "get": {
-                "description": "Get hokku by ID",
+                ],
+                "description": "Get the audit log from the newest entry filtered by the actor, the target and the time range",
                 "consumes": [
                     "application/json"
                 ],
@@ -57,60 +38,40 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Open routes"
+                    "Admin routes"
                 ],
-                "summary": "Get hokku",
+                "summary": "Get audit log",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "id of hokku",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/models.Hokku"
-                        }
+                        "description": "id of the acting user",
+                        "name": "actor",
+                        "in": "query"
                     },
-                    "400": {
-                        "description": "Bad request. Hokku ID must be an integer and larger than 0",
-                        "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
-                        }
+                    {
+                        "type": "string",
+                        "description": "user, hokku, comment, theme, kigo, classic, report or restriction",
+                        "name": "targetType",
+                        "in": "query"
                     },
-                    "404": {
-                        "description": "A hokku with the specified ID was not found",
-                        "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
-                        }
+                    {
+                        "type": "integer",
+                        "description": "id of the target",
+                        "name": "target",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, inclusive",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, exclusive",
+                        "name": "until",
+                        "in": "query"
                     },
-                    "500": {
-                        "description": "Unexpected error",
-                        "schema": {
-                            "$ref": "#/definitions/echo.HTTPError"
-                        }
-                    }
-                }
-            }
-        },
-        "/hokkus": {
-            "get": {
-                "description": "Get all hokkus",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Open routes"
-                ],
-                "summary": "Get all hokkus",
-                "parameters": [
                     {
                         "type": "integer",
                         "description": "Sample size",
@@ -130,7 +91,7 @@ var doc = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Hokku"
+                                "$ref": "#/definitions/models.AuditEntry"
                             }
                         }
                     },
@@ -140,61 +101,14 @@ var doc = `{
                             "$ref": "#/definitions/echo.HTTPError"
                         }
                     },
-                    "500": {
-                        "description": "Unexpected error",
+                    "401": {
+                        "description": "The request requires user authentication",
                         "schema": {
                             "$ref": "#/definitions/echo.HTTPError"
                         }
-                    }
-                }
-            }
-        },
-        "/hokkus/byAuthor/{authorId}": {
-            "get": {
-                "description": "Get all hokkus of current author",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Open routes"
-                ],
-                "summary": "Get hokkus by athor",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Sample size",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Number of items to skip",
-                        "name": "offset",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Author id",
-                        "name": "authorId",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Hokku"
-                            }
-                        }
                     },
-                    "400": {
-                        "description": "Bad query parameters",
+                    "403": {
+                        "description": "The action is allowed only to admins",
                         "schema": {
                             "$ref": "#/definitions/echo.HTTPError"
                         }
@@ -208,48 +122,58 @@ var doc = `{
                 }
             }
         },
-        "/hokkus/byTheme/{themeId}": {
+        "/admin/audit/export": {
             "get": {
-                "description": "Get all hokkus of current author",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
                 ],
+                "description": "Export the filtered audit log as CSV from the newest entry, at most 10000 entries",
                 "produces": [
-                    "application/json"
+                    "text/csv"
                 ],
                 "tags": [
-                    "Open routes"
+                    "Admin routes"
                 ],
-                "summary": "Get hokkus by theme",
+                "summary": "Export audit log",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Sample size",
-                        "name": "limit",
+                        "description": "id of the acting user",
+                        "name": "actor",
                         "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "Number of items to skip",
-                        "name": "offset",
+                        "type": "string",
+                        "description": "user, hokku, comment, theme, kigo, classic, report or restriction",
+                        "name": "targetType",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "description": "thme Id",
-                        "name": "themeId",
-                        "in": "path",
-                        "required": true
+                        "description": "id of the target",
+                        "name": "target",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, inclusive",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, exclusive",
+                        "name": "until",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "CSV file",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Hokku"
-                            }
+                            "type": "string"
                         }
                     },
                     "400": {
@@ -258,6 +182,18 @@ var doc = `{
                             "$ref": "#/definitions/echo.HTTPError"
                         }
                     },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to admins",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
                     "500": {
                         "description": "Unexpected error",
                         "schema": {
@@ -267,9 +203,14 @@ var doc = `{
                 }
             }
         },
-        "/login": {
+        "/admin/classic": {
             "post": {
-                "description": "Login",
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Add a classic poem new hokkus are compared with",
                 "consumes": [
                     "application/json"
                 ],
@@ -277,32 +218,38 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Auth"
+                    "Admin routes"
                 ],
-                "summary": "Authenticate",
+                "summary": "Post classic",
                 "parameters": [
                     {
-                        "description": "The user object can only contain email and password",
-                        "name": "user",
+                        "description": "New classic poem",
+                        "name": "classic",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.Classic"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": ""
+                    "201": {
+                        "description": "Created"
                     },
                     "400": {
-                        "description": "Wrong email or passowrd",
+                        "description": "Dont pass validation",
                         "schema": {
                             "$ref": "#/definitions/echo.HTTPError"
                         }
                     },
-                    "404": {
-                        "description": "A user with the specified Email was not found",
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to admins",
                         "schema": {
                             "$ref": "#/definitions/echo.HTTPError"
                         }
@@ -316,14 +263,14 @@ var doc = `{
                 }
             }
         },
-        "/restricted/hokku": {
-            "put": {
+        "/admin/classic/{id}": {
+            "delete": {
                 "security": [
                     {
                         "cookieAuth": []
                     }
                 ],
-                "description": "Update hokku in store",
+                "description": "Delete a classic poem from the corpus",
                 "consumes": [
                     "application/json"
                 ],
@@ -331,30 +278,27 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Restricted routes"
+                    "Admin routes"
                 ],
-                "summary": "Put hokku",
+                "summary": "Delete classic",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "id of hokku",
+                        "description": "id of classic",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Put Hokku",
-                        "name": "hokku",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Hokku"
-                        }
                     }
                 ],
                 "responses": {
                     "204": {
-                        "description": "OK"
+                        "description": "Deleted succesfuly"
+                    },
+                    "400": {
+                        "description": "Bad request. Id must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
                     },
                     "401": {
                         "description": "The request requires user authentication",
@@ -362,8 +306,14 @@ var doc = `{
                             "$ref": "#/definitions/echo.HTTPError"
                         }
                     },
+                    "403": {
+                        "description": "The action is allowed only to admins",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
                     "404": {
-                        "description": "Not Found",
+                        "description": "A classic with the specified ID was not found",
                         "schema": {
                             "$ref": "#/definitions/echo.HTTPError"
                         }
@@ -375,14 +325,16 @@ var doc = `{
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/admin/classics": {
+            "get": {
                 "security": [
                     {
                         "cookieAuth": []
                     }
                 ],
-                "description": "Create new hokku in Store. Reurn location of new object in header",
+                "description": "Get the classic poems new hokkus are compared with",
                 "consumes": [
                     "application/json"
                 ],
@@ -390,26 +342,35 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Restricted routes"
+                    "Admin routes"
                 ],
-                "summary": "Post hokku",
+                "summary": "Get classics",
                 "parameters": [
                     {
-                        "description": "New Hokku",
-                        "name": "hokku",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Hokku"
-                        }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created"
-                    },
-                    "400": {
-                        "description": "Dont pass validation",
+                        "type": "integer",
+                        "description": "Sample size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Classic"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad query parameters",
                         "schema": {
                             "$ref": "#/definitions/echo.HTTPError"
                         }
@@ -420,8 +381,8 @@ var doc = `{
                             "$ref": "#/definitions/echo.HTTPError"
                         }
                     },
-                    "409": {
-                        "description": "Foreign key constraint fails",
+                    "403": {
+                        "description": "The action is allowed only to admins",
                         "schema": {
                             "$ref": "#/definitions/echo.HTTPError"
                         }
@@ -435,14 +396,14 @@ var doc = `{
                 }
             }
         },
-        "/restricted/hokku/{id}": {
-            "delete": {
+        "/admin/contest": {
+            "post": {
                 "security": [
                     {
                         "cookieAuth": []
                     }
                 ],
-                "description": "Delete hokku from Store",
+                "description": "Create a contest on a theme. Submissions must close before the voting opens",
                 "consumes": [
                     "application/json"
                 ],
@@ -450,24 +411,26 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Restricted routes"
+                    "Admin routes"
                 ],
-                "summary": "Delete hokku",
+                "summary": "Post contest",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "id of hokku",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "New contest",
+                        "name": "contest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Contest"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Deleted succesfuly"
+                    "201": {
+                        "description": "Created"
                     },
                     "400": {
-                        "description": "Bad request. Hokku ID must be an integer and larger than 0",
+                        "description": "Dont pass validation",
                         "schema": {
                             "$ref": "#/definitions/echo.HTTPError"
                         }
@@ -478,8 +441,14 @@ var doc = `{
                             "$ref": "#/definitions/echo.HTTPError"
                         }
                     },
-                    "404": {
-                        "description": "A hokku with the specified ID was not found",
+                    "403": {
+                        "description": "The action is allowed only to admins",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "Foreign key constraint fails",
                         "schema": {
                             "$ref": "#/definitions/echo.HTTPError"
                         }
@@ -493,14 +462,14 @@ var doc = `{
                 }
             }
         },
-        "/restricted/user": {
-            "put": {
+        "/admin/filter/reload": {
+            "post": {
                 "security": [
                     {
                         "cookieAuth": []
                     }
                 ],
-                "description": "Update user in store",
+                "description": "Read the content filter rules and wordlists again",
                 "consumes": [
                     "application/json"
                 ],
@@ -508,27 +477,9 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Restricted routes"
-                ],
-                "summary": "Put user",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "id of user",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Put User",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.User"
-                        }
-                    }
+                    "Admin routes"
                 ],
+                "summary": "Reload content filter",
                 "responses": {
                     "204": {
                         "description": "OK"
@@ -539,14 +490,14 @@ var doc = `{
                             "$ref": "#/definitions/echo.HTTPError"
                         }
                     },
-                    "404": {
-                        "description": "A user with the specified ID was not found",
+                    "403": {
+                        "description": "The action is allowed only to admins",
                         "schema": {
                             "$ref": "#/definitions/echo.HTTPError"
                         }
                     },
                     "500": {
-                        "description": "Unexpected error",
+                        "description": "The rules can't be read, the previous rules are kept",
                         "schema": {
                             "$ref": "#/definitions/echo.HTTPError"
                         }
@@ -554,14 +505,14 @@ var doc = `{
                 }
             }
         },
-        "/restricted/user/{id}": {
-            "delete": {
+        "/admin/flags": {
+            "get": {
                 "security": [
                     {
                         "cookieAuth": []
                     }
                 ],
-                "description": "Delete user from Store",
+                "description": "Get the hokkus flagged for moderation from the newest flag",
                 "consumes": [
                     "application/json"
                 ],
@@ -569,24 +520,35 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Restricted routes"
+                    "Admin routes"
                 ],
-                "summary": "Delete user",
+                "summary": "Get flags",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "id of user",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Sample size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "Deleted succesfuly"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Flag"
+                            }
+                        }
                     },
                     "400": {
-                        "description": "Bad request. User ID must be an integer and larger than 0",
+                        "description": "Bad query parameters",
                         "schema": {
                             "$ref": "#/definitions/echo.HTTPError"
                         }
@@ -597,8 +559,8 @@ var doc = `{
                             "$ref": "#/definitions/echo.HTTPError"
                         }
                     },
-                    "404": {
-                        "description": "A user with the specified ID was not found",
+                    "403": {
+                        "description": "The action is allowed only to admins",
                         "schema": {
                             "$ref": "#/definitions/echo.HTTPError"
                         }
@@ -612,9 +574,14 @@ var doc = `{
                 }
             }
         },
-        "/themes": {
-            "get": {
-                "description": "Get all themes",
+        "/admin/kigo": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Add a seasonal word to the dictionary. Already saved hokkus are not retagged",
                 "consumes": [
                     "application/json"
                 ],
@@ -622,17 +589,46 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Open routes"
+                    "Admin routes"
+                ],
+                "summary": "Post kigo",
+                "parameters": [
+                    {
+                        "description": "New kigo",
+                        "name": "kigo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Kigo"
+                        }
+                    }
                 ],
-                "summary": "Get all themes",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Dont pass validation",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Theme"
-                            }
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to admins",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "The word is already in the dictionary",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
                         }
                     },
                     "500": {
@@ -644,9 +640,14 @@ var doc = `{
                 }
             }
         },
-        "/user": {
-            "post": {
-                "description": "Create new user in Store. Return location of new user in header",
+        "/admin/kigo/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Update a seasonal word of the dictionary",
                 "consumes": [
                     "application/json"
                 ],
@@ -654,23 +655,30 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Auth"
+                    "Admin routes"
                 ],
-                "summary": "Post user",
+                "summary": "Put kigo",
                 "parameters": [
                     {
-                        "description": "New User",
-                        "name": "user",
+                        "type": "integer",
+                        "description": "id of kigo",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Put kigo",
+                        "name": "kigo",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.Kigo"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created"
+                    "204": {
+                        "description": "OK"
                     },
                     "400": {
                         "description": "Dont pass validation",
@@ -678,8 +686,26 @@ var doc = `{
                             "$ref": "#/definitions/echo.HTTPError"
                         }
                     },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to admins",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A kigo with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
                     "409": {
-                        "description": "User with this email already exists",
+                        "description": "The word is already in the dictionary",
                         "schema": {
                             "$ref": "#/definitions/echo.HTTPError"
                         }
@@ -691,11 +717,14 @@ var doc = `{
                         }
                     }
                 }
-            }
-        },
-        "/user/{id}": {
-            "get": {
-                "description": "Get user by ID",
+            },
+            "delete": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Remove a seasonal word from the dictionary",
                 "consumes": [
                     "application/json"
                 ],
@@ -703,33 +732,42 @@ var doc = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Open routes"
+                    "Admin routes"
                 ],
-                "summary": "Get user",
+                "summary": "Delete kigo",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "id of user",
+                        "description": "id of kigo",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "Deleted succesfuly"
+                    },
+                    "400": {
+                        "description": "Bad request. Kigo ID must be an integer",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/echo.HTTPError"
                         }
                     },
-                    "400": {
-                        "description": "Bad request. User ID must be an integer and larger than 0",
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to admins",
                         "schema": {
                             "$ref": "#/definitions/echo.HTTPError"
                         }
                     },
                     "404": {
-                        "description": "A user with the specified ID was not found",
+                        "description": "A kigo with the specified ID was not found",
                         "schema": {
                             "$ref": "#/definitions/echo.HTTPError"
                         }
@@ -742,46 +780,5615 @@ var doc = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "echo.HTTPError": {
-            "type": "object",
-            "properties": {
-                "message": {}
-            }
         },
-        "models.Hokku": {
-            "type": "object",
-            "properties": {
-                "content": {
-                    "type": "string"
-                },
-                "created": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "ownerId": {
-                    "type": "integer"
-                },
-                "themeId": {
-                    "type": "integer"
-                },
-                "title": {
-                    "type": "string"
+        "/admin/theme": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Create new theme in Store. The slug is derived from the title when omitted. Return location of new theme in header",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin routes"
+                ],
+                "summary": "Post theme",
+                "parameters": [
+                    {
+                        "description": "New theme",
+                        "name": "theme",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Theme"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Dont pass validation or the parent theme was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to admins",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "Theme with this title or slug already exists",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/theme/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Update theme in Store",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin routes"
+                ],
+                "summary": "Put theme",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of theme",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Put theme",
+                        "name": "theme",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Theme"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Dont pass validation or the parent theme is wrong",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to admins",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A theme with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "Theme with this title or slug already exists",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Delete theme with its hokkus from Store, or move the hokkus and chains to another theme first. Subthemes become root themes",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin routes"
+                ],
+                "summary": "Delete theme",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of theme",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "id of the theme the hokkus are moved to",
+                        "name": "moveTo",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Deleted succesfuly"
+                    },
+                    "400": {
+                        "description": "Bad request. Theme ID must be an integer and larger than 0, the theme to move hokkus to must exist and differ from the deleted one",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to admins",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A theme with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/user/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Restore the deleted user together with the hokkus deleted along with them. Users restore themselves by logging in with restore set",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin routes"
+                ],
+                "summary": "Restore user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Restored succesfuly"
+                    },
+                    "400": {
+                        "description": "Bad request. User ID must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to admins",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A user with the specified ID was not found in the trash",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/user/{id}/role": {
+            "put": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Appoint the user a moderator or an admin or take the role back",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin routes"
+                ],
+                "summary": "Put user role",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "user, moderator or admin",
+                        "name": "role",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.RoleChange"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Dont pass validation",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to admins",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A user with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Get the webhooks of all users from the newest one. Secrets are not shown",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin routes"
+                ],
+                "summary": "Get all webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Webhook"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to admins",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/analyze": {
+            "post": {
+                "description": "Split the hokku content into lines and count syllables without saving it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Analyze hokku",
+                "parameters": [
+                    {
+                        "description": "The hokku object can only contain content or lines",
+                        "name": "hokku",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Hokku"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/prosody.Analysis"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request params",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/chain/{id}": {
+            "get": {
+                "description": "Get the chain with its stanzas in order",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get chain",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of chain",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ChainDetails"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request. Chain ID must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A chain with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/contest/{id}": {
+            "get": {
+                "description": "Get the contest with its current phase and entries. Results are added when the voting is over",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get contest",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of contest",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ContestDetails"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request. Contest ID must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A contest with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/contests": {
+            "get": {
+                "description": "Get all contests from the latest one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get contests",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Contest"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "get the status of server.",
+                "consumes": [
+                    "*/*"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "root"
+                ],
+                "summary": "Show the status of server.",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/hokku/daily": {
+            "get": {
+                "description": "Get the hokku of the current day in the timezone. Every day gets one hokku that is not repeated within the configured number of days",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get hokku of the day",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "IANA timezone, UTC by default",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Hokku"
+                        }
+                    },
+                    "400": {
+                        "description": "Unknown timezone",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "No hokkus found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/hokku/random": {
+            "get": {
+                "description": "Get a random hokku, optionally of the theme",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get random hokku",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Theme id",
+                        "name": "themeId",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Hokku"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request. Theme ID must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "No hokkus found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/hokku/{id}": {
+            "get": {
+                "description": "Get hokku by ID. Unlisted hokkus are available by ID only, followers-only and private ones require an authorized viewer",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get hokku",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of hokku",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Hokku"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request. Hokku ID must be an integer and larger than 0",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A hokku with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/hokku/{id}/comments": {
+            "get": {
+                "description": "Get comments of the hokku from the first one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get comments",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of hokku",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Sample size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Comment"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A hokku with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/hokku/{id}/similar": {
+            "get": {
+                "description": "Get public hokkus similar to the hokku by their words, theme and tags, from the most similar one. Hokkus of the users muted by the viewer are left out",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get similar hokkus",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of hokku",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Sample size, 10 by default and 50 at most",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Hokku"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request. Hokku ID and limit must be integers",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A hokku with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/hokkus": {
+            "get": {
+                "description": "Get all hokkus",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get all hokkus",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sample size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Hokku"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/hokkus/byAuthor/{authorId}": {
+            "get": {
+                "description": "Get all hokkus of current author",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get hokkus by athor",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sample size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Author id",
+                        "name": "authorId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Hokku"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/hokkus/bySeason/{season}": {
+            "get": {
+                "description": "Get hokkus with a kigo of the season",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get hokkus by season",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sample size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Season: spring, summer, autumn, winter or new-year",
+                        "name": "season",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Hokku"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad query parameters or unknown season",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/hokkus/byTag/{tag}": {
+            "get": {
+                "description": "Get hokkus with the tag. Season tags look like season:winter",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get hokkus by tag",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sample size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tag",
+                        "name": "tag",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Hokku"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/hokkus/byTheme/{themeId}": {
+            "get": {
+                "description": "Get all hokkus of current author",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get hokkus by theme",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sample size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "thme Id",
+                        "name": "themeId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Include hokkus of the subthemes",
+                        "name": "descendants",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Hokku"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/hokkus/top": {
+            "get": {
+                "description": "Get hokkus published during the period ordered by their reactions. Scores are refreshed periodically",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get top hokkus",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "week, month or all, all by default",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Sample size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Hokku"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad query parameters or unknown period",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/hokkus/trending": {
+            "get": {
+                "description": "Get hokkus ordered by their reactions, recent reactions weigh more. Scores are refreshed periodically",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get trending hokkus",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sample size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Hokku"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/kigo": {
+            "get": {
+                "description": "Get seasonal words used to tag hokkus with seasons",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get kigo dictionary",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Kigo"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "description": "Login. Deleted users are restored with their hokkus when they log in with restore set during the retention",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Authenticate",
+                "parameters": [
+                    {
+                        "description": "The user object can only contain email and password",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Restore the deleted user",
+                        "name": "restore",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": ""
+                    },
+                    "400": {
+                        "description": "Wrong email or passowrd",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The user is banned, the reason and the expiry are given",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A user with the specified Email was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/hokku/{id}/unhide": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Show the hokku hidden by a moderator again",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Moderation routes"
+                ],
+                "summary": "Unhide hokku",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of hokku",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request. Id must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to moderators",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A hokku with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/report/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Get the report",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Moderation routes"
+                ],
+                "summary": "Get report",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of report",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Report"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request. Id must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to moderators",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A report with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/report/{id}/claim": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Take the report so other moderators don't handle it",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Moderation routes"
+                ],
+                "summary": "Claim report",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of report",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request. Id must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to moderators",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A report with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "The report is resolved or claimed by another moderator",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/report/{id}/resolve": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Hide or delete the reported hokku or user, or dismiss the report. All unresolved reports\non the same hokku or user are resolved, their reporters and the author are notified",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Moderation routes"
+                ],
+                "summary": "Resolve report",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of report",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Action: hide, delete or dismiss",
+                        "name": "resolution",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Resolution"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Report"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Dont pass validation",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to moderators, only admins can moderate moderators",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A report with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "The report is resolved or claimed by another moderator",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/reports": {
+            "get": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Get the moderation queue from the oldest report",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Moderation routes"
+                ],
+                "summary": "Get reports",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "open, claimed or resolved, open and claimed reports by default",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Sample size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Report"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to moderators",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/restriction/{id}/lift": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Lift the restriction before it expires. Only admins lift restrictions of moderators",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Moderation routes"
+                ],
+                "summary": "Lift restriction",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of restriction",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request. Id must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to moderators",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "The restriction was not found or is already lifted",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/user/{id}/restriction": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Ban the user, suspend their posting or shadow-ban them so their hokkus are shown only to themselves. Restrictions without the expiry are permanent. Only admins restrict moderators",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Moderation routes"
+                ],
+                "summary": "Restrict user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Kind, reason and expiry",
+                        "name": "restriction",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Restriction"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Dont pass validation, the expiry is past or users can` + "`" + `t restrict themselves",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to moderators",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A user with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/user/{id}/restrictions": {
+            "get": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Get all restrictions ever placed on the user from the newest one, lifted and expired ones too",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Moderation routes"
+                ],
+                "summary": "Get user restrictions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Restriction"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request. Id must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to moderators",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/moderation/user/{id}/unhide": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Show the hokkus of the user hidden by a moderator again. Hokkus hidden one by one stay hidden",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Moderation routes"
+                ],
+                "summary": "Unhide user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request. Id must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The action is allowed only to moderators",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A user with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/blocks": {
+            "get": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Get the users blocked by the current user from the newest block",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Get blocked users",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Block"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/bookmarks": {
+            "get": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Get the hokkus bookmarked by the current user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Get bookmarks",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sample size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Hokku"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/chain": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Start a renga with its first stanza in the 5-7-5 form. The current user goes first, the other participants follow in the given order",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Start chain",
+                "parameters": [
+                    {
+                        "description": "New chain with the first stanza",
+                        "name": "chain",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ChainStart"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Dont pass validation or the form check",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "Posting to the theme is closed or posting is suspended",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "Foreign key constraint fails",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/chain/{id}/stanza": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Add the next stanza to the chain. Participants take turns, odd stanzas follow the 5-7-5 form and even ones the 7-7 form",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Append stanza",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of chain",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "The stanza object can only contain title and content or lines",
+                        "name": "stanza",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Hokku"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Dont pass validation or the form check",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The user doesn't participate in the chain, it is not their turn, posting to the theme is closed or posting is suspended",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A chain with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "Another stanza was added first",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/comment/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Delete a comment of the current user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Delete comment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of comment",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Deleted succesfuly"
+                    },
+                    "400": {
+                        "description": "Bad request. Comment ID must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The comment belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A comment with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/contest/{id}/entry": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Submit a public hokku of the current user written on the theme of the contest while the contest is open",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Submit contest entry",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of contest",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "The entry object can only contain hokkuId",
+                        "name": "entry",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ContestEntry"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "The hokku is not public or is written on another theme",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The contest is not open, the hokku belongs to another user or posting is suspended",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A contest or a hokku with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "The hokku is already submitted",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/contest/{id}/vote": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Vote for the entries during the voting window. A single vote lists one hokku, a ranked vote lists up to three hokkus from the best one. Every user votes once and not for own hokkus",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Vote in contest",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of contest",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "The ballot object can only contain hokkuIds",
+                        "name": "ballot",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Ballot"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Dont pass validation or the hokku is not an entry",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The voting is not open or the user votes for own hokku",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A contest with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "The user has already voted",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/drafts": {
+            "get": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Get drafts and scheduled hokkus of the current user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Get drafts",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sample size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Hokku"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/hokku": {
+            "put": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Update hokku of the current user in store. The owner and the theme are kept. Omitted status and visibility keep their values. Scheduled hokkus must be published in the future. Stanzas of a chain keep their status and the form of their position",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Put hokku",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of hokku",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Put Hokku",
+                        "name": "hokku",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Hokku"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Require the 5-7-5 form even if the theme does not",
+                        "name": "strict",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Dont pass validation, the 5-7-5 form check or the form of the stanza, or the publication time has passed",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "Posting to the theme is closed, posting is suspended or the hokku belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "The hokku duplicates existing poems, their ids are listed",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Create new hokku of the current user in Store. Reurn location of new object in header",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Post hokku",
+                "parameters": [
+                    {
+                        "description": "New Hokku",
+                        "name": "hokku",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Hokku"
+                        }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Require the 5-7-5 form even if the theme does not",
+                        "name": "strict",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Dont pass validation or the 5-7-5 form check, or the publication time has passed",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "Posting to the theme is closed or posting is suspended",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "Foreign key constraint fails or the hokku duplicates existing poems, their ids are listed",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/hokku/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Move hokku to the trash. It can be restored during the retention period",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Delete hokku",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of hokku",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Deleted succesfuly"
+                    },
+                    "400": {
+                        "description": "Bad request. Hokku ID must be an integer and larger than 0",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The hokku belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A hokku with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/hokku/{id}/bookmark": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Add the hokku to the bookmarks",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Bookmark hokku",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of hokku",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request. Hokku ID must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A hokku with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "The hokku is already bookmarked",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Remove the hokku from the bookmarks",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Remove bookmark",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of hokku",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request. Hokku ID must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "The hokku or the bookmark was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/hokku/{id}/comment": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Comment the hokku",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Post comment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of hokku",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "The comment object can only contain content",
+                        "name": "comment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Comment"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Dont pass validation",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "Posting is suspended or the user has blocked you",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A hokku with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/hokku/{id}/like": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Like the hokku",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Like hokku",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of hokku",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request. Hokku ID must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The user has blocked you",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A hokku with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "The hokku is already liked",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Remove the like of the hokku",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Unlike hokku",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of hokku",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request. Hokku ID must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "The hokku or the like was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/hokku/{id}/report": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Send the hokku to the moderation queue",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Report hokku",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of hokku",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason of the report: spam, abuse, plagiarism or other",
+                        "name": "report",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Report"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Dont pass validation or the hokku is written by the current user",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A hokku with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "The report is already sent",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/hokku/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Restore deleted hokku of the current user from the trash",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Restore hokku",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of hokku",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Restored succesfuly"
+                    },
+                    "400": {
+                        "description": "Bad request. Hokku ID must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A hokku with the specified ID was not found in the trash",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/hokku/{id}/views": {
+            "get": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Get the total number of views of a hokku of the current user and its daily views for the last days, today included. Views are counted once per user or address within a window and saved with a small delay",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Get hokku views",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of hokku",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of days in the series, 30 by default and 365 at most",
+                        "name": "days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ViewStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request. Hokku ID and days must be integers",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The hokku belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A hokku with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/me/stats": {
+            "get": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Get totals of the published hokkus of the current user and the reactions to them, the followers, the most popular hokkus, posting streaks, a breakdown by theme and the activity series for the last days, today included",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Get author statistics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "day or week, day by default",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of days in the series, 30 by default and 365 at most",
+                        "name": "days",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AuthorStats"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/mutes": {
+            "get": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Get the users muted by the current user from the newest mute",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Get muted users",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Mute"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/notification/{id}/read": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Mark the notification of the current user as read",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Read notification",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of notification",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request. Id must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A notification with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/notifications": {
+            "get": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Get the notifications of the current user from the latest activity. Likes, comments and follows are gathered into one notification until it is read. The X-Unread-Count header holds the number of unread notifications",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Get notifications",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sample size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Notification"
+                            }
+                        },
+                        "headers": {
+                            "X-Unread-Count": {
+                                "type": "integer",
+                                "description": "Number of unread notifications"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/notifications/preferences": {
+            "get": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Get which types of notifications the current user receives",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Get notification preferences",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Turn types of notifications on or off for the current user. Types left out are kept as they are",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Put notification preferences",
+                "parameters": [
+                    {
+                        "description": "Types of notifications and whether they are on",
+                        "name": "preferences",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request params or an unknown type of notifications",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/notifications/read": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Mark all notifications of the current user as read",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Read all notifications",
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/notifications/unread": {
+            "get": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Count the unread notifications of the current user in total and by type",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Count unread notifications",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/trash": {
+            "get": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Get deleted hokkus of the current user that can still be restored",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Get trash",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sample size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Hokku"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/user": {
+            "put": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Update user in store. Users update only themselves unless they are admins, only admins send a changed role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Put user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Put User",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The user is another one or the role is changed by a non-admin",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A user with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/user/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Move user and their hokkus to the trash. They can be restored during the retention period. The webhooks of the user are removed. Users delete only themselves unless they are admins",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Delete user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Deleted succesfuly"
+                    },
+                    "400": {
+                        "description": "Bad request. User ID must be an integer and larger than 0",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The user is another one",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A user with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/user/{id}/block": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Block the user. Blocked users can't follow, like or comment the current user and don't see their followers-only hokkus. Blocking ends their following",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Block user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request. User ID must be an integer and not the current user",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A user with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "The user is already blocked",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Remove the block of the user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Unblock user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request. User ID must be an integer and not the current user",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "The user is not blocked",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/user/{id}/follow": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Follow user. Followers can read followers-only hokkus of the user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Follow user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request. User ID must be an integer and not the current user",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The user has blocked you",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A user with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "User is already followed",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Stop following user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Unfollow user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request. User ID must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "User is not followed",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/user/{id}/mute": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Mute the user. Hokkus and comments of muted users are left out of the feeds and the comments of the current user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Mute user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request. User ID must be an integer and not the current user",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A user with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "The user is already muted",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Remove the mute of the user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Unmute user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad request. User ID must be an integer and not the current user",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "The user is not muted",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/user/{id}/report": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Send the user to the moderation queue",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Report user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason of the report: spam, abuse, plagiarism or other",
+                        "name": "report",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Report"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Dont pass validation or the user is the current user",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A user with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "The report is already sent",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/webhook": {
+            "post": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Register an endpoint that receives the events it subscribes to: hokku.created, hokku.updated, hokku.deleted and user.created. Only public hokkus are sent. Endpoints must resolve to public addresses and redirects are not followed. Payloads are signed with the secret, which is returned only once: the X-Hokku-Signature header holds sha256= and the hex HMAC-SHA256 of the X-Hokku-Timestamp header, a dot and the body. Failed deliveries are retried with growing delays",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Post webhook",
+                "parameters": [
+                    {
+                        "description": "Post webhook",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "The webhook must point to a public address",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/webhook/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Delete the webhook with its delivery log. Admins can delete the webhooks of other users",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Delete webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of webhook",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Deleted succesfuly"
+                    },
+                    "400": {
+                        "description": "Bad request. Id must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The webhook belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A webhook with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/webhook/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Get the delivery log of the webhook from the newest delivery with the outcome of the latest attempt. Admins can see the logs of other users",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Get webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of webhook",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Sample size",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of items to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookDelivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request. Id must be an integer",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The webhook belongs to another user",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A webhook with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/restricted/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "cookieAuth": []
+                    }
+                ],
+                "description": "Get the webhooks of the current user from the newest one. Secrets are not shown",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Restricted routes"
+                ],
+                "summary": "Get webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Webhook"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "The request requires user authentication",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/stream": {
+            "get": {
+                "description": "Push new, updated and deleted public hokkus and the notifications of the current user as Server-Sent Events. Hokkus of the users muted by the current user are left out and the stream of a banned user is closed. The events are hokku.created, hokku.updated, hokku.deleted and notification. Comments are sent as heartbeats. Reconnecting clients send the Last-Event-ID header to get the events they missed, a reset event tells them to load the state again when the events are lost",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Stream updates",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Stream the hokkus of all themes, true by default",
+                        "name": "feed",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Stream the hokkus of the themes",
+                        "name": "theme",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Id of the last received event",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event stream",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "403": {
+                        "description": "The user is banned",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "503": {
+                        "description": "Streaming is turned off",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/themes": {
+            "get": {
+                "description": "Get all themes",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get all themes",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Theme"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/themes/{id}": {
+            "get": {
+                "description": "Get theme by ID or slug with its subthemes and numbers of public hokkus, subthemes included",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get theme",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "id or slug of theme",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ThemeDetails"
+                        }
+                    },
+                    "404": {
+                        "description": "A theme with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/user": {
+            "post": {
+                "description": "Create new user in Store. Return location of new user in header",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Post user",
+                "parameters": [
+                    {
+                        "description": "New User",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Dont pass validation",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "409": {
+                        "description": "User with this email already exists",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}": {
+            "get": {
+                "description": "Get user by ID. The role is shown only to the user and to moderators, the hidden flag only to moderators",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Open routes"
+                ],
+                "summary": "Get user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "id of user",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request. User ID must be an integer and larger than 0",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "404": {
+                        "description": "A user with the specified ID was not found",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    },
+                    "500": {
+                        "description": "Unexpected error",
+                        "schema": {
+                            "$ref": "#/definitions/echo.HTTPError"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "echo.HTTPError": {
+            "type": "object",
+            "properties": {
+                "message": {}
+            }
+        },
+        "models.Activity": {
+            "type": "object",
+            "properties": {
+                "comments": {
+                    "type": "integer"
+                },
+                "day": {
+                    "type": "string"
+                },
+                "hokkus": {
+                    "type": "integer"
+                },
+                "likes": {
+                    "type": "integer"
+                },
+                "views": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actorId": {
+                    "type": "integer"
+                },
+                "after": {
+                    "type": "object"
+                },
+                "before": {
+                    "type": "object"
+                },
+                "created": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "targetId": {
+                    "type": "integer"
+                },
+                "targetType": {
+                    "type": "string"
+                },
+                "userAgent": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AuthorStats": {
+            "type": "object",
+            "properties": {
+                "comments": {
+                    "type": "integer"
+                },
+                "followers": {
+                    "type": "integer"
+                },
+                "hokkus": {
+                    "type": "integer"
+                },
+                "interval": {
+                    "type": "string"
+                },
+                "likes": {
+                    "type": "integer"
+                },
+                "popular": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.HokkuStats"
+                    }
+                },
+                "series": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Activity"
+                    }
+                },
+                "streaks": {
+                    "$ref": "#/definitions/models.Streaks"
+                },
+                "themes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ThemeStats"
+                    }
+                },
+                "views": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Ballot": {
+            "type": "object",
+            "properties": {
+                "contestId": {
+                    "type": "integer"
+                },
+                "created": {
+                    "type": "string"
+                },
+                "hokkuIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "voterId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Block": {
+            "type": "object",
+            "properties": {
+                "blockedId": {
+                    "type": "integer"
+                },
+                "blockerId": {
+                    "type": "integer"
+                },
+                "created": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChainDetails": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "length": {
+                    "type": "integer"
+                },
+                "ownerId": {
+                    "type": "integer"
+                },
+                "participants": {
+                    "description": "Participants are the ids of the poets in the order of their turns,\nthe owner goes first",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "stanzas": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Hokku"
+                    }
+                },
+                "themeId": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ChainStart": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "length": {
+                    "type": "integer"
+                },
+                "ownerId": {
+                    "type": "integer"
+                },
+                "participants": {
+                    "description": "Participants are the ids of the poets in the order of their turns,\nthe owner goes first",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "stanza": {
+                    "$ref": "#/definitions/models.Hokku"
+                },
+                "themeId": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Classic": {
+            "type": "object",
+            "properties": {
+                "author": {
+                    "type": "string"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Comment": {
+            "type": "object",
+            "properties": {
+                "content": {
+                    "type": "string"
+                },
+                "created": {
+                    "type": "string"
+                },
+                "hokkuId": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ownerId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Contest": {
+            "type": "object",
+            "properties": {
+                "closesAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "opensAt": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ContestResult"
+                    }
+                },
+                "resultsPublishedAt": {
+                    "description": "Results are empty until they are published",
+                    "type": "string"
+                },
+                "themeId": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "votingClosesAt": {
+                    "type": "string"
+                },
+                "votingMode": {
+                    "type": "string"
+                },
+                "votingOpensAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ContestDetails": {
+            "type": "object",
+            "properties": {
+                "closesAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Hokku"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "opensAt": {
+                    "type": "string"
+                },
+                "phase": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ContestResult"
+                    }
+                },
+                "resultsPublishedAt": {
+                    "description": "Results are empty until they are published",
+                    "type": "string"
+                },
+                "themeId": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "votingClosesAt": {
+                    "type": "string"
+                },
+                "votingMode": {
+                    "type": "string"
+                },
+                "votingOpensAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ContestEntry": {
+            "type": "object",
+            "properties": {
+                "contestId": {
+                    "type": "integer"
+                },
+                "hokkuId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.ContestResult": {
+            "type": "object",
+            "properties": {
+                "firstVotes": {
+                    "type": "integer"
+                },
+                "hokkuId": {
+                    "type": "integer"
+                },
+                "ownerId": {
+                    "type": "integer"
+                },
+                "place": {
+                    "type": "integer"
+                },
+                "points": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.DailyViews": {
+            "type": "object",
+            "properties": {
+                "day": {
+                    "type": "string"
+                },
+                "hokkuId": {
+                    "type": "integer"
+                },
+                "views": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Duplicates": {
+            "type": "object",
+            "properties": {
+                "classicIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "hokkuIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "models.Flag": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string"
+                },
+                "hokkuId": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "matches": {
+                    "$ref": "#/definitions/models.Duplicates"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Hokku": {
+            "type": "object",
+            "properties": {
+                "chainId": {
+                    "description": "Stanzas of a renga chain reply to the previous stanza, positions\nstart at 1",
+                    "type": "integer"
+                },
+                "content": {
+                    "type": "string"
+                },
+                "created": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "type": "string"
+                },
+                "hidden": {
+                    "description": "Hidden hokkus are hidden by moderators from everyone but the owner",
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "ownerId": {
+                    "type": "integer"
+                },
+                "parentId": {
+                    "type": "integer"
+                },
+                "position": {
+                    "type": "integer"
+                },
+                "publishAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "themeId": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "visibility": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.HokkuStats": {
+            "type": "object",
+            "properties": {
+                "comments": {
+                    "type": "integer"
+                },
+                "hokkuId": {
+                    "type": "integer"
+                },
+                "likes": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "views": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Kigo": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "season": {
+                    "type": "string"
+                },
+                "word": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Mute": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string"
+                },
+                "mutedId": {
+                    "type": "integer"
+                },
+                "muterId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Notification": {
+            "type": "object",
+            "properties": {
+                "actorId": {
+                    "type": "integer"
+                },
+                "actors": {
+                    "type": "integer"
+                },
+                "commentId": {
+                    "type": "integer"
+                },
+                "created": {
+                    "type": "string"
+                },
+                "hokkuId": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "read": {
+                    "type": "boolean"
+                },
+                "reportId": {
+                    "type": "integer"
+                },
+                "resolution": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Report": {
+            "type": "object",
+            "properties": {
+                "authorId": {
+                    "type": "integer"
+                },
+                "comment": {
+                    "type": "string"
+                },
+                "commentId": {
+                    "type": "integer"
+                },
+                "created": {
+                    "type": "string"
+                },
+                "hokkuId": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "moderatorId": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "reporterId": {
+                    "type": "integer"
+                },
+                "resolution": {
+                    "type": "string"
+                },
+                "resolved": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Resolution": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Restriction": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string"
+                },
+                "expires": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "lifted": {
+                    "type": "string"
+                },
+                "liftedBy": {
+                    "type": "integer"
+                },
+                "moderatorId": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.RoleChange": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Streaks": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "type": "integer"
+                },
+                "longest": {
+                    "type": "integer"
                 }
             }
         },
         "models.Theme": {
             "type": "object",
             "properties": {
+                "cover": {
+                    "description": "Cover is a short text shown at the top of the theme page",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "parentId": {
+                    "description": "Root themes have no parent and ParentId 0",
+                    "type": "integer"
+                },
+                "postingClosed": {
+                    "description": "No hokkus can be posted to a closed theme",
+                    "type": "boolean"
+                },
+                "slug": {
+                    "type": "string"
+                },
+                "sortOrder": {
+                    "type": "integer"
+                },
+                "strict": {
+                    "description": "Hokkus of a strict theme must follow the 5-7-5 form",
+                    "type": "boolean"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ThemeCount": {
+            "type": "object",
+            "properties": {
+                "cover": {
+                    "description": "Cover is a short text shown at the top of the theme page",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "hokkuCount": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "parentId": {
+                    "description": "Root themes have no parent and ParentId 0",
+                    "type": "integer"
+                },
+                "postingClosed": {
+                    "description": "No hokkus can be posted to a closed theme",
+                    "type": "boolean"
+                },
+                "slug": {
+                    "type": "string"
+                },
+                "sortOrder": {
+                    "type": "integer"
+                },
+                "strict": {
+                    "description": "Hokkus of a strict theme must follow the 5-7-5 form",
+                    "type": "boolean"
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ThemeDetails": {
+            "type": "object",
+            "properties": {
+                "cover": {
+                    "description": "Cover is a short text shown at the top of the theme page",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "hokkuCount": {
+                    "type": "integer"
+                },
                 "id": {
                     "type": "integer"
                 },
+                "parentId": {
+                    "description": "Root themes have no parent and ParentId 0",
+                    "type": "integer"
+                },
+                "postingClosed": {
+                    "description": "No hokkus can be posted to a closed theme",
+                    "type": "boolean"
+                },
+                "slug": {
+                    "type": "string"
+                },
+                "sortOrder": {
+                    "type": "integer"
+                },
+                "strict": {
+                    "description": "Hokkus of a strict theme must follow the 5-7-5 form",
+                    "type": "boolean"
+                },
+                "subthemes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ThemeCount"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ThemeStats": {
+            "type": "object",
+            "properties": {
+                "comments": {
+                    "type": "integer"
+                },
+                "hokkus": {
+                    "type": "integer"
+                },
+                "likes": {
+                    "type": "integer"
+                },
+                "themeId": {
+                    "type": "integer"
+                },
                 "title": {
                     "type": "string"
+                },
+                "views": {
+                    "type": "integer"
                 }
             }
         },
@@ -791,9 +6398,15 @@ var doc = `{
                 "created": {
                     "type": "string"
                 },
+                "deletedAt": {
+                    "type": "string"
+                },
                 "email": {
                     "type": "string"
                 },
+                "hidden": {
+                    "type": "boolean"
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -802,6 +6415,121 @@ var doc = `{
                 },
                 "password": {
                     "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.ViewStats": {
+            "type": "object",
+            "properties": {
+                "daily": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.DailyViews"
+                    }
+                },
+                "hokkuId": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Webhook": {
+            "type": "object",
+            "properties": {
+                "created": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ownerId": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created": {
+                    "type": "string"
+                },
+                "delivered": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "nextAttempt": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "statusCode": {
+                    "type": "integer"
+                },
+                "webhookId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "prosody.Analysis": {
+            "type": "object",
+            "properties": {
+                "conforms": {
+                    "type": "boolean"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/prosody.Line"
+                    }
+                }
+            }
+        },
+        "prosody.Line": {
+            "type": "object",
+            "properties": {
+                "expected": {
+                    "type": "integer"
+                },
+                "syllables": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
                 }
             }
         }
@@ -0,0 +1,125 @@
+// Package filter screens user texts. A pipeline runs the text through its
+// steps, every step finds fragments with its rule and rejects the text,
+// masks the fragments or sends the text to moderation. The steps are read
+// from a rules file and can be reloaded while the server runs.
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Actions of the steps.
+const (
+	ActionReject   = "reject"
+	ActionMask     = "mask"
+	ActionModerate = "moderate"
+)
+
+// maskRune replaces every rune of masked fragments.
+const maskRune = '*'
+
+// Span is a fragment of a text given by its byte offsets.
+type Span struct {
+	Start, End int
+}
+
+// Rule finds the fragments of a text a step objects to.
+type Rule interface {
+	Find(text string) []Span
+}
+
+// Step applies the action to the fragments found by the rule. The name
+// is reported as the reason of the action.
+type Step struct {
+	Name   string
+	Action string
+	Rule   Rule
+}
+
+// Result is the screened text with the found fragments masked by the
+// masking steps, the names of the steps that found anything and whether
+// the text is rejected or must be moderated.
+type Result struct {
+	Text      string
+	Reasons   []string
+	Rejected  bool
+	Moderated bool
+}
+
+// Pipeline runs the texts through its steps in order. It is safe for
+// concurrent use.
+type Pipeline struct {
+	mu    sync.RWMutex
+	steps []Step
+	path  string
+}
+
+// New returns a pipeline of the steps. Reload doesn't change it.
+func New(steps ...Step) *Pipeline {
+	return &Pipeline{steps: steps}
+}
+
+// Load returns a pipeline of the steps described in the rules file.
+func Load(path string) (*Pipeline, error) {
+	p := &Pipeline{path: path}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload reads the rules file again. The pipeline keeps its steps when the
+// file can't be read.
+func (p *Pipeline) Reload() error {
+	if p.path == "" {
+		return nil
+	}
+	steps, err := ReadRules(p.path)
+	if err != nil {
+		return fmt.Errorf("filter: %w", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.steps = steps
+	return nil
+}
+
+// Check screens the text.
+func (p *Pipeline) Check(text string) *Result {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	res := &Result{Text: text, Reasons: []string{}}
+	for _, step := range p.steps {
+		spans := step.Rule.Find(res.Text)
+		if len(spans) == 0 {
+			continue
+		}
+		res.Reasons = append(res.Reasons, step.Name)
+		switch step.Action {
+		case ActionReject:
+			res.Rejected = true
+		case ActionModerate:
+			res.Moderated = true
+		case ActionMask:
+			res.Text = mask(res.Text, spans)
+		}
+	}
+	return res
+}
+
+// mask replaces every rune of the spans. The spans are ordered and don't
+// overlap.
+func mask(text string, spans []Span) string {
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		b.WriteString(text[last:s.Start])
+		b.WriteString(strings.Repeat(string(maskRune), utf8.RuneCountInString(text[s.Start:s.End])))
+		last = s.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package filter_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/EgorSkurihin/Hokku/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWordlist(t *testing.T) {
+	w := filter.NewWordlist([]string{"мудак", "ёрш", "shit", "fuck*", "хуй*", "ебал*", "ебн*", "пизд*", "бля*"})
+	cases := []struct {
+		text  string
+		found bool
+	}{
+		{"Какой мудак", true},
+		{"Мудаками", true},
+		{"ерши", true},
+		{"SHIIIT happens", true},
+		{"fucking rain", true},
+		{"Old pond", false},
+		{"мудрый старик", false},
+		{"Иди нахуй", true},
+		{"Заебал", true},
+		{"распиздяй", true},
+		{"подъебнуть", true},
+		{"Корабля", false},
+		{"Отблеск", false},
+		{"shittake", false},
+	}
+	for _, cs := range cases {
+		assert.Equal(t, cs.found, len(w.Find(cs.text)) > 0, cs.text)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	l := &filter.Links{Max: 1}
+	assert.Empty(t, l.Find("Read my haiku at http://example.com"))
+	assert.Len(t, l.Find("Buy at shop.ru and www.example.com/sale"), 2)
+	assert.Len(t, l.Find("сайт.рф, spam.xyz"), 2)
+	// Zones followed by letters are parts of words
+	assert.Empty(t, l.Find("old.community and new.netting"))
+}
+
+func TestRepeats(t *testing.T) {
+	r := &filter.Repeats{Max: 3}
+	assert.Empty(t, r.Find("Ооо, снег!"))
+	assert.Equal(t, []filter.Span{{Start: 4, End: 10}}, r.Find("Yes AaAaaa"))
+	assert.Empty(t, r.Find("a      b"))
+	assert.Empty(t, r.Find("******"))
+}
+
+func TestCheck(t *testing.T) {
+	p := filter.New(
+		filter.Step{Name: "profanity", Action: filter.ActionMask, Rule: filter.NewWordlist([]string{"хуй*", "shit"})},
+		filter.Step{Name: "links", Action: filter.ActionModerate, Rule: &filter.Links{Max: 0}},
+		filter.Step{Name: "repeats", Action: filter.ActionReject, Rule: &filter.Repeats{Max: 4}},
+	)
+	res := p.Check("Old pond")
+	assert.Equal(t, &filter.Result{Text: "Old pond", Reasons: []string{}}, res)
+
+	res = p.Check("Хуйня, shit!")
+	assert.Equal(t, "*****, ****!", res.Text)
+	assert.Equal(t, []string{"profanity"}, res.Reasons)
+	assert.False(t, res.Rejected || res.Moderated)
+
+	res = p.Check("shit at spam.com")
+	assert.Equal(t, "**** at spam.com", res.Text)
+	assert.Equal(t, []string{"profanity", "links"}, res.Reasons)
+	assert.True(t, res.Moderated)
+
+	res = p.Check("Buy!!!!!")
+	assert.Equal(t, []string{"repeats"}, res.Reasons)
+	assert.True(t, res.Rejected)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	rules := filepath.Join(dir, "rules.toml")
+	write("words.txt", "# comment\n\nshit\n")
+	write("rules.toml", `
+[[step]]
+    rule="wordlist"
+    action="mask"
+    wordlists=["words.txt"]
+`)
+	p, err := filter.Load(rules)
+	if !assert.NoError(t, err) {
+		return
+	}
+	res := p.Check("shit")
+	assert.Equal(t, "****", res.Text)
+	assert.Equal(t, []string{"wordlist"}, res.Reasons)
+
+	// The wordlists are read again on reload
+	write("words.txt", "pond\n")
+	assert.NoError(t, p.Reload())
+	assert.Equal(t, "Old ****", p.Check("Old pond").Text)
+
+	// Broken rules keep the previous steps
+	write("rules.toml", `
+[[step]]
+    rule="wordlist"
+    action="erase"
+`)
+	assert.Error(t, p.Reload())
+	assert.Equal(t, "Old ****", p.Check("Old pond").Text)
+
+	write("rules.toml", `
+[[step]]
+    rule="repeats"
+    action="reject"
+`)
+	assert.Error(t, p.Reload())
+
+	_, err = filter.Load(filepath.Join(dir, "missing.toml"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,78 @@
+package filter
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+)
+
+// StepConfig describes a step in the rules file. Every rule reads only the
+// settings it needs. The name defaults to the rule.
+type StepConfig struct {
+	Name   string `toml:"name"`
+	Rule   string `toml:"rule"`
+	Action string `toml:"action"`
+	// Paths of the wordlist files relative to the rules file
+	Wordlists []string `toml:"wordlists"`
+	// Texts with more links are spam
+	MaxLinks int `toml:"max_links"`
+	// Longer runs of the same character are spam
+	MaxRepeats int `toml:"max_repeats"`
+}
+
+// Factory builds a rule from its settings. Relative paths are resolved
+// against dir, the directory of the rules file.
+type Factory func(conf *StepConfig, dir string) (Rule, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]Factory{
+		"wordlist": newWordlistRule,
+		"links":    newLinksRule,
+		"repeats":  newRepeatsRule,
+	}
+)
+
+// Register makes the rule available to the rules files under the name.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[name] = factory
+}
+
+// ReadRules builds the steps described in the rules file.
+func ReadRules(path string) ([]Step, error) {
+	rules := struct {
+		Steps []StepConfig `toml:"step"`
+	}{}
+	if _, err := toml.DecodeFile(path, &rules); err != nil {
+		return nil, err
+	}
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	steps := make([]Step, 0, len(rules.Steps))
+	for i := range rules.Steps {
+		conf := &rules.Steps[i]
+		factory, ok := factories[conf.Rule]
+		if !ok {
+			return nil, fmt.Errorf("step %d: unknown rule %q", i+1, conf.Rule)
+		}
+		switch conf.Action {
+		case ActionReject, ActionMask, ActionModerate:
+		default:
+			return nil, fmt.Errorf("step %d: unknown action %q", i+1, conf.Action)
+		}
+		rule, err := factory(conf, filepath.Dir(path))
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+		name := conf.Name
+		if name == "" {
+			name = conf.Rule
+		}
+		steps = append(steps, Step{Name: name, Action: conf.Action, Rule: rule})
+	}
+	return steps, nil
+}
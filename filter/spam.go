@@ -0,0 +1,75 @@
+package filter
+
+import (
+	"errors"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+// linkRegexp matches addresses with a scheme or www and bare domains in
+// the zones popular with spammers.
+var linkRegexp = regexp.MustCompile(`(?i)(?:https?://|www\.)[^\s]+|` +
+	`[\p{L}\d][\p{L}\d-]*\.(?:com|net|org|info|biz|io|me|ru|su|xyz|top|рф)(?:[/?#:][^\s]*)?`)
+
+// Links finds links when a text has more than Max of them.
+type Links struct {
+	Max int
+}
+
+func newLinksRule(conf *StepConfig, dir string) (Rule, error) {
+	if conf.MaxLinks < 0 {
+		return nil, errors.New("max_links can't be negative")
+	}
+	return &Links{Max: conf.MaxLinks}, nil
+}
+
+func (l *Links) Find(text string) []Span {
+	spans := []Span{}
+	for _, m := range linkRegexp.FindAllStringIndex(text, -1) {
+		// A zone followed by letters is a part of a longer word
+		if r, _ := utf8.DecodeRuneInString(text[m[1]:]); m[1] < len(text) && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			continue
+		}
+		spans = append(spans, Span{m[0], m[1]})
+	}
+	if len(spans) <= l.Max {
+		return nil
+	}
+	return spans
+}
+
+// Repeats finds runs of more than Max equal characters other than
+// whitespace and the masks of the previous steps, case is ignored.
+type Repeats struct {
+	Max int
+}
+
+func newRepeatsRule(conf *StepConfig, dir string) (Rule, error) {
+	if conf.MaxRepeats < 1 {
+		return nil, errors.New("max_repeats must be positive")
+	}
+	return &Repeats{Max: conf.MaxRepeats}, nil
+}
+
+func (rp *Repeats) Find(text string) []Span {
+	spans := []Span{}
+	start, count := 0, 0
+	var last rune
+	flush := func(end int) {
+		if count > rp.Max && !unicode.IsSpace(last) && last != maskRune {
+			spans = append(spans, Span{start, end})
+		}
+	}
+	for i, r := range text {
+		r = unicode.ToLower(r)
+		if count > 0 && r == last {
+			count++
+			continue
+		}
+		flush(i)
+		start, count, last = i, 1, r
+	}
+	flush(len(text))
+	return spans
+}
@@ -0,0 +1,163 @@
+package filter
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/EgorSkurihin/Hokku/similar"
+)
+
+// Wordlist finds the listed words. Words are compared lower-cased with ё
+// replaced by е, by their stems too, so inflected forms of the listed words
+// are found, and with repeated letters collapsed. Entries ending with '*'
+// match all words starting with them. Words are also compared without a
+// leading Russian prefix, so "нахуй" is found by "хуй*".
+type Wordlist struct {
+	words    map[string]bool
+	stems    map[string]bool
+	prefixes []string
+}
+
+func NewWordlist(entries []string) *Wordlist {
+	w := &Wordlist{words: map[string]bool{}, stems: map[string]bool{}}
+	for _, e := range entries {
+		e = normalizeWord(e)
+		if e == "" {
+			continue
+		}
+		if strings.HasSuffix(e, "*") {
+			w.prefixes = append(w.prefixes, strings.TrimSuffix(e, "*"))
+			continue
+		}
+		w.words[e] = true
+		w.stems[similar.Stem(e)] = true
+	}
+	return w
+}
+
+// ReadWordlist reads the entries of a wordlist file, one per line. Empty
+// lines and lines starting with '#' are skipped.
+func ReadWordlist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entries := []string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			entries = append(entries, line)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func newWordlistRule(conf *StepConfig, dir string) (Rule, error) {
+	entries := []string{}
+	for _, path := range conf.Wordlists {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		words, err := ReadWordlist(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, words...)
+	}
+	return NewWordlist(entries), nil
+}
+
+func normalizeWord(word string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(word)), "ё", "е")
+}
+
+// collapseRepeats replaces runs of the same rune with one rune.
+func collapseRepeats(word string) string {
+	var b strings.Builder
+	var last rune
+	for _, r := range word {
+		if r != last {
+			b.WriteRune(r)
+		}
+		last = r
+	}
+	return b.String()
+}
+
+// wordPrefixes are the Russian prefixes stripped from words before they are
+// compared again. Longer prefixes go first.
+var wordPrefixes = []string{
+	"недо", "пере", "пона", "разъ", "подъ", "изъ", "объ", "отъ", "раз", "рас",
+	"под", "при", "про", "вы", "за", "из", "ис", "на", "ни", "об", "от", "по",
+	"съ", "до", "в", "о", "с", "у",
+}
+
+// minStripped is the length in runes a word keeps after its prefix is
+// stripped, shorter rests are not compared.
+const minStripped = 3
+
+func (w *Wordlist) match(word string) bool {
+	if w.matchWord(word) {
+		return true
+	}
+	for _, p := range wordPrefixes {
+		rest := strings.TrimPrefix(word, p)
+		if rest != word && utf8.RuneCountInString(rest) >= minStripped && w.matchWord(rest) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Wordlist) matchWord(word string) bool {
+	if w.words[word] || w.stems[similar.Stem(word)] {
+		return true
+	}
+	for _, p := range w.prefixes {
+		if strings.HasPrefix(word, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Wordlist) Find(text string) []Span {
+	spans := []Span{}
+	for _, s := range words(text) {
+		word := normalizeWord(text[s.Start:s.End])
+		if w.match(word) {
+			spans = append(spans, s)
+			continue
+		}
+		if collapsed := collapseRepeats(word); collapsed != word && w.match(collapsed) {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+// words returns the spans of the runs of letters and digits.
+func words(text string) []Span {
+	spans := []Span{}
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if inWord && start == -1 {
+			start = i
+		}
+		if !inWord && start != -1 {
+			spans = append(spans, Span{start, i})
+			start = -1
+		}
+	}
+	if start != -1 {
+		spans = append(spans, Span{start, len(text)})
+	}
+	return spans
+}
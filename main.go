@@ -1,12 +1,24 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/EgorSkurihin/Hokku/api"
+	"github.com/EgorSkurihin/Hokku/clock"
 	"github.com/EgorSkurihin/Hokku/config"
 	_ "github.com/EgorSkurihin/Hokku/docs"
+	"github.com/EgorSkurihin/Hokku/filter"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/outbox"
+	"github.com/EgorSkurihin/Hokku/scheduler"
+	"github.com/EgorSkurihin/Hokku/similar"
 	"github.com/EgorSkurihin/Hokku/store/mysql_store"
+	"github.com/EgorSkurihin/Hokku/stream"
 )
 
 // @title Hokku Rest API
@@ -27,13 +39,90 @@ func main() {
 		log.Fatal(err)
 	}
 	// Create and open storage
-	store := mysql_store.New(&conf.Store)
-	if err := store.Open(); err != nil {
+	db := mysql_store.New(&conf.Store)
+	if err := db.Open(); err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.FillFingerprints(); err != nil {
+		log.Fatal(err)
+	}
+	// Index hokkus for recommendations on every write
+	index := similar.NewIndex()
+	store := similar.NewStore(db, index)
+	if err := store.Rebuild(); err != nil {
 		log.Fatal(err)
 	}
-	defer store.Close()
+
+	// Start background jobs
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	publisher := scheduler.NewPublisher(store, clock.Real{},
+		time.Duration(conf.Jobs.PublishInterval)*time.Second)
+	go publisher.Run(ctx)
+	purger := scheduler.NewPurger(store, clock.Real{},
+		time.Duration(conf.Jobs.PurgeInterval)*time.Second,
+		time.Duration(conf.Server.TrashRetentionDays)*24*time.Hour)
+	go purger.Run(ctx)
+	judge := scheduler.NewJudge(store, clock.Real{},
+		time.Duration(conf.Jobs.JudgeInterval)*time.Second)
+	go judge.Run(ctx)
+	ranker := scheduler.NewRanker(store, clock.Real{},
+		time.Duration(conf.Jobs.RankInterval)*time.Second,
+		models.RankingWeights{
+			Like:     conf.Ranking.LikeWeight,
+			Comment:  conf.Ranking.CommentWeight,
+			Bookmark: conf.Ranking.BookmarkWeight,
+			View:     conf.Ranking.ViewWeight,
+			HalfLife: time.Duration(conf.Ranking.HalfLifeHours * float64(time.Hour)),
+		})
+	go ranker.Run(ctx)
+	views := scheduler.NewViewCounter(store, clock.Real{},
+		time.Duration(conf.Jobs.ViewFlushInterval)*time.Second,
+		time.Duration(conf.Server.ViewWindowMinutes)*time.Minute,
+		conf.Jobs.ViewBufferSize)
+	go views.Run(ctx)
+	webhooks := scheduler.NewWebhookSender(store, clock.Real{},
+		time.Duration(conf.Jobs.WebhookInterval)*time.Second,
+		scheduler.NewWebhookClient(time.Duration(conf.Jobs.WebhookTimeout)*time.Second),
+		conf.Jobs.WebhookAttempts,
+		time.Duration(conf.Jobs.WebhookBackoff)*time.Second)
+	go webhooks.Run(ctx)
 
 	// Start API Server
 	api := api.New(&conf.Server, store)
+	api.Views = views
+	api.Similar = index
+	if conf.Stream.HeartbeatInterval > 0 && conf.Stream.BufferSize > 0 {
+		api.Hub = stream.NewHub(conf.Stream.HistorySize, conf.Stream.BufferSize,
+			time.Duration(conf.Stream.HeartbeatInterval)*time.Second)
+	}
+	// Screen user texts, SIGHUP reloads the rules and wordlists
+	if conf.Filter.Rules != "" {
+		pipeline, err := filter.Load(conf.Filter.Rules)
+		if err != nil {
+			log.Fatal(err)
+		}
+		api.Filter = pipeline
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				if err := pipeline.Reload(); err != nil {
+					log.Println(err)
+				}
+			}
+		}()
+	}
+	// Relay domain events to the stream and the webhooks
+	bus := outbox.NewBus(1000)
+	bus.Subscribe(api.StreamEvent)
+	sinks := []outbox.Sink{bus, outbox.SinkFunc(api.DispatchWebhooks)}
+	if conf.Jobs.OutboxLog {
+		sinks = append(sinks, outbox.NewLog(log.Default()))
+	}
+	relay := scheduler.NewOutboxRelay(store,
+		time.Duration(conf.Jobs.OutboxInterval)*time.Second, conf.Jobs.OutboxAttempts, sinks...)
+	go relay.Run(ctx)
 	log.Fatal(api.Start())
 }
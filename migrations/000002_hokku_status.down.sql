@@ -0,0 +1,5 @@
+DROP INDEX idx_hokkus_status_publish_at ON `hokkus`;
+
+ALTER TABLE `hokkus`
+	DROP COLUMN `publish_at`,
+	DROP COLUMN `status`;
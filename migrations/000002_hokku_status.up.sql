@@ -0,0 +1,7 @@
+USE hokku;
+
+ALTER TABLE `hokkus`
+	ADD COLUMN `status` VARCHAR(16) NOT NULL DEFAULT 'published',
+	ADD COLUMN `publish_at` DATETIME NULL;
+
+CREATE INDEX idx_hokkus_status_publish_at ON hokkus(status, publish_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `follows`;
+
+ALTER TABLE `hokkus` DROP COLUMN `visibility`;
@@ -0,0 +1,16 @@
+USE hokku;
+
+ALTER TABLE `hokkus` ADD COLUMN `visibility` VARCHAR(16) NOT NULL DEFAULT 'public';
+
+CREATE TABLE `follows` (
+	`follower` BIGINT NOT NULL,
+	`followee` BIGINT NOT NULL,
+	`created` DATETIME NOT NULL,
+	PRIMARY KEY (`follower`, `followee`)
+);
+
+ALTER TABLE `follows` ADD CONSTRAINT `Follow_fk0` FOREIGN KEY (`follower`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `follows` ADD CONSTRAINT `Follow_fk1` FOREIGN KEY (`followee`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+CREATE INDEX idx_follows_followee ON follows(followee);
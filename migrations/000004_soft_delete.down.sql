@@ -0,0 +1,6 @@
+DELETE FROM `hokkus` WHERE `deleted_at` IS NOT NULL;
+DELETE FROM `users` WHERE `deleted_at` IS NOT NULL;
+
+ALTER TABLE `hokkus` DROP COLUMN `deleted_at`;
+
+ALTER TABLE `users` DROP COLUMN `deleted_at`;
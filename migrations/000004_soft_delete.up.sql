@@ -0,0 +1,8 @@
+USE hokku;
+
+ALTER TABLE `users` ADD COLUMN `deleted_at` DATETIME NULL;
+
+ALTER TABLE `hokkus` ADD COLUMN `deleted_at` DATETIME NULL;
+
+CREATE INDEX idx_users_deleted_at ON users(deleted_at);
+CREATE INDEX idx_hokkus_deleted_at ON hokkus(deleted_at);
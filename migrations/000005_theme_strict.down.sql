@@ -0,0 +1 @@
+ALTER TABLE `themes` DROP COLUMN `strict`;
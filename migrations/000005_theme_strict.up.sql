@@ -0,0 +1,3 @@
+USE hokku;
+
+ALTER TABLE `themes` ADD COLUMN `strict` BOOLEAN NOT NULL DEFAULT FALSE;
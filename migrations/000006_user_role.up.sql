@@ -0,0 +1,3 @@
+USE hokku;
+
+ALTER TABLE `users` ADD COLUMN `role` VARCHAR(16) NOT NULL DEFAULT 'user';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `kigo`;
+
+DROP TABLE IF EXISTS `hokku_tags`;
@@ -0,0 +1,25 @@
+USE hokku;
+
+CREATE TABLE `hokku_tags` (
+	`hokku` BIGINT NOT NULL,
+	`tag` VARCHAR(40) NOT NULL,
+	PRIMARY KEY (`hokku`, `tag`)
+);
+
+ALTER TABLE `hokku_tags` ADD CONSTRAINT `HokkuTag_fk0` FOREIGN KEY (`hokku`) REFERENCES `hokkus`(`id`) ON DELETE CASCADE;
+
+CREATE INDEX idx_hokku_tags_tag ON hokku_tags(tag);
+
+CREATE TABLE `kigo` (
+	`id` INT NOT NULL AUTO_INCREMENT,
+	`word` VARCHAR(100) NOT NULL UNIQUE,
+	`season` VARCHAR(16) NOT NULL,
+	PRIMARY KEY (`id`)
+);
+
+INSERT INTO `kigo` (`word`, `season`)
+VALUES ('cherry blossoms', 'spring'), ('spring rain', 'spring'), ('сакура', 'spring'), ('весенний дождь', 'spring'),
+       ('cicada', 'summer'), ('summer grass', 'summer'), ('цикада', 'summer'), ('летний дождь', 'summer'),
+       ('autumn wind', 'autumn'), ('harvest moon', 'autumn'), ('стрекоза', 'autumn'), ('осенний вечер', 'autumn'),
+       ('first snow', 'winter'), ('winter solitude', 'winter'), ('первый снег', 'winter'), ('зимний ветер', 'winter'),
+       ('new year', 'new-year'), ('первый день года', 'new-year');
@@ -0,0 +1,9 @@
+ALTER TABLE `themes` DROP FOREIGN KEY `Theme_fk0`;
+
+ALTER TABLE `themes`
+	DROP COLUMN `slug`,
+	DROP COLUMN `description`,
+	DROP COLUMN `parent`,
+	DROP COLUMN `cover`,
+	DROP COLUMN `sort_order`,
+	DROP COLUMN `posting_closed`;
@@ -0,0 +1,15 @@
+USE hokku;
+
+ALTER TABLE `themes`
+	ADD COLUMN `slug` VARCHAR(40) NULL,
+	ADD COLUMN `description` VARCHAR(1000) NOT NULL DEFAULT '',
+	ADD COLUMN `parent` INT NULL,
+	ADD COLUMN `cover` VARCHAR(500) NOT NULL DEFAULT '',
+	ADD COLUMN `sort_order` INT NOT NULL DEFAULT 0,
+	ADD COLUMN `posting_closed` BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE `themes` SET `slug` = CONCAT('theme-', `id`);
+
+ALTER TABLE `themes` MODIFY `slug` VARCHAR(40) NOT NULL UNIQUE;
+
+ALTER TABLE `themes` ADD CONSTRAINT `Theme_fk0` FOREIGN KEY (`parent`) REFERENCES `themes`(`id`) ON DELETE SET NULL;
@@ -0,0 +1,14 @@
+ALTER TABLE `hokkus` DROP FOREIGN KEY `Hokku_fk2`;
+
+ALTER TABLE `hokkus` DROP FOREIGN KEY `Hokku_fk3`;
+
+DROP INDEX idx_hokkus_chain_position ON hokkus;
+
+ALTER TABLE `hokkus`
+	DROP COLUMN `chain`,
+	DROP COLUMN `parent`,
+	DROP COLUMN `position`;
+
+DROP TABLE IF EXISTS `chain_participants`;
+
+DROP TABLE IF EXISTS `chains`;
@@ -0,0 +1,37 @@
+USE hokku;
+
+CREATE TABLE `chains` (
+	`id` BIGINT NOT NULL AUTO_INCREMENT,
+	`title` VARCHAR(255) NOT NULL,
+	`owner` BIGINT NOT NULL,
+	`theme` INT NOT NULL,
+	`created` DATETIME NOT NULL,
+	PRIMARY KEY (`id`)
+);
+
+ALTER TABLE `chains` ADD CONSTRAINT `Chain_fk0` FOREIGN KEY (`owner`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `chains` ADD CONSTRAINT `Chain_fk1` FOREIGN KEY (`theme`) REFERENCES `themes`(`id`) ON DELETE CASCADE;
+
+CREATE TABLE `chain_participants` (
+	`chain` BIGINT NOT NULL,
+	`turn` INT NOT NULL,
+	`participant` BIGINT NOT NULL,
+	PRIMARY KEY (`chain`, `turn`),
+	UNIQUE (`chain`, `participant`)
+);
+
+ALTER TABLE `chain_participants` ADD CONSTRAINT `ChainParticipant_fk0` FOREIGN KEY (`chain`) REFERENCES `chains`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `chain_participants` ADD CONSTRAINT `ChainParticipant_fk1` FOREIGN KEY (`participant`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `hokkus`
+	ADD COLUMN `chain` BIGINT NULL,
+	ADD COLUMN `parent` BIGINT NULL,
+	ADD COLUMN `position` INT NULL;
+
+ALTER TABLE `hokkus` ADD CONSTRAINT `Hokku_fk2` FOREIGN KEY (`chain`) REFERENCES `chains`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `hokkus` ADD CONSTRAINT `Hokku_fk3` FOREIGN KEY (`parent`) REFERENCES `hokkus`(`id`) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX idx_hokkus_chain_position ON hokkus(`chain`, `position`);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS `contest_results`;
+
+DROP TABLE IF EXISTS `contest_votes`;
+
+DROP TABLE IF EXISTS `contest_entries`;
+
+DROP TABLE IF EXISTS `contests`;
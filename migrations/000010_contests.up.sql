@@ -0,0 +1,60 @@
+USE hokku;
+
+CREATE TABLE `contests` (
+	`id` BIGINT NOT NULL AUTO_INCREMENT,
+	`title` VARCHAR(255) NOT NULL,
+	`description` TEXT NOT NULL,
+	`theme` INT NOT NULL,
+	`voting_mode` VARCHAR(10) NOT NULL DEFAULT 'single',
+	`opens_at` DATETIME NOT NULL,
+	`closes_at` DATETIME NOT NULL,
+	`voting_opens_at` DATETIME NOT NULL,
+	`voting_closes_at` DATETIME NOT NULL,
+	`results_published_at` DATETIME NULL,
+	PRIMARY KEY (`id`)
+);
+
+ALTER TABLE `contests` ADD CONSTRAINT `Contest_fk0` FOREIGN KEY (`theme`) REFERENCES `themes`(`id`) ON DELETE CASCADE;
+
+CREATE INDEX idx_contests_voting_closes_at ON contests(`voting_closes_at`);
+
+CREATE TABLE `contest_entries` (
+	`contest` BIGINT NOT NULL,
+	`hokku` BIGINT NOT NULL,
+	`created` DATETIME NOT NULL,
+	PRIMARY KEY (`contest`, `hokku`)
+);
+
+ALTER TABLE `contest_entries` ADD CONSTRAINT `ContestEntry_fk0` FOREIGN KEY (`contest`) REFERENCES `contests`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `contest_entries` ADD CONSTRAINT `ContestEntry_fk1` FOREIGN KEY (`hokku`) REFERENCES `hokkus`(`id`) ON DELETE CASCADE;
+
+-- A ballot is stored as one row per choice, the first choice has number 0.
+-- The primary key keeps one ballot per voter.
+CREATE TABLE `contest_votes` (
+	`contest` BIGINT NOT NULL,
+	`voter` BIGINT NOT NULL,
+	`choice` INT NOT NULL,
+	`hokku` BIGINT NOT NULL,
+	`created` DATETIME NOT NULL,
+	PRIMARY KEY (`contest`, `voter`, `choice`),
+	UNIQUE (`contest`, `voter`, `hokku`)
+);
+
+ALTER TABLE `contest_votes` ADD CONSTRAINT `ContestVote_fk0` FOREIGN KEY (`contest`) REFERENCES `contests`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `contest_votes` ADD CONSTRAINT `ContestVote_fk1` FOREIGN KEY (`voter`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `contest_votes` ADD CONSTRAINT `ContestVote_fk2` FOREIGN KEY (`hokku`) REFERENCES `hokkus`(`id`) ON DELETE CASCADE;
+
+CREATE TABLE `contest_results` (
+	`contest` BIGINT NOT NULL,
+	`hokku` BIGINT NOT NULL,
+	`owner` BIGINT NOT NULL,
+	`place` INT NOT NULL,
+	`points` INT NOT NULL,
+	`first_votes` INT NOT NULL,
+	PRIMARY KEY (`contest`, `hokku`)
+);
+
+ALTER TABLE `contest_results` ADD CONSTRAINT `ContestResult_fk0` FOREIGN KEY (`contest`) REFERENCES `contests`(`id`) ON DELETE CASCADE;
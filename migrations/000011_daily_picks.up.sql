@@ -0,0 +1,10 @@
+USE hokku;
+
+CREATE TABLE `daily_picks` (
+	`day` DATE NOT NULL,
+	`timezone` VARCHAR(64) NOT NULL,
+	`hokku` BIGINT NOT NULL,
+	PRIMARY KEY (`timezone`, `day`)
+);
+
+ALTER TABLE `daily_picks` ADD CONSTRAINT `DailyPick_fk0` FOREIGN KEY (`hokku`) REFERENCES `hokkus`(`id`) ON DELETE CASCADE;
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS `hokku_scores`;
+
+DROP TABLE IF EXISTS `hokku_views`;
+
+DROP TABLE IF EXISTS `comments`;
+
+DROP TABLE IF EXISTS `bookmarks`;
+
+DROP TABLE IF EXISTS `likes`;
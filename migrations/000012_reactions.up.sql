@@ -0,0 +1,56 @@
+USE hokku;
+
+CREATE TABLE `likes` (
+	`user` BIGINT NOT NULL,
+	`hokku` BIGINT NOT NULL,
+	`created` DATETIME NOT NULL,
+	PRIMARY KEY (`user`, `hokku`)
+);
+
+ALTER TABLE `likes` ADD CONSTRAINT `Like_fk0` FOREIGN KEY (`user`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `likes` ADD CONSTRAINT `Like_fk1` FOREIGN KEY (`hokku`) REFERENCES `hokkus`(`id`) ON DELETE CASCADE;
+
+CREATE TABLE `bookmarks` (
+	`user` BIGINT NOT NULL,
+	`hokku` BIGINT NOT NULL,
+	`created` DATETIME NOT NULL,
+	PRIMARY KEY (`user`, `hokku`)
+);
+
+ALTER TABLE `bookmarks` ADD CONSTRAINT `Bookmark_fk0` FOREIGN KEY (`user`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `bookmarks` ADD CONSTRAINT `Bookmark_fk1` FOREIGN KEY (`hokku`) REFERENCES `hokkus`(`id`) ON DELETE CASCADE;
+
+CREATE TABLE `comments` (
+	`id` BIGINT NOT NULL AUTO_INCREMENT,
+	`hokku` BIGINT NOT NULL,
+	`owner` BIGINT NOT NULL,
+	`content` TEXT NOT NULL,
+	`created` DATETIME NOT NULL,
+	PRIMARY KEY (`id`)
+);
+
+ALTER TABLE `comments` ADD CONSTRAINT `Comment_fk0` FOREIGN KEY (`hokku`) REFERENCES `hokkus`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `comments` ADD CONSTRAINT `Comment_fk1` FOREIGN KEY (`owner`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+CREATE INDEX idx_comments_hokku ON comments(`hokku`);
+
+CREATE TABLE `hokku_views` (
+	`hokku` BIGINT NOT NULL,
+	`day` DATE NOT NULL,
+	`views` INT NOT NULL,
+	PRIMARY KEY (`hokku`, `day`)
+);
+
+ALTER TABLE `hokku_views` ADD CONSTRAINT `HokkuViews_fk0` FOREIGN KEY (`hokku`) REFERENCES `hokkus`(`id`) ON DELETE CASCADE;
+
+CREATE TABLE `hokku_scores` (
+	`hokku` BIGINT NOT NULL,
+	`score` DOUBLE NOT NULL,
+	`trending` DOUBLE NOT NULL,
+	PRIMARY KEY (`hokku`)
+);
+
+ALTER TABLE `hokku_scores` ADD CONSTRAINT `HokkuScore_fk0` FOREIGN KEY (`hokku`) REFERENCES `hokkus`(`id`) ON DELETE CASCADE;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `hokku_flags`;
+
+DROP TABLE IF EXISTS `classics`;
+
+ALTER TABLE `hokkus` DROP COLUMN `fingerprint`;
@@ -0,0 +1,23 @@
+USE hokku;
+
+ALTER TABLE `hokkus` ADD COLUMN `fingerprint` BIGINT UNSIGNED NULL;
+
+CREATE TABLE `classics` (
+	`id` INT NOT NULL AUTO_INCREMENT,
+	`title` VARCHAR(255) NOT NULL,
+	`author` VARCHAR(255) NOT NULL,
+	`content` TEXT NOT NULL,
+	`fingerprint` BIGINT UNSIGNED NOT NULL,
+	PRIMARY KEY (`id`)
+);
+
+CREATE TABLE `hokku_flags` (
+	`id` BIGINT NOT NULL AUTO_INCREMENT,
+	`hokku` BIGINT NOT NULL,
+	`reason` VARCHAR(40) NOT NULL,
+	`matches` TEXT NULL,
+	`created` DATETIME NOT NULL,
+	PRIMARY KEY (`id`)
+);
+
+ALTER TABLE `hokku_flags` ADD CONSTRAINT `HokkuFlag_fk0` FOREIGN KEY (`hokku`) REFERENCES `hokkus`(`id`) ON DELETE CASCADE;
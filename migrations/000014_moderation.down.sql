@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `notifications`;
+
+DROP TABLE IF EXISTS `reports`;
+
+ALTER TABLE `hokkus` DROP COLUMN `hidden`;
@@ -0,0 +1,47 @@
+USE hokku;
+
+ALTER TABLE `hokkus` ADD COLUMN `hidden` BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE `reports` (
+	`id` BIGINT NOT NULL AUTO_INCREMENT,
+	`reporter` BIGINT NOT NULL,
+	`hokku` BIGINT NULL,
+	`author` BIGINT NOT NULL,
+	`reason` VARCHAR(40) NOT NULL,
+	`comment` TEXT NOT NULL,
+	`status` VARCHAR(16) NOT NULL DEFAULT 'open',
+	`moderator` BIGINT NULL,
+	`resolution` VARCHAR(16) NULL,
+	`created` DATETIME NOT NULL,
+	`resolved` DATETIME NULL,
+	PRIMARY KEY (`id`)
+);
+
+ALTER TABLE `reports` ADD CONSTRAINT `Report_fk0` FOREIGN KEY (`reporter`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `reports` ADD CONSTRAINT `Report_fk1` FOREIGN KEY (`hokku`) REFERENCES `hokkus`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `reports` ADD CONSTRAINT `Report_fk2` FOREIGN KEY (`author`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `reports` ADD CONSTRAINT `Report_fk3` FOREIGN KEY (`moderator`) REFERENCES `users`(`id`) ON DELETE SET NULL;
+
+CREATE INDEX idx_reports_status ON reports(`status`, `id`);
+
+CREATE TABLE `notifications` (
+	`id` BIGINT NOT NULL AUTO_INCREMENT,
+	`user` BIGINT NOT NULL,
+	`type` VARCHAR(40) NOT NULL,
+	`report` BIGINT NULL,
+	`hokku` BIGINT NULL,
+	`resolution` VARCHAR(16) NULL,
+	`created` DATETIME NOT NULL,
+	PRIMARY KEY (`id`)
+);
+
+ALTER TABLE `notifications` ADD CONSTRAINT `Notification_fk0` FOREIGN KEY (`user`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `notifications` ADD CONSTRAINT `Notification_fk1` FOREIGN KEY (`report`) REFERENCES `reports`(`id`) ON DELETE SET NULL;
+
+ALTER TABLE `notifications` ADD CONSTRAINT `Notification_fk2` FOREIGN KEY (`hokku`) REFERENCES `hokkus`(`id`) ON DELETE SET NULL;
+
+CREATE INDEX idx_notifications_user ON notifications(`user`, `id`);
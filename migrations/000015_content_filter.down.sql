@@ -0,0 +1,7 @@
+ALTER TABLE `reports` DROP FOREIGN KEY `Report_fk4`;
+
+ALTER TABLE `reports` DROP COLUMN `reported_comment`;
+
+DELETE FROM `reports` WHERE `reporter` IS NULL;
+
+ALTER TABLE `reports` MODIFY `reporter` BIGINT NOT NULL;
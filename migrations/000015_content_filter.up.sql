@@ -0,0 +1,7 @@
+USE hokku;
+
+ALTER TABLE `reports` MODIFY `reporter` BIGINT NULL;
+
+ALTER TABLE `reports` ADD COLUMN `reported_comment` BIGINT NULL AFTER `hokku`;
+
+ALTER TABLE `reports` ADD CONSTRAINT `Report_fk4` FOREIGN KEY (`reported_comment`) REFERENCES `comments`(`id`) ON DELETE CASCADE;
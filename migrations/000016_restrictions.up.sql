@@ -0,0 +1,22 @@
+USE hokku;
+
+CREATE TABLE `restrictions` (
+	`id` BIGINT NOT NULL AUTO_INCREMENT,
+	`user` BIGINT NOT NULL,
+	`kind` VARCHAR(16) NOT NULL,
+	`reason` VARCHAR(500) NOT NULL,
+	`expires` DATETIME NULL,
+	`moderator` BIGINT NULL,
+	`created` DATETIME NOT NULL,
+	`lifted_by` BIGINT NULL,
+	`lifted` DATETIME NULL,
+	PRIMARY KEY (`id`)
+);
+
+ALTER TABLE `restrictions` ADD CONSTRAINT `Restriction_fk0` FOREIGN KEY (`user`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `restrictions` ADD CONSTRAINT `Restriction_fk1` FOREIGN KEY (`moderator`) REFERENCES `users`(`id`) ON DELETE SET NULL;
+
+ALTER TABLE `restrictions` ADD CONSTRAINT `Restriction_fk2` FOREIGN KEY (`lifted_by`) REFERENCES `users`(`id`) ON DELETE SET NULL;
+
+CREATE INDEX idx_restrictions_user ON restrictions(`user`, `kind`, `lifted`);
@@ -0,0 +1,30 @@
+USE hokku;
+
+-- Entries outlive the users they mention, so there are no foreign keys
+CREATE TABLE `audit_log` (
+	`id` BIGINT NOT NULL AUTO_INCREMENT,
+	`actor` BIGINT NULL,
+	`action` VARCHAR(40) NOT NULL,
+	`target_type` VARCHAR(16) NULL,
+	`target` BIGINT NULL,
+	`ip` VARCHAR(45) NOT NULL,
+	`user_agent` VARCHAR(512) NOT NULL,
+	`details` VARCHAR(512) NOT NULL,
+	`before_state` JSON NULL,
+	`after_state` JSON NULL,
+	`created` DATETIME NOT NULL,
+	PRIMARY KEY (`id`)
+);
+
+CREATE INDEX idx_audit_log_actor ON audit_log(`actor`, `created`);
+
+CREATE INDEX idx_audit_log_target ON audit_log(`target_type`, `target`, `created`);
+
+CREATE INDEX idx_audit_log_created ON audit_log(`created`);
+
+-- The log is append-only
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON `audit_log` FOR EACH ROW
+	SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'The audit log is append-only';
+
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON `audit_log` FOR EACH ROW
+	SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'The audit log is append-only';
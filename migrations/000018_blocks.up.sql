@@ -0,0 +1,25 @@
+USE hokku;
+
+CREATE TABLE `blocks` (
+	`blocker` BIGINT NOT NULL,
+	`blocked` BIGINT NOT NULL,
+	`created` DATETIME NOT NULL,
+	PRIMARY KEY (`blocker`, `blocked`)
+);
+
+ALTER TABLE `blocks` ADD CONSTRAINT `Block_fk0` FOREIGN KEY (`blocker`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `blocks` ADD CONSTRAINT `Block_fk1` FOREIGN KEY (`blocked`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+CREATE INDEX idx_blocks_blocked ON blocks(blocked);
+
+CREATE TABLE `mutes` (
+	`muter` BIGINT NOT NULL,
+	`muted` BIGINT NOT NULL,
+	`created` DATETIME NOT NULL,
+	PRIMARY KEY (`muter`, `muted`)
+);
+
+ALTER TABLE `mutes` ADD CONSTRAINT `Mute_fk0` FOREIGN KEY (`muter`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `mutes` ADD CONSTRAINT `Mute_fk1` FOREIGN KEY (`muted`) REFERENCES `users`(`id`) ON DELETE CASCADE;
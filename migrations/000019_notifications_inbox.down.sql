@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS `notification_preferences`;
+
+DROP TABLE IF EXISTS `notification_actors`;
+
+ALTER TABLE `notifications` DROP FOREIGN KEY `Notification_fk4`;
+
+ALTER TABLE `notifications` DROP FOREIGN KEY `Notification_fk3`;
+
+DROP INDEX idx_notifications_unread ON notifications;
+
+DROP INDEX idx_notifications_updated ON notifications;
+
+ALTER TABLE `notifications` DROP COLUMN `updated`, DROP COLUMN `read_at`, DROP COLUMN `actors`, DROP COLUMN `comment`, DROP COLUMN `actor`;
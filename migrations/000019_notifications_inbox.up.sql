@@ -0,0 +1,42 @@
+USE hokku;
+
+ALTER TABLE `notifications` ADD COLUMN `actor` BIGINT NULL AFTER `type`;
+
+ALTER TABLE `notifications` ADD COLUMN `comment` BIGINT NULL AFTER `hokku`;
+
+ALTER TABLE `notifications` ADD COLUMN `actors` INT NOT NULL DEFAULT 0;
+
+ALTER TABLE `notifications` ADD COLUMN `read_at` DATETIME NULL;
+
+ALTER TABLE `notifications` ADD COLUMN `updated` DATETIME NULL;
+
+UPDATE `notifications` SET `updated` = `created`;
+
+ALTER TABLE `notifications` MODIFY `updated` DATETIME NOT NULL;
+
+ALTER TABLE `notifications` ADD CONSTRAINT `Notification_fk3` FOREIGN KEY (`actor`) REFERENCES `users`(`id`) ON DELETE SET NULL;
+
+ALTER TABLE `notifications` ADD CONSTRAINT `Notification_fk4` FOREIGN KEY (`comment`) REFERENCES `comments`(`id`) ON DELETE SET NULL;
+
+CREATE INDEX idx_notifications_updated ON notifications(`user`, `updated`, `id`);
+
+CREATE INDEX idx_notifications_unread ON notifications(`user`, `read_at`, `type`, `hokku`);
+
+CREATE TABLE `notification_actors` (
+	`notification` BIGINT NOT NULL,
+	`actor` BIGINT NOT NULL,
+	PRIMARY KEY (`notification`, `actor`)
+);
+
+ALTER TABLE `notification_actors` ADD CONSTRAINT `NotificationActor_fk0` FOREIGN KEY (`notification`) REFERENCES `notifications`(`id`) ON DELETE CASCADE;
+
+ALTER TABLE `notification_actors` ADD CONSTRAINT `NotificationActor_fk1` FOREIGN KEY (`actor`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+CREATE TABLE `notification_preferences` (
+	`user` BIGINT NOT NULL,
+	`type` VARCHAR(40) NOT NULL,
+	`enabled` BOOLEAN NOT NULL,
+	PRIMARY KEY (`user`, `type`)
+);
+
+ALTER TABLE `notification_preferences` ADD CONSTRAINT `NotificationPreference_fk0` FOREIGN KEY (`user`) REFERENCES `users`(`id`) ON DELETE CASCADE;
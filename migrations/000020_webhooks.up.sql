@@ -0,0 +1,34 @@
+USE hokku;
+
+CREATE TABLE `webhooks` (
+	`id` BIGINT NOT NULL AUTO_INCREMENT,
+	`owner` BIGINT NOT NULL,
+	`url` VARCHAR(2048) NOT NULL,
+	`events` JSON NOT NULL,
+	`secret` VARCHAR(64) NOT NULL,
+	`created` DATETIME NOT NULL,
+	PRIMARY KEY (`id`)
+);
+
+ALTER TABLE `webhooks` ADD CONSTRAINT `Webhook_fk0` FOREIGN KEY (`owner`) REFERENCES `users`(`id`) ON DELETE CASCADE;
+
+CREATE TABLE `webhook_deliveries` (
+	`id` BIGINT NOT NULL AUTO_INCREMENT,
+	`webhook` BIGINT NOT NULL,
+	`event` VARCHAR(40) NOT NULL,
+	`payload` JSON NOT NULL,
+	`status` VARCHAR(16) NOT NULL,
+	`attempts` INT NOT NULL DEFAULT 0,
+	`next_attempt` DATETIME NOT NULL,
+	`status_code` INT NULL,
+	`error` VARCHAR(512) NULL,
+	`created` DATETIME NOT NULL,
+	`delivered` DATETIME NULL,
+	PRIMARY KEY (`id`)
+);
+
+ALTER TABLE `webhook_deliveries` ADD CONSTRAINT `WebhookDelivery_fk0` FOREIGN KEY (`webhook`) REFERENCES `webhooks`(`id`) ON DELETE CASCADE;
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(`status`, `next_attempt`);
+
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(`webhook`, `id`);
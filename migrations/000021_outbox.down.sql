@@ -0,0 +1,5 @@
+ALTER TABLE `webhook_deliveries` DROP INDEX `webhook_deliveries_key`;
+
+ALTER TABLE `webhook_deliveries` DROP COLUMN `event_key`;
+
+DROP TABLE IF EXISTS `outbox`;
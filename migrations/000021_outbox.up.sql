@@ -0,0 +1,16 @@
+USE hokku;
+
+CREATE TABLE `outbox` (
+	`id` BIGINT NOT NULL AUTO_INCREMENT,
+	`event_key` CHAR(32) NOT NULL,
+	`type` VARCHAR(40) NOT NULL,
+	`aggregate` BIGINT NOT NULL,
+	`payload` JSON NOT NULL,
+	`created` DATETIME NOT NULL,
+	PRIMARY KEY (`id`),
+	UNIQUE KEY `outbox_key` (`event_key`)
+);
+
+ALTER TABLE `webhook_deliveries` ADD COLUMN `event_key` CHAR(32) NULL;
+
+ALTER TABLE `webhook_deliveries` ADD UNIQUE KEY `webhook_deliveries_key` (`webhook`, `event_key`);
@@ -0,0 +1,3 @@
+USE hokku;
+
+ALTER TABLE `users` ADD COLUMN `hidden` BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+ALTER TABLE `outbox` DROP COLUMN `parked`;
+
+ALTER TABLE `outbox` DROP COLUMN `attempts`;
@@ -0,0 +1,5 @@
+USE hokku;
+
+ALTER TABLE `outbox` ADD COLUMN `attempts` INT NOT NULL DEFAULT 0;
+
+ALTER TABLE `outbox` ADD COLUMN `parked` BOOLEAN NOT NULL DEFAULT FALSE;
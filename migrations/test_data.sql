@@ -5,8 +5,11 @@ VALUES ('mazuo@gmail.com', 'Мацуо Басё', '123123123', NOW()),
        ('kobayasi@email.com', 'Кобаяси Исса', '234234234', NOW()),
        ('fakuda@yandex.com', 'Тиё Факуда', '345345345', NOW());
 
-INSERT INTO `themes` (`title`)
-VALUES ('Жизнь'), ('Любовь'), ('Природа'), ('Времена года');
+INSERT INTO `themes` (`title`, `slug`)
+VALUES ('Жизнь', 'zhizn'), ('Любовь', 'lyubov'), ('Природа', 'priroda'), ('Времена года', 'vremena-goda');
+
+INSERT INTO `themes` (`title`, `slug`, `parent`)
+VALUES ('Осень', 'osen', 4);
 
 INSERT INTO `hokkus` (`title`, `content`, `owner`, `theme`, `created`) 
 VALUES 
@@ -14,3 +17,6 @@ VALUES
 ('Ворон', 'На голой ветке\nворон сидит одиноко.\nОсенний вечер.', '1', '4', NOW()),
 ('***', 'Наша жизнь – росинка.\nПусть лишь капелька росы\nНаша жизнь – и все же…', '2', '3', NOW()),
 ('Улитка', 'Тихо, тихо ползи,\nУлитка, по склону Фудзи\nВверх, до самых высот!', '2', '3', NOW());
+
+INSERT INTO `hokku_tags` (`hokku`, `tag`)
+VALUES (2, 'season:autumn'), (2, 'ворон');
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Audited actions.
+const (
+	AuditLogin           = "login"
+	AuditLoginFailed     = "login.failed"
+	AuditUserUpdate      = "user.update"
+	AuditUserDelete      = "user.delete"
+	AuditUserRestore     = "user.restore"
+	AuditUserUnhide      = "user.unhide"
+	AuditRoleChange      = "user.role"
+	AuditRestrict        = "user.restrict"
+	AuditHokkuDelete     = "hokku.delete"
+	AuditHokkuUnhide     = "hokku.unhide"
+	AuditCommentDelete   = "comment.delete"
+	AuditThemeCreate     = "theme.create"
+	AuditThemeUpdate     = "theme.update"
+	AuditThemeDelete     = "theme.delete"
+	AuditKigoUpdate      = "kigo.update"
+	AuditKigoDelete      = "kigo.delete"
+	AuditClassicDelete   = "classic.delete"
+	AuditReportClaim     = "report.claim"
+	AuditReportResolve   = "report.resolve"
+	AuditRestrictionLift = "restriction.lift"
+	AuditFilterReload    = "filter.reload"
+)
+
+// Types of audited targets.
+const (
+	TargetUser        = "user"
+	TargetHokku       = "hokku"
+	TargetComment     = "comment"
+	TargetTheme       = "theme"
+	TargetKigo        = "kigo"
+	TargetClassic     = "classic"
+	TargetReport      = "report"
+	TargetRestriction = "restriction"
+)
+
+// AuditEntry records who did what to which target and from where. Updates
+// keep the target as it was before and after them, deletes keep it as it
+// was before. ActorId is 0 when nobody is logged in, as on failed logins.
+type AuditEntry struct {
+	Id         int             `json:"id"`
+	ActorId    int             `json:"actorId,omitempty"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"targetType,omitempty"`
+	TargetId   int             `json:"targetId,omitempty"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"userAgent"`
+	Details    string          `json:"details,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After      json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	Created    time.Time       `json:"created"`
+}
+
+// AuditQuery selects audit entries. Zero fields match every entry, the
+// time range includes Since and excludes Until.
+type AuditQuery struct {
+	ActorId    int
+	TargetType string
+	TargetId   int
+	Since      time.Time
+	Until      time.Time
+	Limit      int
+	Offset     int
+}
+
+// Matches reports whether the entry is selected by the query regardless of
+// the limit and the offset.
+func (q *AuditQuery) Matches(e *AuditEntry) bool {
+	return (q.ActorId == 0 || e.ActorId == q.ActorId) &&
+		(q.TargetType == "" || e.TargetType == q.TargetType) &&
+		(q.TargetId == 0 || e.TargetId == q.TargetId) &&
+		(q.Since.IsZero() || !e.Created.Before(q.Since)) &&
+		(q.Until.IsZero() || e.Created.Before(q.Until))
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/prosody"
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// MaxParticipants limits the number of poets taking turns in a chain.
+const MaxParticipants = 10
+
+// Chain is a renga: stanzas written in turns by the participants. Odd
+// stanzas follow the 5-7-5 form and even ones the 7-7 form.
+type Chain struct {
+	Id      int    `json:"id" form:"id"`
+	Title   string `json:"title" form:"title"`
+	OwnerId int    `json:"ownerId" form:"ownerId"`
+	ThemeId int    `json:"themeId" form:"themeId"`
+	// Participants are the ids of the poets in the order of their turns,
+	// the owner goes first
+	Participants []int     `json:"participants" form:"participants"`
+	Length       int       `json:"length"`
+	Created      time.Time `json:"created"`
+}
+
+func (c *Chain) Validate() error {
+	return validation.ValidateStruct(
+		c,
+		validation.Field(&c.Title, validation.Required, validation.Length(1, 255)),
+		validation.Field(&c.ThemeId, validation.Required),
+		validation.Field(&c.Participants, validation.Length(0, MaxParticipants)),
+	)
+}
+
+// BeforeSave puts the owner first in the turn order and drops repeated
+// participants.
+func (c *Chain) BeforeSave() {
+	participants := []int{c.OwnerId}
+	seen := map[int]bool{c.OwnerId: true}
+	for _, id := range c.Participants {
+		if !seen[id] {
+			seen[id] = true
+			participants = append(participants, id)
+		}
+	}
+	c.Participants = participants
+}
+
+// Poet returns the id of the participant whose turn is to write the stanza
+// at the position.
+func (c *Chain) Poet(position int) int {
+	return c.Participants[(position-1)%len(c.Participants)]
+}
+
+// StanzaPattern returns the syllable pattern of the stanza at the position.
+func StanzaPattern(position int) []int {
+	if position%2 == 0 {
+		return prosody.ShortPattern
+	}
+	return prosody.Pattern
+}
+
+// ChainDetails is a chain with its stanzas in order.
+type ChainDetails struct {
+	*Chain
+	Stanzas []*Hokku `json:"stanzas"`
+}
+
+// ChainStart is a new chain with its first stanza.
+type ChainStart struct {
+	Chain
+	Stanza Hokku `json:"stanza"`
+}
+
+// IsParticipant reports whether the user takes turns in the chain.
+func (c *Chain) IsParticipant(userId int) bool {
+	for _, id := range c.Participants {
+		if id == userId {
+			return true
+		}
+	}
+	return false
+}
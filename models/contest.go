@@ -0,0 +1,191 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// Voting modes of a contest. A single vote gives one point to the chosen
+// hokku, a ranked vote orders up to MaxRankedChoices hokkus and gives
+// them MaxRankedChoices, MaxRankedChoices-1, ... points.
+const (
+	VotingSingle = "single"
+	VotingRanked = "ranked"
+)
+
+const MaxRankedChoices = 3
+
+// Phases of a contest.
+const (
+	ContestUpcoming = "upcoming"
+	ContestOpen     = "open"
+	ContestClosed   = "closed"
+	ContestVoting   = "voting"
+	ContestFinished = "finished"
+)
+
+// Contest is a writing contest on a theme. Hokkus are submitted while the
+// contest is open and voted for during the voting window, which can't
+// start before the submissions close. Results are published when the
+// voting is over.
+type Contest struct {
+	Id             int       `json:"id" form:"id"`
+	Title          string    `json:"title" form:"title"`
+	Description    string    `json:"description" form:"description"`
+	ThemeId        int       `json:"themeId" form:"themeId"`
+	VotingMode     string    `json:"votingMode" form:"votingMode"`
+	OpensAt        time.Time `json:"opensAt" form:"opensAt"`
+	ClosesAt       time.Time `json:"closesAt" form:"closesAt"`
+	VotingOpensAt  time.Time `json:"votingOpensAt" form:"votingOpensAt"`
+	VotingClosesAt time.Time `json:"votingClosesAt" form:"votingClosesAt"`
+	// Results are empty until they are published
+	ResultsPublishedAt *time.Time       `json:"resultsPublishedAt"`
+	Results            []*ContestResult `json:"results,omitempty"`
+}
+
+func (c *Contest) Validate() error {
+	err := validation.ValidateStruct(
+		c,
+		validation.Field(&c.Title, validation.Required, validation.Length(1, 255)),
+		validation.Field(&c.Description, validation.RuneLength(0, 1000)),
+		validation.Field(&c.ThemeId, validation.Required),
+		validation.Field(&c.VotingMode, validation.Required, validation.In(VotingSingle, VotingRanked)),
+		validation.Field(&c.OpensAt, validation.Required),
+		validation.Field(&c.ClosesAt, validation.Required),
+		validation.Field(&c.VotingOpensAt, validation.Required),
+		validation.Field(&c.VotingClosesAt, validation.Required),
+	)
+	if err != nil {
+		return err
+	}
+	switch {
+	case !c.OpensAt.Before(c.ClosesAt):
+		return validation.Errors{"closesAt": errors.New("must be after the opening time")}
+	case c.VotingOpensAt.Before(c.ClosesAt):
+		return validation.Errors{"votingOpensAt": errors.New("must not be before the closing time")}
+	case !c.VotingOpensAt.Before(c.VotingClosesAt):
+		return validation.Errors{"votingClosesAt": errors.New("must be after the voting opening time")}
+	}
+	return nil
+}
+
+// Phase returns the phase of the contest at the time.
+func (c *Contest) Phase(now time.Time) string {
+	switch {
+	case now.Before(c.OpensAt):
+		return ContestUpcoming
+	case now.Before(c.ClosesAt):
+		return ContestOpen
+	case now.Before(c.VotingOpensAt):
+		return ContestClosed
+	case now.Before(c.VotingClosesAt):
+		return ContestVoting
+	}
+	return ContestFinished
+}
+
+// MaxChoices returns the number of hokkus a ballot may list.
+func (c *Contest) MaxChoices() int {
+	if c.VotingMode == VotingRanked {
+		return MaxRankedChoices
+	}
+	return 1
+}
+
+// ContestEntry is a hokku submitted to a contest.
+type ContestEntry struct {
+	ContestId int `json:"contestId"`
+	HokkuId   int `json:"hokkuId" form:"hokkuId"`
+}
+
+// Ballot is the vote of a user. A single vote lists one hokku, a ranked
+// vote lists hokkus from the best one.
+type Ballot struct {
+	ContestId int       `json:"contestId"`
+	VoterId   int       `json:"voterId"`
+	HokkuIds  []int     `json:"hokkuIds" form:"hokkuIds"`
+	Created   time.Time `json:"created"`
+}
+
+// Validate checks the ballot lists distinct hokkus and no more than the
+// voting mode of the contest allows.
+func (b *Ballot) Validate(c *Contest) error {
+	err := validation.ValidateStruct(
+		b,
+		validation.Field(&b.HokkuIds, validation.Required, validation.Length(1, c.MaxChoices())),
+	)
+	if err != nil {
+		return err
+	}
+	seen := map[int]bool{}
+	for _, id := range b.HokkuIds {
+		if seen[id] {
+			return validation.Errors{"hokkuIds": errors.New("must not repeat")}
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// ContestResult is the score of an entry. Entries with equal points and
+// first choices share the place.
+type ContestResult struct {
+	HokkuId    int `json:"hokkuId"`
+	OwnerId    int `json:"ownerId"`
+	Place      int `json:"place"`
+	Points     int `json:"points"`
+	FirstVotes int `json:"firstVotes"`
+}
+
+// Tally scores the entries by the ballots and returns the results from the
+// first place. Votes for hokkus that are no longer entries are ignored.
+func (c *Contest) Tally(entries []*Hokku, ballots []*Ballot) []*ContestResult {
+	byHokku := map[int]*ContestResult{}
+	results := make([]*ContestResult, 0, len(entries))
+	for _, h := range entries {
+		r := &ContestResult{HokkuId: h.Id, OwnerId: h.OwnerId}
+		byHokku[h.Id] = r
+		results = append(results, r)
+	}
+	for _, b := range ballots {
+		for i, id := range b.HokkuIds {
+			r, ok := byHokku[id]
+			if !ok || i >= c.MaxChoices() {
+				continue
+			}
+			r.Points += c.MaxChoices() - i
+			if i == 0 {
+				r.FirstVotes++
+			}
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Points != results[j].Points {
+			return results[i].Points > results[j].Points
+		}
+		if results[i].FirstVotes != results[j].FirstVotes {
+			return results[i].FirstVotes > results[j].FirstVotes
+		}
+		return results[i].HokkuId < results[j].HokkuId
+	})
+	for i, r := range results {
+		r.Place = i + 1
+		if i > 0 {
+			prev := results[i-1]
+			if prev.Points == r.Points && prev.FirstVotes == r.FirstVotes {
+				r.Place = prev.Place
+			}
+		}
+	}
+	return results
+}
+
+// ContestDetails is a contest with its phase and entries.
+type ContestDetails struct {
+	*Contest
+	Phase   string   `json:"phase"`
+	Entries []*Hokku `json:"entries"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+// DayLayout formats the days of daily picks.
+const DayLayout = "2006-01-02"
+
+// DailyPick is the hokku of the day in a timezone.
+type DailyPick struct {
+	Day      string `json:"day"`
+	Timezone string `json:"timezone"`
+	HokkuId  int    `json:"hokkuId"`
+}
+
+// PickDaily chooses the hokku of the day in the timezone among the ids,
+// skipping the excluded ones unless all of them are excluded. The choice
+// depends only on the arguments, so every server picks the same hokku.
+func PickDaily(day, timezone string, ids []int, excluded map[int]bool) (int, bool) {
+	candidates := []int{}
+	for _, id := range ids {
+		if !excluded[id] {
+			candidates = append(candidates, id)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = append(candidates, ids...)
+	}
+	if len(candidates) == 0 {
+		return 0, false
+	}
+	sort.Ints(candidates)
+	h := fnv.New32a()
+	h.Write([]byte(day + "/" + timezone))
+	return candidates[h.Sum32()%uint32(len(candidates))], true
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/prosody"
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// Actions taken on hokkus that nearly duplicate existing poems.
+const (
+	DuplicateAccept = "accept"
+	DuplicateFlag   = "flag"
+	DuplicateReject = "reject"
+)
+
+// FlagDuplicate is the reason of flags set on near-duplicate hokkus.
+const FlagDuplicate = "duplicate"
+
+// shingleLength is the length in runes of the pieces of text hashed into
+// a fingerprint.
+const shingleLength = 4
+
+// Classic is a famous poem of the corpus hokkus are compared with.
+type Classic struct {
+	Id      int    `json:"id" form:"id"`
+	Title   string `json:"title" form:"title"`
+	Author  string `json:"author" form:"author"`
+	Content string `json:"content" form:"content"`
+}
+
+func (c *Classic) Validate() error {
+	return validation.ValidateStruct(
+		c,
+		validation.Field(&c.Title, validation.Required, validation.Length(1, 255)),
+		validation.Field(&c.Author, validation.Length(0, 255)),
+		validation.Field(&c.Content, validation.Required),
+	)
+}
+
+// Duplicates are the poems a hokku nearly duplicates.
+type Duplicates struct {
+	HokkuIds   []int `json:"hokkuIds"`
+	ClassicIds []int `json:"classicIds"`
+}
+
+func (d *Duplicates) Empty() bool {
+	return len(d.HokkuIds) == 0 && len(d.ClassicIds) == 0
+}
+
+// Flag sends a hokku to moderation.
+type Flag struct {
+	Id      int         `json:"id"`
+	HokkuId int         `json:"hokkuId"`
+	Reason  string      `json:"reason"`
+	Matches *Duplicates `json:"matches,omitempty"`
+	Created time.Time   `json:"created"`
+}
+
+// Fingerprint returns the simhash of the text. Case, punctuation and
+// spacing are ignored, so copies of a poem get the same fingerprint and
+// slightly changed copies get fingerprints differing in a few bits.
+func Fingerprint(text string) uint64 {
+	normalized := []rune(strings.ReplaceAll(strings.Join(prosody.Words(text), " "), "ё", "е"))
+	if len(normalized) == 0 {
+		return 0
+	}
+	var counts [64]int
+	add := func(shingle []rune) {
+		h := fnv.New64a()
+		h.Write([]byte(string(shingle)))
+		sum := h.Sum64()
+		for i := range counts {
+			if sum&(1<<uint(i)) != 0 {
+				counts[i]++
+			} else {
+				counts[i]--
+			}
+		}
+	}
+	if len(normalized) <= shingleLength {
+		add(normalized)
+	}
+	for i := 0; i+shingleLength <= len(normalized); i++ {
+		add(normalized[i : i+shingleLength])
+	}
+	var fp uint64
+	for i, n := range counts {
+		if n > 0 {
+			fp |= 1 << uint(i)
+		}
+	}
+	return fp
+}
+
+// FingerprintDistance is the number of bits the fingerprints differ in.
+func FingerprintDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
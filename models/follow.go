@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+type Follow struct {
+	FollowerId int       `json:"followerId"`
+	FolloweeId int       `json:"followeeId"`
+	Created    time.Time `json:"created"`
+}
+
+// Block keeps the blocked user away from the blocker: they can't follow
+// the blocker, like or comment their hokkus or see their followers-only
+// hokkus.
+type Block struct {
+	BlockerId int       `json:"blockerId"`
+	BlockedId int       `json:"blockedId"`
+	Created   time.Time `json:"created"`
+}
+
+// Mute leaves the hokkus and comments of the muted user out of what the
+// muter reads.
+type Mute struct {
+	MuterId int       `json:"muterId"`
+	MutedId int       `json:"mutedId"`
+	Created time.Time `json:"created"`
+}
@@ -1,24 +1,190 @@
 package models
 
 import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/EgorSkurihin/Hokku/prosody"
 	validation "github.com/go-ozzo/ozzo-validation"
 )
 
+// Publication statuses of a hokku. Only published hokkus are shown in
+// public listings.
+const (
+	StatusDraft     = "draft"
+	StatusScheduled = "scheduled"
+	StatusPublished = "published"
+)
+
+// Visibility levels of a hokku. Unlisted hokkus are left out of listings
+// but can be opened by a direct link; followers-only and private ones
+// require an authorized viewer.
+const (
+	VisibilityPublic    = "public"
+	VisibilityUnlisted  = "unlisted"
+	VisibilityFollowers = "followers"
+	VisibilityPrivate   = "private"
+)
+
+// Limits on the shape of a hokku. The line and tag lengths are counted
+// in runes.
+const (
+	MaxLines      = 5
+	MaxLineLength = 100
+	MaxTags       = 10
+	MaxTagLength  = 40
+)
+
+var tagRegexp = regexp.MustCompile(`^[\p{L}\p{N}_:-]+$`)
+
 type Hokku struct {
-	Id      int       `json:"id" form:"id"`
-	Title   string    `json:"title" form:"title"`
-	Content string    `json:"content" form:"content"`
-	Created time.Time `json:"created" form:"created"`
-	OwnerId int       `json:"ownerId" form:"ownerId"`
-	ThemeId int       `json:"themeId" form:"themeId"`
+	Id         int        `json:"id" form:"id"`
+	Title      string     `json:"title" form:"title"`
+	Content    string     `json:"content" form:"content"`
+	Lines      []string   `json:"lines" form:"lines"`
+	Tags       []string   `json:"tags" form:"tags"`
+	Created    time.Time  `json:"created" form:"created"`
+	OwnerId    int        `json:"ownerId" form:"ownerId"`
+	ThemeId    int        `json:"themeId" form:"themeId"`
+	Status     string     `json:"status" form:"status"`
+	PublishAt  *time.Time `json:"publishAt,omitempty" form:"publishAt"`
+	Visibility string     `json:"visibility" form:"visibility"`
+	DeletedAt  *time.Time `json:"deletedAt,omitempty"`
+	// Hidden hokkus are hidden by moderators from everyone but the owner
+	Hidden bool `json:"hidden,omitempty"`
+	// Stanzas of a renga chain reply to the previous stanza, positions
+	// start at 1
+	ChainId  int `json:"chainId,omitempty"`
+	ParentId int `json:"parentId,omitempty"`
+	Position int `json:"position,omitempty"`
 }
 
 func (h *Hokku) Validate() error {
+	publishAtRules := []validation.Rule{}
+	if h.Status == StatusScheduled {
+		publishAtRules = append(publishAtRules, validation.Required)
+	}
 	return validation.ValidateStruct(
 		h,
 		validation.Field(&h.Title, validation.Required, validation.Length(1, 255)),
-		validation.Field(&h.Content, validation.Required),
+		validation.Field(&h.Content, validation.Required, linesRule{}),
+		validation.Field(&h.Tags, validation.Length(0, MaxTags), validation.Each(
+			validation.RuneLength(1, MaxTagLength), validation.Match(tagRegexp))),
+		validation.Field(&h.Status, validation.In(StatusDraft, StatusScheduled, StatusPublished)),
+		validation.Field(&h.PublishAt, publishAtRules...),
+		validation.Field(&h.Visibility, validation.In(
+			VisibilityPublic, VisibilityUnlisted, VisibilityFollowers, VisibilityPrivate)),
 	)
 }
+
+// Normalize brings Content and Lines to the same canonical form: line
+// endings are unified, whitespace inside lines is collapsed and empty
+// lines are dropped. Lines take precedence over Content when both are sent.
+// Tags are lower-cased, deduplicated and sorted.
+func (h *Hokku) Normalize() {
+	if len(h.Lines) > 0 {
+		h.Lines = NormalizeLines(h.Lines)
+	} else {
+		h.Lines = NormalizeLines([]string{h.Content})
+	}
+	h.Content = strings.Join(h.Lines, "\n")
+	h.Tags = NormalizeTags(h.Tags)
+}
+
+// NormalizeTags trims the tags and their leading '#', lower-cases them and
+// returns them sorted without duplicates and empty tags.
+func NormalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	res := []string{}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimLeft(strings.TrimSpace(t), "#"))
+		if t != "" && !seen[t] {
+			seen[t] = true
+			res = append(res, t)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// AttachSeasons replaces the season tags of the hokku with the tags of the
+// seasons whose kigo are found in the content.
+func (h *Hokku) AttachSeasons(dict []*Kigo) {
+	tags := []string{}
+	for _, t := range h.Tags {
+		if !strings.HasPrefix(t, seasonTagPrefix) {
+			tags = append(tags, t)
+		}
+	}
+	for _, season := range DetectSeasons(h.Content, dict) {
+		tags = append(tags, SeasonTag(season))
+	}
+	h.Tags = NormalizeTags(tags)
+}
+
+// NormalizeLines splits the given text fragments into trimmed non-empty
+// lines with single spaces between words.
+func NormalizeLines(fragments []string) []string {
+	lines := []string{}
+	for _, l := range prosody.SplitLines(strings.Join(fragments, "\n")) {
+		lines = append(lines, strings.Join(strings.Fields(l), " "))
+	}
+	return lines
+}
+
+// linesRule checks the number and the length of lines in the content.
+type linesRule struct{}
+
+func (linesRule) Validate(value interface{}) error {
+	s, _ := value.(string)
+	lines := NormalizeLines([]string{s})
+	if len(lines) > MaxLines {
+		return fmt.Errorf("must have at most %d lines", MaxLines)
+	}
+	for _, l := range lines {
+		if utf8.RuneCountInString(l) > MaxLineLength {
+			return fmt.Errorf("lines must be at most %d characters long", MaxLineLength)
+		}
+	}
+	return nil
+}
+
+// BeforeSave fills defaults for fields omitted by the client. Hokkus
+// without an explicit status are published immediately and are public.
+func (h *Hokku) BeforeSave() {
+	if h.Status == "" {
+		h.Status = StatusPublished
+	}
+	if h.Visibility == "" {
+		h.Visibility = VisibilityPublic
+	}
+	if h.Status != StatusScheduled {
+		h.PublishAt = nil
+	}
+}
+
+func (h *Hokku) IsPublished() bool {
+	return h.Status == StatusPublished
+}
+
+// Listed reports whether the hokku may appear in listings shown to the
+// viewer. Anonymous viewers have id 0.
+func (h *Hokku) Listed(viewerId int, isFollower bool) bool {
+	if h.OwnerId == viewerId {
+		return h.IsPublished()
+	}
+	return h.IsPublished() && !h.Hidden && (h.Visibility == VisibilityPublic ||
+		h.Visibility == VisibilityFollowers && isFollower)
+}
+
+// VisibleTo reports whether the viewer may open the hokku by a direct link.
+func (h *Hokku) VisibleTo(viewerId int, isFollower bool) bool {
+	if h.OwnerId == viewerId {
+		return true
+	}
+	return h.Listed(viewerId, isFollower) || h.IsPublished() && !h.Hidden && h.Visibility == VisibilityUnlisted
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/EgorSkurihin/Hokku/prosody"
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// Seasons of the kigo dictionary. The New Year is a season of its own in
+// the haiku tradition.
+const (
+	SeasonSpring  = "spring"
+	SeasonSummer  = "summer"
+	SeasonAutumn  = "autumn"
+	SeasonWinter  = "winter"
+	SeasonNewYear = "new-year"
+)
+
+var Seasons = []string{SeasonSpring, SeasonSummer, SeasonAutumn, SeasonWinter, SeasonNewYear}
+
+// seasonTagPrefix marks the tags attached by kigo detection.
+const seasonTagPrefix = "season:"
+
+// Kigo is a seasonal word or phrase of the dictionary.
+type Kigo struct {
+	Id     int    `json:"id" form:"id"`
+	Word   string `json:"word" form:"word"`
+	Season string `json:"season" form:"season"`
+}
+
+func (k *Kigo) Validate() error {
+	seasons := make([]interface{}, len(Seasons))
+	for i, s := range Seasons {
+		seasons[i] = s
+	}
+	return validation.ValidateStruct(
+		k,
+		validation.Field(&k.Word, validation.Required, validation.RuneLength(1, 100)),
+		validation.Field(&k.Season, validation.Required, validation.In(seasons...)),
+	)
+}
+
+// IsSeason reports whether s is one of the known seasons.
+func IsSeason(s string) bool {
+	for _, season := range Seasons {
+		if s == season {
+			return true
+		}
+	}
+	return false
+}
+
+// SeasonTag returns the tag attached to hokkus with a kigo of the season.
+func SeasonTag(season string) string {
+	return seasonTagPrefix + season
+}
+
+// DetectSeasons returns the sorted seasons of the dictionary words found
+// in the text. Words are compared case-insensitively and a phrase matches
+// only as a whole sequence of words.
+func DetectSeasons(text string, dict []*Kigo) []string {
+	words := prosody.Words(text)
+	found := map[string]bool{}
+	for _, k := range dict {
+		if !found[k.Season] && containsPhrase(words, prosody.Words(k.Word)) {
+			found[k.Season] = true
+		}
+	}
+	seasons := []string{}
+	for s := range found {
+		seasons = append(seasons, s)
+	}
+	sort.Strings(seasons)
+	return seasons
+}
+
+func containsPhrase(words, phrase []string) bool {
+	if len(phrase) == 0 {
+		return false
+	}
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		if strings.Join(words[i:i+len(phrase)], " ") == strings.Join(phrase, " ") {
+			return true
+		}
+	}
+	return false
+}
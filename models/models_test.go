@@ -1,9 +1,15 @@
 package models_test
 
 import (
+	"context"
+	"errors"
+	"net"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/prosody"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -128,6 +134,62 @@ func TestHokkuValidate(t *testing.T) {
 			},
 			isValid: false,
 		},
+		{
+			name: "too many lines",
+			h: func() *models.Hokku {
+				h := testHokku()
+				h.Content = strings.Repeat("Example\n", models.MaxLines+1)
+				return h
+			},
+			isValid: false,
+		},
+		{
+			name: "too long line",
+			h: func() *models.Hokku {
+				h := testHokku()
+				h.Content = strings.Repeat("a", models.MaxLineLength+1)
+				return h
+			},
+			isValid: false,
+		},
+		{
+			name: "draft",
+			h: func() *models.Hokku {
+				h := testHokku()
+				h.Status = models.StatusDraft
+				return h
+			},
+			isValid: true,
+		},
+		{
+			name: "unknown status",
+			h: func() *models.Hokku {
+				h := testHokku()
+				h.Status = "unknown"
+				return h
+			},
+			isValid: false,
+		},
+		{
+			name: "scheduled without publishAt",
+			h: func() *models.Hokku {
+				h := testHokku()
+				h.Status = models.StatusScheduled
+				return h
+			},
+			isValid: false,
+		},
+		{
+			name: "scheduled",
+			h: func() *models.Hokku {
+				h := testHokku()
+				h.Status = models.StatusScheduled
+				publishAt := time.Now().Add(time.Hour)
+				h.PublishAt = &publishAt
+				return h
+			},
+			isValid: true,
+		},
 		/* {
 			name: "themeId < 1",
 			h: func() *models.Hokku {
@@ -156,6 +218,95 @@ func TestHokkuValidate(t *testing.T) {
 	}
 }
 
+func TestHokkuNormalize(t *testing.T) {
+	cases := []struct {
+		name    string
+		h       *models.Hokku
+		content string
+		lines   []string
+		tags    []string
+	}{
+		{
+			name:    "content",
+			h:       &models.Hokku{Content: " Old  pond\r\n\nfrog\rsplash\n"},
+			content: "Old pond\nfrog\nsplash",
+			lines:   []string{"Old pond", "frog", "splash"},
+		},
+		{
+			name:    "lines take precedence",
+			h:       &models.Hokku{Content: "ignored", Lines: []string{"Old pond ", "", "frog\nsplash"}},
+			content: "Old pond\nfrog\nsplash",
+			lines:   []string{"Old pond", "frog", "splash"},
+		},
+		{
+			name:    "empty",
+			h:       &models.Hokku{},
+			content: "",
+			lines:   []string{},
+		},
+		{
+			name:    "tags",
+			h:       &models.Hokku{Content: "Old pond", Tags: []string{" #Pond", "frog", "pond", ""}},
+			content: "Old pond",
+			lines:   []string{"Old pond"},
+			tags:    []string{"frog", "pond"},
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			cs.h.Normalize()
+			assert.Equal(t, cs.content, cs.h.Content)
+			assert.Equal(t, cs.lines, cs.h.Lines)
+			if cs.tags != nil {
+				assert.Equal(t, cs.tags, cs.h.Tags)
+			}
+		})
+	}
+}
+
+func TestHokkuBeforeSave(t *testing.T) {
+	h := testHokku()
+	h.BeforeSave()
+	assert.Equal(t, models.StatusPublished, h.Status)
+	assert.Equal(t, models.VisibilityPublic, h.Visibility)
+
+	publishAt := time.Now()
+	h.Status = models.StatusDraft
+	h.PublishAt = &publishAt
+	h.BeforeSave()
+	assert.Nil(t, h.PublishAt)
+}
+
+func TestHokkuVisibleTo(t *testing.T) {
+	cases := []struct {
+		name       string
+		visibility string
+		viewerId   int
+		isFollower bool
+		hidden     bool
+		listed     bool
+		visible    bool
+	}{
+		{name: "public", visibility: models.VisibilityPublic, listed: true, visible: true},
+		{name: "unlisted", visibility: models.VisibilityUnlisted, listed: false, visible: true},
+		{name: "followers by anonymous", visibility: models.VisibilityFollowers, listed: false, visible: false},
+		{name: "followers by follower", visibility: models.VisibilityFollowers, viewerId: 2, isFollower: true, listed: true, visible: true},
+		{name: "private", visibility: models.VisibilityPrivate, viewerId: 2, isFollower: true, listed: false, visible: false},
+		{name: "private by owner", visibility: models.VisibilityPrivate, viewerId: 1, listed: true, visible: true},
+		{name: "hidden", visibility: models.VisibilityPublic, hidden: true, listed: false, visible: false},
+		{name: "hidden unlisted", visibility: models.VisibilityUnlisted, hidden: true, listed: false, visible: false},
+		{name: "hidden by owner", visibility: models.VisibilityPublic, viewerId: 1, hidden: true, listed: true, visible: true},
+	}
+	for _, c := range cases {
+		h := testHokku()
+		h.Status = models.StatusPublished
+		h.Visibility = c.visibility
+		h.Hidden = c.hidden
+		assert.Equal(t, c.listed, h.Listed(c.viewerId, c.isFollower), c.name)
+		assert.Equal(t, c.visible, h.VisibleTo(c.viewerId, c.isFollower), c.name)
+	}
+}
+
 func TestThemeValidate(t *testing.T) {
 	cases := []struct {
 		name    string
@@ -176,6 +327,42 @@ func TestThemeValidate(t *testing.T) {
 			},
 			isValid: false,
 		},
+		{
+			name: "slug",
+			t: func() *models.Theme {
+				t := testTheme()
+				t.Slug = "example-theme"
+				return t
+			},
+			isValid: true,
+		},
+		{
+			name: "bad slug",
+			t: func() *models.Theme {
+				t := testTheme()
+				t.Slug = "Example theme"
+				return t
+			},
+			isValid: false,
+		},
+		{
+			name: "slug without letters",
+			t: func() *models.Theme {
+				t := testTheme()
+				t.Slug = "2024"
+				return t
+			},
+			isValid: false,
+		},
+		{
+			name: "negative parent",
+			t: func() *models.Theme {
+				t := testTheme()
+				t.ParentId = -1
+				return t
+			},
+			isValid: false,
+		},
 	}
 	for _, c := range cases {
 		if c.isValid {
@@ -185,3 +372,392 @@ func TestThemeValidate(t *testing.T) {
 		}
 	}
 }
+
+func TestSlugify(t *testing.T) {
+	cases := []struct {
+		title string
+		slug  string
+	}{
+		{title: "Example Theme", slug: "example-theme"},
+		{title: "  Spring, rain & wind!  ", slug: "spring-rain-wind"},
+		{title: "Времена года", slug: "vremena-goda"},
+		{title: "Жизнь", slug: "zhizn"},
+		{title: "***", slug: "theme"},
+		{title: "2024", slug: "theme-2024"},
+		{title: "1-2-3", slug: "theme-1-2-3"},
+	}
+	for _, cs := range cases {
+		t.Run(cs.title, func(t *testing.T) {
+			assert.Equal(t, cs.slug, models.Slugify(cs.title))
+		})
+	}
+}
+
+func TestThemeTree(t *testing.T) {
+	themes := []*models.Theme{
+		{Id: 1, Title: "Root"},
+		{Id: 2, Title: "B", ParentId: 1},
+		{Id: 3, Title: "A", ParentId: 1},
+		{Id: 4, Title: "Leaf", ParentId: 2},
+		{Id: 5, Title: "Other"},
+	}
+	assert.Equal(t, []int{1, 2, 3, 4}, models.DescendantIds(themes, 1))
+	assert.Equal(t, []int{5}, models.DescendantIds(themes, 5))
+
+	d := models.NewThemeDetails(themes[0], themes, map[int]int{1: 1, 2: 2, 4: 3, 5: 10})
+	assert.Equal(t, 6, d.HokkuCount)
+	assert.Len(t, d.Subthemes, 2)
+	assert.Equal(t, 3, d.Subthemes[0].Id)
+	assert.Equal(t, 0, d.Subthemes[0].HokkuCount)
+	assert.Equal(t, 2, d.Subthemes[1].Id)
+	assert.Equal(t, 5, d.Subthemes[1].HokkuCount)
+}
+
+func TestKigoValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		k       *models.Kigo
+		isValid bool
+	}{
+		{
+			name:    "valid",
+			k:       &models.Kigo{Word: "first snow", Season: models.SeasonWinter},
+			isValid: true,
+		},
+		{
+			name:    "empty word",
+			k:       &models.Kigo{Season: models.SeasonWinter},
+			isValid: false,
+		},
+		{
+			name:    "unknown season",
+			k:       &models.Kigo{Word: "first snow", Season: "monsoon"},
+			isValid: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			if cs.isValid {
+				assert.NoError(t, cs.k.Validate())
+			} else {
+				assert.Error(t, cs.k.Validate())
+			}
+		})
+	}
+}
+
+func TestDetectSeasons(t *testing.T) {
+	dict := []*models.Kigo{
+		{Word: "first snow", Season: models.SeasonWinter},
+		{Word: "autumn wind", Season: models.SeasonAutumn},
+		{Word: "осенний вечер", Season: models.SeasonAutumn},
+	}
+	cases := []struct {
+		name    string
+		text    string
+		seasons []string
+	}{
+		{
+			name:    "phrase",
+			text:    "The First snow\nfalls",
+			seasons: []string{models.SeasonWinter},
+		},
+		{
+			name:    "several seasons",
+			text:    "autumn wind\nbefore the first snow",
+			seasons: []string{models.SeasonAutumn, models.SeasonWinter},
+		},
+		{
+			name:    "phrase across lines",
+			text:    "На голой ветке\nворон сидит одиноко.\nОсенний\nвечер.",
+			seasons: []string{models.SeasonAutumn},
+		},
+		{
+			name:    "part of a phrase",
+			text:    "the first rain",
+			seasons: []string{},
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			assert.Equal(t, cs.seasons, models.DetectSeasons(cs.text, dict))
+		})
+	}
+}
+
+func TestHokkuAttachSeasons(t *testing.T) {
+	h := testHokku()
+	h.Content = "first snow"
+	h.Tags = []string{"night", models.SeasonTag(models.SeasonSummer)}
+	h.AttachSeasons([]*models.Kigo{{Word: "first snow", Season: models.SeasonWinter}})
+	assert.Equal(t, []string{"night", "season:winter"}, h.Tags)
+}
+
+func TestChainTurns(t *testing.T) {
+	c := &models.Chain{Title: "Chain", OwnerId: 2, ThemeId: 1, Participants: []int{3, 2, 1, 3}}
+	assert.NoError(t, c.Validate())
+	c.BeforeSave()
+	assert.Equal(t, []int{2, 3, 1}, c.Participants)
+	assert.Equal(t, 2, c.Poet(1))
+	assert.Equal(t, 3, c.Poet(2))
+	assert.Equal(t, 1, c.Poet(3))
+	assert.Equal(t, 2, c.Poet(4))
+	assert.True(t, c.IsParticipant(1))
+	assert.False(t, c.IsParticipant(4))
+
+	assert.Equal(t, prosody.Pattern, models.StanzaPattern(1))
+	assert.Equal(t, prosody.ShortPattern, models.StanzaPattern(2))
+
+	c.Participants = []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	assert.Error(t, c.Validate())
+}
+
+func TestContestPhase(t *testing.T) {
+	start := time.Date(2030, time.February, 1, 0, 0, 0, 0, time.UTC)
+	c := &models.Contest{Title: "Contest", ThemeId: 1, VotingMode: models.VotingSingle,
+		OpensAt: start, ClosesAt: start.AddDate(0, 0, 7),
+		VotingOpensAt: start.AddDate(0, 0, 8), VotingClosesAt: start.AddDate(0, 0, 14)}
+	assert.NoError(t, c.Validate())
+	assert.Equal(t, models.ContestUpcoming, c.Phase(start.Add(-time.Second)))
+	assert.Equal(t, models.ContestOpen, c.Phase(start))
+	assert.Equal(t, models.ContestClosed, c.Phase(start.AddDate(0, 0, 7)))
+	assert.Equal(t, models.ContestVoting, c.Phase(start.AddDate(0, 0, 8)))
+	assert.Equal(t, models.ContestFinished, c.Phase(start.AddDate(0, 0, 14)))
+
+	c.VotingOpensAt = start.AddDate(0, 0, 6)
+	assert.Error(t, c.Validate())
+}
+
+func TestContestTally(t *testing.T) {
+	entries := []*models.Hokku{{Id: 1, OwnerId: 1}, {Id: 2, OwnerId: 2}, {Id: 3, OwnerId: 3}}
+	ballots := []*models.Ballot{
+		{VoterId: 4, HokkuIds: []int{1, 2}},
+		{VoterId: 5, HokkuIds: []int{2, 1}},
+		{VoterId: 6, HokkuIds: []int{100, 3}},
+	}
+	c := &models.Contest{VotingMode: models.VotingRanked}
+	assert.Equal(t, []*models.ContestResult{
+		{HokkuId: 1, OwnerId: 1, Place: 1, Points: 5, FirstVotes: 1},
+		{HokkuId: 2, OwnerId: 2, Place: 1, Points: 5, FirstVotes: 1},
+		{HokkuId: 3, OwnerId: 3, Place: 3, Points: 2},
+	}, c.Tally(entries, ballots))
+
+	c.VotingMode = models.VotingSingle
+	assert.Equal(t, []*models.ContestResult{
+		{HokkuId: 1, OwnerId: 1, Place: 1, Points: 1, FirstVotes: 1},
+		{HokkuId: 2, OwnerId: 2, Place: 1, Points: 1, FirstVotes: 1},
+		{HokkuId: 3, OwnerId: 3, Place: 3},
+	}, c.Tally(entries, ballots))
+}
+
+func TestBallotValidate(t *testing.T) {
+	single := &models.Contest{VotingMode: models.VotingSingle}
+	ranked := &models.Contest{VotingMode: models.VotingRanked}
+	assert.NoError(t, (&models.Ballot{HokkuIds: []int{1}}).Validate(single))
+	assert.Error(t, (&models.Ballot{HokkuIds: []int{1, 2}}).Validate(single))
+	assert.Error(t, (&models.Ballot{}).Validate(single))
+	assert.NoError(t, (&models.Ballot{HokkuIds: []int{1, 2, 3}}).Validate(ranked))
+	assert.Error(t, (&models.Ballot{HokkuIds: []int{1, 2, 3, 4}}).Validate(ranked))
+	assert.Error(t, (&models.Ballot{HokkuIds: []int{1, 1}}).Validate(ranked))
+}
+
+func TestPickDaily(t *testing.T) {
+	ids := []int{3, 1, 2}
+	id, ok := models.PickDaily("2030-03-01", "UTC", ids, nil)
+	assert.True(t, ok)
+	again, _ := models.PickDaily("2030-03-01", "UTC", []int{1, 2, 3}, nil)
+	assert.Equal(t, id, again)
+
+	id, _ = models.PickDaily("2030-03-01", "UTC", ids, map[int]bool{1: true, 2: true})
+	assert.Equal(t, 3, id)
+	_, ok = models.PickDaily("2030-03-01", "UTC", ids, map[int]bool{1: true, 2: true, 3: true})
+	assert.True(t, ok)
+	_, ok = models.PickDaily("2030-03-01", "UTC", nil, nil)
+	assert.False(t, ok)
+}
+
+func TestRankingScore(t *testing.T) {
+	now := time.Date(2030, time.March, 1, 0, 0, 0, 0, time.UTC)
+	w := &models.RankingWeights{Like: 1, Comment: 3, Bookmark: 2, View: 0.1, HalfLife: 24 * time.Hour}
+	e := &models.Engagement{HokkuId: 1, Created: now, Likes: 2, Comments: 1, Bookmarks: 1, Views: 10}
+
+	s := w.Score(e, now)
+	assert.Equal(t, 1, s.HokkuId)
+	assert.InDelta(t, 8, s.Score, 1e-9)
+	assert.InDelta(t, 8, s.Trending, 1e-9)
+
+	s = w.Score(e, now.Add(48*time.Hour))
+	assert.InDelta(t, 8, s.Score, 1e-9)
+	assert.InDelta(t, 2, s.Trending, 1e-9)
+
+	w.HalfLife = 0
+	assert.InDelta(t, 8, w.Score(e, now.Add(48*time.Hour)).Trending, 1e-9)
+}
+
+func TestNewViewStats(t *testing.T) {
+	views := []*models.DailyViews{
+		{HokkuId: 1, Day: "2030-02-20", Views: 4},
+		{HokkuId: 1, Day: "2030-03-01", Views: 2},
+		{HokkuId: 1, Day: "2030-03-03", Views: 1},
+	}
+	first := time.Date(2030, time.February, 28, 12, 0, 0, 0, time.UTC)
+	stats := models.NewViewStats(1, views, first, first.AddDate(0, 0, 3))
+	assert.Equal(t, 7, stats.Total)
+	series := []int{}
+	for _, d := range stats.Daily {
+		series = append(series, d.Views)
+	}
+	assert.Equal(t, []int{0, 2, 0, 1}, series)
+	assert.Equal(t, "2030-02-28", stats.Daily[0].Day)
+}
+
+func TestPostingStreaks(t *testing.T) {
+	today := time.Date(2030, time.March, 10, 15, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		days     []string
+		expected models.Streaks
+	}{
+		{
+			name:     "no posts",
+			expected: models.Streaks{},
+		},
+		{
+			name:     "posted today",
+			days:     []string{"2030-03-01", "2030-03-02", "2030-03-03", "2030-03-09", "2030-03-10"},
+			expected: models.Streaks{Current: 2, Longest: 3},
+		},
+		{
+			name:     "posted yesterday",
+			days:     []string{"2030-03-07", "2030-03-08", "2030-03-09"},
+			expected: models.Streaks{Current: 3, Longest: 3},
+		},
+		{
+			name:     "broken",
+			days:     []string{"2030-03-07", "2030-03-08"},
+			expected: models.Streaks{Current: 0, Longest: 2},
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			assert.Equal(t, cs.expected, models.PostingStreaks(cs.days, today))
+		})
+	}
+}
+
+func TestActivitySeries(t *testing.T) {
+	daily := []*models.Activity{
+		{Day: "2030-03-02", Hokkus: 1},
+		{Day: "2030-03-04", Likes: 2, Views: 5},
+		{Day: "2030-03-05", Comments: 1},
+	}
+	// Saturday to Wednesday
+	first := time.Date(2030, time.March, 2, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 0, 4)
+
+	series := models.ActivitySeries(daily, first, last, models.IntervalDay)
+	assert.Len(t, series, 5)
+	assert.Equal(t, &models.Activity{Day: "2030-03-03"}, series[1])
+	assert.Equal(t, &models.Activity{Day: "2030-03-04", Likes: 2, Views: 5}, series[2])
+
+	series = models.ActivitySeries(daily, first, last, models.IntervalWeek)
+	assert.Equal(t, []*models.Activity{
+		{Day: "2030-03-02", Hokkus: 1},
+		{Day: "2030-03-04", Likes: 2, Comments: 1, Views: 5},
+	}, series)
+}
+
+func TestFingerprint(t *testing.T) {
+	basho := "An old silent pond\nA frog jumps into the pond\nSplash! Silence again"
+	cases := []struct {
+		name      string
+		content   string
+		duplicate bool
+	}{
+		{
+			name:      "punctuation and case",
+			content:   "an old silent pond, a frog jumps into the pond — splash! silence again.",
+			duplicate: true,
+		},
+		{
+			name:      "changed word",
+			content:   "The old silent pond\nA frog jumps into the pond\nSplash! Silence again",
+			duplicate: true,
+		},
+		{
+			name:      "another poem",
+			content:   "First snow\nOn the half-finished bridge",
+			duplicate: false,
+		},
+		{
+			name:      "another translation",
+			content:   "Старый пруд.\nПрыгнула в воду лягушка.\nВсплеск в тишине.",
+			duplicate: false,
+		},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			d := models.FingerprintDistance(models.Fingerprint(basho), models.Fingerprint(cs.content))
+			assert.Equal(t, cs.duplicate, d <= 8, d)
+		})
+	}
+	assert.Equal(t, uint64(0), models.Fingerprint("!!!"))
+}
+
+func TestNotificationDescribe(t *testing.T) {
+	cases := []struct {
+		name         string
+		notification models.Notification
+		message      string
+	}{
+		{name: "one like", notification: models.Notification{Type: models.NotificationLike, Actors: 1}, message: "Someone liked your hokku"},
+		{name: "likes", notification: models.Notification{Type: models.NotificationLike, Actors: 5}, message: "5 people liked your hokku"},
+		{name: "comments", notification: models.Notification{Type: models.NotificationComment, Actors: 2}, message: "2 people commented on your hokku"},
+		{name: "follow", notification: models.Notification{Type: models.NotificationFollow, Actors: 1}, message: "Someone followed you"},
+		{name: "report", notification: models.Notification{Type: models.NotificationReportResolved, Resolution: models.ResolutionHide},
+			message: "Your report was resolved: hide"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.message, c.notification.Describe(), c.name)
+	}
+	assert.True(t, (&models.Notification{Type: models.NotificationFollow}).Aggregated())
+	assert.False(t, (&models.Notification{Type: models.NotificationContentModerated}).Aggregated())
+}
+
+func TestWebhookCheckHost(t *testing.T) {
+	lookup := func(ctx context.Context, network, host string) ([]net.IP, error) {
+		switch host {
+		case "example.com":
+			return []net.IP{net.ParseIP("93.184.216.34")}, nil
+		case "mixed.example.com":
+			return []net.IP{net.ParseIP("93.184.216.34"), net.ParseIP("10.0.0.5")}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	cases := []struct {
+		name    string
+		url     string
+		isValid bool
+	}{
+		{name: "public host", url: "https://example.com/hook", isValid: true},
+		{name: "public ip", url: "http://93.184.216.34:8080/hook", isValid: true},
+		{name: "one internal address", url: "https://mixed.example.com/hook", isValid: false},
+		{name: "unknown host", url: "https://unknown.example.com/hook", isValid: false},
+		{name: "loopback", url: "http://127.0.0.1:8080/hook", isValid: false},
+		{name: "ipv6 loopback", url: "http://[::1]/hook", isValid: false},
+		{name: "private", url: "http://192.168.1.10/hook", isValid: false},
+		{name: "link-local", url: "http://169.254.169.254/latest/meta-data", isValid: false},
+		{name: "unspecified", url: "http://0.0.0.0/hook", isValid: false},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			w := &models.Webhook{URL: cs.url}
+			err := w.CheckHost(context.Background(), lookup)
+			if cs.isValid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
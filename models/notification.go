@@ -0,0 +1,87 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Types of notifications. Reporters learn how their reports were resolved,
+// authors learn that their content was hidden or deleted by a moderator,
+// that their hokkus were liked or commented and that they were followed.
+const (
+	NotificationReportResolved   = "report.resolved"
+	NotificationContentModerated = "content.moderated"
+	NotificationLike             = "hokku.liked"
+	NotificationComment          = "hokku.commented"
+	NotificationFollow           = "user.followed"
+)
+
+// NotificationTypes lists the types users can turn off.
+var NotificationTypes = []string{
+	NotificationReportResolved,
+	NotificationContentModerated,
+	NotificationLike,
+	NotificationComment,
+	NotificationFollow,
+}
+
+// Notification tells a user about an event concerning them. Notifications
+// about likes, comments and follows gather the events until they are read:
+// ActorId is the latest actor and Actors counts the distinct ones.
+type Notification struct {
+	Id         int       `json:"id"`
+	UserId     int       `json:"userId"`
+	Type       string    `json:"type"`
+	ActorId    int       `json:"actorId,omitempty"`
+	Actors     int       `json:"actors,omitempty"`
+	ReportId   int       `json:"reportId,omitempty"`
+	HokkuId    int       `json:"hokkuId,omitempty"`
+	CommentId  int       `json:"commentId,omitempty"`
+	Resolution string    `json:"resolution,omitempty"`
+	Message    string    `json:"message"`
+	Read       bool      `json:"read"`
+	Created    time.Time `json:"created"`
+	Updated    time.Time `json:"updated"`
+}
+
+// Aggregated reports whether the notifications of the type gather events
+// of several actors.
+func (n *Notification) Aggregated() bool {
+	switch n.Type {
+	case NotificationLike, NotificationComment, NotificationFollow:
+		return true
+	}
+	return false
+}
+
+// Describe returns the text shown to the user, as "5 people liked your
+// hokku".
+func (n *Notification) Describe() string {
+	who := "Someone"
+	if n.Actors > 1 {
+		who = fmt.Sprintf("%d people", n.Actors)
+	}
+	switch n.Type {
+	case NotificationReportResolved:
+		return "Your report was resolved: " + n.Resolution
+	case NotificationContentModerated:
+		return "Your content was moderated: " + n.Resolution
+	case NotificationLike:
+		return who + " liked your hokku"
+	case NotificationComment:
+		return who + " commented on your hokku"
+	case NotificationFollow:
+		return who + " followed you"
+	}
+	return ""
+}
+
+// ValidNotificationType reports whether the type is known.
+func ValidNotificationType(t string) bool {
+	for _, known := range NotificationTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
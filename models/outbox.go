@@ -0,0 +1,72 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Domain events written to the outbox.
+const (
+	EventHokkuCreated = "hokku.created"
+	EventHokkuUpdated = "hokku.updated"
+	EventHokkuDeleted = "hokku.deleted"
+	EventUserCreated  = "user.created"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the
+// change it describes. Events are relayed at least once, the key tells
+// repeated deliveries of the same event apart. Attempts counts the failed
+// relays of the event, it is parked and no longer relayed after too many.
+type OutboxEvent struct {
+	Id          int             `json:"id"`
+	Key         string          `json:"key"`
+	Type        string          `json:"type"`
+	AggregateId int             `json:"aggregateId"`
+	Payload     json.RawMessage `json:"payload"`
+	Created     time.Time       `json:"created"`
+	Attempts    int             `json:"attempts"`
+	Parked      bool            `json:"parked"`
+}
+
+// HokkuEvent is the payload of hokku events. Listed tells whether the
+// hokku is listed publicly after the change and WasListed whether it was
+// before. Deleted hokkus are not listed.
+type HokkuEvent struct {
+	Id        int  `json:"id"`
+	OwnerId   int  `json:"ownerId"`
+	ThemeId   int  `json:"themeId"`
+	Listed    bool `json:"listed"`
+	WasListed bool `json:"wasListed"`
+}
+
+// NewHokkuEvent returns the payload of an event about the hokku as it is
+// now.
+func NewHokkuEvent(h *Hokku) *HokkuEvent {
+	return &HokkuEvent{Id: h.Id, OwnerId: h.OwnerId, ThemeId: h.ThemeId, Listed: h.DeletedAt == nil && h.Listed(0, false)}
+}
+
+// UserEvent is the payload of user events. It holds only public fields.
+type UserEvent struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// NewOutboxEvent returns the event about the aggregate with a fresh key.
+func NewOutboxEvent(typ string, aggregateId int, payload interface{}) (*OutboxEvent, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &OutboxEvent{
+		Key:         hex.EncodeToString(key),
+		Type:        typ,
+		AggregateId: aggregateId,
+		Payload:     raw,
+	}, nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Engagement is the number of reactions to a hokku.
+type Engagement struct {
+	HokkuId   int
+	Created   time.Time
+	Likes     int
+	Comments  int
+	Bookmarks int
+	Views     int
+}
+
+// RankingWeights are the points given for every reaction. Trending scores
+// halve every HalfLife after the publication, a zero HalfLife disables
+// the decay.
+type RankingWeights struct {
+	Like     float64
+	Comment  float64
+	Bookmark float64
+	View     float64
+	HalfLife time.Duration
+}
+
+// HokkuScore is the weighted sum of the reactions to a hokku and its
+// decayed trending score.
+type HokkuScore struct {
+	HokkuId  int     `json:"hokkuId"`
+	Score    float64 `json:"score"`
+	Trending float64 `json:"trending"`
+}
+
+// Score scores the engagement at the time.
+func (w *RankingWeights) Score(e *Engagement, now time.Time) *HokkuScore {
+	score := w.Like*float64(e.Likes) + w.Comment*float64(e.Comments) +
+		w.Bookmark*float64(e.Bookmarks) + w.View*float64(e.Views)
+	trending := score
+	if age := now.Sub(e.Created); w.HalfLife > 0 && age > 0 {
+		trending = score * math.Pow(0.5, float64(age)/float64(w.HalfLife))
+	}
+	return &HokkuScore{HokkuId: e.HokkuId, Score: score, Trending: trending}
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// Like and Bookmark are kept once per user and hokku.
+type Like struct {
+	UserId  int       `json:"userId"`
+	HokkuId int       `json:"hokkuId"`
+	Created time.Time `json:"created"`
+}
+
+type Bookmark struct {
+	UserId  int       `json:"userId"`
+	HokkuId int       `json:"hokkuId"`
+	Created time.Time `json:"created"`
+}
+
+type Comment struct {
+	Id      int       `json:"id" form:"id"`
+	HokkuId int       `json:"hokkuId" form:"hokkuId"`
+	OwnerId int       `json:"ownerId" form:"ownerId"`
+	Content string    `json:"content" form:"content"`
+	Created time.Time `json:"created"`
+}
+
+func (c *Comment) Validate() error {
+	return validation.ValidateStruct(
+		c,
+		validation.Field(&c.Content, validation.Required, validation.RuneLength(1, 1000)),
+	)
+}
+
+// DailyViews is the number of views of a hokku during a day formatted with
+// DayLayout.
+type DailyViews struct {
+	HokkuId int    `json:"hokkuId"`
+	Day     string `json:"day"`
+	Views   int    `json:"views"`
+}
+
+// ViewStats is the total number of views of a hokku and its daily views.
+type ViewStats struct {
+	HokkuId int           `json:"hokkuId"`
+	Total   int           `json:"total"`
+	Daily   []*DailyViews `json:"daily"`
+}
+
+// NewViewStats sums the views and lists the daily views from the first to
+// the last day inclusive, days without views get zero.
+func NewViewStats(hokkuId int, views []*DailyViews, first, last time.Time) *ViewStats {
+	stats := &ViewStats{HokkuId: hokkuId, Daily: []*DailyViews{}}
+	byDay := map[string]int{}
+	for _, v := range views {
+		stats.Total += v.Views
+		byDay[v.Day] += v.Views
+	}
+	end := last.Format(DayLayout)
+	for day := first; day.Format(DayLayout) <= end; day = day.AddDate(0, 0, 1) {
+		d := day.Format(DayLayout)
+		stats.Daily = append(stats.Daily, &DailyViews{HokkuId: hokkuId, Day: d, Views: byDay[d]})
+	}
+	return stats
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// Reasons of reports.
+const (
+	ReasonSpam       = "spam"
+	ReasonAbuse      = "abuse"
+	ReasonPlagiarism = "plagiarism"
+	ReasonOther      = "other"
+	// Reports of the content filter have this reason
+	ReasonFilter = "filter"
+)
+
+// Statuses of reports. Open reports wait in the moderation queue until a
+// moderator claims them.
+const (
+	ReportOpen     = "open"
+	ReportClaimed  = "claimed"
+	ReportResolved = "resolved"
+)
+
+// Resolutions of reports. Hiding a user hides all their hokkus, deleting
+// a user moves them to the trash. Comments are deleted either way.
+const (
+	ResolutionHide    = "hide"
+	ResolutionDelete  = "delete"
+	ResolutionDismiss = "dismiss"
+)
+
+// Report complains about a hokku, a comment to the hokku when CommentId is
+// set or, when HokkuId is 0, about a user. AuthorId is the reported user or
+// the owner of the reported hokku or comment. Reports with ReporterId 0 are
+// sent by the content filter.
+type Report struct {
+	Id          int        `json:"id"`
+	ReporterId  int        `json:"reporterId,omitempty"`
+	HokkuId     int        `json:"hokkuId,omitempty"`
+	CommentId   int        `json:"commentId,omitempty"`
+	AuthorId    int        `json:"authorId"`
+	Reason      string     `json:"reason" form:"reason"`
+	Comment     string     `json:"comment" form:"comment"`
+	Status      string     `json:"status"`
+	ModeratorId int        `json:"moderatorId,omitempty"`
+	Resolution  string     `json:"resolution,omitempty"`
+	Created     time.Time  `json:"created"`
+	Resolved    *time.Time `json:"resolved,omitempty"`
+}
+
+func (r *Report) Validate() error {
+	return validation.ValidateStruct(
+		r,
+		validation.Field(&r.Reason, validation.Required,
+			validation.In(ReasonSpam, ReasonAbuse, ReasonPlagiarism, ReasonOther)),
+		validation.Field(&r.Comment, validation.RuneLength(0, 1000)),
+	)
+}
+
+// Resolution is the decision of a moderator on a report.
+type Resolution struct {
+	Action string `json:"action" form:"action"`
+}
+
+func (r *Resolution) Validate() error {
+	return validation.ValidateStruct(
+		r,
+		validation.Field(&r.Action, validation.Required,
+			validation.In(ResolutionHide, ResolutionDelete, ResolutionDismiss)),
+	)
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// Kinds of restrictions. Banned users can't log in or use their sessions,
+// suspended users can read and react but can't post, hokkus of
+// shadow-banned users are shown only to themselves.
+const (
+	RestrictionBan        = "ban"
+	RestrictionSuspension = "suspension"
+	RestrictionShadowBan  = "shadowban"
+)
+
+// Restriction limits a user until it expires or is lifted, restrictions
+// without the expiry are permanent. The moderators who placed and lifted
+// it are recorded.
+type Restriction struct {
+	Id          int        `json:"id"`
+	UserId      int        `json:"userId"`
+	Kind        string     `json:"kind" form:"kind"`
+	Reason      string     `json:"reason" form:"reason"`
+	Expires     *time.Time `json:"expires,omitempty" form:"expires"`
+	ModeratorId int        `json:"moderatorId"`
+	Created     time.Time  `json:"created"`
+	LiftedBy    int        `json:"liftedBy,omitempty"`
+	Lifted      *time.Time `json:"lifted,omitempty"`
+}
+
+func (r *Restriction) Validate() error {
+	return validation.ValidateStruct(
+		r,
+		validation.Field(&r.Kind, validation.Required,
+			validation.In(RestrictionBan, RestrictionSuspension, RestrictionShadowBan)),
+		validation.Field(&r.Reason, validation.Required, validation.RuneLength(1, 500)),
+	)
+}
+
+// Active reports whether the restriction applies at the time.
+func (r *Restriction) Active(now time.Time) bool {
+	return r.Lifted == nil && (r.Expires == nil || r.Expires.After(now))
+}
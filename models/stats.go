@@ -0,0 +1,118 @@
+package models
+
+import "time"
+
+// Intervals of the activity series.
+const (
+	IntervalDay  = "day"
+	IntervalWeek = "week"
+)
+
+// AuthorTotals counts the published hokkus of an author, the reactions to
+// them and the followers of the author.
+type AuthorTotals struct {
+	Hokkus    int `json:"hokkus"`
+	Likes     int `json:"likes"`
+	Comments  int `json:"comments"`
+	Views     int `json:"views"`
+	Followers int `json:"followers"`
+}
+
+type HokkuStats struct {
+	HokkuId  int    `json:"hokkuId"`
+	Title    string `json:"title"`
+	Likes    int    `json:"likes"`
+	Comments int    `json:"comments"`
+	Views    int    `json:"views"`
+}
+
+type ThemeStats struct {
+	ThemeId  int    `json:"themeId"`
+	Title    string `json:"title"`
+	Hokkus   int    `json:"hokkus"`
+	Likes    int    `json:"likes"`
+	Comments int    `json:"comments"`
+	Views    int    `json:"views"`
+}
+
+// Activity is what happened to the hokkus of an author during the day or
+// the week starting on the day.
+type Activity struct {
+	Day      string `json:"day"`
+	Hokkus   int    `json:"hokkus"`
+	Likes    int    `json:"likes"`
+	Comments int    `json:"comments"`
+	Views    int    `json:"views"`
+}
+
+// Streaks are numbers of consecutive days with published hokkus. The
+// current streak isn't broken until the end of the day after the last
+// post.
+type Streaks struct {
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+}
+
+type AuthorStats struct {
+	AuthorTotals
+	Streaks  Streaks       `json:"streaks"`
+	Popular  []*HokkuStats `json:"popular"`
+	Themes   []*ThemeStats `json:"themes"`
+	Interval string        `json:"interval"`
+	Series   []*Activity   `json:"series"`
+}
+
+// PostingStreaks finds the streaks in the ascending posting days.
+func PostingStreaks(days []string, today time.Time) Streaks {
+	s := Streaks{}
+	run := 0
+	var prev time.Time
+	for i, d := range days {
+		day, err := time.Parse(DayLayout, d)
+		if err != nil {
+			continue
+		}
+		if i > 0 && day.Equal(prev.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > s.Longest {
+			s.Longest = run
+		}
+		prev = day
+	}
+	last := today.Format(DayLayout)
+	yesterday := today.AddDate(0, 0, -1).Format(DayLayout)
+	if len(days) > 0 && (days[len(days)-1] == last || days[len(days)-1] == yesterday) {
+		s.Current = run
+	}
+	return s
+}
+
+// ActivitySeries lists the activity from the first to the last day
+// inclusive, days without activity get zeros. Weekly series sum the days
+// of the weeks starting on Monday, the first week starts on the first day.
+func ActivitySeries(daily []*Activity, first, last time.Time, interval string) []*Activity {
+	byDay := map[string]*Activity{}
+	for _, a := range daily {
+		byDay[a.Day] = a
+	}
+	series := []*Activity{}
+	var point *Activity
+	end := last.Format(DayLayout)
+	for day := first; day.Format(DayLayout) <= end; day = day.AddDate(0, 0, 1) {
+		d := day.Format(DayLayout)
+		if point == nil || interval != IntervalWeek || day.Weekday() == time.Monday {
+			point = &Activity{Day: d}
+			series = append(series, point)
+		}
+		if a, ok := byDay[d]; ok {
+			point.Hokkus += a.Hokkus
+			point.Likes += a.Likes
+			point.Comments += a.Comments
+			point.Views += a.Views
+		}
+	}
+	return series
+}
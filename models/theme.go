@@ -1,15 +1,161 @@
 package models
 
-import validation "github.com/go-ozzo/ozzo-validation"
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// Slugs need a letter so they can't be taken for theme ids.
+var (
+	slugRegexp       = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	slugLetterRegexp = regexp.MustCompile(`[a-z]`)
+)
 
 type Theme struct {
-	Id    int    `json:"id" form:"id"`
-	Title string `json:"title" form:"title"`
+	Id          int    `json:"id" form:"id"`
+	Title       string `json:"title" form:"title"`
+	Slug        string `json:"slug" form:"slug"`
+	Description string `json:"description" form:"description"`
+	// Root themes have no parent and ParentId 0
+	ParentId int `json:"parentId" form:"parentId"`
+	// Cover is a short text shown at the top of the theme page
+	Cover     string `json:"cover" form:"cover"`
+	SortOrder int    `json:"sortOrder" form:"sortOrder"`
+	// Hokkus of a strict theme must follow the 5-7-5 form
+	Strict bool `json:"strict" form:"strict"`
+	// No hokkus can be posted to a closed theme
+	PostingClosed bool `json:"postingClosed" form:"postingClosed"`
 }
 
 func (t *Theme) Validate() error {
 	return validation.ValidateStruct(
 		t,
 		validation.Field(&t.Title, validation.Required, validation.Length(1, 40)),
+		validation.Field(&t.Slug, validation.Length(1, 40), validation.Match(slugRegexp),
+			validation.Match(slugLetterRegexp).Error("must contain a letter")),
+		validation.Field(&t.Description, validation.RuneLength(0, 1000)),
+		validation.Field(&t.ParentId, validation.Min(0)),
+		validation.Field(&t.Cover, validation.RuneLength(0, 500)),
 	)
 }
+
+// BeforeSave derives the slug from the title when the client omitted it.
+func (t *Theme) BeforeSave() {
+	if t.Slug == "" {
+		t.Slug = Slugify(t.Title)
+	}
+}
+
+var cyrillicTranslit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// Slugify turns the title into a lower-case slug of latin letters, digits
+// and dashes. Russian letters are transliterated, other characters
+// separate words. Titles without such characters give "theme" and slugs
+// without letters are prefixed with "theme-".
+func Slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		s, ok := cyrillicTranslit[r]
+		switch {
+		case ok:
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			s = string(r)
+		default:
+			dash = b.Len() > 0
+			continue
+		}
+		if s == "" {
+			continue
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteString(s)
+	}
+	slug := b.String()
+	if slug == "" {
+		return "theme"
+	}
+	if !slugLetterRegexp.MatchString(slug) {
+		slug = "theme-" + slug
+	}
+	if len(slug) > 40 {
+		slug = strings.TrimRight(slug[:40], "-")
+	}
+	return slug
+}
+
+// SortThemes orders themes by their sort order and then by title.
+func SortThemes(themes []*Theme) {
+	sort.SliceStable(themes, func(i, j int) bool {
+		if themes[i].SortOrder != themes[j].SortOrder {
+			return themes[i].SortOrder < themes[j].SortOrder
+		}
+		return themes[i].Title < themes[j].Title
+	})
+}
+
+// DescendantIds returns the id of the theme followed by the ids of all its
+// descendants.
+func DescendantIds(themes []*Theme, id int) []int {
+	ids := []int{id}
+	seen := map[int]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, t := range themes {
+			if t.ParentId == ids[i] && !seen[t.Id] {
+				seen[t.Id] = true
+				ids = append(ids, t.Id)
+			}
+		}
+	}
+	return ids
+}
+
+// ThemeCount is a theme with the number of public hokkus in it and its
+// descendants.
+type ThemeCount struct {
+	*Theme
+	HokkuCount int `json:"hokkuCount"`
+}
+
+// ThemeDetails is a theme with its direct subthemes.
+type ThemeDetails struct {
+	ThemeCount
+	Subthemes []*ThemeCount `json:"subthemes"`
+}
+
+// NewThemeDetails builds the details of the theme from the list of all
+// themes and the numbers of public hokkus per theme.
+func NewThemeDetails(theme *Theme, themes []*Theme, counts map[int]int) *ThemeDetails {
+	count := func(t *Theme) *ThemeCount {
+		tc := &ThemeCount{Theme: t}
+		for _, id := range DescendantIds(themes, t.Id) {
+			tc.HokkuCount += counts[id]
+		}
+		return tc
+	}
+	subthemes := []*Theme{}
+	for _, t := range themes {
+		if t.ParentId == theme.Id && t.Id != theme.Id {
+			subthemes = append(subthemes, t)
+		}
+	}
+	SortThemes(subthemes)
+	d := &ThemeDetails{ThemeCount: *count(theme), Subthemes: []*ThemeCount{}}
+	for _, t := range subthemes {
+		d.Subthemes = append(d.Subthemes, count(t))
+	}
+	return d
+}
@@ -8,13 +8,26 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// User roles. Admins maintain themes and the kigo dictionary, moderators
+// and admins handle reports.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// User is an account. Hokkus of hidden users are hidden by moderators from
+// everyone but the user, including the ones posted later.
 type User struct {
-	Id             int       `json:"id" form:"id"`
-	Email          string    `json:"email" form:"email"`
-	Name           string    `json:"name" form:"name"`
-	OpenPassword   string    `json:"password" form:"password"`
-	HashedPassword string    `json:"-"`
-	Created        time.Time `json:"created"`
+	Id             int        `json:"id" form:"id"`
+	Email          string     `json:"email" form:"email"`
+	Name           string     `json:"name" form:"name"`
+	OpenPassword   string     `json:"password" form:"password"`
+	HashedPassword string     `json:"-"`
+	Created        time.Time  `json:"created"`
+	Role           string     `json:"role,omitempty"`
+	Hidden         bool       `json:"hidden,omitempty"`
+	DeletedAt      *time.Time `json:"deletedAt,omitempty"`
 }
 
 func (u *User) Validate() error {
@@ -26,6 +39,18 @@ func (u *User) Validate() error {
 	)
 }
 
+// RoleChange sets the role of a user.
+type RoleChange struct {
+	Role string `json:"role" form:"role"`
+}
+
+func (r *RoleChange) Validate() error {
+	return validation.ValidateStruct(
+		r,
+		validation.Field(&r.Role, validation.Required, validation.In(RoleUser, RoleModerator, RoleAdmin)),
+	)
+}
+
 func (u *User) BeforeCreate() error {
 	if len(u.OpenPassword) > 0 {
 		h, err := hashString(u.OpenPassword)
@@ -38,6 +63,14 @@ func (u *User) BeforeCreate() error {
 	return nil
 }
 
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+func (u *User) IsModerator() bool {
+	return u.Role == RoleModerator || u.Role == RoleAdmin
+}
+
 func hashString(s string) (string, error) {
 	b, err := bcrypt.GenerateFromPassword([]byte(s), bcrypt.DefaultCost)
 	if err != nil {
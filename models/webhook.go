@@ -0,0 +1,146 @@
+package models
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+)
+
+// Events sent to webhooks.
+const (
+	WebhookHokkuCreated = EventHokkuCreated
+	WebhookHokkuUpdated = EventHokkuUpdated
+	WebhookHokkuDeleted = EventHokkuDeleted
+	WebhookUserCreated  = EventUserCreated
+)
+
+// WebhookEvents lists the events webhooks subscribe to.
+var WebhookEvents = []string{WebhookHokkuCreated, WebhookHokkuUpdated, WebhookHokkuDeleted, WebhookUserCreated}
+
+// States of webhook deliveries. Pending deliveries are retried until they
+// succeed or run out of attempts and fail.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is an endpoint of an integration that receives the events it
+// subscribed to. Payloads are signed with the secret, which is shown only
+// when the webhook is created.
+type Webhook struct {
+	Id      int       `json:"id"`
+	OwnerId int       `json:"ownerId"`
+	URL     string    `json:"url" form:"url"`
+	Events  []string  `json:"events" form:"events"`
+	Secret  string    `json:"secret,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+var webhookScheme = regexp.MustCompile(`^https?://`)
+
+func (w *Webhook) Validate() error {
+	err := validation.ValidateStruct(
+		w,
+		validation.Field(&w.URL, validation.Required, validation.Length(1, 2048), is.URL, validation.Match(webhookScheme)),
+		validation.Field(&w.Events, validation.Required),
+	)
+	if err != nil {
+		return err
+	}
+	for _, e := range w.Events {
+		if !validWebhookEvent(e) {
+			return errors.New("unknown event " + e)
+		}
+	}
+	return nil
+}
+
+// CheckHost resolves the host of the webhook with lookup and fails unless
+// all its addresses are public, so webhooks can't reach the servers of the
+// site and its network.
+func (w *Webhook) CheckHost(ctx context.Context, lookup func(ctx context.Context, network, host string) ([]net.IP, error)) error {
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		if ips, err = lookup(ctx, "ip", host); err != nil {
+			return err
+		}
+	}
+	if len(ips) == 0 {
+		return errors.New("no addresses for " + host)
+	}
+	for _, ip := range ips {
+		if !PublicIP(ip) {
+			return errors.New("internal address " + ip.String())
+		}
+	}
+	return nil
+}
+
+// PublicIP reports whether webhooks may be sent to the address. Loopback,
+// private, link-local, multicast and unspecified addresses are not public.
+func PublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast())
+}
+
+// Subscribed reports whether the webhook receives the event.
+func (w *Webhook) Subscribed(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func validWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is an event queued for a webhook with the outcome of the
+// latest attempt to deliver it.
+type WebhookDelivery struct {
+	Id          int             `json:"id"`
+	WebhookId   int             `json:"webhookId"`
+	Key         string          `json:"key,omitempty"`
+	Event       string          `json:"event"`
+	Payload     json.RawMessage `json:"payload" swaggertype:"object"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"nextAttempt"`
+	StatusCode  int             `json:"statusCode,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Created     time.Time       `json:"created"`
+	Delivered   *time.Time      `json:"delivered,omitempty"`
+}
+
+// Sign returns the signature of the payload sent at the Unix time, the
+// hex-encoded HMAC-SHA256 of the time and the payload joined with a dot.
+func (w *Webhook) Sign(timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(w.Secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,108 @@
+// Package outbox contains the sinks that domain events are relayed to from
+// the outbox.
+package outbox
+
+import (
+	"log"
+	"sync"
+
+	"github.com/EgorSkurihin/Hokku/models"
+)
+
+// Sink takes relayed events. Events are relayed at least once, so a sink
+// may get the same event again after it or another sink failed.
+type Sink interface {
+	Send(*models.OutboxEvent) error
+}
+
+// SinkFunc turns a function into a sink.
+type SinkFunc func(*models.OutboxEvent) error
+
+func (f SinkFunc) Send(e *models.OutboxEvent) error {
+	return f(e)
+}
+
+type subscriber struct {
+	handler SinkFunc
+	types   map[string]bool
+}
+
+// Bus hands events to the handlers subscribed inside the process. It
+// remembers the keys of the last size events all handlers took and skips
+// them when they are relayed again.
+type Bus struct {
+	mu          sync.Mutex
+	subscribers []subscriber
+	size        int
+	seen        map[string]bool
+	keys        []string
+}
+
+func NewBus(size int) *Bus {
+	return &Bus{size: size, seen: map[string]bool{}}
+}
+
+// Subscribe makes the handler take the events of the types, or all events
+// when no types are given.
+func (b *Bus) Subscribe(handler SinkFunc, types ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sub := subscriber{handler: handler}
+	if len(types) > 0 {
+		sub.types = map[string]bool{}
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+	b.subscribers = append(b.subscribers, sub)
+}
+
+// Send hands the event to every subscribed handler and returns the first
+// error. The event is remembered only when every handler took it.
+func (b *Bus) Send(e *models.OutboxEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.seen[e.Key] {
+		return nil
+	}
+	var first error
+	for _, sub := range b.subscribers {
+		if sub.types != nil && !sub.types[e.Type] {
+			continue
+		}
+		if err := sub.handler(e); err != nil && first == nil {
+			first = err
+		}
+	}
+	if first != nil {
+		return first
+	}
+	b.remember(e.Key)
+	return nil
+}
+
+func (b *Bus) remember(key string) {
+	if b.size <= 0 {
+		return
+	}
+	if len(b.keys) == b.size {
+		delete(b.seen, b.keys[0])
+		b.keys = b.keys[1:]
+	}
+	b.keys = append(b.keys, key)
+	b.seen[key] = true
+}
+
+// Log writes events to the logger.
+type Log struct {
+	logger *log.Logger
+}
+
+func NewLog(logger *log.Logger) *Log {
+	return &Log{logger: logger}
+}
+
+func (l *Log) Send(e *models.OutboxEvent) error {
+	l.logger.Printf("outbox: %s %d key=%s payload=%s", e.Type, e.AggregateId, e.Key, e.Payload)
+	return nil
+}
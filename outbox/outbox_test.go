@@ -0,0 +1,56 @@
+package outbox_test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/outbox"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBus(t *testing.T) {
+	bus := outbox.NewBus(2)
+	all, users := []string{}, []string{}
+	fail := true
+	bus.Subscribe(func(e *models.OutboxEvent) error {
+		all = append(all, e.Key)
+		return nil
+	})
+	bus.Subscribe(func(e *models.OutboxEvent) error {
+		users = append(users, e.Key)
+		if fail {
+			return errors.New("failed")
+		}
+		return nil
+	}, models.EventUserCreated)
+
+	hokku := &models.OutboxEvent{Key: "a", Type: models.EventHokkuCreated}
+	user := &models.OutboxEvent{Key: "b", Type: models.EventUserCreated}
+	assert.NoError(t, bus.Send(hokku))
+	assert.Error(t, bus.Send(user))
+	fail = false
+	assert.NoError(t, bus.Send(user))
+	// Taken events are skipped until they are forgotten
+	assert.NoError(t, bus.Send(hokku))
+	assert.NoError(t, bus.Send(user))
+	assert.Equal(t, []string{"a", "b", "b"}, all)
+	assert.Equal(t, []string{"b", "b"}, users)
+
+	assert.NoError(t, bus.Send(&models.OutboxEvent{Key: "c", Type: models.EventHokkuDeleted}))
+	assert.NoError(t, bus.Send(hokku))
+	assert.Equal(t, []string{"a", "b", "b", "c", "a"}, all)
+}
+
+func TestLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sink := outbox.NewLog(log.New(buf, "", 0))
+	e, err := models.NewOutboxEvent(models.EventUserCreated, 7, &models.UserEvent{Id: 7, Name: "Basho"})
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Send(e))
+	assert.True(t, strings.HasPrefix(buf.String(), "outbox: user.created 7 key="+e.Key))
+	assert.Contains(t, buf.String(), `"name":"Basho"`)
+}
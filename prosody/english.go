@@ -0,0 +1,94 @@
+package prosody
+
+import (
+	_ "embed"
+	"strconv"
+	"strings"
+)
+
+// english.txt lists English words the heuristic below gets wrong, mostly
+// words with adjacent vowels read as separate syllables.
+//
+//go:embed english.txt
+var englishDictionaryFile string
+
+var englishDictionary = parseDictionary(englishDictionaryFile)
+
+// parseDictionary parses lines of "word syllables". Empty lines and lines
+// starting with # are skipped.
+func parseDictionary(data string) map[string]int {
+	dict := map[string]int{}
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		dict[fields[0]] = n
+	}
+	return dict
+}
+
+func isEnglishVowel(r rune) bool {
+	return strings.ContainsRune("aeiouyāēīōūáéíóúàèìòùâêîôûäëïöü", r)
+}
+
+// countEnglish counts vowel groups of the word and corrects the count for
+// silent endings: "stone", "jumped", "makes", "lonely".
+func countEnglish(word string) int {
+	if strings.Contains(word, "-") {
+		count := 0
+		for _, part := range strings.Split(word, "-") {
+			if part != "" {
+				count += countEnglish(part)
+			}
+		}
+		return count
+	}
+	word = strings.ReplaceAll(word, "’", "'")
+	word = strings.TrimSuffix(word, "'s")
+	if n, ok := englishDictionary[word]; ok {
+		return n
+	}
+
+	runes := []rune(word)
+	count := 0
+	prevVowel := false
+	for _, r := range runes {
+		vowel := isEnglishVowel(r)
+		if vowel && !prevVowel {
+			count++
+		}
+		prevVowel = vowel
+	}
+
+	// consonantBefore reports whether the rune preceding the suffix of n
+	// runes is a consonant
+	consonantBefore := func(n int) bool {
+		return len(runes) > n && !isEnglishVowel(runes[len(runes)-n-1])
+	}
+	switch {
+	case strings.HasSuffix(word, "le") && consonantBefore(2):
+		// "table", "little": the final "le" is a syllable of its own
+	case strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "ee"):
+		count--
+	case strings.HasSuffix(word, "ed") && consonantBefore(2) &&
+		!strings.HasSuffix(word, "ted") && !strings.HasSuffix(word, "ded"):
+		count--
+	case strings.HasSuffix(word, "es") && consonantBefore(2) &&
+		!strings.HasSuffix(word, "ses") && !strings.HasSuffix(word, "xes") &&
+		!strings.HasSuffix(word, "zes") && !strings.HasSuffix(word, "ches") &&
+		!strings.HasSuffix(word, "shes") && !strings.HasSuffix(word, "ges") &&
+		!strings.HasSuffix(word, "ces"):
+		count--
+	case strings.HasSuffix(word, "ely") && consonantBefore(3):
+		count--
+	}
+	if count < 1 {
+		count = 1
+	}
+	return count
+}
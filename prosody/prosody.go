@@ -0,0 +1,148 @@
+// Package prosody analyses the form of hokku: it splits a poem into lines
+// and counts syllables in Russian and English words.
+package prosody
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Pattern is the syllable count of the lines of a classic hokku.
+var Pattern = []int{5, 7, 5}
+
+// ShortPattern is the syllable count of the two-line stanzas alternating
+// with hokku in a renga.
+var ShortPattern = []int{7, 7}
+
+type Line struct {
+	Text      string `json:"text"`
+	Syllables int    `json:"syllables"`
+	Expected  int    `json:"expected"`
+}
+
+type Analysis struct {
+	Lines    []Line `json:"lines"`
+	Conforms bool   `json:"conforms"`
+}
+
+// Analyze counts syllables of every line of the content and checks the
+// lines against Pattern.
+func Analyze(content string) *Analysis {
+	return AnalyzePattern(content, Pattern)
+}
+
+// AnalyzePattern counts syllables of every line of the content and checks
+// the lines against the pattern.
+func AnalyzePattern(content string, pattern []int) *Analysis {
+	lines := SplitLines(content)
+	a := &Analysis{
+		Lines:    make([]Line, 0, len(lines)),
+		Conforms: len(lines) == len(pattern),
+	}
+	for i, text := range lines {
+		l := Line{Text: text, Syllables: CountSyllables(text)}
+		if i < len(pattern) {
+			l.Expected = pattern[i]
+		}
+		if l.Syllables != l.Expected {
+			a.Conforms = false
+		}
+		a.Lines = append(a.Lines, l)
+	}
+	return a
+}
+
+// FormError describes a poem that doesn't follow the expected pattern.
+type FormError struct {
+	Lines   []Line `json:"lines"`
+	pattern []int
+}
+
+func (e *FormError) Error() string {
+	if len(e.Lines) != len(e.pattern) {
+		return fmt.Sprintf("expected %d lines, got %d", len(e.pattern), len(e.Lines))
+	}
+	problems := []string{}
+	for i, l := range e.Lines {
+		if l.Syllables != l.Expected {
+			problems = append(problems, fmt.Sprintf("line %d has %d syllables, expected %d", i+1, l.Syllables, l.Expected))
+		}
+	}
+	return strings.Join(problems, "; ")
+}
+
+// Check returns a *FormError if the content doesn't follow Pattern.
+func Check(content string) error {
+	return CheckPattern(content, Pattern)
+}
+
+// CheckPattern returns a *FormError if the content doesn't follow the
+// pattern.
+func CheckPattern(content string, pattern []int) error {
+	a := AnalyzePattern(content, pattern)
+	if !a.Conforms {
+		return &FormError{Lines: a.Lines, pattern: pattern}
+	}
+	return nil
+}
+
+// SplitLines splits the content into trimmed non-empty lines. Windows and
+// old Mac line endings are accepted.
+func SplitLines(content string) []string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	content = strings.ReplaceAll(content, "\r", "\n")
+	lines := []string{}
+	for _, l := range strings.Split(content, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// CountSyllables returns the number of syllables in a line of text.
+func CountSyllables(text string) int {
+	count := 0
+	for _, w := range Words(text) {
+		count += CountWordSyllables(w)
+	}
+	return count
+}
+
+// Words splits text into lower-cased words. Apostrophes and hyphens
+// inside a word are kept.
+func Words(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\'' && r != '’' && r != '-'
+	})
+	res := make([]string, 0, len(words))
+	for _, w := range words {
+		if w = strings.Trim(w, "'’-"); w != "" {
+			res = append(res, w)
+		}
+	}
+	return res
+}
+
+// CountWordSyllables counts syllables of a single lower-cased word. Words
+// written in Cyrillic are counted as Russian, the others as English.
+func CountWordSyllables(word string) int {
+	for _, r := range word {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return countRussian(word)
+		}
+	}
+	return countEnglish(word)
+}
+
+// In Russian every vowel forms a syllable.
+func countRussian(word string) int {
+	count := 0
+	for _, r := range word {
+		if strings.ContainsRune("аеёиоуыэюя", r) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,108 @@
+package prosody_test
+
+import (
+	"testing"
+
+	"github.com/EgorSkurihin/Hokku/prosody"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountWordSyllables(t *testing.T) {
+	cases := []struct {
+		word      string
+		syllables int
+	}{
+		{"ворон", 2},
+		{"одиноко", 4},
+		{"ёлка", 2},
+		{"в", 0},
+		{"pond", 1},
+		{"silence", 2},
+		{"stone", 1},
+		{"the", 1},
+		{"table", 2},
+		{"jumped", 1},
+		{"wanted", 2},
+		{"makes", 1},
+		{"roses", 2},
+		{"lonely", 2},
+		{"tree", 1},
+		{"cherry", 2},
+		{"quiet", 2},
+		{"poem", 2},
+		{"snow-covered", 3},
+		{"bashō's", 2},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.syllables, prosody.CountWordSyllables(c.word), c.word)
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	lines := prosody.SplitLines("  first line \r\nsecond\r\n\n third\r")
+	assert.Equal(t, []string{"first line", "second", "third"}, lines)
+}
+
+func TestAnalyze(t *testing.T) {
+	cases := []struct {
+		name      string
+		content   string
+		syllables []int
+		conforms  bool
+	}{
+		{
+			name:      "english 5-7-5",
+			content:   "An old silent pond\nA frog jumps into the pond—\nSplash! Silence again.",
+			syllables: []int{5, 7, 5},
+			conforms:  true,
+		},
+		{
+			name:      "russian 5-7-5",
+			content:   "Старый пруд молчит.\r\nЛягушка прыгнет в воду —\r\nВсплеск и тишина.",
+			syllables: []int{5, 7, 5},
+			conforms:  true,
+		},
+		{
+			name:      "russian translation",
+			content:   "Тихо, тихо ползи,\nУлитка, по склону Фудзи,\nВверх, до самых высот!",
+			syllables: []int{6, 8, 6},
+			conforms:  false,
+		},
+		{
+			name:      "two lines",
+			content:   "An old silent pond\nA frog jumps into the pond—",
+			syllables: []int{5, 7},
+			conforms:  false,
+		},
+	}
+	for _, c := range cases {
+		a := prosody.Analyze(c.content)
+		syllables := []int{}
+		for _, l := range a.Lines {
+			syllables = append(syllables, l.Syllables)
+		}
+		assert.Equal(t, c.syllables, syllables, c.name)
+		assert.Equal(t, c.conforms, a.Conforms, c.name)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	assert.NoError(t, prosody.Check("An old silent pond\nA frog jumps into the pond—\nSplash! Silence again."))
+
+	err := prosody.Check("An old silent pond\nA green frog jumps into the pond—\nSplash! Silence again.")
+	var formErr *prosody.FormError
+	if assert.ErrorAs(t, err, &formErr) {
+		assert.Len(t, formErr.Lines, 3)
+		assert.Equal(t, "line 2 has 8 syllables, expected 7", err.Error())
+	}
+
+	err = prosody.Check("An old silent pond")
+	assert.EqualError(t, err, "expected 3 lines, got 1")
+}
+
+func TestCheckPattern(t *testing.T) {
+	assert.NoError(t, prosody.CheckPattern("A frog jumps into the pond—\nA frog jumps into the pond—", prosody.ShortPattern))
+
+	err := prosody.CheckPattern("An old silent pond\nA frog jumps into the pond—\nSplash! Silence again.", prosody.ShortPattern)
+	assert.EqualError(t, err, "expected 2 lines, got 3")
+}
@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/store"
+)
+
+// Judge periodically computes and publishes the results of the contests
+// whose voting is over.
+type Judge struct {
+	store    store.Store
+	clock    clock.Clock
+	interval time.Duration
+}
+
+func NewJudge(store store.Store, clock clock.Clock, interval time.Duration) *Judge {
+	return &Judge{
+		store:    store,
+		clock:    clock,
+		interval: interval,
+	}
+}
+
+// Run judges finished contests every interval until ctx is cancelled.
+func (j *Judge) Run(ctx context.Context) {
+	every(ctx, j.interval, func() {
+		if _, err := j.JudgeDue(); err != nil {
+			log.Printf("judge: %v", err)
+		}
+	})
+}
+
+// JudgeDue publishes the results of the contests whose voting closed by
+// the current time and returns the number of judged contests. Contests
+// judged meanwhile by another server are skipped.
+func (j *Judge) JudgeDue() (int, error) {
+	now := j.clock.Now()
+	contests, err := j.store.GetUnjudgedContests(now)
+	if err != nil {
+		return 0, err
+	}
+	judged := 0
+	for _, c := range contests {
+		entries, err := j.store.GetContestEntries(c.Id)
+		if err != nil {
+			return judged, err
+		}
+		ballots, err := j.store.GetBallots(c.Id)
+		if err != nil {
+			return judged, err
+		}
+		err = j.store.PublishContestResults(c.Id, c.Tally(entries, ballots), now)
+		if errors.Is(err, store.ErrAlreadyExist) {
+			continue
+		}
+		if err != nil {
+			return judged, err
+		}
+		judged++
+	}
+	return judged, nil
+}
@@ -0,0 +1,43 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/scheduler"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJudgeDue(t *testing.T) {
+	s := test_store.New()
+	clk := clock.NewMock(test_store.ContestStart.AddDate(0, 0, 10))
+	j := scheduler.NewJudge(s, clk, time.Minute)
+
+	n, err := j.JudgeDue()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	clk.Set(test_store.ContestStart.AddDate(0, 0, 14))
+	n, err = j.JudgeDue()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	c, _ := s.GetContest(1)
+	assert.Equal(t, clk.Now(), *c.ResultsPublishedAt)
+	assert.Equal(t, []*models.ContestResult{
+		{HokkuId: 1, OwnerId: 1, Place: 1, Points: 1, FirstVotes: 1},
+		{HokkuId: 5, OwnerId: 2, Place: 2},
+	}, c.Results)
+	c, _ = s.GetContest(2)
+	assert.Equal(t, []*models.ContestResult{
+		{HokkuId: 4, OwnerId: 1, Place: 1, Points: 3, FirstVotes: 1},
+		{HokkuId: 2, OwnerId: 2, Place: 2, Points: 2},
+	}, c.Results)
+
+	n, err = j.JudgeDue()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
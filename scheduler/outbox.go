@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/outbox"
+	"github.com/EgorSkurihin/Hokku/store"
+)
+
+// outboxBatch is the number of events read from the outbox at once
+const outboxBatch = 100
+
+// OutboxRelay periodically drains the outbox to the sinks in the order the
+// events were written. An event is deleted once every sink took it, a
+// failing sink stops the relay until the next run, when the event is sent
+// again to every sink. An event that failed the given number of attempts is
+// parked in the outbox and the relay goes on with the next events.
+type OutboxRelay struct {
+	store    store.Store
+	interval time.Duration
+	attempts int
+	sinks    []outbox.Sink
+}
+
+func NewOutboxRelay(store store.Store, interval time.Duration, attempts int, sinks ...outbox.Sink) *OutboxRelay {
+	return &OutboxRelay{
+		store:    store,
+		interval: interval,
+		attempts: attempts,
+		sinks:    sinks,
+	}
+}
+
+// Run relays the events every interval until ctx is cancelled.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	every(ctx, r.interval, func() {
+		if _, err := r.Relay(); err != nil {
+			log.Printf("outbox: %v", err)
+		}
+	})
+}
+
+// Relay sends the events in the outbox to the sinks and returns the number
+// of relayed events.
+func (r *OutboxRelay) Relay() (int, error) {
+	relayed := 0
+	for {
+		events, err := r.store.GetOutboxEvents(outboxBatch)
+		if err != nil {
+			return relayed, err
+		}
+		for _, e := range events {
+			if err := r.send(e); err != nil {
+				parked, failErr := r.store.FailOutboxEvent(e.Id, r.attempts)
+				if failErr != nil {
+					return relayed, failErr
+				}
+				if !parked {
+					return relayed, err
+				}
+				log.Printf("outbox: %v, parked after %d attempts", err, r.attempts)
+				continue
+			}
+			if err := r.store.DeleteOutboxEvent(e.Id); err != nil {
+				return relayed, err
+			}
+			relayed++
+		}
+		if len(events) < outboxBatch {
+			return relayed, nil
+		}
+	}
+}
+
+// send sends the event to every sink and stops at the first failing one.
+func (r *OutboxRelay) send(e *models.OutboxEvent) error {
+	for _, sink := range r.sinks {
+		if err := sink.Send(e); err != nil {
+			return fmt.Errorf("relay %s %s: %w", e.Type, e.Key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package scheduler_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/outbox"
+	"github.com/EgorSkurihin/Hokku/scheduler"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboxRelay(t *testing.T) {
+	s := test_store.New()
+	_, err := s.CreateHokku(&models.Hokku{Title: "Example", Content: "1", OwnerId: 1, ThemeId: 1})
+	assert.NoError(t, err)
+	assert.NoError(t, s.DeleteHokku(1))
+	if !assert.Len(t, s.Outbox, 2) {
+		return
+	}
+	first, second := s.Outbox[0].Key, s.Outbox[1].Key
+
+	// A failing sink stops the relay and the event is sent to every sink
+	// again on the next run
+	taken, failed := []string{}, []string{}
+	fail := true
+	relay := scheduler.NewOutboxRelay(s, time.Minute, 3,
+		outbox.SinkFunc(func(e *models.OutboxEvent) error {
+			taken = append(taken, e.Key)
+			return nil
+		}),
+		outbox.SinkFunc(func(e *models.OutboxEvent) error {
+			if fail && e.Type == models.EventHokkuDeleted {
+				failed = append(failed, e.Key)
+				return errors.New("failed")
+			}
+			return nil
+		}))
+	n, err := relay.Relay()
+	assert.Error(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, s.Outbox, 1)
+	fail = false
+	n, err = relay.Relay()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Empty(t, s.Outbox)
+	assert.Equal(t, []string{first, second, second}, taken)
+	assert.Equal(t, []string{second}, failed)
+}
+
+func TestOutboxRelayParks(t *testing.T) {
+	s := test_store.New()
+	_, err := s.CreateHokku(&models.Hokku{Title: "Example", Content: "1", OwnerId: 1, ThemeId: 1})
+	assert.NoError(t, err)
+	assert.NoError(t, s.DeleteHokku(1))
+	if !assert.Len(t, s.Outbox, 2) {
+		return
+	}
+	first, second := s.Outbox[0].Key, s.Outbox[1].Key
+
+	// The first event stops the relay until it failed twice, then it is
+	// parked and the second one goes on
+	taken := []string{}
+	relay := scheduler.NewOutboxRelay(s, time.Minute, 2,
+		outbox.SinkFunc(func(e *models.OutboxEvent) error {
+			if e.Key == first {
+				return errors.New("failed")
+			}
+			taken = append(taken, e.Key)
+			return nil
+		}))
+	n, err := relay.Relay()
+	assert.Error(t, err)
+	assert.Equal(t, 0, n)
+	assert.Empty(t, taken)
+	n, err = relay.Relay()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{second}, taken)
+	if assert.Len(t, s.Outbox, 1) {
+		assert.Equal(t, first, s.Outbox[0].Key)
+		assert.Equal(t, 2, s.Outbox[0].Attempts)
+		assert.True(t, s.Outbox[0].Parked)
+	}
+
+	// Parked events are not relayed again
+	n, err = relay.Relay()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
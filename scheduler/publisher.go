@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/store"
+)
+
+// Publisher periodically publishes scheduled hokkus whose publication
+// time has come.
+type Publisher struct {
+	store    store.Store
+	clock    clock.Clock
+	interval time.Duration
+}
+
+func NewPublisher(store store.Store, clock clock.Clock, interval time.Duration) *Publisher {
+	return &Publisher{
+		store:    store,
+		clock:    clock,
+		interval: interval,
+	}
+}
+
+// Run publishes due hokkus every interval until ctx is cancelled.
+func (p *Publisher) Run(ctx context.Context) {
+	every(ctx, p.interval, func() {
+		if _, err := p.PublishDue(); err != nil {
+			log.Printf("publisher: %v", err)
+		}
+	})
+}
+
+// PublishDue publishes the hokkus scheduled up to the current time and
+// returns their number.
+func (p *Publisher) PublishDue() (int, error) {
+	return p.store.PublishScheduled(p.clock.Now())
+}
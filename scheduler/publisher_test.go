@@ -0,0 +1,58 @@
+package scheduler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/scheduler"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishDue(t *testing.T) {
+	s := test_store.New()
+	clk := clock.NewMock(test_store.ScheduledAt.Add(-time.Hour))
+	p := scheduler.NewPublisher(s, clk, time.Minute)
+
+	n, err := p.PublishDue()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	h, _ := s.GetHokku(1, 7)
+	assert.Equal(t, models.StatusScheduled, h.Status)
+
+	clk.Add(time.Hour)
+	n, err = p.PublishDue()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	h, _ = s.GetHokku(1, 7)
+	assert.Equal(t, models.StatusPublished, h.Status)
+	assert.Equal(t, test_store.ScheduledAt, h.Created)
+	assert.Nil(t, h.PublishAt)
+
+	n, err = p.PublishDue()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestPublisherRun(t *testing.T) {
+	s := test_store.New()
+	clk := clock.NewMock(test_store.ScheduledAt)
+	p := scheduler.NewPublisher(s, clk, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+	// Run publishes once right away, before waiting for the first tick
+	assert.Eventually(t, func() bool {
+		hs, _ := s.GetDrafts(1, 0, 0)
+		return len(hs) == 1
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+}
@@ -0,0 +1,42 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/store"
+)
+
+// Purger periodically removes users and hokkus that stayed in the trash
+// longer than the retention period.
+type Purger struct {
+	store     store.Store
+	clock     clock.Clock
+	interval  time.Duration
+	retention time.Duration
+}
+
+func NewPurger(store store.Store, clock clock.Clock, interval, retention time.Duration) *Purger {
+	return &Purger{
+		store:     store,
+		clock:     clock,
+		interval:  interval,
+		retention: retention,
+	}
+}
+
+// Run purges expired items every interval until ctx is cancelled.
+func (p *Purger) Run(ctx context.Context) {
+	every(ctx, p.interval, func() {
+		if _, err := p.PurgeExpired(); err != nil {
+			log.Printf("purger: %v", err)
+		}
+	})
+}
+
+// PurgeExpired permanently removes expired items and returns their number.
+func (p *Purger) PurgeExpired() (int, error) {
+	return p.store.PurgeDeleted(p.clock.Now().Add(-p.retention))
+}
@@ -0,0 +1,33 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/scheduler"
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeExpired(t *testing.T) {
+	s := test_store.New()
+	assert.NoError(t, s.DeleteHokku(1))
+	h := s.Hokkus[0]
+	clk := clock.NewMock(h.DeletedAt.Add(time.Hour))
+	p := scheduler.NewPurger(s, clk, time.Minute, 24*time.Hour)
+
+	n, err := p.PurgeExpired()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.NoError(t, s.RestoreHokku(h.OwnerId, h.Id, clk.Now().Add(-24*time.Hour)))
+	assert.NoError(t, s.DeleteHokku(1))
+
+	clk.Add(24 * time.Hour)
+	n, err = p.PurgeExpired()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	err = s.RestoreHokku(h.OwnerId, h.Id, time.Time{})
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+}
@@ -0,0 +1,53 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+)
+
+// Ranker periodically scores hokkus by the reactions to them for the
+// trending and top lists.
+type Ranker struct {
+	store    store.Store
+	clock    clock.Clock
+	interval time.Duration
+	weights  models.RankingWeights
+}
+
+func NewRanker(store store.Store, clock clock.Clock, interval time.Duration, weights models.RankingWeights) *Ranker {
+	return &Ranker{
+		store:    store,
+		clock:    clock,
+		interval: interval,
+		weights:  weights,
+	}
+}
+
+// Run refreshes the scores every interval until ctx is cancelled.
+func (r *Ranker) Run(ctx context.Context) {
+	every(ctx, r.interval, func() {
+		if _, err := r.Refresh(); err != nil {
+			log.Printf("ranker: %v", err)
+		}
+	})
+}
+
+// Refresh scores the public hokkus at the current time, saves the scores
+// and returns their number.
+func (r *Ranker) Refresh() (int, error) {
+	engagement, err := r.store.GetEngagement()
+	if err != nil {
+		return 0, err
+	}
+	now := r.clock.Now()
+	scores := make([]*models.HokkuScore, 0, len(engagement))
+	for _, e := range engagement {
+		scores = append(scores, r.weights.Score(e, now))
+	}
+	return len(scores), r.store.SaveScores(scores)
+}
@@ -0,0 +1,34 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/scheduler"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRankerRefresh(t *testing.T) {
+	s := test_store.New()
+	clk := clock.NewMock(time.Date(2030, time.March, 1, 0, 0, 0, 0, time.UTC))
+	weights := models.RankingWeights{Like: 1, Comment: 2, Bookmark: 3, View: 0.1}
+	r := scheduler.NewRanker(s, clk, time.Minute, weights)
+
+	n, err := r.Refresh()
+	assert.NoError(t, err)
+	ids, _ := s.GetPublicHokkuIds()
+	assert.Equal(t, len(ids), n)
+
+	top, err := s.GetTopHokkus(0, time.Time{}, 3, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, top, 3) {
+		// Hokku 1 has two likes and two comments, hokku 3 is bookmarked
+		// and hokku 4 is viewed 15 times
+		assert.Equal(t, 1, top[0].Id)
+		assert.Equal(t, 3, top[1].Id)
+		assert.Equal(t, 4, top[2].Id)
+	}
+}
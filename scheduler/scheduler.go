@@ -0,0 +1,23 @@
+// Package scheduler contains the background jobs run inside the server
+// process.
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+// every calls job right away and then every interval until ctx is
+// cancelled.
+func every(ctx context.Context, interval time.Duration, job func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+)
+
+type viewKey struct {
+	hokkuId int
+	viewer  string
+}
+
+type dayKey struct {
+	hokkuId int
+	day     string
+}
+
+// ViewCounter counts hokku views in memory and periodically adds them to
+// the store, so reading a hokku doesn't write to the store. A viewer is
+// counted once per hokku within the window.
+type ViewCounter struct {
+	store    store.Store
+	clock    clock.Clock
+	interval time.Duration
+	window   time.Duration
+	buffer   int
+	full     chan struct{}
+
+	mu      sync.Mutex
+	seen    map[viewKey]time.Time
+	pending map[dayKey]int
+}
+
+// NewViewCounter returns a counter flushed every interval or as soon as
+// views of buffer hokkus and days are pending.
+func NewViewCounter(store store.Store, clock clock.Clock, interval, window time.Duration, buffer int) *ViewCounter {
+	return &ViewCounter{
+		store:    store,
+		clock:    clock,
+		interval: interval,
+		window:   window,
+		buffer:   buffer,
+		full:     make(chan struct{}, 1),
+		seen:     map[viewKey]time.Time{},
+		pending:  map[dayKey]int{},
+	}
+}
+
+// Count registers a view of the hokku by the viewer, a user or an address,
+// and reports whether it is counted.
+func (v *ViewCounter) Count(hokkuId int, viewer string) bool {
+	now := v.clock.Now()
+	key := viewKey{hokkuId, viewer}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if last, ok := v.seen[key]; ok && now.Sub(last) < v.window {
+		return false
+	}
+	v.seen[key] = now
+	v.pending[dayKey{hokkuId, now.UTC().Format(models.DayLayout)}]++
+	if len(v.pending) >= v.buffer {
+		select {
+		case v.full <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+// Run flushes the views every interval or when the buffer is full until
+// ctx is cancelled, then flushes the rest.
+func (v *ViewCounter) Run(ctx context.Context) {
+	ticker := time.NewTicker(v.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if _, err := v.Flush(); err != nil {
+				log.Printf("views: %v", err)
+			}
+			return
+		case <-ticker.C:
+		case <-v.full:
+		}
+		if _, err := v.Flush(); err != nil {
+			log.Printf("views: %v", err)
+		}
+	}
+}
+
+// Flush adds the pending views to the store and returns the number of
+// the added daily views. Views that failed to be added are kept for the
+// next flush.
+func (v *ViewCounter) Flush() (int, error) {
+	now := v.clock.Now()
+	v.mu.Lock()
+	pending := v.pending
+	v.pending = map[dayKey]int{}
+	for key, last := range v.seen {
+		if now.Sub(last) >= v.window {
+			delete(v.seen, key)
+		}
+	}
+	v.mu.Unlock()
+
+	views := make([]*models.DailyViews, 0, len(pending))
+	for key, n := range pending {
+		views = append(views, &models.DailyViews{HokkuId: key.hokkuId, Day: key.day, Views: n})
+	}
+	sort.Slice(views, func(i, j int) bool {
+		if views[i].HokkuId != views[j].HokkuId {
+			return views[i].HokkuId < views[j].HokkuId
+		}
+		return views[i].Day < views[j].Day
+	})
+	if err := v.store.AddViews(views); err != nil {
+		v.mu.Lock()
+		for key, n := range pending {
+			v.pending[key] += n
+		}
+		v.mu.Unlock()
+		return 0, err
+	}
+	return len(views), nil
+}
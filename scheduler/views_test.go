@@ -0,0 +1,93 @@
+package scheduler_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/scheduler"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViewCounter(t *testing.T) {
+	s := test_store.New()
+	clk := clock.NewMock(time.Date(2030, time.January, 2, 23, 50, 0, 0, time.UTC))
+	v := scheduler.NewViewCounter(s, clk, time.Minute, 30*time.Minute, 100)
+
+	assert.True(t, v.Count(4, "user:1"))
+	assert.False(t, v.Count(4, "user:1"))
+	assert.True(t, v.Count(4, "ip:10.0.0.1"))
+	assert.True(t, v.Count(3, "user:1"))
+	// The next day
+	clk.Add(20 * time.Minute)
+	assert.False(t, v.Count(4, "user:1"))
+	clk.Add(20 * time.Minute)
+	assert.True(t, v.Count(4, "user:1"))
+	assert.True(t, v.Count(100, "user:1"))
+
+	n, err := v.Flush()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	views, _ := s.GetViews(4)
+	assert.Equal(t, []*models.DailyViews{
+		{HokkuId: 4, Day: "2030-01-01", Views: 10},
+		{HokkuId: 4, Day: "2030-01-02", Views: 7},
+		{HokkuId: 4, Day: "2030-01-03", Views: 1},
+	}, views)
+	views, _ = s.GetViews(3)
+	assert.Len(t, views, 1)
+	views, _ = s.GetViews(100)
+	assert.Empty(t, views)
+
+	n, err = v.Flush()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+// lockedStore lets the test read the views while the counter adds them.
+type lockedStore struct {
+	*test_store.TestStore
+	mu sync.Mutex
+}
+
+func (s *lockedStore) AddViews(views []*models.DailyViews) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.TestStore.AddViews(views)
+}
+
+func (s *lockedStore) GetViews(hokkuId int) ([]*models.DailyViews, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.TestStore.GetViews(hokkuId)
+}
+
+func TestViewCounterFlushesFullBuffer(t *testing.T) {
+	s := &lockedStore{TestStore: test_store.New()}
+	clk := clock.NewMock(time.Date(2030, time.January, 2, 0, 0, 0, 0, time.UTC))
+	v := scheduler.NewViewCounter(s, clk, time.Hour, time.Minute, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		v.Run(ctx)
+		close(done)
+	}()
+
+	v.Count(1, "user:2")
+	v.Count(2, "user:2")
+	assert.Eventually(t, func() bool {
+		views, _ := s.GetViews(2)
+		return len(views) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// The rest is flushed on exit
+	v.Count(3, "user:2")
+	cancel()
+	<-done
+	views, _ := s.GetViews(3)
+	assert.Len(t, views, 1)
+}
@@ -0,0 +1,185 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+)
+
+const (
+	// webhookBatch is the number of deliveries sent on every run
+	webhookBatch = 100
+	// maxWebhookBackoff caps the wait between attempts
+	maxWebhookBackoff = 24 * time.Hour
+)
+
+// Errors kept in delivery logs. The details are only logged, since they
+// would tell the owners of webhooks about the network of the site.
+var (
+	errDeliveryUnreachable = errors.New("the endpoint could not be reached")
+	errDeliveryStatus      = errors.New("the endpoint answered with an error status")
+)
+
+// WebhookSender periodically sends the queued webhook deliveries. Failed
+// deliveries are retried after backoff, doubled on every attempt, until
+// they run out of attempts.
+type WebhookSender struct {
+	store    store.Store
+	clock    clock.Clock
+	interval time.Duration
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+}
+
+func NewWebhookSender(store store.Store, clock clock.Clock, interval time.Duration, client *http.Client,
+	attempts int, backoff time.Duration) *WebhookSender {
+	return &WebhookSender{
+		store:    store,
+		clock:    clock,
+		interval: interval,
+		client:   client,
+		attempts: attempts,
+		backoff:  backoff,
+	}
+}
+
+// NewWebhookClient returns the client for webhook deliveries. It connects
+// only to public addresses, checked when dialling so hosts can't resolve to
+// internal addresses after the webhook was created, and doesn't follow
+// redirects.
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !models.PublicIP(ip) {
+				return fmt.Errorf("dial %s: internal address", address)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Run sends due deliveries every interval until ctx is cancelled.
+func (w *WebhookSender) Run(ctx context.Context) {
+	every(ctx, w.interval, func() {
+		if _, err := w.SendDue(); err != nil {
+			log.Printf("webhooks: %v", err)
+		}
+	})
+}
+
+// SendDue sends the deliveries due at the current time and returns the
+// number of the delivered ones.
+func (w *WebhookSender) SendDue() (int, error) {
+	deliveries, err := w.store.GetDueWebhookDeliveries(w.clock.Now(), webhookBatch)
+	if err != nil {
+		return 0, err
+	}
+	hooks := map[int]*models.Webhook{}
+	delivered := 0
+	for _, d := range deliveries {
+		hook, ok := hooks[d.WebhookId]
+		if !ok {
+			hook, err = w.store.GetWebhook(d.WebhookId)
+			if errors.Is(err, store.ErrNoRecord) {
+				// Deliveries of deleted webhooks are deleted along with them
+				continue
+			}
+			if err != nil {
+				return delivered, err
+			}
+			hooks[d.WebhookId] = hook
+		}
+		w.attempt(hook, d)
+		if err := w.store.UpdateWebhookDelivery(d); err != nil {
+			return delivered, err
+		}
+		if d.Status == models.DeliveryDelivered {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// attempt sends the delivery once and records the outcome.
+func (w *WebhookSender) attempt(hook *models.Webhook, d *models.WebhookDelivery) {
+	now := w.clock.Now()
+	d.Attempts++
+	d.StatusCode, d.Error = 0, ""
+	err := w.post(hook, d, now)
+	if err == nil {
+		d.Status, d.Delivered = models.DeliveryDelivered, &now
+		return
+	}
+	if !errors.Is(err, errDeliveryStatus) {
+		log.Printf("webhooks: delivery %d: %v", d.Id, err)
+		err = errDeliveryUnreachable
+	}
+	d.Error = err.Error()
+	if d.Attempts >= w.attempts {
+		d.Status = models.DeliveryFailed
+		return
+	}
+	backoff := w.backoff << (d.Attempts - 1)
+	if backoff > maxWebhookBackoff || backoff <= 0 {
+		backoff = maxWebhookBackoff
+	}
+	d.NextAttempt = now.Add(backoff)
+}
+
+// post sends the signed payload and fails with errDeliveryStatus unless the
+// endpoint answers with a 2xx status. Redirects are failures too.
+func (w *WebhookSender) post(hook *models.Webhook, d *models.WebhookDelivery, now time.Time) error {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Hokku-Webhooks")
+	req.Header.Set("X-Hokku-Event", d.Event)
+	req.Header.Set("X-Hokku-Delivery", strconv.Itoa(d.Id))
+	req.Header.Set("X-Hokku-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Hokku-Signature", hook.Sign(timestamp, d.Payload))
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain a bit of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	d.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errDeliveryStatus
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package scheduler_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/clock"
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/scheduler"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/stretchr/testify/assert"
+)
+
+// receiver records the requests of the webhook sender and answers with
+// the queued statuses, then with 204.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	body, _ := io.ReadAll(req.Body)
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	status := http.StatusNoContent
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func TestSendDueWebhooks(t *testing.T) {
+	rcv := &receiver{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway}}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+	s := test_store.New()
+	clk := clock.NewMock(time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC))
+	hook := &models.Webhook{OwnerId: 1, URL: srv.URL, Events: []string{models.WebhookHokkuCreated}, Secret: "secret"}
+	_, err := s.CreateWebhook(hook)
+	assert.NoError(t, err)
+	_, err = s.CreateWebhook(&models.Webhook{OwnerId: 2, URL: srv.URL, Events: []string{models.WebhookUserCreated}, Secret: "other"})
+	assert.NoError(t, err)
+	n, err := s.EnqueueWebhookDeliveries("key", models.WebhookHokkuCreated, []byte(`{"id":1}`), clk.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	sender := scheduler.NewWebhookSender(s, clk, time.Minute, srv.Client(), 3, time.Minute)
+
+	// The first attempt fails and is retried after the backoff
+	delivered, err := sender.SendDue()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	d := s.WebhookDeliveries[0]
+	assert.Equal(t, models.DeliveryPending, d.Status)
+	assert.Equal(t, http.StatusInternalServerError, d.StatusCode)
+	assert.Equal(t, clk.Now().Add(time.Minute), d.NextAttempt)
+	delivered, err = sender.SendDue()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	assert.Len(t, rcv.requests, 1)
+
+	// The backoff doubles
+	clk.Add(time.Minute)
+	_, err = sender.SendDue()
+	assert.NoError(t, err)
+	d = s.WebhookDeliveries[0]
+	assert.Equal(t, 2, d.Attempts)
+	assert.Equal(t, clk.Now().Add(2*time.Minute), d.NextAttempt)
+
+	clk.Add(2 * time.Minute)
+	delivered, err = sender.SendDue()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	d = s.WebhookDeliveries[0]
+	assert.Equal(t, models.DeliveryDelivered, d.Status)
+	assert.Equal(t, http.StatusNoContent, d.StatusCode)
+	assert.Empty(t, d.Error)
+	assert.Equal(t, clk.Now(), *d.Delivered)
+
+	if assert.Len(t, rcv.requests, 3) {
+		req := rcv.requests[2]
+		assert.Equal(t, `{"id":1}`, string(rcv.bodies[2]))
+		assert.Equal(t, models.WebhookHokkuCreated, req.Header.Get("X-Hokku-Event"))
+		assert.Equal(t, strconv.Itoa(d.Id), req.Header.Get("X-Hokku-Delivery"))
+		timestamp, err := strconv.ParseInt(req.Header.Get("X-Hokku-Timestamp"), 10, 64)
+		assert.NoError(t, err)
+		assert.Equal(t, clk.Now().Unix(), timestamp)
+		assert.Equal(t, hook.Sign(timestamp, rcv.bodies[2]), req.Header.Get("X-Hokku-Signature"))
+	}
+}
+
+func TestWebhookDeliveryFails(t *testing.T) {
+	rcv := &receiver{statuses: []int{http.StatusNotFound, http.StatusNotFound}}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+	s := test_store.New()
+	clk := clock.NewMock(time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC))
+	_, err := s.CreateWebhook(&models.Webhook{OwnerId: 1, URL: srv.URL, Events: []string{models.WebhookUserCreated}, Secret: "secret"})
+	assert.NoError(t, err)
+	_, err = s.EnqueueWebhookDeliveries("key", models.WebhookUserCreated, []byte(`{}`), clk.Now())
+	assert.NoError(t, err)
+	sender := scheduler.NewWebhookSender(s, clk, time.Minute, srv.Client(), 2, time.Second)
+	for i := 0; i < 3; i++ {
+		_, err := sender.SendDue()
+		assert.NoError(t, err)
+		clk.Add(time.Hour)
+	}
+	d := s.WebhookDeliveries[0]
+	assert.Equal(t, models.DeliveryFailed, d.Status)
+	assert.Equal(t, 2, d.Attempts)
+	assert.Equal(t, http.StatusNotFound, d.StatusCode)
+	assert.Equal(t, "the endpoint answered with an error status", d.Error)
+	assert.Len(t, rcv.requests, 2)
+}
+
+func TestWebhookClient(t *testing.T) {
+	rcv := &receiver{}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+	s := test_store.New()
+	clk := clock.NewMock(time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC))
+	_, err := s.CreateWebhook(&models.Webhook{OwnerId: 1, URL: srv.URL, Events: []string{models.WebhookUserCreated}, Secret: "secret"})
+	assert.NoError(t, err)
+	_, err = s.EnqueueWebhookDeliveries("key", models.WebhookUserCreated, []byte(`{}`), clk.Now())
+	assert.NoError(t, err)
+
+	// The test server listens on a loopback address
+	sender := scheduler.NewWebhookSender(s, clk, time.Minute, scheduler.NewWebhookClient(time.Second), 1, time.Second)
+	_, err = sender.SendDue()
+	assert.NoError(t, err)
+	d := s.WebhookDeliveries[0]
+	assert.Equal(t, models.DeliveryFailed, d.Status)
+	assert.Equal(t, 0, d.StatusCode)
+	assert.Equal(t, "the endpoint could not be reached", d.Error)
+	assert.Empty(t, rcv.requests)
+}
+
+func TestWebhookRedirect(t *testing.T) {
+	target := &receiver{}
+	dst := httptest.NewServer(target)
+	defer dst.Close()
+	srv := httptest.NewServer(http.RedirectHandler(dst.URL, http.StatusFound))
+	defer srv.Close()
+	s := test_store.New()
+	clk := clock.NewMock(time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC))
+	_, err := s.CreateWebhook(&models.Webhook{OwnerId: 1, URL: srv.URL, Events: []string{models.WebhookUserCreated}, Secret: "secret"})
+	assert.NoError(t, err)
+	_, err = s.EnqueueWebhookDeliveries("key", models.WebhookUserCreated, []byte(`{}`), clk.Now())
+	assert.NoError(t, err)
+	client := srv.Client()
+	client.CheckRedirect = scheduler.NewWebhookClient(time.Second).CheckRedirect
+	sender := scheduler.NewWebhookSender(s, clk, time.Minute, client, 1, time.Second)
+	_, err = sender.SendDue()
+	assert.NoError(t, err)
+	d := s.WebhookDeliveries[0]
+	assert.Equal(t, models.DeliveryFailed, d.Status)
+	assert.Equal(t, http.StatusFound, d.StatusCode)
+	assert.Empty(t, target.requests)
+}
@@ -0,0 +1,185 @@
+// Package similar recommends hokkus similar to a given one. Hokkus are
+// compared by TF-IDF vectors of the stems of their words, their theme and
+// tags, the index is kept in memory.
+package similar
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/EgorSkurihin/Hokku/models"
+)
+
+// featureWeight is how many times the theme and every tag count in a
+// vector compared to a word.
+const featureWeight = 2
+
+// Match is an indexed hokku and its cosine similarity to the compared one.
+type Match struct {
+	HokkuId int
+	Score   float64
+}
+
+// Index keeps the term frequencies of the indexed hokkus. The vector norms
+// depend on the whole index, they are recalculated lazily after changes.
+type Index struct {
+	mu       sync.Mutex
+	docs     map[int]map[string]float64
+	postings map[string]map[int]bool
+	norms    map[int]float64
+	dirty    bool
+}
+
+func NewIndex() *Index {
+	return &Index{
+		docs:     map[int]map[string]float64{},
+		postings: map[string]map[int]bool{},
+		norms:    map[int]float64{},
+	}
+}
+
+// terms counts the terms of the hokku: the stems of the title and the
+// content words, the theme and the tags.
+func terms(h *models.Hokku) map[string]float64 {
+	tf := map[string]float64{}
+	for _, t := range Tokens(h.Title + "\n" + h.Content) {
+		tf[t]++
+	}
+	if h.ThemeId != 0 {
+		tf["theme:"+strconv.Itoa(h.ThemeId)] += featureWeight
+	}
+	for _, tag := range h.Tags {
+		tf["tag:"+tag] += featureWeight
+	}
+	return tf
+}
+
+// Add indexes the hokku replacing its previous version.
+func (ix *Index) Add(h *models.Hokku) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(h.Id)
+	tf := terms(h)
+	ix.docs[h.Id] = tf
+	for t := range tf {
+		if ix.postings[t] == nil {
+			ix.postings[t] = map[int]bool{}
+		}
+		ix.postings[t][h.Id] = true
+	}
+	ix.dirty = true
+}
+
+// Remove drops the hokku from the index.
+func (ix *Index) Remove(id int) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.remove(id)
+}
+
+func (ix *Index) remove(id int) {
+	tf, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for t := range tf {
+		delete(ix.postings[t], id)
+		if len(ix.postings[t]) == 0 {
+			delete(ix.postings, t)
+		}
+	}
+	delete(ix.docs, id)
+	delete(ix.norms, id)
+	ix.dirty = true
+}
+
+// Reset replaces the indexed hokkus.
+func (ix *Index) Reset(hs []*models.Hokku) {
+	fresh := NewIndex()
+	for _, h := range hs {
+		fresh.Add(h)
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.docs, ix.postings, ix.norms, ix.dirty = fresh.docs, fresh.postings, fresh.norms, true
+}
+
+// ThemeHokkus returns the ids of the indexed hokkus of the theme.
+func (ix *Index) ThemeHokkus(themeId int) []int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ids := []int{}
+	for id := range ix.postings["theme:"+strconv.Itoa(themeId)] {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Len returns the number of indexed hokkus.
+func (ix *Index) Len() int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return len(ix.docs)
+}
+
+func (ix *Index) idf(term string) float64 {
+	return math.Log(float64(1+len(ix.docs))/float64(1+len(ix.postings[term]))) + 1
+}
+
+func (ix *Index) weight(term string, tf float64) float64 {
+	return (1 + math.Log(tf)) * ix.idf(term)
+}
+
+func (ix *Index) updateNorms() {
+	if !ix.dirty {
+		return
+	}
+	for id, tf := range ix.docs {
+		sum := 0.0
+		for t, n := range tf {
+			w := ix.weight(t, n)
+			sum += w * w
+		}
+		ix.norms[id] = math.Sqrt(sum)
+	}
+	ix.dirty = false
+}
+
+// Similar returns at most limit indexed hokkus sharing terms with the
+// hokku, from the most similar one, or all of them when limit is 0. The
+// hokku itself is skipped, it doesn't have to be indexed.
+func (ix *Index) Similar(h *models.Hokku, limit int) []Match {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.updateNorms()
+
+	dots := map[int]float64{}
+	queryNorm := 0.0
+	for t, n := range terms(h) {
+		w := ix.weight(t, n)
+		queryNorm += w * w
+		for id := range ix.postings[t] {
+			dots[id] += w * ix.weight(t, ix.docs[id][t])
+		}
+	}
+	queryNorm = math.Sqrt(queryNorm)
+	matches := []Match{}
+	for id, dot := range dots {
+		if id == h.Id || ix.norms[id] == 0 {
+			continue
+		}
+		matches = append(matches, Match{HokkuId: id, Score: dot / (queryNorm * ix.norms[id])})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].HokkuId > matches[j].HokkuId
+	})
+	if limit > 0 && limit < len(matches) {
+		matches = matches[:limit]
+	}
+	return matches
+}
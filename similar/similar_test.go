@@ -0,0 +1,158 @@
+package similar_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/similar"
+	"github.com/EgorSkurihin/Hokku/store"
+	"github.com/EgorSkurihin/Hokku/store/test_store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStem(t *testing.T) {
+	cases := []struct {
+		words []string
+		stem  string
+	}{
+		{[]string{"ворона", "вороны", "вороном", "ворон"}, "ворон"},
+		{[]string{"снега", "снегом", "снег"}, "снег"},
+		{[]string{"летний", "летнего", "летнему"}, "летн"},
+		{[]string{"falling", "falls", "falled"}, "fall"},
+		{[]string{"flowers", "flower"}, "flower"},
+		{[]string{"cherries", "cherry"}, "cherry"},
+		{[]string{"moss"}, "moss"},
+	}
+	for _, cs := range cases {
+		for _, w := range cs.words {
+			assert.Equal(t, cs.stem, similar.Stem(w), w)
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	assert.Equal(t, []string{"old", "pond", "лягушк", "прыгнул"},
+		similar.Tokens("The old pond, a лягушка прыгнула"))
+}
+
+func TestIndexSimilar(t *testing.T) {
+	ix := similar.NewIndex()
+	ix.Add(&models.Hokku{Id: 1, Title: "Осень", Content: "На голой ветке\nВорон сидит одиноко\nОсенний вечер", ThemeId: 1})
+	ix.Add(&models.Hokku{Id: 2, Title: "Вороны", Content: "Вороны на ветках\nКричат в осеннем тумане", ThemeId: 1})
+	ix.Add(&models.Hokku{Id: 3, Title: "Spring", Content: "Cherry blossoms fall\nOn the quiet pond", ThemeId: 2, Tags: []string{"spring"}})
+	ix.Add(&models.Hokku{Id: 4, Title: "Blossom", Content: "Blossoming cherry\nPetals falling", ThemeId: 3, Tags: []string{"spring"}})
+	assert.Equal(t, 4, ix.Len())
+
+	matches := ix.Similar(&models.Hokku{Id: 1, Title: "Осень", Content: "На голой ветке\nВорон сидит одиноко\nОсенний вечер", ThemeId: 1}, 10)
+	if assert.Len(t, matches, 1) {
+		assert.Equal(t, 2, matches[0].HokkuId)
+		assert.True(t, matches[0].Score > 0 && matches[0].Score < 1)
+	}
+	// A hokku that is not indexed
+	matches = ix.Similar(&models.Hokku{Title: "Cherry", Content: "Cherry blossom petals", ThemeId: 2}, 1)
+	if assert.Len(t, matches, 1) {
+		assert.Equal(t, 3, matches[0].HokkuId)
+	}
+	assert.Len(t, ix.Similar(&models.Hokku{Title: "Cherry", Content: "Cherry blossom petals", ThemeId: 2}, 0), 2)
+	matches = ix.Similar(&models.Hokku{Id: 3, Title: "Spring", Content: "Cherry blossoms fall\nOn the quiet pond", ThemeId: 2, Tags: []string{"spring"}}, 10)
+	if assert.Len(t, matches, 1) {
+		assert.Equal(t, 4, matches[0].HokkuId)
+	}
+
+	ix.Remove(4)
+	assert.Empty(t, ix.Similar(&models.Hokku{Id: 3, Title: "Spring", Content: "Cherry blossoms fall", ThemeId: 2}, 10))
+}
+
+func TestStoreKeepsIndex(t *testing.T) {
+	ix := similar.NewIndex()
+	s := similar.NewStore(test_store.New(), ix)
+	assert.NoError(t, s.Rebuild())
+	ids, _ := s.GetPublicHokkuIds()
+	assert.Equal(t, len(ids), ix.Len())
+
+	h := &models.Hokku{Title: "Title", Content: "Content", OwnerId: 1, ThemeId: 1, Created: time.Now(),
+		Status: models.StatusPublished, Visibility: models.VisibilityPublic}
+	id, err := s.CreateHokku(h)
+	assert.NoError(t, err)
+	assert.Equal(t, len(ids)+1, ix.Len())
+
+	h.Id, h.Visibility = id, models.VisibilityPrivate
+	assert.NoError(t, s.UpdateHokku(h))
+	assert.Equal(t, len(ids), ix.Len())
+
+	assert.NoError(t, s.DeleteHokku(1))
+	assert.Equal(t, len(ids)-1, ix.Len())
+	assert.NoError(t, s.RestoreHokku(1, 1, time.Time{}))
+	assert.Equal(t, len(ids), ix.Len())
+
+	// Shadow-banned users are left out until the ban is lifted
+	listed, err := s.GetHokkusByAuthor(0, 1, 100, 0)
+	assert.NoError(t, err)
+	banId, err := s.CreateRestriction(&models.Restriction{UserId: 1, Kind: models.RestrictionShadowBan, Reason: "Spam"})
+	assert.NoError(t, err)
+	assert.Equal(t, len(ids)-len(listed), ix.Len())
+	assert.NoError(t, s.LiftRestriction(banId, 3))
+	assert.Equal(t, len(ids), ix.Len())
+}
+
+func TestStoreWithTx(t *testing.T) {
+	ix := similar.NewIndex()
+	s := similar.NewStore(test_store.New(), ix)
+	assert.NoError(t, s.Rebuild())
+	ids, _ := s.GetPublicHokkuIds()
+
+	// Dropped changes leave the store and the index as they were
+	err := s.WithTx(context.Background(), func(tx store.Store) error {
+		if err := tx.DeleteHokku(1); err != nil {
+			return err
+		}
+		return tx.DeleteHokku(1000)
+	})
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+	_, err = s.GetHokku(0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, len(ids), ix.Len())
+	assert.Panics(t, func() {
+		s.WithTx(context.Background(), func(tx store.Store) error {
+			tx.DeleteHokku(1)
+			panic("failed")
+		})
+	})
+	_, err = s.GetHokku(0, 1)
+	assert.NoError(t, err)
+
+	// Kept changes are indexed
+	err = s.WithTx(context.Background(), func(tx store.Store) error {
+		if err := tx.DeleteHokku(1); err != nil {
+			return err
+		}
+		return tx.HideHokku(4)
+	})
+	assert.NoError(t, err)
+	_, err = s.GetHokku(0, 1)
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+	assert.Equal(t, len(ids)-2, ix.Len())
+
+	// Units of work changing nothing don't rebuild the index
+	ix.Add(&models.Hokku{Id: 1000, Title: "Stale", Content: "Stale", ThemeId: 1})
+	assert.NoError(t, s.WithTx(context.Background(), func(tx store.Store) error { return nil }))
+	assert.Equal(t, len(ids)-1, ix.Len())
+
+	// Moved hokkus are reindexed
+	err = s.WithTx(context.Background(), func(tx store.Store) error {
+		_, err := tx.MoveHokkus(1, 2)
+		return err
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, ix.ThemeHokkus(1))
+	assert.Contains(t, ix.ThemeHokkus(2), 3)
+	assert.Equal(t, len(ids)-2, ix.Len())
+
+	// So are the hokkus of hidden users
+	assert.NoError(t, s.HideUser(2))
+	assert.NotContains(t, ix.ThemeHokkus(2), 2)
+	assert.NoError(t, s.UnhideUser(2))
+	assert.Contains(t, ix.ThemeHokkus(2), 2)
+}
@@ -0,0 +1,103 @@
+package similar
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/EgorSkurihin/Hokku/prosody"
+)
+
+// minStem is the shortest stem in runes an ending is cut to.
+const minStem = 3
+
+var stopWords = toSet(`
+a an and are as at be but by for from has have he her his i in is it its
+me my no not of on or our she so that the their them then there they this
+to was we were what when where which who will with you your
+а без бы в во вот вы да для до его её ее ей ему если есть же за и из или
+им их к как ко когда кто ли мне мы на над не нет ни но ну о об он она они
+оно от по под при с со так там то тоже ты у уж уже чем что чтобы эта это
+этот я
+`)
+
+// Endings are tried from the longest one.
+var englishEndings = []struct{ suffix, replacement string }{
+	{"ational", "ate"}, {"ization", "ize"}, {"fulness", "ful"}, {"ousness", "ous"},
+	{"iveness", "ive"}, {"ingly", ""}, {"ments", ""}, {"ness", ""}, {"ment", ""},
+	{"edly", ""}, {"ing", ""}, {"ies", "y"}, {"ied", "y"}, {"ed", ""}, {"ly", ""},
+	{"es", ""}, {"s", ""},
+}
+
+var russianEndings = sortByLength(strings.Fields(`
+	ивши ывши авши явши ующий ющий ащий ящий ость ости ться тся
+	ями ами ого его ому ему ыми ими ией иям иях ешь ишь ете ите ает яет ует
+	ают яют уют ала ила ыла ела ать ять еть ить уть
+	ой ей ый ий ая яя ое ее ые ие ую юю ом ем ах ях ам ям ов ев ью ия ии
+	ть
+	а я о е ы и у ю ь й
+`))
+
+func toSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+func sortByLength(endings []string) []string {
+	sort.SliceStable(endings, func(i, j int) bool {
+		return utf8.RuneCountInString(endings[i]) > utf8.RuneCountInString(endings[j])
+	})
+	return endings
+}
+
+// Tokens splits the text into stems of the words that are not stop words.
+func Tokens(text string) []string {
+	res := []string{}
+	for _, w := range prosody.Words(text) {
+		w = strings.ReplaceAll(w, "ё", "е")
+		if utf8.RuneCountInString(w) < 2 || stopWords[w] {
+			continue
+		}
+		res = append(res, Stem(w))
+	}
+	return res
+}
+
+// Stem cuts the inflectional ending of a lower-cased word. Words written
+// in Cyrillic are stemmed as Russian, the others as English.
+func Stem(word string) string {
+	for _, r := range word {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return stemRussian(word)
+		}
+	}
+	return stemEnglish(word)
+}
+
+func stemEnglish(word string) string {
+	word = strings.TrimSuffix(strings.TrimSuffix(word, "'s"), "’s")
+	if strings.HasSuffix(word, "ss") {
+		return word
+	}
+	for _, e := range englishEndings {
+		stem := strings.TrimSuffix(word, e.suffix)
+		if stem != word && len(stem) >= minStem {
+			return stem + e.replacement
+		}
+	}
+	return word
+}
+
+func stemRussian(word string) string {
+	for _, e := range russianEndings {
+		stem := strings.TrimSuffix(word, e)
+		if stem != word && utf8.RuneCountInString(stem) >= minStem {
+			return stem
+		}
+	}
+	return word
+}
@@ -0,0 +1,298 @@
+package similar
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
+)
+
+// rebuildPage is the number of hokkus read at once by Rebuild.
+const rebuildPage = 500
+
+// Store keeps the index up to date with the hokkus listed to anonymous
+// viewers. Hokkus changed in a unit of work are indexed once it is kept,
+// writes changing hokkus nobody keeps track of rebuild the whole index.
+type Store struct {
+	store.Store
+	index *Index
+	// pending collects the changes of the unit of work the store runs, it
+	// is nil outside units of work
+	pending *pending
+}
+
+// pending holds the hokkus changed in a unit of work, all of them when
+// rebuild is set.
+type pending struct {
+	ids     map[int]bool
+	rebuild bool
+}
+
+func NewStore(s store.Store, index *Index) *Store {
+	return &Store{Store: s, index: index}
+}
+
+// Rebuild indexes the hokkus listed to anonymous viewers from scratch.
+func (s *Store) Rebuild() error {
+	hs := []*models.Hokku{}
+	for offset := 0; ; offset += rebuildPage {
+		page, err := s.Store.GetHokkus(0, rebuildPage, offset)
+		if err != nil {
+			return err
+		}
+		hs = append(hs, page...)
+		if len(page) < rebuildPage {
+			break
+		}
+	}
+	s.index.Reset(hs)
+	return nil
+}
+
+// reindex adds the hokku to the index if it is listed to anonymous
+// viewers and removes it otherwise.
+func (s *Store) reindex(id int) {
+	h, err := s.Store.GetHokku(0, id)
+	if err != nil || !h.Listed(0, false) {
+		s.index.Remove(id)
+		return
+	}
+	s.index.Add(h)
+}
+
+func (s *Store) rebuild() {
+	if err := s.Rebuild(); err != nil {
+		log.Printf("similar: %v", err)
+	}
+}
+
+// changed reindexes the hokkus or, in a unit of work, keeps them for later.
+func (s *Store) changed(ids ...int) {
+	if s.pending != nil {
+		for _, id := range ids {
+			s.pending.ids[id] = true
+		}
+		return
+	}
+	for _, id := range ids {
+		s.reindex(id)
+	}
+}
+
+// changedAll rebuilds the index or, in a unit of work, once it is kept.
+func (s *Store) changedAll() {
+	if s.pending != nil {
+		s.pending.rebuild = true
+		return
+	}
+	s.rebuild()
+}
+
+// authorHokkus returns the ids of the hokkus of the user listed to
+// anonymous viewers.
+func (s *Store) authorHokkus(userId int) ([]int, error) {
+	ids := []int{}
+	for offset := 0; ; offset += rebuildPage {
+		page, err := s.Store.GetHokkusByAuthor(0, userId, rebuildPage, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range page {
+			ids = append(ids, h.Id)
+		}
+		if len(page) < rebuildPage {
+			return ids, nil
+		}
+	}
+}
+
+// changedAuthor reindexes the hokkus of the user found by authorHokkus,
+// the whole index when they couldn't be read.
+func (s *Store) changedAuthor(ids []int, err error) {
+	if err != nil {
+		log.Printf("similar: %v", err)
+		s.changedAll()
+		return
+	}
+	s.changed(ids...)
+}
+
+// WithTx hands the function the unit of work of the underlying store and
+// indexes the hokkus it changed once the changes are kept.
+func (s *Store) WithTx(ctx context.Context, fn func(store.Store) error) error {
+	p := &pending{ids: map[int]bool{}}
+	err := s.Store.WithTx(ctx, func(tx store.Store) error {
+		return fn(&Store{Store: tx, index: s.index, pending: p})
+	})
+	if err != nil {
+		return err
+	}
+	if p.rebuild {
+		s.changedAll()
+		return nil
+	}
+	for id := range p.ids {
+		s.changed(id)
+	}
+	return nil
+}
+
+func (s *Store) DeleteTheme(id int) error {
+	err := s.Store.DeleteTheme(id)
+	if err == nil {
+		s.changed(s.index.ThemeHokkus(id)...)
+	}
+	return err
+}
+
+func (s *Store) MoveHokkus(from, to int) (int, error) {
+	n, err := s.Store.MoveHokkus(from, to)
+	if err == nil {
+		s.changed(s.index.ThemeHokkus(from)...)
+	}
+	return n, err
+}
+
+func (s *Store) CreateHokku(h *models.Hokku) (int, error) {
+	id, err := s.Store.CreateHokku(h)
+	if err == nil {
+		s.changed(id)
+	}
+	return id, err
+}
+
+func (s *Store) UpdateHokku(h *models.Hokku) error {
+	err := s.Store.UpdateHokku(h)
+	if err == nil {
+		s.changed(h.Id)
+	}
+	return err
+}
+
+func (s *Store) DeleteHokku(id int) error {
+	err := s.Store.DeleteHokku(id)
+	if err == nil {
+		s.changed(id)
+	}
+	return err
+}
+
+func (s *Store) RestoreHokku(ownerId, id int, deletedSince time.Time) error {
+	err := s.Store.RestoreHokku(ownerId, id, deletedSince)
+	if err == nil {
+		s.changed(id)
+	}
+	return err
+}
+
+func (s *Store) CreateChain(chain *models.Chain, first *models.Hokku) (int, error) {
+	id, err := s.Store.CreateChain(chain, first)
+	if err != nil {
+		return id, err
+	}
+	stanzas, err := s.Store.GetChainStanzas(id)
+	if err != nil {
+		log.Printf("similar: %v", err)
+		return id, nil
+	}
+	for _, h := range stanzas {
+		s.changed(h.Id)
+	}
+	return id, nil
+}
+
+func (s *Store) AppendStanza(h *models.Hokku) (int, error) {
+	id, err := s.Store.AppendStanza(h)
+	if err == nil {
+		s.changed(id)
+	}
+	return id, err
+}
+
+func (s *Store) PublishScheduled(now time.Time) (int, error) {
+	n, err := s.Store.PublishScheduled(now)
+	if err == nil && n > 0 {
+		s.changedAll()
+	}
+	return n, err
+}
+
+func (s *Store) DeleteUser(id int) error {
+	ids, idsErr := s.authorHokkus(id)
+	err := s.Store.DeleteUser(id)
+	if err == nil {
+		s.changedAuthor(ids, idsErr)
+	}
+	return err
+}
+
+func (s *Store) RestoreUser(id int, deletedSince time.Time) error {
+	err := s.Store.RestoreUser(id, deletedSince)
+	if err == nil {
+		s.changedAuthor(s.authorHokkus(id))
+	}
+	return err
+}
+
+func (s *Store) HideHokku(id int) error {
+	err := s.Store.HideHokku(id)
+	if err == nil {
+		s.changed(id)
+	}
+	return err
+}
+
+func (s *Store) UnhideHokku(id int) error {
+	err := s.Store.UnhideHokku(id)
+	if err == nil {
+		s.changed(id)
+	}
+	return err
+}
+
+func (s *Store) HideUser(id int) error {
+	ids, idsErr := s.authorHokkus(id)
+	err := s.Store.HideUser(id)
+	if err == nil {
+		s.changedAuthor(ids, idsErr)
+	}
+	return err
+}
+
+func (s *Store) UnhideUser(id int) error {
+	err := s.Store.UnhideUser(id)
+	if err == nil {
+		s.changedAuthor(s.authorHokkus(id))
+	}
+	return err
+}
+
+// CreateRestriction and LiftRestriction reindex the hokkus of the user
+// when shadow bans, which hide them from everybody else, change.
+func (s *Store) CreateRestriction(r *models.Restriction) (int, error) {
+	if r.Kind != models.RestrictionShadowBan {
+		return s.Store.CreateRestriction(r)
+	}
+	ids, idsErr := s.authorHokkus(r.UserId)
+	id, err := s.Store.CreateRestriction(r)
+	if err == nil {
+		s.changedAuthor(ids, idsErr)
+	}
+	return id, err
+}
+
+func (s *Store) LiftRestriction(id, moderatorId int) error {
+	r, rErr := s.Store.GetRestriction(id)
+	err := s.Store.LiftRestriction(id, moderatorId)
+	switch {
+	case err != nil:
+	case rErr != nil:
+		log.Printf("similar: %v", rErr)
+		s.changedAll()
+	case r.Kind == models.RestrictionShadowBan:
+		s.changedAuthor(s.authorHokkus(r.UserId))
+	}
+	return err
+}
@@ -2,8 +2,11 @@ package mysql_store
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/EgorSkurihin/Hokku/config"
 	"github.com/EgorSkurihin/Hokku/models"
@@ -15,6 +18,10 @@ import (
 type MySqlStore struct {
 	dsn string
 	DB  *sql.DB
+	// tx is set on the store of a unit of work, savepoints counts the
+	// savepoints in it
+	tx         *sql.Tx
+	savepoints *int
 }
 
 func New(conf *config.Store) *MySqlStore {
@@ -38,36 +45,99 @@ func (s *MySqlStore) Open() error {
 	return nil
 }
 
+// Close closes the database. The store of a unit of work leaves it open.
 func (s *MySqlStore) Close() {
+	if s.tx != nil {
+		return
+	}
 	s.DB.Close()
 }
 
+const themeColumns = "id, title, slug, description, parent, cover, sort_order, strict, posting_closed"
+
+func scanTheme(row rowScanner) (*models.Theme, error) {
+	t := &models.Theme{}
+	var parent sql.NullInt64
+	err := row.Scan(
+		&t.Id,
+		&t.Title,
+		&t.Slug,
+		&t.Description,
+		&parent,
+		&t.Cover,
+		&t.SortOrder,
+		&t.Strict,
+		&t.PostingClosed,
+	)
+	if err != nil {
+		return nil, err
+	}
+	t.ParentId = int(parent.Int64)
+	return t, nil
+}
+
+// themeParent maps the root theme to NULL.
+func themeParent(t *models.Theme) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(t.ParentId), Valid: t.ParentId != 0}
+}
+
 func (s *MySqlStore) GetThemes() ([]*models.Theme, error) {
 	themes := []*models.Theme{}
-	rows, err := s.DB.Query("SELECT * FROM themes;")
+	rows, err := s.conn().Query("SELECT " + themeColumns + " FROM themes ORDER BY sort_order, title;")
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	for rows.Next() {
-		t := &models.Theme{}
-		err := rows.Scan(
-			&t.Id,
-			&t.Title,
-		)
+		t, err := scanTheme(rows)
 		if err != nil {
 			return nil, err
 		}
 		themes = append(themes, t)
 	}
-	return themes, nil
+	return themes, rows.Err()
+}
+
+func (s *MySqlStore) getTheme(cond string, arg interface{}) (*models.Theme, error) {
+	t, err := scanTheme(s.conn().QueryRow("SELECT "+themeColumns+" FROM themes WHERE "+cond+";", arg))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrNoRecord
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *MySqlStore) GetTheme(id int) (*models.Theme, error) {
+	return s.getTheme("id = ?", id)
+}
+
+func (s *MySqlStore) GetThemeBySlug(slug string) (*models.Theme, error) {
+	return s.getTheme("slug = ?", slug)
+}
+
+// constraintError maps violations of unique and foreign key constraints.
+func constraintError(err error) error {
+	me, ok := err.(*mysql.MySQLError)
+	if ok {
+		switch me.Number {
+		case 1062:
+			return store.ErrAlreadyExist
+		case 1452:
+			return store.ErrForeignKeyConstraint
+		}
+	}
+	return err
 }
 
 func (s *MySqlStore) CreateTheme(theme *models.Theme) (int, error) {
-	stmt := "INSERT INTO themes (title) VALUES (?)"
-	res, err := s.DB.Exec(stmt, theme.Title)
+	stmt := `INSERT INTO themes (title, slug, description, parent, cover, sort_order, strict, posting_closed)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := s.conn().Exec(stmt, theme.Title, theme.Slug, theme.Description, themeParent(theme),
+		theme.Cover, theme.SortOrder, theme.Strict, theme.PostingClosed)
 	if err != nil {
-		return 0, err
+		return 0, constraintError(err)
 	}
 	id, err := res.LastInsertId()
 	if err != nil {
@@ -76,8 +146,19 @@ func (s *MySqlStore) CreateTheme(theme *models.Theme) (int, error) {
 	return int(id), nil
 }
 
+func (s *MySqlStore) UpdateTheme(theme *models.Theme) error {
+	if _, err := s.GetTheme(theme.Id); err != nil {
+		return err
+	}
+	stmt := `UPDATE themes SET title = ?, slug = ?, description = ?, parent = ?, cover = ?,
+		sort_order = ?, strict = ?, posting_closed = ? WHERE id = ?`
+	_, err := s.conn().Exec(stmt, theme.Title, theme.Slug, theme.Description, themeParent(theme),
+		theme.Cover, theme.SortOrder, theme.Strict, theme.PostingClosed, theme.Id)
+	return constraintError(err)
+}
+
 func (s *MySqlStore) DeleteTheme(id int) error {
-	res, err := s.DB.Exec("DELETE FROM themes WHERE id = ?", id)
+	res, err := s.conn().Exec("DELETE FROM themes WHERE id = ?", id)
 	if err != nil {
 		return err
 	}
@@ -91,39 +172,90 @@ func (s *MySqlStore) DeleteTheme(id int) error {
 	return nil
 }
 
-func (s *MySqlStore) GetUsers() ([]*models.User, error) {
-	users := []*models.User{}
-	rows, err := s.DB.Query("SELECT * FROM users;")
+func (s *MySqlStore) MoveHokkus(from, to int) (int, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	ids, err := queryIds(tx, "SELECT id FROM hokkus WHERE theme = ? FOR UPDATE", from)
+	if err != nil {
+		return 0, err
+	}
+	err = changeHokkus(tx, models.EventHokkuUpdated, ids, func() error {
+		if _, err := tx.Exec("UPDATE hokkus SET theme = ? WHERE theme = ?", to, from); err != nil {
+			return err
+		}
+		_, err := tx.Exec("UPDATE chains SET theme = ? WHERE theme = ?", to, from)
+		return err
+	})
+	if err != nil {
+		return 0, constraintError(err)
+	}
+	return len(ids), tx.Commit()
+}
+
+func (s *MySqlStore) CountHokkusByTheme() (map[int]int, error) {
+	cond, args := listedFilter(0)
+	rows, err := s.conn().Query("SELECT theme, COUNT(*) FROM hokkus WHERE "+cond+" GROUP BY theme;", args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+	counts := map[int]int{}
 	for rows.Next() {
-		u := &models.User{}
-		err := rows.Scan(
-			&u.Id,
-			&u.Email,
-			&u.Name,
-			&u.HashedPassword,
-			&u.Created,
-		)
-		if err != nil {
+		var theme, count int
+		if err := rows.Scan(&theme, &count); err != nil {
 			return nil, err
 		}
-		users = append(users, u)
+		counts[theme] = count
 	}
-	return users, nil
+	return counts, rows.Err()
 }
 
-func (s *MySqlStore) GetUser(id int) (*models.User, error) {
+const userColumns = "id, email, name, password, created, role, hidden, deleted_at"
+
+func scanUser(row rowScanner) (*models.User, error) {
 	u := &models.User{}
-	err := s.DB.QueryRow("SELECT * FROM users WHERE id=?", id).Scan(
+	var deletedAt sql.NullTime
+	err := row.Scan(
 		&u.Id,
 		&u.Email,
 		&u.Name,
 		&u.HashedPassword,
 		&u.Created,
+		&u.Role,
+		&u.Hidden,
+		&deletedAt,
 	)
+	if err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		u.DeletedAt = &deletedAt.Time
+	}
+	return u, nil
+}
+
+func (s *MySqlStore) GetUsers() ([]*models.User, error) {
+	users := []*models.User{}
+	rows, err := s.conn().Query("SELECT " + userColumns + " FROM users WHERE deleted_at IS NULL;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (s *MySqlStore) GetUser(id int) (*models.User, error) {
+	u, err := scanUser(s.conn().QueryRow("SELECT "+userColumns+" FROM users WHERE id=? AND deleted_at IS NULL", id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, store.ErrNoRecord
@@ -134,14 +266,7 @@ func (s *MySqlStore) GetUser(id int) (*models.User, error) {
 }
 
 func (s *MySqlStore) GetUserByEmail(email string) (*models.User, error) {
-	u := &models.User{}
-	err := s.DB.QueryRow("SELECT * FROM users WHERE email=?", email).Scan(
-		&u.Id,
-		&u.Email,
-		&u.Name,
-		&u.HashedPassword,
-		&u.Created,
-	)
+	u, err := scanUser(s.conn().QueryRow("SELECT "+userColumns+" FROM users WHERE email=? AND deleted_at IS NULL", email))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, store.ErrNoRecord
@@ -152,8 +277,16 @@ func (s *MySqlStore) GetUserByEmail(email string) (*models.User, error) {
 }
 
 func (s *MySqlStore) CreateUser(user *models.User) (int, error) {
-	stmt := "INSERT INTO users (name, email, password, created) VALUES (?, ?, ?, NOW())"
-	res, err := s.DB.Exec(stmt, user.Name, user.Email, user.HashedPassword)
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	tx, err := s.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	stmt := "INSERT INTO users (name, email, password, created, role) VALUES (?, ?, ?, NOW(), ?)"
+	res, err := tx.Exec(stmt, user.Name, user.Email, user.HashedPassword, user.Role)
 	if err != nil {
 		me, ok := err.(*mysql.MySQLError)
 		if ok {
@@ -167,11 +300,20 @@ func (s *MySqlStore) CreateUser(user *models.User) (int, error) {
 	if err != nil {
 		return 0, err
 	}
-	return int(id), nil
+	if err := writeEvent(tx, models.EventUserCreated, int(id), &models.UserEvent{Id: int(id), Name: user.Name}); err != nil {
+		return 0, err
+	}
+	return int(id), tx.Commit()
 }
 
+// DeleteUser moves the user and all their hokkus to the trash.
 func (s *MySqlStore) DeleteUser(id int) error {
-	res, err := s.DB.Exec("DELETE FROM users WHERE id = ?", id)
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec("UPDATE users SET deleted_at = NOW() WHERE id = ? AND deleted_at IS NULL", id)
 	if err != nil {
 		return err
 	}
@@ -182,12 +324,27 @@ func (s *MySqlStore) DeleteUser(id int) error {
 	if affeted == 0 {
 		return store.ErrNoRecord
 	}
-	return nil
+	ids, err := queryIds(tx, "SELECT id FROM hokkus WHERE owner = ? AND deleted_at IS NULL FOR UPDATE", id)
+	if err != nil {
+		return err
+	}
+	err = changeHokkus(tx, models.EventHokkuDeleted, ids, func() error {
+		// Hokkus get the same deletion time as the user, so restoring the
+		// user brings back exactly the hokkus deleted along with them
+		stmt := `UPDATE hokkus SET deleted_at = (SELECT deleted_at FROM users WHERE id = ?)
+			WHERE owner = ? AND deleted_at IS NULL`
+		_, err := tx.Exec(stmt, id, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 func (s *MySqlStore) UpdateUser(user *models.User) error {
-	stmt := `UPDATE users SET name = ?, email = ? WHERE id = ?`
-	res, err := s.DB.Exec(stmt, user.Name, user.Email, user.Id)
+	stmt := `UPDATE users SET name = ?, email = ? WHERE id = ? AND deleted_at IS NULL`
+	res, err := s.conn().Exec(stmt, user.Name, user.Email, user.Id)
 	if err != nil {
 		return err
 	}
@@ -201,91 +358,148 @@ func (s *MySqlStore) UpdateUser(user *models.User) error {
 	return nil
 }
 
-func (s *MySqlStore) GetHokkus(limit, offset int) ([]*models.Hokku, error) {
-	hs := []*models.Hokku{}
-	rows, err := s.DB.Query("SELECT * FROM hokkus LIMIT ? OFFSET ?;", limit, offset)
+// Tags are aggregated into a comma-separated list, tags can't contain
+// commas.
+const hokkuColumns = "id, title, content, created, owner, theme, status, publish_at, visibility, deleted_at, chain, parent, position, hidden, " +
+	"(SELECT GROUP_CONCAT(tag ORDER BY tag SEPARATOR ',') FROM hokku_tags WHERE hokku_tags.hokku = hokkus.id)"
+
+// followerCond holds when the viewer follows the owner of the hokku and
+// isn't blocked by them.
+const followerCond = "EXISTS (SELECT 1 FROM follows WHERE follower = ? AND followee = hokkus.owner) AND " +
+	"NOT EXISTS (SELECT 1 FROM blocks WHERE blocker = hokkus.owner AND blocked = ?)"
+
+// unmutedCond holds when the viewer didn't mute the owner of the hokku.
+const unmutedCond = "NOT EXISTS (SELECT 1 FROM mutes WHERE muter = ? AND muted = hokkus.owner)"
+
+// shownCond holds for the hokkus shown to users other than the owner: not
+// hidden by moderators and not written by a shadow-banned user.
+const shownCond = "NOT hidden AND NOT EXISTS (SELECT 1 FROM users WHERE users.id = hokkus.owner AND users.hidden)" +
+	" AND NOT EXISTS (SELECT 1 FROM restrictions WHERE restrictions.user = hokkus.owner AND kind = '" +
+	models.RestrictionShadowBan + "' AND lifted IS NULL AND (expires IS NULL OR expires > NOW()))"
+
+// listedFilter limits a hokkus query to the rows listed to the viewer,
+// see models.Hokku.Listed.
+func listedFilter(viewerId int) (string, []interface{}) {
+	cond := "deleted_at IS NULL AND status = ? AND (owner = ? OR " + shownCond + " AND " + unmutedCond +
+		" AND (visibility = ? OR (visibility = ? AND " + followerCond + ")))"
+	return cond, []interface{}{
+		models.StatusPublished, viewerId, viewerId, models.VisibilityPublic, models.VisibilityFollowers, viewerId, viewerId,
+	}
+}
+
+// visibleFilter limits a hokkus query to the rows the viewer may open by
+// a direct link, see models.Hokku.VisibleTo.
+func visibleFilter(viewerId int) (string, []interface{}) {
+	cond := "deleted_at IS NULL AND (owner = ? OR (status = ? AND " + shownCond + " AND (visibility IN (?, ?) OR (visibility = ? AND " + followerCond + "))))"
+	return cond, []interface{}{
+		viewerId, models.StatusPublished, models.VisibilityPublic, models.VisibilityUnlisted,
+		models.VisibilityFollowers, viewerId, viewerId,
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanHokku(row rowScanner) (*models.Hokku, error) {
+	h := &models.Hokku{}
+	var publishAt, deletedAt sql.NullTime
+	var chain, parent, position sql.NullInt64
+	var tags sql.NullString
+	err := row.Scan(
+		&h.Id,
+		&h.Title,
+		&h.Content,
+		&h.Created,
+		&h.OwnerId,
+		&h.ThemeId,
+		&h.Status,
+		&publishAt,
+		&h.Visibility,
+		&deletedAt,
+		&chain,
+		&parent,
+		&position,
+		&h.Hidden,
+		&tags,
+	)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	for rows.Next() {
-		h := &models.Hokku{}
-		err := rows.Scan(
-			&h.Id,
-			&h.Title,
-			&h.Content,
-			&h.Created,
-			&h.OwnerId,
-			&h.ThemeId,
-		)
-		if err != nil {
-			return nil, err
-		}
-		hs = append(hs, h)
+	if publishAt.Valid {
+		h.PublishAt = &publishAt.Time
+	}
+	if deletedAt.Valid {
+		h.DeletedAt = &deletedAt.Time
 	}
-	return hs, nil
+	h.ChainId, h.ParentId, h.Position = int(chain.Int64), int(parent.Int64), int(position.Int64)
+	if tags.Valid {
+		h.Tags = strings.Split(tags.String, ",")
+	}
+	h.Normalize()
+	return h, nil
 }
 
-func (s *MySqlStore) GetHokkusByAuthor(authorId, limit, offset int) ([]*models.Hokku, error) {
+func (s *MySqlStore) queryHokkus(query string, args ...interface{}) ([]*models.Hokku, error) {
 	hs := []*models.Hokku{}
-	rows, err := s.DB.Query("SELECT * FROM hokkus WHERE theme = ? LIMIT ? OFFSET ?;", authorId, limit, offset)
+	rows, err := s.conn().Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	for rows.Next() {
-		h := &models.Hokku{}
-		err := rows.Scan(
-			&h.Id,
-			&h.Title,
-			&h.Content,
-			&h.Created,
-			&h.OwnerId,
-			&h.ThemeId,
-		)
+		h, err := scanHokku(rows)
 		if err != nil {
 			return nil, err
 		}
 		hs = append(hs, h)
 	}
-	return hs, nil
+	return hs, rows.Err()
 }
 
-func (s *MySqlStore) GetHokkusByTheme(themeId, limit, offset int) ([]*models.Hokku, error) {
-	hs := []*models.Hokku{}
-	rows, err := s.DB.Query("SELECT * FROM hokkus WHERE theme = ? LIMIT ? OFFSET ?;", themeId, limit, offset)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	for rows.Next() {
-		h := &models.Hokku{}
-		err := rows.Scan(
-			&h.Id,
-			&h.Title,
-			&h.Content,
-			&h.Created,
-			&h.OwnerId,
-			&h.ThemeId,
-		)
+func (s *MySqlStore) GetHokkus(viewerId, limit, offset int) ([]*models.Hokku, error) {
+	cond, args := listedFilter(viewerId)
+	args = append(args, limit, offset)
+	return s.queryHokkus("SELECT "+hokkuColumns+" FROM hokkus WHERE "+cond+" LIMIT ? OFFSET ?;", args...)
+}
+
+func (s *MySqlStore) GetHokkusByAuthor(viewerId, authorId, limit, offset int) ([]*models.Hokku, error) {
+	cond, args := listedFilter(viewerId)
+	args = append([]interface{}{authorId}, append(args, limit, offset)...)
+	return s.queryHokkus("SELECT "+hokkuColumns+" FROM hokkus WHERE owner = ? AND "+cond+" LIMIT ? OFFSET ?;", args...)
+}
+
+func (s *MySqlStore) GetHokkusByTheme(viewerId, themeId int, withDescendants bool, limit, offset int) ([]*models.Hokku, error) {
+	themeIds := []int{themeId}
+	if withDescendants {
+		// MySQL 5.7 has no recursive queries, the theme tree is small
+		// enough to be walked in memory
+		themes, err := s.GetThemes()
 		if err != nil {
 			return nil, err
 		}
-		hs = append(hs, h)
+		themeIds = models.DescendantIds(themes, themeId)
+	}
+	args := make([]interface{}, 0, len(themeIds))
+	for _, id := range themeIds {
+		args = append(args, id)
 	}
-	return hs, nil
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(themeIds)), ", ")
+	cond, condArgs := listedFilter(viewerId)
+	args = append(args, append(condArgs, limit, offset)...)
+	return s.queryHokkus("SELECT "+hokkuColumns+" FROM hokkus WHERE theme IN ("+placeholders+") AND "+cond+" LIMIT ? OFFSET ?;", args...)
 }
 
-func (s *MySqlStore) GetHokku(id int) (*models.Hokku, error) {
-	h := &models.Hokku{}
-	err := s.DB.QueryRow("SELECT * FROM hokkus WHERE id=?;", id).Scan(
-		&h.Id,
-		&h.Title,
-		&h.Content,
-		&h.Created,
-		&h.OwnerId,
-		&h.ThemeId,
-	)
+func (s *MySqlStore) GetHokkusByTag(viewerId int, tag string, limit, offset int) ([]*models.Hokku, error) {
+	cond, args := listedFilter(viewerId)
+	args = append([]interface{}{tag}, append(args, limit, offset)...)
+	return s.queryHokkus("SELECT "+hokkuColumns+" FROM hokkus WHERE id IN (SELECT hokku FROM hokku_tags WHERE tag = ?) AND "+cond+" LIMIT ? OFFSET ?;", args...)
+}
+
+func (s *MySqlStore) GetHokku(viewerId, id int) (*models.Hokku, error) {
+	cond, args := visibleFilter(viewerId)
+	args = append([]interface{}{id}, args...)
+	h, err := scanHokku(s.conn().QueryRow("SELECT "+hokkuColumns+" FROM hokkus WHERE id = ? AND "+cond+";", args...))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, store.ErrNoRecord
@@ -295,43 +509,201 @@ func (s *MySqlStore) GetHokku(id int) (*models.Hokku, error) {
 	return h, nil
 }
 
+func (s *MySqlStore) GetRandomHokku(viewerId, themeId int) (*models.Hokku, error) {
+	cond, args := listedFilter(viewerId)
+	args = append(args, themeId, themeId)
+	hs, err := s.queryHokkus("SELECT "+hokkuColumns+" FROM hokkus WHERE "+cond+" AND (? = 0 OR theme = ?) ORDER BY RAND() LIMIT 1;", args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(hs) == 0 {
+		return nil, store.ErrNoRecord
+	}
+	return hs[0], nil
+}
+
 func (s *MySqlStore) CreateHokku(hokku *models.Hokku) (int, error) {
-	stmt := "INSERT INTO hokkus (title, content, created, owner, theme) VALUES (?, ?, Now(), ?, ?)"
-	res, err := s.DB.Exec(stmt, hokku.Title, hokku.Content, hokku.OwnerId, hokku.ThemeId)
+	tx, err := s.begin()
 	if err != nil {
-		me, ok := err.(*mysql.MySQLError)
-		if ok {
-			if me.Number == 1452 {
-				return 0, store.ErrForeignKeyConstraint
-			}
-		}
 		return 0, err
 	}
+	defer tx.Rollback()
+	id, err := insertHokku(tx, hokku)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeHokkuEvent(tx, models.EventHokkuCreated, id); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// nullId maps the zero id to NULL.
+func nullId(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
+
+func insertHokku(tx querier, hokku *models.Hokku) (int, error) {
+	hokku.Normalize()
+	stmt := `INSERT INTO hokkus (title, content, fingerprint, created, owner, theme, status, publish_at, visibility, chain, parent, position)
+		VALUES (?, ?, ?, Now(), ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := tx.Exec(stmt, hokku.Title, hokku.Content, models.Fingerprint(hokku.Content), hokku.OwnerId, hokku.ThemeId,
+		hokku.Status, hokku.PublishAt, hokku.Visibility,
+		nullId(hokku.ChainId), nullId(hokku.ParentId), nullId(hokku.Position))
+	if err != nil {
+		return 0, constraintError(err)
+	}
 	id, err := res.LastInsertId()
 	if err != nil {
 		return 0, err
 	}
-	return int(id), nil
+	hokku.Id = int(id)
+	if err := setTags(tx, hokku.Id, hokku.Tags); err != nil {
+		return 0, err
+	}
+	return hokku.Id, nil
+}
+
+// setTags replaces the tags of the hokku.
+func setTags(tx querier, hokkuId int, tags []string) error {
+	if _, err := tx.Exec("DELETE FROM hokku_tags WHERE hokku = ?", hokkuId); err != nil {
+		return err
+	}
+	for _, t := range tags {
+		if _, err := tx.Exec("INSERT INTO hokku_tags (hokku, tag) VALUES (?, ?)", hokkuId, t); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (s *MySqlStore) DeleteHokku(id int) error {
-	res, err := s.DB.Exec("DELETE FROM hokkus WHERE id = ?", id)
+	tx, err := s.begin()
 	if err != nil {
 		return err
 	}
-	affeted, err := res.RowsAffected()
+	defer tx.Rollback()
+	err = changeHokkus(tx, models.EventHokkuDeleted, []int{id}, func() error {
+		res, err := tx.Exec("UPDATE hokkus SET deleted_at = NOW() WHERE id = ? AND deleted_at IS NULL", id)
+		return expectAffected(res, err)
+	})
 	if err != nil {
 		return err
 	}
-	if affeted == 0 {
-		return store.ErrNoRecord
+	return tx.Commit()
+}
+
+func (s *MySqlStore) UpdateHokku(hokku *models.Hokku) error {
+	hokku.Normalize()
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = changeHokkus(tx, models.EventHokkuUpdated, []int{hokku.Id}, func() error {
+		stmt := `UPDATE hokkus SET title = ?, content = ?, fingerprint = ?, status = ?, publish_at = ?, visibility = ?, created = NOW()
+			WHERE id = ? AND deleted_at IS NULL`
+		res, err := tx.Exec(stmt, hokku.Title, hokku.Content, models.Fingerprint(hokku.Content),
+			hokku.Status, hokku.PublishAt, hokku.Visibility, hokku.Id)
+		if err := expectAffected(res, err); err != nil {
+			return err
+		}
+		return setTags(tx, hokku.Id, hokku.Tags)
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// writeEvent writes the event about the aggregate to the outbox.
+func writeEvent(tx querier, typ string, aggregateId int, payload interface{}) error {
+	e, err := models.NewOutboxEvent(typ, aggregateId, payload)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO outbox (event_key, type, aggregate, payload, created) VALUES (?, ?, ?, ?, NOW())",
+		e.Key, e.Type, e.AggregateId, string(e.Payload))
+	return err
+}
+
+// writeHokkuEvent writes the event about the new hokku to the outbox.
+func writeHokkuEvent(tx querier, typ string, id int) error {
+	e, err := hokkuEvent(tx, id)
+	if err != nil {
+		return err
+	}
+	return writeEvent(tx, typ, id, e)
+}
+
+// changeHokkus runs the change of the hokkus and writes the event about
+// each of them to the outbox. The hokkus are read in the transaction before
+// and after the change, so the events tell how they were listed before and
+// after it.
+func changeHokkus(tx querier, typ string, ids []int, change func() error) error {
+	before := make([]*models.HokkuEvent, len(ids))
+	for i, id := range ids {
+		e, err := hokkuEvent(tx, id)
+		if err != nil {
+			return err
+		}
+		before[i] = e
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	for i, id := range ids {
+		e, err := hokkuEvent(tx, id)
+		if err != nil {
+			return err
+		}
+		e.WasListed = before[i].Listed
+		if err := writeEvent(tx, typ, id, e); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (s *MySqlStore) UpdateHokku(hokku *models.Hokku) error {
-	stmt := `UPDATE hokkus SET title = ?, content = ?, created = NOW() WHERE id = ?`
-	res, err := s.DB.Exec(stmt, hokku.Title, hokku.Content, hokku.Id)
+// hokkuEvent reads the hokku for an event about it. Hokkus that are not
+// shown, like the ones of hidden or shadow-banned users, count as hidden.
+func hokkuEvent(tx querier, id int) (*models.HokkuEvent, error) {
+	h := &models.Hokku{Id: id}
+	var deletedAt sql.NullTime
+	stmt := "SELECT owner, theme, status, visibility, NOT (" + shownCond + "), deleted_at FROM hokkus WHERE id = ?"
+	err := tx.QueryRow(stmt, id).Scan(&h.OwnerId, &h.ThemeId, &h.Status, &h.Visibility, &h.Hidden, &deletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrNoRecord
+		}
+		return nil, err
+	}
+	if deletedAt.Valid {
+		h.DeletedAt = &deletedAt.Time
+	}
+	return models.NewHokkuEvent(h), nil
+}
+
+// queryIds returns the ids selected by the query.
+func queryIds(tx querier, query string, args ...interface{}) ([]int, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// expectAffected returns ErrNoRecord when the statement changed no rows.
+func expectAffected(res sql.Result, err error) error {
 	if err != nil {
 		return err
 	}
@@ -344,3 +716,1838 @@ func (s *MySqlStore) UpdateHokku(hokku *models.Hokku) error {
 	}
 	return nil
 }
+
+func (s *MySqlStore) GetOutboxEvents(limit int) ([]*models.OutboxEvent, error) {
+	rows, err := s.conn().Query("SELECT id, event_key, type, aggregate, payload, created, attempts, parked FROM outbox WHERE NOT parked ORDER BY id LIMIT ?", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.OutboxEvent{}
+	for rows.Next() {
+		e := &models.OutboxEvent{}
+		var payload string
+		if err := rows.Scan(&e.Id, &e.Key, &e.Type, &e.AggregateId, &payload, &e.Created, &e.Attempts, &e.Parked); err != nil {
+			return nil, err
+		}
+		e.Payload = json.RawMessage(payload)
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
+
+func (s *MySqlStore) DeleteOutboxEvent(id int) error {
+	return s.deleteRelation("DELETE FROM outbox WHERE id = ?", id)
+}
+
+func (s *MySqlStore) FailOutboxEvent(id, attempts int) (bool, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	// Assignments are evaluated in order, so parked sees the new count
+	res, err := tx.Exec("UPDATE outbox SET attempts = attempts + 1, parked = attempts >= ? WHERE id = ?", attempts, id)
+	if err := expectAffected(res, err); err != nil {
+		return false, err
+	}
+	var parked bool
+	if err := tx.QueryRow("SELECT parked FROM outbox WHERE id = ?", id).Scan(&parked); err != nil {
+		return false, err
+	}
+	return parked, tx.Commit()
+}
+
+// deleteReaction removes the row of the user and the hokku from the table
+// of likes or bookmarks.
+func (s *MySqlStore) deleteReaction(table string, userId, hokkuId int) error {
+	res, err := s.conn().Exec("DELETE FROM "+table+" WHERE user = ? AND hokku = ?", userId, hokkuId)
+	if err != nil {
+		return err
+	}
+	affeted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affeted == 0 {
+		return store.ErrNoRecord
+	}
+	return nil
+}
+
+// ownerBlockedCond holds when the owner of the hokku blocked the user, it
+// takes the hokku and the user.
+const ownerBlockedCond = "EXISTS (SELECT 1 FROM blocks JOIN hokkus ON hokkus.owner = blocks.blocker WHERE hokkus.id = ? AND blocks.blocked = ?)"
+
+// insertUnlessBlocked runs an INSERT ... SELECT statement that selects
+// nothing when a block applies and returns ErrBlocked when nothing was
+// inserted.
+func (s *MySqlStore) insertUnlessBlocked(stmt string, args ...interface{}) (sql.Result, error) {
+	res, err := s.conn().Exec(stmt, args...)
+	if err != nil {
+		return nil, constraintError(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, store.ErrBlocked
+	}
+	return res, nil
+}
+
+func (s *MySqlStore) Like(userId, hokkuId int) error {
+	_, err := s.insertUnlessBlocked("INSERT INTO likes (user, hokku, created) SELECT ?, ?, NOW() FROM DUAL WHERE NOT "+ownerBlockedCond,
+		userId, hokkuId, hokkuId, userId)
+	return err
+}
+
+func (s *MySqlStore) Unlike(userId, hokkuId int) error {
+	return s.deleteReaction("likes", userId, hokkuId)
+}
+
+func (s *MySqlStore) Bookmark(userId, hokkuId int) error {
+	_, err := s.conn().Exec("INSERT INTO bookmarks (user, hokku, created) VALUES (?, ?, NOW())", userId, hokkuId)
+	return constraintError(err)
+}
+
+func (s *MySqlStore) Unbookmark(userId, hokkuId int) error {
+	return s.deleteReaction("bookmarks", userId, hokkuId)
+}
+
+func (s *MySqlStore) GetBookmarks(userId, limit, offset int) ([]*models.Hokku, error) {
+	cond, args := visibleFilter(userId)
+	args = append([]interface{}{userId}, args...)
+	args = append(args, limit, offset)
+	return s.queryHokkus("SELECT "+hokkuColumns+` FROM hokkus
+		WHERE id IN (SELECT hokku FROM bookmarks WHERE user = ?) AND `+cond+" LIMIT ? OFFSET ?;", args...)
+}
+
+func (s *MySqlStore) GetComments(viewerId, hokkuId, limit, offset int) ([]*models.Comment, error) {
+	rows, err := s.conn().Query(`SELECT id, hokku, owner, content, created FROM comments
+		WHERE hokku = ? AND NOT EXISTS (SELECT 1 FROM mutes WHERE muter = ? AND muted = comments.owner)
+		ORDER BY id LIMIT ? OFFSET ?`, hokkuId, viewerId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	comments := []*models.Comment{}
+	for rows.Next() {
+		c := &models.Comment{}
+		if err := rows.Scan(&c.Id, &c.HokkuId, &c.OwnerId, &c.Content, &c.Created); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+func (s *MySqlStore) GetComment(id int) (*models.Comment, error) {
+	c := &models.Comment{}
+	err := s.conn().QueryRow("SELECT id, hokku, owner, content, created FROM comments WHERE id = ?", id).
+		Scan(&c.Id, &c.HokkuId, &c.OwnerId, &c.Content, &c.Created)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrNoRecord
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *MySqlStore) CreateComment(comment *models.Comment) (int, error) {
+	res, err := s.insertUnlessBlocked("INSERT INTO comments (hokku, owner, content, created) SELECT ?, ?, ?, NOW() FROM DUAL WHERE NOT "+
+		ownerBlockedCond, comment.HokkuId, comment.OwnerId, comment.Content, comment.HokkuId, comment.OwnerId)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (s *MySqlStore) DeleteComment(id int) error {
+	res, err := s.conn().Exec("DELETE FROM comments WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	affeted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affeted == 0 {
+		return store.ErrNoRecord
+	}
+	return nil
+}
+
+func (s *MySqlStore) GetEngagement() ([]*models.Engagement, error) {
+	cond, args := listedFilter(0)
+	rows, err := s.conn().Query(`SELECT id, created,
+		(SELECT COUNT(*) FROM likes WHERE hokku = hokkus.id),
+		(SELECT COUNT(*) FROM comments WHERE hokku = hokkus.id),
+		(SELECT COUNT(*) FROM bookmarks WHERE hokku = hokkus.id),
+		(SELECT COALESCE(SUM(views), 0) FROM hokku_views WHERE hokku = hokkus.id)
+		FROM hokkus WHERE `+cond, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Engagement{}
+	for rows.Next() {
+		e := &models.Engagement{}
+		if err := rows.Scan(&e.HokkuId, &e.Created, &e.Likes, &e.Comments, &e.Bookmarks, &e.Views); err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
+
+// scoresBatch is the number of scores saved by one statement.
+const scoresBatch = 500
+
+func (s *MySqlStore) SaveScores(scores []*models.HokkuScore) error {
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM hokku_scores"); err != nil {
+		return err
+	}
+	for start := 0; start < len(scores); start += scoresBatch {
+		end := start + scoresBatch
+		if end > len(scores) {
+			end = len(scores)
+		}
+		values := make([]string, 0, end-start)
+		args := make([]interface{}, 0, 3*(end-start))
+		for _, sc := range scores[start:end] {
+			values = append(values, "(?, ?, ?)")
+			args = append(args, sc.HokkuId, sc.Score, sc.Trending)
+		}
+		stmt := "INSERT INTO hokku_scores (hokku, score, trending) VALUES " + strings.Join(values, ", ")
+		if _, err := tx.Exec(stmt, args...); err != nil {
+			return constraintError(err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *MySqlStore) GetTrendingHokkus(viewerId, limit, offset int) ([]*models.Hokku, error) {
+	cond, args := listedFilter(viewerId)
+	args = append(args, limit, offset)
+	return s.queryHokkus("SELECT "+hokkuColumns+" FROM hokkus WHERE "+cond+`
+		ORDER BY COALESCE((SELECT trending FROM hokku_scores WHERE hokku = hokkus.id), 0) DESC, id DESC
+		LIMIT ? OFFSET ?;`, args...)
+}
+
+func (s *MySqlStore) GetTopHokkus(viewerId int, since time.Time, limit, offset int) ([]*models.Hokku, error) {
+	cond, args := listedFilter(viewerId)
+	args = append(args, since, limit, offset)
+	return s.queryHokkus("SELECT "+hokkuColumns+" FROM hokkus WHERE "+cond+` AND created >= ?
+		ORDER BY COALESCE((SELECT score FROM hokku_scores WHERE hokku = hokkus.id), 0) DESC, id DESC
+		LIMIT ? OFFSET ?;`, args...)
+}
+
+// viewsBatch is the number of daily views added by one statement.
+const viewsBatch = 500
+
+func (s *MySqlStore) AddViews(views []*models.DailyViews) error {
+	for start := 0; start < len(views); start += viewsBatch {
+		end := start + viewsBatch
+		if end > len(views) {
+			end = len(views)
+		}
+		values := make([]string, 0, end-start)
+		args := make([]interface{}, 0, 3*(end-start))
+		for _, v := range views[start:end] {
+			values = append(values, "(?, ?, ?)")
+			args = append(args, v.HokkuId, v.Day, v.Views)
+		}
+		// IGNORE drops the views of hokkus purged since they were counted
+		stmt := "INSERT IGNORE INTO hokku_views (hokku, day, views) VALUES " + strings.Join(values, ", ") +
+			" ON DUPLICATE KEY UPDATE views = views + VALUES(views)"
+		if _, err := s.conn().Exec(stmt, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MySqlStore) GetViews(hokkuId int) ([]*models.DailyViews, error) {
+	rows, err := s.conn().Query("SELECT hokku, day, views FROM hokku_views WHERE hokku = ? ORDER BY day;", hokkuId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.DailyViews{}
+	for rows.Next() {
+		v := &models.DailyViews{}
+		var day time.Time
+		if err := rows.Scan(&v.HokkuId, &day, &v.Views); err != nil {
+			return nil, err
+		}
+		v.Day = day.Format(models.DayLayout)
+		res = append(res, v)
+	}
+	return res, rows.Err()
+}
+
+// authoredHokkus selects the published hokkus of the author given as an
+// argument with the number of reactions to them.
+const authoredHokkus = `(SELECT id, title, theme, created,
+		(SELECT COUNT(*) FROM likes WHERE hokku = hokkus.id) AS likes,
+		(SELECT COUNT(*) FROM comments WHERE hokku = hokkus.id) AS comments,
+		(SELECT COALESCE(SUM(views), 0) FROM hokku_views WHERE hokku = hokkus.id) AS views
+	FROM hokkus WHERE owner = ? AND deleted_at IS NULL AND status = ?) AS authored`
+
+func (s *MySqlStore) GetAuthorTotals(userId int) (*models.AuthorTotals, error) {
+	t := &models.AuthorTotals{}
+	err := s.conn().QueryRow(`SELECT COUNT(*), COALESCE(SUM(likes), 0), COALESCE(SUM(comments), 0), COALESCE(SUM(views), 0),
+		(SELECT COUNT(*) FROM follows JOIN users ON users.id = follows.follower
+			WHERE follows.followee = ? AND users.deleted_at IS NULL)
+		FROM `+authoredHokkus+`;`,
+		userId, userId, models.StatusPublished).Scan(&t.Hokkus, &t.Likes, &t.Comments, &t.Views, &t.Followers)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *MySqlStore) GetPopularHokkus(userId, limit int) ([]*models.HokkuStats, error) {
+	rows, err := s.conn().Query(`SELECT id, title, likes, comments, views FROM `+authoredHokkus+`
+		ORDER BY likes DESC, comments DESC, views DESC, id DESC LIMIT ?;`,
+		userId, models.StatusPublished, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.HokkuStats{}
+	for rows.Next() {
+		st := &models.HokkuStats{}
+		if err := rows.Scan(&st.HokkuId, &st.Title, &st.Likes, &st.Comments, &st.Views); err != nil {
+			return nil, err
+		}
+		res = append(res, st)
+	}
+	return res, rows.Err()
+}
+
+func (s *MySqlStore) GetThemeStats(userId int) ([]*models.ThemeStats, error) {
+	rows, err := s.conn().Query(`SELECT themes.id, themes.title, COUNT(*), SUM(likes), SUM(comments), SUM(views)
+		FROM `+authoredHokkus+` JOIN themes ON themes.id = authored.theme
+		GROUP BY themes.id, themes.title ORDER BY COUNT(*) DESC, themes.id;`,
+		userId, models.StatusPublished)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.ThemeStats{}
+	for rows.Next() {
+		ts := &models.ThemeStats{}
+		if err := rows.Scan(&ts.ThemeId, &ts.Title, &ts.Hokkus, &ts.Likes, &ts.Comments, &ts.Views); err != nil {
+			return nil, err
+		}
+		res = append(res, ts)
+	}
+	return res, rows.Err()
+}
+
+func (s *MySqlStore) GetPostingDays(userId int) ([]string, error) {
+	rows, err := s.conn().Query(`SELECT DISTINCT DATE(created) AS day FROM hokkus
+		WHERE owner = ? AND deleted_at IS NULL AND status = ? ORDER BY day;`,
+		userId, models.StatusPublished)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []string{}
+	for rows.Next() {
+		var day time.Time
+		if err := rows.Scan(&day); err != nil {
+			return nil, err
+		}
+		res = append(res, day.Format(models.DayLayout))
+	}
+	return res, rows.Err()
+}
+
+func (s *MySqlStore) GetActivity(userId int, since time.Time) ([]*models.Activity, error) {
+	rows, err := s.conn().Query(`SELECT day, SUM(hokkus), SUM(likes), SUM(comments), SUM(views) FROM (
+		SELECT DATE(created) AS day, COUNT(*) AS hokkus, 0 AS likes, 0 AS comments, 0 AS views
+			FROM `+authoredHokkus+` WHERE created >= ? GROUP BY DATE(created)
+		UNION ALL
+		SELECT DATE(likes.created), 0, COUNT(*), 0, 0
+			FROM likes JOIN `+authoredHokkus+` ON authored.id = likes.hokku
+			WHERE likes.created >= ? GROUP BY DATE(likes.created)
+		UNION ALL
+		SELECT DATE(comments.created), 0, 0, COUNT(*), 0
+			FROM comments JOIN `+authoredHokkus+` ON authored.id = comments.hokku
+			WHERE comments.created >= ? GROUP BY DATE(comments.created)
+		UNION ALL
+		SELECT hokku_views.day, 0, 0, 0, SUM(hokku_views.views)
+			FROM hokku_views JOIN `+authoredHokkus+` ON authored.id = hokku_views.hokku
+			WHERE hokku_views.day >= DATE(?) GROUP BY hokku_views.day
+		) AS activity GROUP BY day ORDER BY day;`,
+		userId, models.StatusPublished, since,
+		userId, models.StatusPublished, since,
+		userId, models.StatusPublished, since,
+		userId, models.StatusPublished, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Activity{}
+	for rows.Next() {
+		a := &models.Activity{}
+		var day time.Time
+		if err := rows.Scan(&day, &a.Hokkus, &a.Likes, &a.Comments, &a.Views); err != nil {
+			return nil, err
+		}
+		a.Day = day.Format(models.DayLayout)
+		res = append(res, a)
+	}
+	return res, rows.Err()
+}
+
+func (s *MySqlStore) CreateChain(chain *models.Chain, first *models.Hokku) (int, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec("INSERT INTO chains (title, owner, theme, created) VALUES (?, ?, ?, NOW())",
+		chain.Title, chain.OwnerId, chain.ThemeId)
+	if err != nil {
+		return 0, constraintError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	chain.Id = int(id)
+	for turn, participant := range chain.Participants {
+		stmt := "INSERT INTO chain_participants (chain, turn, participant) VALUES (?, ?, ?)"
+		if _, err := tx.Exec(stmt, chain.Id, turn, participant); err != nil {
+			return 0, constraintError(err)
+		}
+	}
+	first.ChainId, first.ParentId, first.Position = chain.Id, 0, 1
+	firstId, err := insertHokku(tx, first)
+	if err != nil {
+		return 0, err
+	}
+	first.Id = firstId
+	chain.Length = 1
+	if err := writeHokkuEvent(tx, models.EventHokkuCreated, firstId); err != nil {
+		return 0, err
+	}
+	return chain.Id, tx.Commit()
+}
+
+func (s *MySqlStore) GetChain(id int) (*models.Chain, error) {
+	c := &models.Chain{}
+	stmt := `SELECT id, title, owner, theme, created,
+		(SELECT COALESCE(MAX(position), 0) FROM hokkus WHERE chain = chains.id) FROM chains WHERE id = ?`
+	err := s.conn().QueryRow(stmt, id).Scan(&c.Id, &c.Title, &c.OwnerId, &c.ThemeId, &c.Created, &c.Length)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrNoRecord
+		}
+		return nil, err
+	}
+	rows, err := s.conn().Query("SELECT participant FROM chain_participants WHERE chain = ? ORDER BY turn", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	c.Participants = []int{}
+	for rows.Next() {
+		var participant int
+		if err := rows.Scan(&participant); err != nil {
+			return nil, err
+		}
+		c.Participants = append(c.Participants, participant)
+	}
+	return c, rows.Err()
+}
+
+func (s *MySqlStore) GetChainStanzas(chainId int) ([]*models.Hokku, error) {
+	return s.queryHokkus("SELECT "+hokkuColumns+" FROM hokkus WHERE chain = ? AND deleted_at IS NULL AND "+shownCond+" ORDER BY position;", chainId)
+}
+
+// AppendStanza relies on the unique index on the chain and the position:
+// of two stanzas appended concurrently at the same position only the
+// first one is saved.
+func (s *MySqlStore) AppendStanza(stanza *models.Hokku) (int, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	var parent sql.NullInt64
+	err = tx.QueryRow("SELECT id FROM hokkus WHERE chain = ? AND position = ?", stanza.ChainId, stanza.Position-1).Scan(&parent)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	stanza.ParentId = int(parent.Int64)
+	id, err := insertHokku(tx, stanza)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeHokkuEvent(tx, models.EventHokkuCreated, id); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+const contestColumns = "id, title, description, theme, voting_mode, opens_at, closes_at, voting_opens_at, voting_closes_at, results_published_at"
+
+func scanContest(row rowScanner) (*models.Contest, error) {
+	c := &models.Contest{}
+	var publishedAt sql.NullTime
+	err := row.Scan(&c.Id, &c.Title, &c.Description, &c.ThemeId, &c.VotingMode,
+		&c.OpensAt, &c.ClosesAt, &c.VotingOpensAt, &c.VotingClosesAt, &publishedAt)
+	if err != nil {
+		return nil, err
+	}
+	if publishedAt.Valid {
+		c.ResultsPublishedAt = &publishedAt.Time
+	}
+	return c, nil
+}
+
+func (s *MySqlStore) queryContests(query string, args ...interface{}) ([]*models.Contest, error) {
+	contests := []*models.Contest{}
+	rows, err := s.conn().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		c, err := scanContest(rows)
+		if err != nil {
+			return nil, err
+		}
+		contests = append(contests, c)
+	}
+	return contests, rows.Err()
+}
+
+func (s *MySqlStore) GetContests() ([]*models.Contest, error) {
+	return s.queryContests("SELECT " + contestColumns + " FROM contests ORDER BY opens_at DESC, id DESC;")
+}
+
+func (s *MySqlStore) GetContest(id int) (*models.Contest, error) {
+	c, err := scanContest(s.conn().QueryRow("SELECT "+contestColumns+" FROM contests WHERE id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrNoRecord
+		}
+		return nil, err
+	}
+	if c.ResultsPublishedAt == nil {
+		return c, nil
+	}
+	rows, err := s.conn().Query(`SELECT hokku, owner, place, points, first_votes FROM contest_results
+		WHERE contest = ? ORDER BY place, hokku`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	c.Results = []*models.ContestResult{}
+	for rows.Next() {
+		r := &models.ContestResult{}
+		if err := rows.Scan(&r.HokkuId, &r.OwnerId, &r.Place, &r.Points, &r.FirstVotes); err != nil {
+			return nil, err
+		}
+		c.Results = append(c.Results, r)
+	}
+	return c, rows.Err()
+}
+
+func (s *MySqlStore) CreateContest(contest *models.Contest) (int, error) {
+	stmt := `INSERT INTO contests (title, description, theme, voting_mode, opens_at, closes_at, voting_opens_at, voting_closes_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := s.conn().Exec(stmt, contest.Title, contest.Description, contest.ThemeId, contest.VotingMode,
+		contest.OpensAt, contest.ClosesAt, contest.VotingOpensAt, contest.VotingClosesAt)
+	if err != nil {
+		return 0, constraintError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	contest.Id = int(id)
+	return contest.Id, nil
+}
+
+func (s *MySqlStore) GetContestEntries(contestId int) ([]*models.Hokku, error) {
+	return s.queryHokkus("SELECT "+hokkuColumns+` FROM hokkus
+		WHERE id IN (SELECT hokku FROM contest_entries WHERE contest = ?) AND deleted_at IS NULL
+		AND status = ? AND visibility IN (?, ?) AND `+shownCond+` ORDER BY id;`,
+		contestId, models.StatusPublished, models.VisibilityPublic, models.VisibilityUnlisted)
+}
+
+func (s *MySqlStore) CreateContestEntry(entry *models.ContestEntry) error {
+	_, err := s.conn().Exec("INSERT INTO contest_entries (contest, hokku, created) VALUES (?, ?, NOW())",
+		entry.ContestId, entry.HokkuId)
+	return constraintError(err)
+}
+
+func (s *MySqlStore) GetBallots(contestId int) ([]*models.Ballot, error) {
+	rows, err := s.conn().Query(`SELECT voter, hokku, created FROM contest_votes
+		WHERE contest = ? ORDER BY voter, choice`, contestId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ballots := []*models.Ballot{}
+	var b *models.Ballot
+	for rows.Next() {
+		var voter, hokku int
+		var created time.Time
+		if err := rows.Scan(&voter, &hokku, &created); err != nil {
+			return nil, err
+		}
+		if b == nil || b.VoterId != voter {
+			b = &models.Ballot{ContestId: contestId, VoterId: voter, HokkuIds: []int{}, Created: created}
+			ballots = append(ballots, b)
+		}
+		b.HokkuIds = append(b.HokkuIds, hokku)
+	}
+	return ballots, rows.Err()
+}
+
+// CreateBallot relies on the primary key of the votes: a second ballot of
+// the user clashes with the first choice of the saved one.
+func (s *MySqlStore) CreateBallot(ballot *models.Ballot) error {
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for choice, hokku := range ballot.HokkuIds {
+		stmt := "INSERT INTO contest_votes (contest, voter, choice, hokku, created) VALUES (?, ?, ?, ?, NOW())"
+		if _, err := tx.Exec(stmt, ballot.ContestId, ballot.VoterId, choice, hokku); err != nil {
+			return constraintError(err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *MySqlStore) GetUnjudgedContests(now time.Time) ([]*models.Contest, error) {
+	return s.queryContests("SELECT "+contestColumns+` FROM contests
+		WHERE results_published_at IS NULL AND voting_closes_at <= ? ORDER BY id;`, now)
+}
+
+func (s *MySqlStore) PublishContestResults(contestId int, results []*models.ContestResult, now time.Time) error {
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec("UPDATE contests SET results_published_at = ? WHERE id = ? AND results_published_at IS NULL",
+		now, contestId)
+	if err != nil {
+		return err
+	}
+	affeted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affeted == 0 {
+		if _, err := s.GetContest(contestId); err != nil {
+			return err
+		}
+		return store.ErrAlreadyExist
+	}
+	for _, r := range results {
+		stmt := `INSERT INTO contest_results (contest, hokku, owner, place, points, first_votes)
+			VALUES (?, ?, ?, ?, ?, ?)`
+		if _, err := tx.Exec(stmt, contestId, r.HokkuId, r.OwnerId, r.Place, r.Points, r.FirstVotes); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *MySqlStore) GetPublicHokkuIds() ([]int, error) {
+	cond, args := listedFilter(0)
+	rows, err := s.conn().Query("SELECT id FROM hokkus WHERE "+cond+" ORDER BY id;", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *MySqlStore) GetDailyPicks(timezone, since string) ([]*models.DailyPick, error) {
+	rows, err := s.conn().Query(`SELECT day, timezone, hokku FROM daily_picks
+		WHERE timezone = ? AND day >= ? ORDER BY day DESC`, timezone, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	picks := []*models.DailyPick{}
+	for rows.Next() {
+		p := &models.DailyPick{}
+		var day time.Time
+		if err := rows.Scan(&day, &p.Timezone, &p.HokkuId); err != nil {
+			return nil, err
+		}
+		p.Day = day.Format(models.DayLayout)
+		picks = append(picks, p)
+	}
+	return picks, rows.Err()
+}
+
+// CreateDailyPick relies on the primary key of the picks: of two servers
+// picking the hokku of the same day only the first one saves its pick.
+func (s *MySqlStore) CreateDailyPick(pick *models.DailyPick) error {
+	_, err := s.conn().Exec("INSERT INTO daily_picks (day, timezone, hokku) VALUES (?, ?, ?)",
+		pick.Day, pick.Timezone, pick.HokkuId)
+	return constraintError(err)
+}
+
+func (s *MySqlStore) GetDrafts(ownerId, limit, offset int) ([]*models.Hokku, error) {
+	return s.queryHokkus("SELECT "+hokkuColumns+" FROM hokkus WHERE owner = ? AND status <> ? AND deleted_at IS NULL LIMIT ? OFFSET ?;",
+		ownerId, models.StatusPublished, limit, offset)
+}
+
+// PublishScheduled publishes every scheduled hokku whose publication time
+// is not after now and returns the number of published hokkus.
+func (s *MySqlStore) PublishScheduled(now time.Time) (int, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	ids, err := queryIds(tx, "SELECT id FROM hokkus WHERE status = ? AND publish_at <= ? AND deleted_at IS NULL FOR UPDATE",
+		models.StatusScheduled, now)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	err = changeHokkus(tx, models.EventHokkuUpdated, ids, func() error {
+		args := []interface{}{models.StatusPublished}
+		for _, id := range ids {
+			args = append(args, id)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+		_, err := tx.Exec(`UPDATE hokkus SET status = ?, created = publish_at, publish_at = NULL
+			WHERE id IN (`+placeholders+`)`, args...)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), tx.Commit()
+}
+
+func (s *MySqlStore) GetKigo() ([]*models.Kigo, error) {
+	kigo := []*models.Kigo{}
+	rows, err := s.conn().Query("SELECT id, word, season FROM kigo ORDER BY season, word;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		k := &models.Kigo{}
+		if err := rows.Scan(&k.Id, &k.Word, &k.Season); err != nil {
+			return nil, err
+		}
+		kigo = append(kigo, k)
+	}
+	return kigo, rows.Err()
+}
+
+func (s *MySqlStore) CreateKigo(kigo *models.Kigo) (int, error) {
+	res, err := s.conn().Exec("INSERT INTO kigo (word, season) VALUES (?, ?)", kigo.Word, kigo.Season)
+	if err != nil {
+		me, ok := err.(*mysql.MySQLError)
+		if ok {
+			if me.Number == 1062 {
+				return 0, store.ErrAlreadyExist
+			}
+		}
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+func (s *MySqlStore) UpdateKigo(kigo *models.Kigo) error {
+	res, err := s.conn().Exec("UPDATE kigo SET word = ?, season = ? WHERE id = ?", kigo.Word, kigo.Season, kigo.Id)
+	if err != nil {
+		me, ok := err.(*mysql.MySQLError)
+		if ok {
+			if me.Number == 1062 {
+				return store.ErrAlreadyExist
+			}
+		}
+		return err
+	}
+	affeted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affeted == 0 {
+		return store.ErrNoRecord
+	}
+	return nil
+}
+
+func (s *MySqlStore) DeleteKigo(id int) error {
+	res, err := s.conn().Exec("DELETE FROM kigo WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	affeted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affeted == 0 {
+		return store.ErrNoRecord
+	}
+	return nil
+}
+
+// FillFingerprints saves the fingerprints of the hokkus created before
+// fingerprints were introduced and returns their number.
+func (s *MySqlStore) FillFingerprints() (int, error) {
+	rows, err := s.conn().Query("SELECT id, content FROM hokkus WHERE fingerprint IS NULL")
+	if err != nil {
+		return 0, err
+	}
+	fingerprints := map[int]uint64{}
+	for rows.Next() {
+		var id int
+		var content string
+		if err := rows.Scan(&id, &content); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		fingerprints[id] = models.Fingerprint(content)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for id, fp := range fingerprints {
+		if _, err := s.conn().Exec("UPDATE hokkus SET fingerprint = ? WHERE id = ?", fp, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(fingerprints), nil
+}
+
+func (s *MySqlStore) FindDuplicates(userId int, fingerprint uint64, maxDistance int) (*models.Duplicates, error) {
+	d := &models.Duplicates{HokkuIds: []int{}, ClassicIds: []int{}}
+	cond, args := visibleFilter(userId)
+	args = append([]interface{}{userId, fingerprint, maxDistance}, args...)
+	rows, err := s.conn().Query(`SELECT id FROM hokkus WHERE owner <> ? AND BIT_COUNT(fingerprint ^ ?) <= ? AND `+cond+`
+		ORDER BY id;`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		d.HokkuIds = append(d.HokkuIds, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	classics, err := s.conn().Query("SELECT id FROM classics WHERE BIT_COUNT(fingerprint ^ ?) <= ? ORDER BY id;",
+		fingerprint, maxDistance)
+	if err != nil {
+		return nil, err
+	}
+	defer classics.Close()
+	for classics.Next() {
+		var id int
+		if err := classics.Scan(&id); err != nil {
+			return nil, err
+		}
+		d.ClassicIds = append(d.ClassicIds, id)
+	}
+	return d, classics.Err()
+}
+
+func (s *MySqlStore) GetClassics(limit, offset int) ([]*models.Classic, error) {
+	rows, err := s.conn().Query("SELECT id, title, author, content FROM classics ORDER BY id LIMIT ? OFFSET ?;", limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Classic{}
+	for rows.Next() {
+		c := &models.Classic{}
+		if err := rows.Scan(&c.Id, &c.Title, &c.Author, &c.Content); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+func (s *MySqlStore) CreateClassic(c *models.Classic) (int, error) {
+	res, err := s.conn().Exec("INSERT INTO classics (title, author, content, fingerprint) VALUES (?, ?, ?, ?)",
+		c.Title, c.Author, c.Content, models.Fingerprint(c.Content))
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	c.Id = int(id)
+	return c.Id, nil
+}
+
+func (s *MySqlStore) DeleteClassic(id int) error {
+	res, err := s.conn().Exec("DELETE FROM classics WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return store.ErrNoRecord
+	}
+	return nil
+}
+
+func (s *MySqlStore) CreateFlag(f *models.Flag) (int, error) {
+	var matches sql.NullString
+	if f.Matches != nil {
+		data, err := json.Marshal(f.Matches)
+		if err != nil {
+			return 0, err
+		}
+		matches = sql.NullString{String: string(data), Valid: true}
+	}
+	res, err := s.conn().Exec("INSERT INTO hokku_flags (hokku, reason, matches, created) VALUES (?, ?, ?, NOW())",
+		f.HokkuId, f.Reason, matches)
+	if err != nil {
+		return 0, constraintError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	f.Id = int(id)
+	return f.Id, nil
+}
+
+func (s *MySqlStore) GetFlags(limit, offset int) ([]*models.Flag, error) {
+	rows, err := s.conn().Query("SELECT id, hokku, reason, matches, created FROM hokku_flags ORDER BY id DESC LIMIT ? OFFSET ?;",
+		limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Flag{}
+	for rows.Next() {
+		f := &models.Flag{}
+		var matches sql.NullString
+		if err := rows.Scan(&f.Id, &f.HokkuId, &f.Reason, &matches, &f.Created); err != nil {
+			return nil, err
+		}
+		if matches.Valid {
+			f.Matches = &models.Duplicates{}
+			if err := json.Unmarshal([]byte(matches.String), f.Matches); err != nil {
+				return nil, err
+			}
+		}
+		res = append(res, f)
+	}
+	return res, rows.Err()
+}
+
+const reportColumns = "id, reporter, hokku, reported_comment, author, reason, comment, status, moderator, resolution, created, resolved"
+
+func scanReport(row rowScanner) (*models.Report, error) {
+	r := &models.Report{}
+	var reporter, hokku, comment, moderator sql.NullInt64
+	var resolution sql.NullString
+	var resolved sql.NullTime
+	err := row.Scan(&r.Id, &reporter, &hokku, &comment, &r.AuthorId, &r.Reason, &r.Comment, &r.Status,
+		&moderator, &resolution, &r.Created, &resolved)
+	if err != nil {
+		return nil, err
+	}
+	r.ReporterId, r.HokkuId, r.CommentId = int(reporter.Int64), int(hokku.Int64), int(comment.Int64)
+	r.ModeratorId, r.Resolution = int(moderator.Int64), resolution.String
+	if resolved.Valid {
+		r.Resolved = &resolved.Time
+	}
+	return r, nil
+}
+
+func (s *MySqlStore) queryReports(query string, args ...interface{}) ([]*models.Report, error) {
+	rows, err := s.conn().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Report{}
+	for rows.Next() {
+		r, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+// sameTargetCond limits a reports query to the reports on the hokku, the
+// comment, or on the user when both are NULL.
+const sameTargetCond = "author = ? AND hokku <=> ? AND reported_comment <=> ?"
+
+func (s *MySqlStore) CreateReport(r *models.Report) (int, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	var pending int
+	err = tx.QueryRow("SELECT COUNT(*) FROM reports WHERE reporter <=> ? AND "+sameTargetCond+" AND status <> ? FOR UPDATE",
+		nullId(r.ReporterId), r.AuthorId, nullId(r.HokkuId), nullId(r.CommentId), models.ReportResolved).Scan(&pending)
+	if err != nil {
+		return 0, err
+	}
+	if pending > 0 {
+		return 0, store.ErrAlreadyExist
+	}
+	res, err := tx.Exec(`INSERT INTO reports (reporter, hokku, reported_comment, author, reason, comment, status, created)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NOW())`,
+		nullId(r.ReporterId), nullId(r.HokkuId), nullId(r.CommentId), r.AuthorId, r.Reason, r.Comment, models.ReportOpen)
+	if err != nil {
+		return 0, constraintError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	r.Id = int(id)
+	return r.Id, tx.Commit()
+}
+
+func (s *MySqlStore) GetReports(status string, limit, offset int) ([]*models.Report, error) {
+	if status == "" {
+		return s.queryReports("SELECT "+reportColumns+" FROM reports WHERE status <> ? ORDER BY id LIMIT ? OFFSET ?;",
+			models.ReportResolved, limit, offset)
+	}
+	return s.queryReports("SELECT "+reportColumns+" FROM reports WHERE status = ? ORDER BY id LIMIT ? OFFSET ?;",
+		status, limit, offset)
+}
+
+func (s *MySqlStore) GetReport(id int) (*models.Report, error) {
+	r, err := scanReport(s.conn().QueryRow("SELECT "+reportColumns+" FROM reports WHERE id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrNoRecord
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// ClaimReport doesn't change a report already claimed by the moderator,
+// MySQL reports no affected rows then.
+func (s *MySqlStore) ClaimReport(id, moderatorId int) error {
+	res, err := s.conn().Exec(`UPDATE reports SET status = ?, moderator = ?
+		WHERE id = ? AND (status = ? OR status = ? AND moderator = ?)`,
+		models.ReportClaimed, moderatorId, id, models.ReportOpen, models.ReportClaimed, moderatorId)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+	r, err := s.GetReport(id)
+	if err != nil {
+		return err
+	}
+	if r.Status != models.ReportClaimed || r.ModeratorId != moderatorId {
+		return store.ErrAlreadyExist
+	}
+	return nil
+}
+
+func (s *MySqlStore) ResolveReport(id, moderatorId int, resolution string) ([]*models.Report, error) {
+	tx, err := s.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	r, err := scanReport(tx.QueryRow("SELECT "+reportColumns+" FROM reports WHERE id = ? FOR UPDATE", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrNoRecord
+		}
+		return nil, err
+	}
+	if r.Status == models.ReportResolved || r.Status == models.ReportClaimed && r.ModeratorId != moderatorId {
+		return nil, store.ErrAlreadyExist
+	}
+	rows, err := tx.Query("SELECT id FROM reports WHERE "+sameTargetCond+" AND status <> ? FOR UPDATE",
+		r.AuthorId, nullId(r.HokkuId), nullId(r.CommentId), models.ReportResolved)
+	if err != nil {
+		return nil, err
+	}
+	ids := []interface{}{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := append([]interface{}{models.ReportResolved, moderatorId, resolution}, ids...)
+	if _, err := tx.Exec(`UPDATE reports SET status = ?, moderator = ?, resolution = ?, resolved = NOW()
+		WHERE id IN (`+placeholders+`)`, args...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.queryReports("SELECT "+reportColumns+" FROM reports WHERE id IN ("+placeholders+") ORDER BY id;", ids...)
+}
+
+func (s *MySqlStore) HideHokku(id int) error {
+	return s.setHokkuHidden(id, true)
+}
+
+func (s *MySqlStore) UnhideHokku(id int) error {
+	return s.setHokkuHidden(id, false)
+}
+
+func (s *MySqlStore) setHokkuHidden(id int, hidden bool) error {
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var exists bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM hokkus WHERE id = ? AND deleted_at IS NULL)", id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return store.ErrNoRecord
+	}
+	err = changeHokkus(tx, models.EventHokkuUpdated, []int{id}, func() error {
+		_, err := tx.Exec("UPDATE hokkus SET hidden = ? WHERE id = ?", hidden, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *MySqlStore) HideUser(id int) error {
+	return s.setUserHidden(id, true)
+}
+
+func (s *MySqlStore) UnhideUser(id int) error {
+	return s.setUserHidden(id, false)
+}
+
+func (s *MySqlStore) setUserHidden(id int, hidden bool) error {
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var exists bool
+	err = tx.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE id = ? AND deleted_at IS NULL)", id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return store.ErrNoRecord
+	}
+	ids, err := queryIds(tx, "SELECT id FROM hokkus WHERE owner = ? AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+	err = changeHokkus(tx, models.EventHokkuUpdated, ids, func() error {
+		_, err := tx.Exec("UPDATE users SET hidden = ? WHERE id = ?", hidden, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *MySqlStore) SetRole(id int, role string) error {
+	if _, err := s.GetUser(id); err != nil {
+		return err
+	}
+	_, err := s.conn().Exec("UPDATE users SET role = ? WHERE id = ?", role, id)
+	return err
+}
+
+const restrictionColumns = "id, user, kind, reason, expires, moderator, created, lifted_by, lifted"
+
+func (s *MySqlStore) queryRestrictions(query string, args ...interface{}) ([]*models.Restriction, error) {
+	rows, err := s.conn().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Restriction{}
+	for rows.Next() {
+		r := &models.Restriction{}
+		var expires, lifted sql.NullTime
+		var moderator, liftedBy sql.NullInt64
+		err := rows.Scan(&r.Id, &r.UserId, &r.Kind, &r.Reason, &expires, &moderator, &r.Created, &liftedBy, &lifted)
+		if err != nil {
+			return nil, err
+		}
+		if expires.Valid {
+			r.Expires = &expires.Time
+		}
+		if lifted.Valid {
+			r.Lifted = &lifted.Time
+		}
+		r.ModeratorId, r.LiftedBy = int(moderator.Int64), int(liftedBy.Int64)
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+func (s *MySqlStore) CreateRestriction(r *models.Restriction) (int, error) {
+	res, err := s.conn().Exec(`INSERT INTO restrictions (user, kind, reason, expires, moderator, created)
+		VALUES (?, ?, ?, ?, ?, NOW())`, r.UserId, r.Kind, r.Reason, r.Expires, nullId(r.ModeratorId))
+	if err != nil {
+		return 0, constraintError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	r.Id = int(id)
+	return r.Id, nil
+}
+
+func (s *MySqlStore) GetRestriction(id int) (*models.Restriction, error) {
+	res, err := s.queryRestrictions("SELECT "+restrictionColumns+" FROM restrictions WHERE id = ?;", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, store.ErrNoRecord
+	}
+	return res[0], nil
+}
+
+func (s *MySqlStore) GetRestrictions(userId int) ([]*models.Restriction, error) {
+	return s.queryRestrictions("SELECT "+restrictionColumns+" FROM restrictions WHERE user = ? ORDER BY id DESC;", userId)
+}
+
+func (s *MySqlStore) GetActiveRestrictions(userId int, now time.Time) ([]*models.Restriction, error) {
+	return s.queryRestrictions("SELECT "+restrictionColumns+` FROM restrictions
+		WHERE user = ? AND lifted IS NULL AND (expires IS NULL OR expires > ?) ORDER BY id DESC;`, userId, now)
+}
+
+func (s *MySqlStore) LiftRestriction(id, moderatorId int) error {
+	res, err := s.conn().Exec("UPDATE restrictions SET lifted_by = ?, lifted = NOW() WHERE id = ? AND lifted IS NULL",
+		nullId(moderatorId), id)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return store.ErrNoRecord
+	}
+	return nil
+}
+
+// nullJSON stores empty snapshots as NULL.
+func nullJSON(raw json.RawMessage) sql.NullString {
+	return sql.NullString{String: string(raw), Valid: len(raw) != 0}
+}
+
+func (s *MySqlStore) CreateAuditEntry(e *models.AuditEntry) (int, error) {
+	var targetType sql.NullString
+	if e.TargetType != "" {
+		targetType = sql.NullString{String: e.TargetType, Valid: true}
+	}
+	res, err := s.conn().Exec(`INSERT INTO audit_log (actor, action, target_type, target, ip, user_agent, details,
+		before_state, after_state, created) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, NOW())`,
+		nullId(e.ActorId), e.Action, targetType, nullId(e.TargetId), e.IP, e.UserAgent, e.Details,
+		nullJSON(e.Before), nullJSON(e.After))
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	e.Id = int(id)
+	return e.Id, nil
+}
+
+func (s *MySqlStore) GetAuditLog(q *models.AuditQuery) ([]*models.AuditEntry, error) {
+	conds, args := []string{"TRUE"}, []interface{}{}
+	if q.ActorId != 0 {
+		conds, args = append(conds, "actor = ?"), append(args, q.ActorId)
+	}
+	if q.TargetType != "" {
+		conds, args = append(conds, "target_type = ?"), append(args, q.TargetType)
+	}
+	if q.TargetId != 0 {
+		conds, args = append(conds, "target = ?"), append(args, q.TargetId)
+	}
+	if !q.Since.IsZero() {
+		conds, args = append(conds, "created >= ?"), append(args, q.Since)
+	}
+	if !q.Until.IsZero() {
+		conds, args = append(conds, "created < ?"), append(args, q.Until)
+	}
+	query := `SELECT id, actor, action, target_type, target, ip, user_agent, details, before_state, after_state, created
+		FROM audit_log WHERE ` + strings.Join(conds, " AND ") + " ORDER BY id DESC"
+	if q.Limit != 0 {
+		query, args = query+" LIMIT ? OFFSET ?", append(args, q.Limit, q.Offset)
+	}
+	rows, err := s.conn().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.AuditEntry{}
+	for rows.Next() {
+		e := &models.AuditEntry{}
+		var actor, target sql.NullInt64
+		var targetType, before, after sql.NullString
+		err := rows.Scan(&e.Id, &actor, &e.Action, &targetType, &target, &e.IP, &e.UserAgent, &e.Details,
+			&before, &after, &e.Created)
+		if err != nil {
+			return nil, err
+		}
+		e.ActorId, e.TargetType, e.TargetId = int(actor.Int64), targetType.String, int(target.Int64)
+		if before.Valid {
+			e.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			e.After = json.RawMessage(after.String)
+		}
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
+
+func (s *MySqlStore) CreateNotification(n *models.Notification) (int, error) {
+	var resolution sql.NullString
+	if n.Resolution != "" {
+		resolution = sql.NullString{String: n.Resolution, Valid: true}
+	}
+	tx, err := s.begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if n.Aggregated() {
+		var id int
+		err := tx.QueryRow(`SELECT id FROM notifications WHERE user = ? AND read_at IS NULL AND type = ? AND hokku <=> ?
+			ORDER BY id DESC LIMIT 1 FOR UPDATE`, n.UserId, n.Type, nullId(n.HokkuId)).Scan(&id)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return 0, err
+		}
+		if err == nil {
+			n.Id = id
+			return s.addNotificationActor(tx, n)
+		}
+	}
+	res, err := tx.Exec(`INSERT INTO notifications (user, type, actor, report, hokku, comment, resolution, created, updated)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NOW(), NOW())`, n.UserId, n.Type, nullId(n.ActorId), nullId(n.ReportId),
+		nullId(n.HokkuId), nullId(n.CommentId), resolution)
+	if err != nil {
+		return 0, constraintError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	n.Id = int(id)
+	if n.ActorId != 0 {
+		return s.addNotificationActor(tx, n)
+	}
+	err = tx.QueryRow("SELECT created, updated FROM notifications WHERE id = ?", n.Id).Scan(&n.Created, &n.Updated)
+	if err != nil {
+		return 0, err
+	}
+	return n.Id, tx.Commit()
+}
+
+// addNotificationActor gathers the actor of the event into the saved
+// notification, reads back what it counts now and commits the transaction.
+func (s *MySqlStore) addNotificationActor(tx transaction, n *models.Notification) (int, error) {
+	if _, err := tx.Exec("INSERT IGNORE INTO notification_actors (notification, actor) VALUES (?, ?)", n.Id, n.ActorId); err != nil {
+		return 0, constraintError(err)
+	}
+	_, err := tx.Exec(`UPDATE notifications SET actor = ?, comment = ?, updated = NOW(),
+		actors = (SELECT COUNT(*) FROM notification_actors WHERE notification = ?) WHERE id = ?`,
+		n.ActorId, nullId(n.CommentId), n.Id, n.Id)
+	if err != nil {
+		return 0, constraintError(err)
+	}
+	err = tx.QueryRow("SELECT actors, created, updated FROM notifications WHERE id = ?", n.Id).Scan(&n.Actors, &n.Created, &n.Updated)
+	if err != nil {
+		return 0, err
+	}
+	return n.Id, tx.Commit()
+}
+
+func (s *MySqlStore) GetNotifications(userId, limit, offset int) ([]*models.Notification, error) {
+	rows, err := s.conn().Query(`SELECT id, user, type, actor, actors, report, hokku, comment, resolution, read_at IS NOT NULL,
+		created, updated FROM notifications WHERE user = ? ORDER BY updated DESC, id DESC LIMIT ? OFFSET ?;`, userId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Notification{}
+	for rows.Next() {
+		n := &models.Notification{}
+		var actor, report, hokku, comment sql.NullInt64
+		var resolution sql.NullString
+		if err := rows.Scan(&n.Id, &n.UserId, &n.Type, &actor, &n.Actors, &report, &hokku, &comment, &resolution, &n.Read,
+			&n.Created, &n.Updated); err != nil {
+			return nil, err
+		}
+		n.ActorId, n.ReportId, n.HokkuId, n.CommentId = int(actor.Int64), int(report.Int64), int(hokku.Int64), int(comment.Int64)
+		n.Resolution = resolution.String
+		res = append(res, n)
+	}
+	return res, rows.Err()
+}
+
+func (s *MySqlStore) CountUnreadNotifications(userId int) (map[string]int, error) {
+	rows, err := s.conn().Query("SELECT type, COUNT(*) FROM notifications WHERE user = ? AND read_at IS NULL GROUP BY type", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := map[string]int{}
+	for rows.Next() {
+		var t string
+		var count int
+		if err := rows.Scan(&t, &count); err != nil {
+			return nil, err
+		}
+		res[t] = count
+	}
+	return res, rows.Err()
+}
+
+func (s *MySqlStore) ReadNotification(userId, id int) error {
+	res, err := s.conn().Exec("UPDATE notifications SET read_at = NOW() WHERE id = ? AND user = ? AND read_at IS NULL", id, userId)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 1 {
+		return nil
+	}
+	var exists bool
+	err = s.conn().QueryRow("SELECT EXISTS (SELECT 1 FROM notifications WHERE id = ? AND user = ?)", id, userId).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return store.ErrNoRecord
+	}
+	return nil
+}
+
+func (s *MySqlStore) ReadNotifications(userId int) error {
+	_, err := s.conn().Exec("UPDATE notifications SET read_at = NOW() WHERE user = ? AND read_at IS NULL", userId)
+	return err
+}
+
+func (s *MySqlStore) GetNotificationPreferences(userId int) (map[string]bool, error) {
+	rows, err := s.conn().Query("SELECT type, enabled FROM notification_preferences WHERE user = ?", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := map[string]bool{}
+	for rows.Next() {
+		var t string
+		var enabled bool
+		if err := rows.Scan(&t, &enabled); err != nil {
+			return nil, err
+		}
+		res[t] = enabled
+	}
+	return res, rows.Err()
+}
+
+func (s *MySqlStore) SetNotificationPreferences(userId int, prefs map[string]bool) error {
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for t, enabled := range prefs {
+		_, err := tx.Exec(`INSERT INTO notification_preferences (user, type, enabled) VALUES (?, ?, ?)
+			ON DUPLICATE KEY UPDATE enabled = VALUES(enabled)`, userId, t, enabled)
+		if err != nil {
+			return constraintError(err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *MySqlStore) CreateWebhook(w *models.Webhook) (int, error) {
+	events, err := json.Marshal(w.Events)
+	if err != nil {
+		return 0, err
+	}
+	res, err := s.conn().Exec("INSERT INTO webhooks (owner, url, events, secret, created) VALUES (?, ?, ?, ?, NOW())",
+		w.OwnerId, w.URL, string(events), w.Secret)
+	if err != nil {
+		return 0, constraintError(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	w.Id = int(id)
+	return w.Id, nil
+}
+
+const webhookColumns = "id, owner, url, events, secret, created"
+
+func scanWebhook(row interface{ Scan(...interface{}) error }) (*models.Webhook, error) {
+	w := &models.Webhook{}
+	var events string
+	if err := row.Scan(&w.Id, &w.OwnerId, &w.URL, &events, &w.Secret, &w.Created); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(events), &w.Events); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (s *MySqlStore) GetWebhook(id int) (*models.Webhook, error) {
+	w, err := scanWebhook(s.conn().QueryRow("SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrNoRecord
+		}
+		return nil, err
+	}
+	return w, nil
+}
+
+func (s *MySqlStore) GetWebhooks(ownerId int) ([]*models.Webhook, error) {
+	rows, err := s.conn().Query("SELECT "+webhookColumns+" FROM webhooks WHERE ? = 0 OR owner = ? ORDER BY id DESC", ownerId, ownerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, w)
+	}
+	return res, rows.Err()
+}
+
+func (s *MySqlStore) DeleteWebhook(id int) error {
+	return s.deleteRelation("DELETE FROM webhooks WHERE id = ?", id)
+}
+
+func (s *MySqlStore) EnqueueWebhookDeliveries(key, event string, payload []byte, at time.Time) (int, error) {
+	// Repeated keys update nothing and aren't counted
+	res, err := s.conn().Exec(`INSERT INTO webhook_deliveries (webhook, event_key, event, payload, status, attempts, next_attempt, created)
+		SELECT id, ?, ?, ?, ?, 0, ?, NOW() FROM webhooks WHERE JSON_CONTAINS(events, JSON_QUOTE(?))
+		ON DUPLICATE KEY UPDATE id = id`,
+		key, event, string(payload), models.DeliveryPending, at, event)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+const deliveryColumns = "id, webhook, event_key, event, payload, status, attempts, next_attempt, status_code, error, created, delivered"
+
+func (s *MySqlStore) queryDeliveries(query string, args ...interface{}) ([]*models.WebhookDelivery, error) {
+	rows, err := s.conn().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.WebhookDelivery{}
+	for rows.Next() {
+		d := &models.WebhookDelivery{}
+		var payload string
+		var key, deliveryError sql.NullString
+		var statusCode sql.NullInt64
+		var delivered sql.NullTime
+		if err := rows.Scan(&d.Id, &d.WebhookId, &key, &d.Event, &payload, &d.Status, &d.Attempts, &d.NextAttempt,
+			&statusCode, &deliveryError, &d.Created, &delivered); err != nil {
+			return nil, err
+		}
+		d.Key, d.Payload = key.String, json.RawMessage(payload)
+		d.StatusCode, d.Error = int(statusCode.Int64), deliveryError.String
+		if delivered.Valid {
+			d.Delivered = &delivered.Time
+		}
+		res = append(res, d)
+	}
+	return res, rows.Err()
+}
+
+func (s *MySqlStore) GetDueWebhookDeliveries(now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	return s.queryDeliveries("SELECT "+deliveryColumns+` FROM webhook_deliveries
+		WHERE status = ? AND next_attempt <= ? ORDER BY next_attempt, id LIMIT ?`, models.DeliveryPending, now, limit)
+}
+
+func (s *MySqlStore) UpdateWebhookDelivery(d *models.WebhookDelivery) error {
+	var deliveryError sql.NullString
+	if d.Error != "" {
+		deliveryError = sql.NullString{String: d.Error, Valid: true}
+	}
+	_, err := s.conn().Exec(`UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt = ?, status_code = ?,
+		error = ?, delivered = ? WHERE id = ?`, d.Status, d.Attempts, d.NextAttempt, nullId(d.StatusCode),
+		deliveryError, d.Delivered, d.Id)
+	return err
+}
+
+func (s *MySqlStore) GetWebhookDeliveries(webhookId, limit, offset int) ([]*models.WebhookDelivery, error) {
+	return s.queryDeliveries("SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE webhook = ? ORDER BY id DESC LIMIT ? OFFSET ?",
+		webhookId, limit, offset)
+}
+
+func (s *MySqlStore) Follow(followerId, followeeId int) error {
+	stmt := `INSERT INTO follows (follower, followee, created) SELECT ?, ?, NOW() FROM DUAL
+		WHERE NOT EXISTS (SELECT 1 FROM blocks WHERE blocker = ? AND blocked = ?)`
+	_, err := s.insertUnlessBlocked(stmt, followerId, followeeId, followeeId, followerId)
+	return err
+}
+
+func (s *MySqlStore) Unfollow(followerId, followeeId int) error {
+	res, err := s.conn().Exec("DELETE FROM follows WHERE follower = ? AND followee = ?", followerId, followeeId)
+	if err != nil {
+		return err
+	}
+	affeted, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affeted == 0 {
+		return store.ErrNoRecord
+	}
+	return nil
+}
+
+func (s *MySqlStore) Block(blockerId, blockedId int) error {
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("INSERT INTO blocks (blocker, blocked, created) VALUES (?, ?, NOW())", blockerId, blockedId); err != nil {
+		return constraintError(err)
+	}
+	if _, err := tx.Exec("DELETE FROM follows WHERE follower = ? AND followee = ?", blockedId, blockerId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *MySqlStore) Unblock(blockerId, blockedId int) error {
+	return s.deleteRelation("DELETE FROM blocks WHERE blocker = ? AND blocked = ?", blockerId, blockedId)
+}
+
+func (s *MySqlStore) GetBlocks(blockerId int) ([]*models.Block, error) {
+	rows, err := s.conn().Query("SELECT blocker, blocked, created FROM blocks WHERE blocker = ? ORDER BY created DESC", blockerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Block{}
+	for rows.Next() {
+		b := &models.Block{}
+		if err := rows.Scan(&b.BlockerId, &b.BlockedId, &b.Created); err != nil {
+			return nil, err
+		}
+		res = append(res, b)
+	}
+	return res, rows.Err()
+}
+
+func (s *MySqlStore) Mute(muterId, mutedId int) error {
+	_, err := s.conn().Exec("INSERT INTO mutes (muter, muted, created) VALUES (?, ?, NOW())", muterId, mutedId)
+	return constraintError(err)
+}
+
+func (s *MySqlStore) Unmute(muterId, mutedId int) error {
+	return s.deleteRelation("DELETE FROM mutes WHERE muter = ? AND muted = ?", muterId, mutedId)
+}
+
+func (s *MySqlStore) GetMutes(muterId int) ([]*models.Mute, error) {
+	rows, err := s.conn().Query("SELECT muter, muted, created FROM mutes WHERE muter = ? ORDER BY created DESC", muterId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []*models.Mute{}
+	for rows.Next() {
+		m := &models.Mute{}
+		if err := rows.Scan(&m.MuterId, &m.MutedId, &m.Created); err != nil {
+			return nil, err
+		}
+		res = append(res, m)
+	}
+	return res, rows.Err()
+}
+
+// deleteRelation runs the delete statement and returns ErrNoRecord when
+// there was nothing to delete.
+func (s *MySqlStore) deleteRelation(stmt string, args ...interface{}) error {
+	res, err := s.conn().Exec(stmt, args...)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return store.ErrNoRecord
+	}
+	return nil
+}
+
+func (s *MySqlStore) GetTrash(ownerId int, deletedSince time.Time, limit, offset int) ([]*models.Hokku, error) {
+	return s.queryHokkus("SELECT "+hokkuColumns+" FROM hokkus WHERE owner = ? AND deleted_at >= ? ORDER BY deleted_at DESC LIMIT ? OFFSET ?;",
+		ownerId, deletedSince, limit, offset)
+}
+
+func (s *MySqlStore) RestoreHokku(ownerId, id int, deletedSince time.Time) error {
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = changeHokkus(tx, models.EventHokkuUpdated, []int{id}, func() error {
+		stmt := "UPDATE hokkus SET deleted_at = NULL WHERE id = ? AND owner = ? AND deleted_at >= ?"
+		res, err := tx.Exec(stmt, id, ownerId, deletedSince)
+		return expectAffected(res, err)
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RestoreUser restores the user together with the hokkus deleted along
+// with them.
+func (s *MySqlStore) RestoreUser(id int, deletedSince time.Time) error {
+	tx, err := s.begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	ids, err := queryIds(tx, `SELECT h.id FROM hokkus h JOIN users u ON h.owner = u.id
+		WHERE u.id = ? AND u.deleted_at >= ? AND h.deleted_at = u.deleted_at FOR UPDATE`, id, deletedSince)
+	if err != nil {
+		return err
+	}
+	err = changeHokkus(tx, models.EventHokkuUpdated, ids, func() error {
+		stmt := `UPDATE hokkus h JOIN users u ON h.owner = u.id SET h.deleted_at = NULL
+			WHERE u.id = ? AND u.deleted_at >= ? AND h.deleted_at = u.deleted_at`
+		if _, err := tx.Exec(stmt, id, deletedSince); err != nil {
+			return err
+		}
+		res, err := tx.Exec("UPDATE users SET deleted_at = NULL WHERE id = ? AND deleted_at >= ?", id, deletedSince)
+		return expectAffected(res, err)
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *MySqlStore) GetDeletedUserByEmail(email string, deletedSince time.Time) (*models.User, error) {
+	u, err := scanUser(s.conn().QueryRow("SELECT "+userColumns+" FROM users WHERE email=? AND deleted_at >= ?", email, deletedSince))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, store.ErrNoRecord
+		}
+		return nil, err
+	}
+	return u, nil
+}
+
+// PurgeDeleted permanently removes the users and hokkus deleted before
+// the given time and returns the number of removed rows.
+func (s *MySqlStore) PurgeDeleted(before time.Time) (int, error) {
+	purged := 0
+	for _, stmt := range []string{
+		"DELETE FROM hokkus WHERE deleted_at < ?",
+		"DELETE FROM users WHERE deleted_at < ?",
+	} {
+		res, err := s.conn().Exec(stmt, before)
+		if err != nil {
+			return purged, err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return purged, err
+		}
+		purged += int(affected)
+	}
+	return purged, nil
+}
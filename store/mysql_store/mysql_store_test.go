@@ -1,9 +1,13 @@
 package mysql_store_test
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/EgorSkurihin/Hokku/models"
+	"github.com/EgorSkurihin/Hokku/store"
 	"github.com/EgorSkurihin/Hokku/store/mysql_store"
 	"github.com/EgorSkurihin/Hokku/store/test_store"
 	"github.com/stretchr/testify/assert"
@@ -23,60 +27,98 @@ func AddTestData(t *testing.T, s *mysql_store.MySqlStore) {
 		assert.NoError(t, err)
 	}
 	for i, h := range test_store.Hokkus {
+		if h.ChainId != 0 {
+			continue
+		}
 		h.ThemeId = lastThemeId - (i % lastThemeId)
 		h.OwnerId = lastUserId - (i % lastUserId)
 		_, err := s.CreateHokku(h)
 		assert.NoError(t, err)
 	}
+	for _, c := range test_store.Chains {
+		chain := *c
+		for _, h := range test_store.Hokkus {
+			if h.ChainId != c.Id {
+				continue
+			}
+			stanza := *h
+			if h.Position == 1 {
+				_, err = s.CreateChain(&chain, &stanza)
+			} else {
+				stanza.ChainId = chain.Id
+				_, err = s.AppendStanza(&stanza)
+			}
+			assert.NoError(t, err)
+		}
+	}
 }
 
 func TestGetHokkus(t *testing.T) {
 	s, teardown := mysql_store.TestMysqlStore(t)
-	defer teardown("users", "themes", "hokkus")
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
 	AddTestData(t, s)
 
-	res, err := s.GetHokkus(10, 0)
+	res, err := s.GetHokkus(0, 10, 0)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, res)
 }
 
 func TestGetHokkusByTheme(t *testing.T) {
 	s, teardown := mysql_store.TestMysqlStore(t)
-	defer teardown("users", "themes", "hokkus")
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
 	AddTestData(t, s)
 
 	themeId := 1
 	s.DB.QueryRow("SELECT MAX(id) FROM themes;").Scan(&themeId)
-	res, err := s.GetHokkusByTheme(themeId, 10, 0)
+	res, err := s.GetHokkusByTheme(0, themeId, false, 10, 0)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, res)
 }
 
+func TestGetHokkusByThemeDescendants(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
+	AddTestData(t, s)
+
+	own, err := s.GetHokkusByTheme(0, 1, false, 100, 0)
+	assert.NoError(t, err)
+	all, err := s.GetHokkusByTheme(0, 1, true, 100, 0)
+	assert.NoError(t, err)
+	sub, err := s.GetHokkusByTheme(0, 4, false, 100, 0)
+	assert.NoError(t, err)
+	assert.Len(t, all, len(own)+len(sub))
+
+	counts, err := s.CountHokkusByTheme()
+	assert.NoError(t, err)
+	assert.Equal(t, len(own), counts[1])
+	assert.Equal(t, len(sub), counts[4])
+}
+
 func TestGetHokkusByAuthor(t *testing.T) {
 	s, teardown := mysql_store.TestMysqlStore(t)
-	defer teardown("users", "themes", "hokkus")
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
 	AddTestData(t, s)
 
 	userId := 0
 	s.DB.QueryRow("SELECT MAX(id) FROM users;").Scan(&userId)
-	res, err := s.GetHokkusByAuthor(userId-1, 10, 0)
+	res, err := s.GetHokkusByAuthor(0, userId-1, 10, 0)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, res)
 }
 
 func TestGetHokku(t *testing.T) {
 	s, teardown := mysql_store.TestMysqlStore(t)
-	defer teardown("users", "themes", "hokkus")
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
 	AddTestData(t, s)
 
-	res, err := s.GetHokku(1)
+	res, err := s.GetHokku(0, 1)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, res)
 }
 
 func TestDeleteHokku(t *testing.T) {
 	s, teardown := mysql_store.TestMysqlStore(t)
-	defer teardown("users", "themes", "hokkus")
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
 	AddTestData(t, s)
 
 	err := s.DeleteHokku(1)
@@ -85,7 +127,7 @@ func TestDeleteHokku(t *testing.T) {
 
 func TestUpdateHokku(t *testing.T) {
 	s, teardown := mysql_store.TestMysqlStore(t)
-	defer teardown("users", "themes", "hokkus")
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
 	AddTestData(t, s)
 
 	h := &models.Hokku{Id: 1, Title: "qwewqewe", Content: "asdqweasd"}
@@ -93,9 +135,27 @@ func TestUpdateHokku(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestHokkuLines(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
+	AddTestData(t, s)
+
+	lines := []string{"An old pond", "A frog jumps in", "Splash!"}
+	h := &models.Hokku{Title: "Lines", Lines: []string{" An old  pond\r\n", "A frog jumps in\nSplash!"},
+		OwnerId: test_store.Hokkus[0].OwnerId, ThemeId: test_store.Hokkus[0].ThemeId,
+		Status: models.StatusPublished, Visibility: models.VisibilityPublic}
+	id, err := s.CreateHokku(h)
+	assert.NoError(t, err)
+
+	res, err := s.GetHokku(h.OwnerId, id)
+	assert.NoError(t, err)
+	assert.Equal(t, lines, res.Lines)
+	assert.Equal(t, "An old pond\nA frog jumps in\nSplash!", res.Content)
+}
+
 func TestGetUsers(t *testing.T) {
 	s, teardown := mysql_store.TestMysqlStore(t)
-	defer teardown("users", "themes", "hokkus")
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
 	AddTestData(t, s)
 
 	res, err := s.GetUsers()
@@ -105,7 +165,7 @@ func TestGetUsers(t *testing.T) {
 
 func TestGetUser(t *testing.T) {
 	s, teardown := mysql_store.TestMysqlStore(t)
-	defer teardown("users", "themes", "hokkus")
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
 	AddTestData(t, s)
 
 	res, err := s.GetUser(1)
@@ -115,7 +175,7 @@ func TestGetUser(t *testing.T) {
 
 func TestDeleteUser(t *testing.T) {
 	s, teardown := mysql_store.TestMysqlStore(t)
-	defer teardown("users", "themes", "hokkus")
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
 	AddTestData(t, s)
 
 	err := s.DeleteUser(1)
@@ -124,7 +184,7 @@ func TestDeleteUser(t *testing.T) {
 
 func TestUpdateUser(t *testing.T) {
 	s, teardown := mysql_store.TestMysqlStore(t)
-	defer teardown("users", "themes", "hokkus")
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
 	AddTestData(t, s)
 
 	u := &models.User{Id: 1, Name: "qwewqewe", Email: "qwe@email.com"}
@@ -134,7 +194,7 @@ func TestUpdateUser(t *testing.T) {
 
 func TestGetThemes(t *testing.T) {
 	s, teardown := mysql_store.TestMysqlStore(t)
-	defer teardown("users", "themes", "hokkus")
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
 	AddTestData(t, s)
 
 	res, err := s.GetThemes()
@@ -144,9 +204,1100 @@ func TestGetThemes(t *testing.T) {
 
 func TestDeleteTheme(t *testing.T) {
 	s, teardown := mysql_store.TestMysqlStore(t)
-	defer teardown("users", "themes", "hokkus")
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
 	AddTestData(t, s)
 
 	err := s.DeleteTheme(1)
 	assert.NoError(t, err)
 }
+
+func TestGetDrafts(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
+	AddTestData(t, s)
+
+	res, err := s.GetDrafts(test_store.Hokkus[5].OwnerId, 10, 0)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res)
+	for _, h := range res {
+		assert.NotEqual(t, models.StatusPublished, h.Status)
+	}
+}
+
+func TestPublishScheduled(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
+	AddTestData(t, s)
+
+	n, err := s.PublishScheduled(test_store.ScheduledAt.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = s.PublishScheduled(test_store.ScheduledAt)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestHokkuVisibility(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "follows")
+	AddTestData(t, s)
+
+	followers := test_store.Hokkus[8]
+	_, err := s.GetHokku(0, followers.Id)
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+
+	follower := followers.OwnerId%3 + 1
+	assert.NoError(t, s.Follow(follower, followers.OwnerId))
+	res, err := s.GetHokku(follower, followers.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, models.VisibilityFollowers, res.Visibility)
+
+	assert.NoError(t, s.Unfollow(follower, followers.OwnerId))
+	_, err = s.GetHokku(follower, followers.Id)
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+}
+
+func TestTrash(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
+	AddTestData(t, s)
+
+	h, err := s.GetHokku(0, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, s.DeleteHokku(h.Id))
+	_, err = s.GetHokku(h.OwnerId, h.Id)
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+
+	since := time.Now().Add(-time.Hour)
+	res, err := s.GetTrash(h.OwnerId, since, 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+
+	assert.NoError(t, s.RestoreHokku(h.OwnerId, h.Id, since))
+	_, err = s.GetHokku(0, h.Id)
+	assert.NoError(t, err)
+}
+
+func TestRestoreUser(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
+	AddTestData(t, s)
+
+	assert.NoError(t, s.DeleteUser(1))
+	_, err := s.GetUser(1)
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+	deleted, err := s.GetDeletedUserByEmail(test_store.Users[0].Email, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, deleted.Id)
+	assert.NotNil(t, deleted.DeletedAt)
+	_, err = s.GetDeletedUserByEmail(test_store.Users[0].Email, time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+	res, err := s.GetHokkusByAuthor(0, 1, 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+
+	assert.NoError(t, s.RestoreUser(1, time.Now().Add(-time.Hour)))
+	_, err = s.GetUser(1)
+	assert.NoError(t, err)
+	res, err = s.GetHokkusByAuthor(0, 1, 10, 0)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, res)
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
+	AddTestData(t, s)
+
+	assert.NoError(t, s.DeleteHokku(1))
+	n, err := s.PurgeDeleted(time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	n, err = s.PurgeDeleted(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.ErrorIs(t, s.RestoreHokku(0, 1, time.Time{}), store.ErrNoRecord)
+}
+
+func TestGetTheme(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
+	AddTestData(t, s)
+
+	res, err := s.GetTheme(3)
+	assert.NoError(t, err)
+	assert.True(t, res.Strict)
+	_, err = s.GetTheme(1000)
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+
+	res, err = s.GetThemeBySlug("example-theme-4")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.ParentId)
+	assert.Equal(t, "Cover", res.Cover)
+}
+
+func TestUpdateTheme(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
+	AddTestData(t, s)
+
+	th := &models.Theme{Id: 2, Title: "Updated", Slug: "updated", ParentId: 1, SortOrder: 5, PostingClosed: true}
+	assert.NoError(t, s.UpdateTheme(th))
+	res, err := s.GetTheme(2)
+	assert.NoError(t, err)
+	assert.Equal(t, th, res)
+
+	th.Slug = "example-theme-1"
+	assert.ErrorIs(t, s.UpdateTheme(th), store.ErrAlreadyExist)
+	th.Slug, th.ParentId = "updated", 1000
+	assert.ErrorIs(t, s.UpdateTheme(th), store.ErrForeignKeyConstraint)
+	th.Id = 1000
+	assert.ErrorIs(t, s.UpdateTheme(th), store.ErrNoRecord)
+}
+
+func TestDeleteThemeOrphansSubthemes(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
+	AddTestData(t, s)
+
+	assert.NoError(t, s.DeleteTheme(1))
+	res, err := s.GetTheme(4)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, res.ParentId)
+}
+
+func TestMoveHokkus(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants", "outbox")
+	AddTestData(t, s)
+
+	before, err := s.GetHokkusByTheme(0, 1, false, 100, 0)
+	assert.NoError(t, err)
+	_, err = s.MoveHokkus(1, 100000)
+	assert.ErrorIs(t, err, store.ErrForeignKeyConstraint)
+	n, err := s.MoveHokkus(1, 2)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, n, len(before))
+	res, err := s.GetHokkusByTheme(0, 1, false, 100, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+	for _, h := range before {
+		moved, err := s.GetHokku(0, h.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, moved.ThemeId)
+	}
+}
+
+func TestGetHokkusByTag(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
+	AddTestData(t, s)
+
+	res, err := s.GetHokkusByTag(0, models.SeasonTag(models.SeasonWinter), 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+	assert.Equal(t, []string{"nature", "season:winter"}, res[0].Tags)
+
+	res, err = s.GetHokkusByTag(0, "nature", 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+}
+
+func TestKigo(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("kigo")
+
+	for _, k := range test_store.Kigo {
+		_, err := s.CreateKigo(&models.Kigo{Word: k.Word, Season: k.Season})
+		assert.NoError(t, err)
+	}
+	_, err := s.CreateKigo(&models.Kigo{Word: test_store.Kigo[0].Word, Season: models.SeasonSpring})
+	assert.ErrorIs(t, err, store.ErrAlreadyExist)
+
+	res, err := s.GetKigo()
+	assert.NoError(t, err)
+	assert.Len(t, res, len(test_store.Kigo))
+
+	k := res[0]
+	k.Season = models.SeasonNewYear
+	assert.NoError(t, s.UpdateKigo(k))
+	assert.NoError(t, s.DeleteKigo(k.Id))
+	assert.ErrorIs(t, s.DeleteKigo(k.Id), store.ErrNoRecord)
+}
+
+func TestChain(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
+	AddTestData(t, s)
+
+	chain := &models.Chain{Title: "Chain", OwnerId: 1, ThemeId: 1, Participants: []int{1, 2}}
+	first := &models.Hokku{Title: "1", Content: "First", OwnerId: 1, ThemeId: 1,
+		Status: models.StatusPublished, Visibility: models.VisibilityPublic}
+	id, err := s.CreateChain(chain, first)
+	assert.NoError(t, err)
+
+	second := &models.Hokku{Title: "2", Content: "Second", OwnerId: 2, ThemeId: 1, ChainId: id, Position: 2,
+		Status: models.StatusPublished, Visibility: models.VisibilityPublic}
+	_, err = s.AppendStanza(second)
+	assert.NoError(t, err)
+	assert.Equal(t, first.Id, second.ParentId)
+	again := *second
+	_, err = s.AppendStanza(&again)
+	assert.ErrorIs(t, err, store.ErrAlreadyExist)
+
+	res, err := s.GetChain(id)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, res.Participants)
+	assert.Equal(t, 2, res.Length)
+
+	stanzas, err := s.GetChainStanzas(id)
+	assert.NoError(t, err)
+	if assert.Len(t, stanzas, 2) {
+		assert.Equal(t, "First", stanzas[0].Content)
+		assert.Equal(t, stanzas[0].Id, stanzas[1].ParentId)
+	}
+
+	_, err = s.GetChain(1000)
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+}
+
+func TestContest(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants",
+		"contests", "contest_entries", "contest_votes", "contest_results")
+	AddTestData(t, s)
+
+	start := time.Date(2030, time.February, 1, 0, 0, 0, 0, time.UTC)
+	contest := &models.Contest{Title: "Contest", ThemeId: 1, VotingMode: models.VotingRanked,
+		OpensAt: start, ClosesAt: start.AddDate(0, 0, 7),
+		VotingOpensAt: start.AddDate(0, 0, 7), VotingClosesAt: start.AddDate(0, 0, 14)}
+	id, err := s.CreateContest(contest)
+	assert.NoError(t, err)
+
+	first := &models.Hokku{Title: "1", Content: "First", OwnerId: 1, ThemeId: 1,
+		Status: models.StatusPublished, Visibility: models.VisibilityPublic}
+	firstId, err := s.CreateHokku(first)
+	assert.NoError(t, err)
+	second := &models.Hokku{Title: "2", Content: "Second", OwnerId: 2, ThemeId: 1,
+		Status: models.StatusPublished, Visibility: models.VisibilityPublic}
+	secondId, err := s.CreateHokku(second)
+	assert.NoError(t, err)
+	assert.NoError(t, s.CreateContestEntry(&models.ContestEntry{ContestId: id, HokkuId: firstId}))
+	assert.NoError(t, s.CreateContestEntry(&models.ContestEntry{ContestId: id, HokkuId: secondId}))
+	err = s.CreateContestEntry(&models.ContestEntry{ContestId: id, HokkuId: firstId})
+	assert.ErrorIs(t, err, store.ErrAlreadyExist)
+
+	entries, err := s.GetContestEntries(id)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// Entries made private leave the contest
+	second.Id, second.Visibility = secondId, models.VisibilityPrivate
+	assert.NoError(t, s.UpdateHokku(second))
+	entries, err = s.GetContestEntries(id)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	second.Visibility = models.VisibilityPublic
+	assert.NoError(t, s.UpdateHokku(second))
+
+	assert.NoError(t, s.CreateBallot(&models.Ballot{ContestId: id, VoterId: 3, HokkuIds: []int{secondId, firstId}}))
+	err = s.CreateBallot(&models.Ballot{ContestId: id, VoterId: 3, HokkuIds: []int{firstId}})
+	assert.ErrorIs(t, err, store.ErrAlreadyExist)
+	ballots, err := s.GetBallots(id)
+	assert.NoError(t, err)
+	if assert.Len(t, ballots, 1) {
+		assert.Equal(t, []int{secondId, firstId}, ballots[0].HokkuIds)
+	}
+
+	due, err := s.GetUnjudgedContests(start.AddDate(0, 0, 10))
+	assert.NoError(t, err)
+	assert.Len(t, due, 0)
+	due, err = s.GetUnjudgedContests(start.AddDate(0, 0, 14))
+	assert.NoError(t, err)
+	assert.Len(t, due, 1)
+
+	results := contest.Tally(entries, ballots)
+	now := start.AddDate(0, 0, 14)
+	assert.NoError(t, s.PublishContestResults(id, results, now))
+	err = s.PublishContestResults(id, results, now)
+	assert.ErrorIs(t, err, store.ErrAlreadyExist)
+
+	res, err := s.GetContest(id)
+	assert.NoError(t, err)
+	assert.Equal(t, now, *res.ResultsPublishedAt)
+	assert.Equal(t, results, res.Results)
+
+	_, err = s.GetContest(1000)
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+}
+
+func TestGetRandomHokku(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants")
+	AddTestData(t, s)
+
+	h, err := s.GetRandomHokku(0, 0)
+	assert.NoError(t, err)
+	assert.True(t, h.Listed(0, false))
+	h, err = s.GetRandomHokku(0, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, h.ThemeId)
+	_, err = s.GetRandomHokku(0, 1000)
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+}
+
+func TestDailyPicks(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants", "daily_picks")
+	AddTestData(t, s)
+
+	ids, err := s.GetPublicHokkuIds()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ids)
+
+	pick := &models.DailyPick{Day: "2030-03-01", Timezone: "UTC", HokkuId: ids[0]}
+	assert.NoError(t, s.CreateDailyPick(pick))
+	again := *pick
+	again.HokkuId = ids[len(ids)-1]
+	assert.ErrorIs(t, s.CreateDailyPick(&again), store.ErrAlreadyExist)
+	assert.NoError(t, s.CreateDailyPick(&models.DailyPick{Day: "2030-03-02", Timezone: "UTC", HokkuId: ids[0]}))
+	assert.NoError(t, s.CreateDailyPick(&models.DailyPick{Day: "2030-03-02", Timezone: "Asia/Tokyo", HokkuId: ids[0]}))
+
+	picks, err := s.GetDailyPicks("UTC", "2030-03-01")
+	assert.NoError(t, err)
+	if assert.Len(t, picks, 2) {
+		assert.Equal(t, "2030-03-02", picks[0].Day)
+		assert.Equal(t, pick, picks[1])
+	}
+	picks, err = s.GetDailyPicks("UTC", "2030-03-02")
+	assert.NoError(t, err)
+	assert.Len(t, picks, 1)
+}
+
+func TestReactions(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants", "likes", "bookmarks", "comments")
+	AddTestData(t, s)
+
+	ids, err := s.GetPublicHokkuIds()
+	assert.NoError(t, err)
+	hokkuId := ids[0]
+	user, err := s.GetUserByEmail(test_store.Users[0].Email)
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.Like(user.Id, hokkuId))
+	assert.ErrorIs(t, s.Like(user.Id, hokkuId), store.ErrAlreadyExist)
+	assert.NoError(t, s.Unlike(user.Id, hokkuId))
+	assert.ErrorIs(t, s.Unlike(user.Id, hokkuId), store.ErrNoRecord)
+
+	assert.NoError(t, s.Bookmark(user.Id, hokkuId))
+	assert.ErrorIs(t, s.Bookmark(user.Id, 100000), store.ErrForeignKeyConstraint)
+	bookmarks, err := s.GetBookmarks(user.Id, 10, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, bookmarks, 1) {
+		assert.Equal(t, hokkuId, bookmarks[0].Id)
+	}
+	assert.NoError(t, s.Unbookmark(user.Id, hokkuId))
+
+	comment := &models.Comment{HokkuId: hokkuId, OwnerId: user.Id, Content: "Beautiful"}
+	id, err := s.CreateComment(comment)
+	assert.NoError(t, err)
+	got, err := s.GetComment(id)
+	assert.NoError(t, err)
+	assert.Equal(t, comment.Content, got.Content)
+	comments, err := s.GetComments(user.Id, hokkuId, 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, comments, 1)
+	assert.NoError(t, s.DeleteComment(id))
+	assert.ErrorIs(t, s.DeleteComment(id), store.ErrNoRecord)
+}
+
+func TestScores(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants", "likes", "hokku_scores")
+	AddTestData(t, s)
+
+	ids, err := s.GetPublicHokkuIds()
+	assert.NoError(t, err)
+	user, err := s.GetUserByEmail(test_store.Users[0].Email)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Like(user.Id, ids[0]))
+
+	engagement, err := s.GetEngagement()
+	assert.NoError(t, err)
+	likes := 0
+	for _, e := range engagement {
+		likes += e.Likes
+	}
+	assert.Equal(t, 1, likes)
+
+	assert.NoError(t, s.SaveScores([]*models.HokkuScore{
+		{HokkuId: ids[0], Score: 1, Trending: 3},
+		{HokkuId: ids[1], Score: 2, Trending: 1},
+	}))
+	trending, err := s.GetTrendingHokkus(0, 2, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, trending, 2) {
+		assert.Equal(t, ids[0], trending[0].Id)
+		assert.Equal(t, ids[1], trending[1].Id)
+	}
+	top, err := s.GetTopHokkus(0, time.Time{}, 1, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, top, 1) {
+		assert.Equal(t, ids[1], top[0].Id)
+	}
+}
+
+func TestViews(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants", "hokku_views")
+	AddTestData(t, s)
+
+	ids, err := s.GetPublicHokkuIds()
+	assert.NoError(t, err)
+	assert.NoError(t, s.AddViews([]*models.DailyViews{
+		{HokkuId: ids[0], Day: "2030-01-02", Views: 2},
+		{HokkuId: ids[0], Day: "2030-01-01", Views: 1},
+		{HokkuId: 100000, Day: "2030-01-01", Views: 1},
+	}))
+	assert.NoError(t, s.AddViews([]*models.DailyViews{{HokkuId: ids[0], Day: "2030-01-02", Views: 3}}))
+	views, err := s.GetViews(ids[0])
+	assert.NoError(t, err)
+	assert.Equal(t, []*models.DailyViews{
+		{HokkuId: ids[0], Day: "2030-01-01", Views: 1},
+		{HokkuId: ids[0], Day: "2030-01-02", Views: 5},
+	}, views)
+}
+
+func TestAuthorStats(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants", "follows", "likes", "comments", "hokku_views")
+	AddTestData(t, s)
+
+	author, err := s.GetUserByEmail(test_store.Users[0].Email)
+	assert.NoError(t, err)
+	reader, err := s.GetUserByEmail(test_store.Users[1].Email)
+	assert.NoError(t, err)
+	hs, err := s.GetHokkusByAuthor(author.Id, author.Id, 100, 0)
+	assert.NoError(t, err)
+	if !assert.NotEmpty(t, hs) {
+		return
+	}
+	hokkuId := hs[0].Id
+	assert.NoError(t, s.Follow(reader.Id, author.Id))
+	assert.NoError(t, s.Like(reader.Id, hokkuId))
+	_, err = s.CreateComment(&models.Comment{HokkuId: hokkuId, OwnerId: reader.Id, Content: "Beautiful"})
+	assert.NoError(t, err)
+	today := time.Now().UTC().Format(models.DayLayout)
+	assert.NoError(t, s.AddViews([]*models.DailyViews{{HokkuId: hokkuId, Day: today, Views: 4}}))
+
+	totals, err := s.GetAuthorTotals(author.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, len(hs), totals.Hokkus)
+	assert.Equal(t, 1, totals.Likes)
+	assert.Equal(t, 1, totals.Comments)
+	assert.Equal(t, 4, totals.Views)
+	assert.Equal(t, 1, totals.Followers)
+
+	popular, err := s.GetPopularHokkus(author.Id, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, popular, 1) {
+		assert.Equal(t, hokkuId, popular[0].HokkuId)
+	}
+	themes, err := s.GetThemeStats(author.Id)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, themes)
+	days, err := s.GetPostingDays(author.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{today}, days)
+
+	activity, err := s.GetActivity(author.Id, time.Now().AddDate(0, 0, -1))
+	assert.NoError(t, err)
+	if assert.Len(t, activity, 1) {
+		assert.Equal(t, &models.Activity{Day: today, Hokkus: len(hs), Likes: 1, Comments: 1, Views: 4}, activity[0])
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants", "classics", "hokku_flags")
+	AddTestData(t, s)
+
+	author, err := s.GetUserByEmail(test_store.Users[0].Email)
+	assert.NoError(t, err)
+	other, err := s.GetUserByEmail(test_store.Users[1].Email)
+	assert.NoError(t, err)
+	classic := *test_store.Classics[0]
+	classicId, err := s.CreateClassic(&classic)
+	assert.NoError(t, err)
+	themes, err := s.GetThemes()
+	assert.NoError(t, err)
+	h := &models.Hokku{Title: "Pond", Content: "An old silent pond", OwnerId: author.Id, ThemeId: themes[0].Id,
+		Status: models.StatusPublished, Visibility: models.VisibilityPublic}
+	hokkuId, err := s.CreateHokku(h)
+	assert.NoError(t, err)
+
+	d, err := s.FindDuplicates(other.Id, models.Fingerprint(classic.Content), 8)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{classicId}, d.ClassicIds)
+	d, err = s.FindDuplicates(other.Id, models.Fingerprint("An old, silent pond!"), 8)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{hokkuId}, d.HokkuIds)
+	d, err = s.FindDuplicates(author.Id, models.Fingerprint("An old, silent pond!"), 8)
+	assert.NoError(t, err)
+	assert.Empty(t, d.HokkuIds)
+
+	_, err = s.CreateFlag(&models.Flag{HokkuId: hokkuId, Reason: models.FlagDuplicate, Matches: d})
+	assert.NoError(t, err)
+	flags, err := s.GetFlags(10, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, flags, 1) {
+		assert.Equal(t, hokkuId, flags[0].HokkuId)
+		assert.Equal(t, d, flags[0].Matches)
+	}
+	assert.NoError(t, s.DeleteClassic(classicId))
+	assert.ErrorIs(t, s.DeleteClassic(classicId), store.ErrNoRecord)
+}
+
+func TestModeration(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants", "comments", "reports", "notifications")
+	AddTestData(t, s)
+
+	reporter, err := s.GetUserByEmail(test_store.Users[0].Email)
+	assert.NoError(t, err)
+	author, err := s.GetUserByEmail(test_store.Users[1].Email)
+	assert.NoError(t, err)
+	moderator, err := s.GetUserByEmail(test_store.Users[2].Email)
+	assert.NoError(t, err)
+	themes, err := s.GetThemes()
+	assert.NoError(t, err)
+	h := &models.Hokku{Title: "Spam", Content: "Buy now", OwnerId: author.Id, ThemeId: themes[0].Id,
+		Status: models.StatusPublished, Visibility: models.VisibilityPublic}
+	hokkuId, err := s.CreateHokku(h)
+	assert.NoError(t, err)
+
+	report := &models.Report{ReporterId: reporter.Id, HokkuId: hokkuId, AuthorId: author.Id, Reason: models.ReasonSpam}
+	reportId, err := s.CreateReport(report)
+	assert.NoError(t, err)
+	_, err = s.CreateReport(&models.Report{ReporterId: reporter.Id, HokkuId: hokkuId, AuthorId: author.Id, Reason: models.ReasonAbuse})
+	assert.ErrorIs(t, err, store.ErrAlreadyExist)
+	_, err = s.CreateReport(&models.Report{ReporterId: reporter.Id, AuthorId: author.Id, Reason: models.ReasonAbuse})
+	assert.NoError(t, err)
+	// The content filter reports a comment to the hokku
+	commentId, err := s.CreateComment(&models.Comment{HokkuId: hokkuId, OwnerId: author.Id, Content: "spam.com"})
+	assert.NoError(t, err)
+	filtered := &models.Report{HokkuId: hokkuId, CommentId: commentId, AuthorId: author.Id, Reason: models.ReasonFilter}
+	filteredId, err := s.CreateReport(filtered)
+	assert.NoError(t, err)
+	_, err = s.CreateReport(&models.Report{HokkuId: hokkuId, CommentId: commentId, AuthorId: author.Id, Reason: models.ReasonFilter})
+	assert.ErrorIs(t, err, store.ErrAlreadyExist)
+	saved, err := s.GetReport(filteredId)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, saved.ReporterId)
+	assert.Equal(t, commentId, saved.CommentId)
+
+	open, err := s.GetReports("", 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, open, 3)
+	assert.NoError(t, s.ClaimReport(reportId, moderator.Id))
+	assert.NoError(t, s.ClaimReport(reportId, moderator.Id))
+	assert.ErrorIs(t, s.ClaimReport(reportId, reporter.Id), store.ErrAlreadyExist)
+	_, err = s.ResolveReport(reportId, reporter.Id, models.ResolutionDismiss)
+	assert.ErrorIs(t, err, store.ErrAlreadyExist)
+
+	assert.NoError(t, s.HideHokku(hokkuId))
+	resolved, err := s.ResolveReport(reportId, moderator.Id, models.ResolutionHide)
+	assert.NoError(t, err)
+	if assert.Len(t, resolved, 1) {
+		assert.Equal(t, models.ReportResolved, resolved[0].Status)
+		assert.Equal(t, moderator.Id, resolved[0].ModeratorId)
+		assert.NotNil(t, resolved[0].Resolved)
+	}
+	_, err = s.GetHokku(reporter.Id, hokkuId)
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+	hidden, err := s.GetHokku(author.Id, hokkuId)
+	assert.NoError(t, err)
+	assert.True(t, hidden.Hidden)
+	hs, err := s.GetHokkusByAuthor(0, author.Id, 100, 0)
+	assert.NoError(t, err)
+	for _, h := range hs {
+		assert.NotEqual(t, hokkuId, h.Id)
+	}
+	assert.NoError(t, s.UnhideHokku(hokkuId))
+	_, err = s.GetHokku(reporter.Id, hokkuId)
+	assert.NoError(t, err)
+
+	// Hiding the user hides the hokkus posted later too
+	assert.NoError(t, s.HideUser(author.Id))
+	later, err := s.CreateHokku(&models.Hokku{Title: "Later", Content: "Buy again", OwnerId: author.Id, ThemeId: themes[0].Id,
+		Status: models.StatusPublished, Visibility: models.VisibilityPublic})
+	assert.NoError(t, err)
+	for _, id := range []int{hokkuId, later} {
+		_, err = s.GetHokku(reporter.Id, id)
+		assert.ErrorIs(t, err, store.ErrNoRecord)
+	}
+	hiddenUser, err := s.GetUser(author.Id)
+	assert.NoError(t, err)
+	assert.True(t, hiddenUser.Hidden)
+	assert.NoError(t, s.UnhideUser(author.Id))
+	_, err = s.GetHokku(reporter.Id, later)
+	assert.NoError(t, err)
+	assert.NoError(t, s.HideHokku(hokkuId))
+
+	_, err = s.CreateNotification(&models.Notification{UserId: reporter.Id, Type: models.NotificationReportResolved,
+		ReportId: reportId, HokkuId: hokkuId, Resolution: models.ResolutionHide})
+	assert.NoError(t, err)
+	notifications, err := s.GetNotifications(reporter.Id, 10, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, notifications, 1) {
+		assert.Equal(t, reportId, notifications[0].ReportId)
+		assert.Equal(t, models.ResolutionHide, notifications[0].Resolution)
+	}
+
+	assert.NoError(t, s.SetRole(moderator.Id, models.RoleModerator))
+	u, err := s.GetUser(moderator.Id)
+	assert.NoError(t, err)
+	assert.True(t, u.IsModerator())
+}
+
+func TestRestrictions(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants", "restrictions")
+	AddTestData(t, s)
+
+	user, err := s.GetUserByEmail(test_store.Users[0].Email)
+	assert.NoError(t, err)
+	viewer, err := s.GetUserByEmail(test_store.Users[1].Email)
+	assert.NoError(t, err)
+	moderator, err := s.GetUserByEmail(test_store.Users[2].Email)
+	assert.NoError(t, err)
+	listed, err := s.GetHokkusByAuthor(viewer.Id, user.Id, 100, 0)
+	assert.NoError(t, err)
+	if !assert.NotEmpty(t, listed) {
+		return
+	}
+
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	banId, err := s.CreateRestriction(&models.Restriction{UserId: user.Id, Kind: models.RestrictionBan, Reason: "Spam",
+		Expires: &expires, ModeratorId: moderator.Id})
+	assert.NoError(t, err)
+	shadowId, err := s.CreateRestriction(&models.Restriction{UserId: user.Id, Kind: models.RestrictionShadowBan, Reason: "Spam",
+		ModeratorId: moderator.Id})
+	assert.NoError(t, err)
+	_, err = s.CreateRestriction(&models.Restriction{UserId: 0, Kind: models.RestrictionBan, Reason: "Spam"})
+	assert.ErrorIs(t, err, store.ErrForeignKeyConstraint)
+
+	active, err := s.GetActiveRestrictions(user.Id, time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, active, 2)
+	active, err = s.GetActiveRestrictions(user.Id, expires.Add(time.Minute))
+	assert.NoError(t, err)
+	if assert.Len(t, active, 1) {
+		assert.Equal(t, shadowId, active[0].Id)
+	}
+
+	// Hokkus of shadow-banned users are shown only to themselves
+	hs, err := s.GetHokkusByAuthor(viewer.Id, user.Id, 100, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, hs)
+	_, err = s.GetHokku(viewer.Id, listed[0].Id)
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+	hs, err = s.GetHokkusByAuthor(user.Id, user.Id, 100, 0)
+	assert.NoError(t, err)
+	assert.Len(t, hs, len(listed))
+
+	r, err := s.GetRestriction(shadowId)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Id, r.UserId)
+	_, err = s.GetRestriction(0)
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+
+	assert.NoError(t, s.LiftRestriction(shadowId, moderator.Id))
+	assert.ErrorIs(t, s.LiftRestriction(shadowId, moderator.Id), store.ErrNoRecord)
+	hs, err = s.GetHokkusByAuthor(viewer.Id, user.Id, 100, 0)
+	assert.NoError(t, err)
+	assert.Len(t, hs, len(listed))
+
+	restrictions, err := s.GetRestrictions(user.Id)
+	assert.NoError(t, err)
+	if assert.Len(t, restrictions, 2) {
+		assert.Equal(t, shadowId, restrictions[0].Id)
+		assert.Equal(t, moderator.Id, restrictions[0].LiftedBy)
+		assert.NotNil(t, restrictions[0].Lifted)
+		assert.Equal(t, banId, restrictions[1].Id)
+		assert.Equal(t, moderator.Id, restrictions[1].ModeratorId)
+		assert.Equal(t, expires, *restrictions[1].Expires)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants", "follows", "likes", "comments",
+		"blocks", "mutes")
+	AddTestData(t, s)
+
+	owner, err := s.GetUserByEmail(test_store.Users[1].Email)
+	assert.NoError(t, err)
+	viewer, err := s.GetUserByEmail(test_store.Users[0].Email)
+	assert.NoError(t, err)
+	own, err := s.GetHokkusByAuthor(owner.Id, owner.Id, 100, 0)
+	assert.NoError(t, err)
+	var public, followers *models.Hokku
+	for _, h := range own {
+		switch h.Visibility {
+		case models.VisibilityPublic:
+			public = h
+		case models.VisibilityFollowers:
+			followers = h
+		}
+	}
+	if !assert.NotNil(t, public) || !assert.NotNil(t, followers) {
+		return
+	}
+	assert.NoError(t, s.Follow(viewer.Id, owner.Id))
+	_, err = s.GetHokku(viewer.Id, followers.Id)
+	assert.NoError(t, err)
+
+	// Blocking ends the following and keeps the user from coming back
+	assert.NoError(t, s.Block(owner.Id, viewer.Id))
+	assert.ErrorIs(t, s.Block(owner.Id, viewer.Id), store.ErrAlreadyExist)
+	assert.ErrorIs(t, s.Unfollow(viewer.Id, owner.Id), store.ErrNoRecord)
+	assert.ErrorIs(t, s.Follow(viewer.Id, owner.Id), store.ErrBlocked)
+	assert.ErrorIs(t, s.Like(viewer.Id, public.Id), store.ErrBlocked)
+	_, err = s.CreateComment(&models.Comment{HokkuId: public.Id, OwnerId: viewer.Id, Content: "Hi"})
+	assert.ErrorIs(t, err, store.ErrBlocked)
+	assert.ErrorIs(t, s.Like(viewer.Id, 100000), store.ErrForeignKeyConstraint)
+	blocks, err := s.GetBlocks(owner.Id)
+	assert.NoError(t, err)
+	if assert.Len(t, blocks, 1) {
+		assert.Equal(t, viewer.Id, blocks[0].BlockedId)
+	}
+
+	// Blocked users don't see followers-only hokkus even when still following
+	assert.NoError(t, s.Unblock(owner.Id, viewer.Id))
+	assert.ErrorIs(t, s.Unblock(owner.Id, viewer.Id), store.ErrNoRecord)
+	assert.NoError(t, s.Follow(viewer.Id, owner.Id))
+	_, err = s.DB.Exec("INSERT INTO blocks (blocker, blocked, created) VALUES (?, ?, NOW())", owner.Id, viewer.Id)
+	assert.NoError(t, err)
+	_, err = s.GetHokku(viewer.Id, followers.Id)
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+	_, err = s.GetHokku(viewer.Id, public.Id)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Unblock(owner.Id, viewer.Id))
+
+	// Muted users are left out of the feeds and the comments
+	_, err = s.CreateComment(&models.Comment{HokkuId: public.Id, OwnerId: owner.Id, Content: "Mine"})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Mute(viewer.Id, owner.Id))
+	assert.ErrorIs(t, s.Mute(viewer.Id, owner.Id), store.ErrAlreadyExist)
+	hs, err := s.GetHokkusByAuthor(viewer.Id, owner.Id, 100, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, hs)
+	comments, err := s.GetComments(viewer.Id, public.Id, 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, comments)
+	comments, err = s.GetComments(owner.Id, public.Id, 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, comments, 1)
+	mutes, err := s.GetMutes(viewer.Id)
+	assert.NoError(t, err)
+	assert.Len(t, mutes, 1)
+	assert.NoError(t, s.Unmute(viewer.Id, owner.Id))
+	hs, err = s.GetHokkusByAuthor(viewer.Id, owner.Id, 100, 0)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, hs)
+}
+
+func TestNotifications(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants", "notifications",
+		"notification_actors", "notification_preferences")
+	AddTestData(t, s)
+
+	author, err := s.GetUserByEmail(test_store.Users[0].Email)
+	assert.NoError(t, err)
+	fan, err := s.GetUserByEmail(test_store.Users[1].Email)
+	assert.NoError(t, err)
+	other, err := s.GetUserByEmail(test_store.Users[2].Email)
+	assert.NoError(t, err)
+	hs, err := s.GetHokkusByAuthor(author.Id, author.Id, 1, 0)
+	assert.NoError(t, err)
+	if !assert.NotEmpty(t, hs) {
+		return
+	}
+	like := func(actorId int) int {
+		id, err := s.CreateNotification(&models.Notification{UserId: author.Id, Type: models.NotificationLike,
+			ActorId: actorId, HokkuId: hs[0].Id})
+		assert.NoError(t, err)
+		return id
+	}
+
+	// Likes are gathered by distinct actors until read
+	first := like(fan.Id)
+	assert.Equal(t, first, like(other.Id))
+	assert.Equal(t, first, like(fan.Id))
+	followId, err := s.CreateNotification(&models.Notification{UserId: author.Id, Type: models.NotificationFollow, ActorId: fan.Id})
+	assert.NoError(t, err)
+	notifications, err := s.GetNotifications(author.Id, 10, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, notifications, 2) {
+		assert.Equal(t, followId, notifications[0].Id)
+		assert.Equal(t, first, notifications[1].Id)
+		assert.Equal(t, 2, notifications[1].Actors)
+		assert.Equal(t, fan.Id, notifications[1].ActorId)
+	}
+	unread, err := s.CountUnreadNotifications(author.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{models.NotificationLike: 1, models.NotificationFollow: 1}, unread)
+
+	assert.NoError(t, s.ReadNotification(author.Id, first))
+	assert.NoError(t, s.ReadNotification(author.Id, first))
+	assert.ErrorIs(t, s.ReadNotification(fan.Id, first), store.ErrNoRecord)
+	assert.NotEqual(t, first, like(other.Id))
+	assert.NoError(t, s.ReadNotifications(author.Id))
+	unread, err = s.CountUnreadNotifications(author.Id)
+	assert.NoError(t, err)
+	assert.Empty(t, unread)
+
+	assert.NoError(t, s.SetNotificationPreferences(author.Id, map[string]bool{models.NotificationLike: false}))
+	assert.NoError(t, s.SetNotificationPreferences(author.Id, map[string]bool{models.NotificationLike: true,
+		models.NotificationFollow: false}))
+	prefs, err := s.GetNotificationPreferences(author.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{models.NotificationLike: true, models.NotificationFollow: false}, prefs)
+}
+
+func TestAuditLog(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("audit_log")
+
+	_, err := s.CreateAuditEntry(&models.AuditEntry{Action: models.AuditLoginFailed, IP: "10.0.0.1", Details: "Wrong password"})
+	assert.NoError(t, err)
+	id, err := s.CreateAuditEntry(&models.AuditEntry{ActorId: 3, Action: models.AuditRoleChange, TargetType: models.TargetUser,
+		TargetId: 1, IP: "10.0.0.2", UserAgent: "test", Before: []byte(`{"role":"user"}`), After: []byte(`{"role":"admin"}`)})
+	assert.NoError(t, err)
+
+	entries, err := s.GetAuditLog(&models.AuditQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	entries, err = s.GetAuditLog(&models.AuditQuery{ActorId: 3, TargetType: models.TargetUser, TargetId: 1,
+		Since: time.Now().Add(-time.Hour), Until: time.Now().Add(time.Hour), Limit: 10})
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, id, entries[0].Id)
+		assert.JSONEq(t, `{"role":"user"}`, string(entries[0].Before))
+		assert.JSONEq(t, `{"role":"admin"}`, string(entries[0].After))
+	}
+	entries, err = s.GetAuditLog(&models.AuditQuery{Until: time.Now().Add(-time.Hour)})
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// The log is append-only
+	_, err = s.DB.Exec("UPDATE audit_log SET action = ? WHERE id = ?", models.AuditLogin, id)
+	assert.Error(t, err)
+	_, err = s.DB.Exec("DELETE FROM audit_log WHERE id = ?", id)
+	assert.Error(t, err)
+}
+
+func TestWebhooks(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants", "webhooks", "webhook_deliveries")
+	AddTestData(t, s)
+
+	owner, err := s.GetUserByEmail(test_store.Users[0].Email)
+	assert.NoError(t, err)
+	hook := &models.Webhook{OwnerId: owner.Id, URL: "https://example.com/hook",
+		Events: []string{models.WebhookHokkuCreated, models.WebhookUserCreated}, Secret: "secret"}
+	id, err := s.CreateWebhook(hook)
+	assert.NoError(t, err)
+	_, err = s.CreateWebhook(&models.Webhook{OwnerId: owner.Id, URL: "https://example.com/other",
+		Events: []string{models.WebhookHokkuDeleted}, Secret: "other"})
+	assert.NoError(t, err)
+	_, err = s.CreateWebhook(&models.Webhook{OwnerId: 100000, URL: "https://example.com", Events: []string{models.WebhookUserCreated}})
+	assert.ErrorIs(t, err, store.ErrForeignKeyConstraint)
+	got, err := s.GetWebhook(id)
+	assert.NoError(t, err)
+	assert.Equal(t, hook.Events, got.Events)
+	assert.Equal(t, "secret", got.Secret)
+	hooks, err := s.GetWebhooks(owner.Id)
+	assert.NoError(t, err)
+	assert.Len(t, hooks, 2)
+
+	// Only subscribed webhooks get the event
+	now := time.Now().Truncate(time.Second)
+	n, err := s.EnqueueWebhookDeliveries("key", models.WebhookHokkuCreated, []byte(`{"event":"hokku.created"}`), now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = s.EnqueueWebhookDeliveries("key", models.WebhookHokkuCreated, []byte(`{"event":"hokku.created"}`), now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	due, err := s.GetDueWebhookDeliveries(now.Add(-time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, due)
+	due, err = s.GetDueWebhookDeliveries(now, 10)
+	assert.NoError(t, err)
+	if !assert.Len(t, due, 1) {
+		return
+	}
+	d := due[0]
+	assert.Equal(t, id, d.WebhookId)
+	assert.Equal(t, "key", d.Key)
+	assert.JSONEq(t, `{"event":"hokku.created"}`, string(d.Payload))
+
+	d.Status, d.Attempts, d.StatusCode, d.Delivered = models.DeliveryDelivered, 1, 200, &now
+	assert.NoError(t, s.UpdateWebhookDelivery(d))
+	due, err = s.GetDueWebhookDeliveries(now, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, due)
+	deliveries, err := s.GetWebhookDeliveries(id, 10, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, models.DeliveryDelivered, deliveries[0].Status)
+		assert.Equal(t, 200, deliveries[0].StatusCode)
+		assert.NotNil(t, deliveries[0].Delivered)
+	}
+
+	// Deleting the webhook deletes its log
+	assert.NoError(t, s.DeleteWebhook(id))
+	assert.ErrorIs(t, s.DeleteWebhook(id), store.ErrNoRecord)
+	_, err = s.GetWebhook(id)
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+	deliveries, err = s.GetWebhookDeliveries(id, 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, deliveries)
+}
+
+func TestOutbox(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants", "outbox")
+	AddTestData(t, s)
+
+	owner, err := s.GetUserByEmail(test_store.Users[0].Email)
+	assert.NoError(t, err)
+	themes, err := s.GetThemes()
+	assert.NoError(t, err)
+	h := &models.Hokku{Title: "Outbox", Content: "Old pond", OwnerId: owner.Id, ThemeId: themes[0].Id,
+		Status: models.StatusPublished, Visibility: models.VisibilityPublic}
+	id, err := s.CreateHokku(h)
+	assert.NoError(t, err)
+	h.Visibility = models.VisibilityPrivate
+	assert.NoError(t, s.UpdateHokku(h))
+	assert.NoError(t, s.DeleteHokku(id))
+
+	// Failed changes write no events
+	_, err = s.CreateHokku(&models.Hokku{Title: "Outbox", Content: "Old pond", OwnerId: owner.Id, ThemeId: 100000})
+	assert.ErrorIs(t, err, store.ErrForeignKeyConstraint)
+	_, err = s.CreateUser(&models.User{Name: "Twin", Email: owner.Email})
+	assert.ErrorIs(t, err, store.ErrAlreadyExist)
+	assert.ErrorIs(t, s.DeleteHokku(id), store.ErrNoRecord)
+
+	events, err := s.GetOutboxEvents(1000)
+	assert.NoError(t, err)
+	types, listed := []string{}, []bool{}
+	for _, e := range events {
+		if e.Type == models.EventUserCreated || e.AggregateId != id {
+			continue
+		}
+		p := &models.HokkuEvent{}
+		assert.NoError(t, json.Unmarshal(e.Payload, p))
+		assert.Equal(t, themes[0].Id, p.ThemeId)
+		assert.Len(t, e.Key, 32)
+		types, listed = append(types, e.Type), append(listed, p.Listed)
+	}
+	assert.Equal(t, []string{models.EventHokkuCreated, models.EventHokkuUpdated, models.EventHokkuDeleted}, types)
+	assert.Equal(t, []bool{true, false, false}, listed)
+
+	// Failed events are parked after the given number of attempts
+	parked, err := s.FailOutboxEvent(events[0].Id, 2)
+	assert.NoError(t, err)
+	assert.False(t, parked)
+	parked, err = s.FailOutboxEvent(events[0].Id, 2)
+	assert.NoError(t, err)
+	assert.True(t, parked)
+	_, err = s.FailOutboxEvent(0, 2)
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+	rest, err := s.GetOutboxEvents(1000)
+	assert.NoError(t, err)
+	if assert.Len(t, rest, len(events)-1) {
+		assert.Equal(t, events[1].Id, rest[0].Id)
+	}
+
+	users := 0
+	for _, e := range events {
+		if e.Type == models.EventUserCreated {
+			users++
+		}
+		assert.NoError(t, s.DeleteOutboxEvent(e.Id))
+	}
+	assert.GreaterOrEqual(t, users, len(test_store.Users))
+	assert.ErrorIs(t, s.DeleteOutboxEvent(events[0].Id), store.ErrNoRecord)
+	events, err = s.GetOutboxEvents(1000)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestWithTx(t *testing.T) {
+	s, teardown := mysql_store.TestMysqlStore(t)
+	defer teardown("users", "themes", "hokkus", "hokku_tags", "chains", "chain_participants", "outbox")
+	AddTestData(t, s)
+
+	owner, err := s.GetUserByEmail(test_store.Users[0].Email)
+	assert.NoError(t, err)
+	themes, err := s.GetThemes()
+	assert.NoError(t, err)
+	newHokku := func() *models.Hokku {
+		return &models.Hokku{Title: "Unit of work", Content: "Old pond", OwnerId: owner.Id, ThemeId: themes[0].Id,
+			Status: models.StatusPublished, Visibility: models.VisibilityPublic}
+	}
+	exists := func(id int) bool {
+		_, err := s.GetHokku(owner.Id, id)
+		return err == nil
+	}
+
+	// Dropped changes are rolled back along with their outbox events
+	var dropped int
+	err = s.WithTx(context.Background(), func(tx store.Store) error {
+		dropped, err = tx.CreateHokku(newHokku())
+		if err != nil {
+			return err
+		}
+		return tx.DeleteHokku(100000)
+	})
+	assert.ErrorIs(t, err, store.ErrNoRecord)
+	assert.False(t, exists(dropped))
+	events, err := s.GetOutboxEvents(1000)
+	assert.NoError(t, err)
+	for _, e := range events {
+		assert.NotEqual(t, dropped, e.AggregateId)
+	}
+	assert.Panics(t, func() {
+		s.WithTx(context.Background(), func(tx store.Store) error {
+			dropped, _ = tx.CreateHokku(newHokku())
+			panic("failed")
+		})
+	})
+	assert.False(t, exists(dropped))
+
+	// Nested units of work roll back only their own changes
+	var kept, nested int
+	err = s.WithTx(context.Background(), func(tx store.Store) error {
+		kept, err = tx.CreateHokku(newHokku())
+		if err != nil {
+			return err
+		}
+		err = tx.WithTx(context.Background(), func(tx store.Store) error {
+			nested, err = tx.CreateHokku(newHokku())
+			if err != nil {
+				return err
+			}
+			return store.ErrBlocked
+		})
+		assert.ErrorIs(t, err, store.ErrBlocked)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, exists(kept))
+	assert.False(t, exists(nested))
+}
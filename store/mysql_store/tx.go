@@ -0,0 +1,102 @@
+package mysql_store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/EgorSkurihin/Hokku/store"
+)
+
+// querier runs statements on the database or inside a transaction.
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// transaction is a transaction begun by a store method. Inside a unit of
+// work it is a savepoint, so the method commits or rolls back only its own
+// statements.
+type transaction interface {
+	querier
+	Commit() error
+	Rollback() error
+}
+
+// savepoint is a transaction nested in the transaction of a unit of work.
+type savepoint struct {
+	*sql.Tx
+	name string
+	done bool
+}
+
+func (sp *savepoint) Commit() error {
+	if sp.done {
+		return sql.ErrTxDone
+	}
+	sp.done = true
+	_, err := sp.Tx.Exec("RELEASE SAVEPOINT " + sp.name)
+	return err
+}
+
+func (sp *savepoint) Rollback() error {
+	if sp.done {
+		return sql.ErrTxDone
+	}
+	sp.done = true
+	_, err := sp.Tx.Exec("ROLLBACK TO SAVEPOINT " + sp.name)
+	return err
+}
+
+// conn returns the transaction of the unit of work, or the database
+// outside of one.
+func (s *MySqlStore) conn() querier {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.DB
+}
+
+// begin begins a transaction, or a savepoint inside a unit of work.
+func (s *MySqlStore) begin() (transaction, error) {
+	return s.beginTx(context.Background())
+}
+
+func (s *MySqlStore) beginTx(ctx context.Context) (transaction, error) {
+	if s.tx == nil {
+		return s.DB.BeginTx(ctx, nil)
+	}
+	*s.savepoints++
+	sp := &savepoint{Tx: s.tx, name: fmt.Sprintf("sp%d", *s.savepoints)}
+	if _, err := s.tx.Exec("SAVEPOINT " + sp.name); err != nil {
+		return nil, err
+	}
+	return sp, nil
+}
+
+// WithTx runs fn with a store whose methods all run in one transaction. The
+// transaction is committed when fn returns nil and rolled back when it
+// returns an error or panics. Units of work nested in fn are savepoints.
+// The store given to fn must not be used concurrently or after fn returns.
+func (s *MySqlStore) WithTx(ctx context.Context, fn func(store.Store) error) error {
+	t, err := s.beginTx(ctx)
+	if err != nil {
+		return err
+	}
+	tx := &MySqlStore{dsn: s.dsn, DB: s.DB, tx: s.tx, savepoints: s.savepoints}
+	if sqlTx, ok := t.(*sql.Tx); ok {
+		tx.tx, tx.savepoints = sqlTx, new(int)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			t.Rollback()
+			panic(p)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		t.Rollback()
+		return err
+	}
+	return t.Commit()
+}
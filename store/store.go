@@ -1,7 +1,9 @@
 package store
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"github.com/EgorSkurihin/Hokku/models"
 )
@@ -10,28 +12,258 @@ var (
 	ErrAlreadyExist         = errors.New("duplicate entry")
 	ErrNoRecord             = errors.New("no matching entry found")
 	ErrForeignKeyConstraint = errors.New("foreign key constraint fails")
+	ErrBlocked              = errors.New("blocked by the user")
 )
 
+// Store persists users, themes, hokkus with their tags and reactions, the
+// kigo dictionary, chains and contests. Deleted users and hokkus stay in
+// the trash until purged and are ignored by every read method except
+// GetTrash. Hokkus hidden by moderators and hokkus of shadow-banned users
+// are read only by their owners.
 type Store interface {
 	Open() error
 	Close()
 
+	// WithTx runs the function as a unit of work: the changes made through
+	// the store it is given are kept together when the function returns
+	// nil and dropped together when it returns an error or panics.
+	WithTx(context.Context, func(Store) error) error
+
+	// Themes are returned in their sort order. Deleting a theme makes its
+	// subthemes root themes.
 	GetThemes() ([]*models.Theme, error)
+	GetTheme(int) (*models.Theme, error)
+	GetThemeBySlug(string) (*models.Theme, error)
 	CreateTheme(*models.Theme) (int, error)
+	UpdateTheme(*models.Theme) error
 	DeleteTheme(int) error
+	// MoveHokkus moves the hokkus and the chains of the theme to another
+	// one and returns the number of moved hokkus.
+	MoveHokkus(from, to int) (int, error)
+	// CountHokkusByTheme returns the number of public hokkus per theme id.
+	CountHokkusByTheme() (map[int]int, error)
 
 	GetUsers() ([]*models.User, error)
 	GetUser(int) (*models.User, error)
 	GetUserByEmail(string) (*models.User, error)
+	// CreateUser writes the user.created event to the outbox.
 	CreateUser(*models.User) (int, error)
 	DeleteUser(int) error
 	UpdateUser(*models.User) error
 
-	GetHokkus(int, int) ([]*models.Hokku, error)
-	GetHokkusByAuthor(int, int, int) ([]*models.Hokku, error)
-	GetHokkusByTheme(int, int, int) ([]*models.Hokku, error)
-	GetHokku(int) (*models.Hokku, error)
+	// Hokku read methods take the id of the viewing user first (0 for
+	// anonymous viewers) and return only hokkus visible to them. Lists
+	// leave out hokkus of the users muted by the viewer. Hokkus are
+	// normalized on write and come back with both Content and Lines.
+	GetHokkus(int, int, int) ([]*models.Hokku, error)
+	GetHokkusByAuthor(int, int, int, int) ([]*models.Hokku, error)
+	// GetHokkusByTheme includes hokkus of the descendant themes when the
+	// flag is set.
+	GetHokkusByTheme(int, int, bool, int, int) ([]*models.Hokku, error)
+	GetHokkusByTag(int, string, int, int) ([]*models.Hokku, error)
+	GetHokku(int, int) (*models.Hokku, error)
+	// GetRandomHokku returns a random hokku listed to the viewer from the
+	// theme, or from any theme when the theme id is 0.
+	GetRandomHokku(int, int) (*models.Hokku, error)
+	// CreateHokku, DeleteHokku and UpdateHokku write the hokku.created,
+	// hokku.deleted and hokku.updated events to the outbox in the same
+	// transaction as the change.
 	CreateHokku(*models.Hokku) (int, error)
 	DeleteHokku(int) error
 	UpdateHokku(*models.Hokku) error
+
+	// Likes and bookmarks are kept once per user and hokku. Bookmarked
+	// hokkus are returned if they are still visible to the user. Like and
+	// CreateComment return ErrBlocked when the owner of the hokku blocked
+	// the user. GetComments takes the id of the viewing user first and
+	// leaves out comments of the users muted by them.
+	Like(int, int) error
+	Unlike(int, int) error
+	Bookmark(int, int) error
+	Unbookmark(int, int) error
+	GetBookmarks(int, int, int) ([]*models.Hokku, error)
+	GetComments(int, int, int, int) ([]*models.Comment, error)
+	GetComment(int) (*models.Comment, error)
+	CreateComment(*models.Comment) (int, error)
+	DeleteComment(int) error
+
+	// GetEngagement returns the reactions to the hokkus listed to anonymous
+	// viewers. SaveScores replaces the saved scores. Trending hokkus are
+	// ordered by their trending score, top hokkus are published since the
+	// time and ordered by their score.
+	GetEngagement() ([]*models.Engagement, error)
+	SaveScores([]*models.HokkuScore) error
+	GetTrendingHokkus(int, int, int) ([]*models.Hokku, error)
+	GetTopHokkus(int, time.Time, int, int) ([]*models.Hokku, error)
+
+	// AddViews adds the views to the daily views of the hokkus, views of
+	// removed hokkus are dropped. GetViews returns the daily views of the
+	// hokku from the oldest day.
+	AddViews([]*models.DailyViews) error
+	GetViews(int) ([]*models.DailyViews, error)
+
+	// Author statistics count the published hokkus of the author that are
+	// not deleted and the reactions to them. Popular hokkus are ordered by
+	// likes, comments and views, themes by the number of hokkus. Posting
+	// days are ascending, daily activity starts from the time.
+	GetAuthorTotals(int) (*models.AuthorTotals, error)
+	GetPopularHokkus(int, int) ([]*models.HokkuStats, error)
+	GetThemeStats(int) ([]*models.ThemeStats, error)
+	GetPostingDays(int) ([]string, error)
+	GetActivity(int, time.Time) ([]*models.Activity, error)
+
+	// CreateChain saves the chain with its first stanza and sets their ids.
+	// AppendStanza adds the stanza at its position replying to the previous
+	// stanza and returns ErrAlreadyExist if the position is taken. Stanzas
+	// are returned in order, the chain length counts deleted stanzas too.
+	CreateChain(*models.Chain, *models.Hokku) (int, error)
+	GetChain(int) (*models.Chain, error)
+	GetChainStanzas(int) ([]*models.Hokku, error)
+	AppendStanza(*models.Hokku) (int, error)
+
+	// Contests are returned from the latest one. Entries are the hokkus
+	// submitted to the contest that are still published, public or
+	// unlisted and shown, ballots are the votes of its users, one per
+	// user. PublishContestResults returns ErrAlreadyExist when the
+	// results are already published.
+	GetContests() ([]*models.Contest, error)
+	GetContest(int) (*models.Contest, error)
+	CreateContest(*models.Contest) (int, error)
+	GetContestEntries(int) ([]*models.Hokku, error)
+	CreateContestEntry(*models.ContestEntry) error
+	GetBallots(int) ([]*models.Ballot, error)
+	CreateBallot(*models.Ballot) error
+	// GetUnjudgedContests returns the contests whose voting closed by the
+	// time and whose results are not published yet.
+	GetUnjudgedContests(time.Time) ([]*models.Contest, error)
+	PublishContestResults(int, []*models.ContestResult, time.Time) error
+
+	// Daily picks are kept per day and timezone. GetDailyPicks returns the
+	// picks of the timezone from the day on, latest first. CreateDailyPick
+	// returns ErrAlreadyExist when the day already has a pick.
+	// GetPublicHokkuIds returns the ids of the hokkus listed to anonymous
+	// viewers.
+	GetPublicHokkuIds() ([]int, error)
+	GetDailyPicks(string, string) ([]*models.DailyPick, error)
+	CreateDailyPick(*models.DailyPick) error
+
+	GetDrafts(int, int, int) ([]*models.Hokku, error)
+	PublishScheduled(time.Time) (int, error)
+
+	GetKigo() ([]*models.Kigo, error)
+	CreateKigo(*models.Kigo) (int, error)
+	UpdateKigo(*models.Kigo) error
+	DeleteKigo(int) error
+
+	// Fingerprints of hokkus and classics are saved on write, see
+	// models.Fingerprint. FindDuplicates returns the hokkus of other users
+	// visible to the user and the classics whose fingerprints differ from
+	// the fingerprint in at most the given number of bits.
+	FindDuplicates(int, uint64, int) (*models.Duplicates, error)
+	GetClassics(int, int) ([]*models.Classic, error)
+	CreateClassic(*models.Classic) (int, error)
+	DeleteClassic(int) error
+	// Flags are returned from the newest one.
+	CreateFlag(*models.Flag) (int, error)
+	GetFlags(int, int) ([]*models.Flag, error)
+
+	// CreateReport returns ErrAlreadyExist when the reporter has an
+	// unresolved report on the same hokku, comment or user. The content
+	// filter reports as reporter 0. GetReports returns the reports with the
+	// status from the oldest one, unresolved reports when the status is
+	// empty. ClaimReport and ResolveReport return ErrAlreadyExist when the
+	// report is resolved or claimed by another moderator. ResolveReport
+	// resolves all unresolved reports on the same hokku, comment or user
+	// and returns them.
+	CreateReport(*models.Report) (int, error)
+	GetReports(string, int, int) ([]*models.Report, error)
+	GetReport(int) (*models.Report, error)
+	ClaimReport(int, int) error
+	ResolveReport(int, int, string) ([]*models.Report, error)
+	// HideHokku hides the hokku from everyone but its owner, HideUser hides
+	// all hokkus of the user, including the ones posted later. UnhideHokku
+	// and UnhideUser show them again.
+	HideHokku(int) error
+	HideUser(int) error
+	UnhideHokku(int) error
+	UnhideUser(int) error
+	// SetRole changes the role of the user.
+	SetRole(int, string) error
+
+	// Restrictions of the user are returned from the newest one. Active
+	// restrictions are neither lifted nor expired by the time.
+	// LiftRestriction records the moderator lifting the restriction and
+	// returns ErrNoRecord when it is not found or already lifted.
+	CreateRestriction(*models.Restriction) (int, error)
+	GetRestriction(int) (*models.Restriction, error)
+	GetRestrictions(int) ([]*models.Restriction, error)
+	GetActiveRestrictions(int, time.Time) ([]*models.Restriction, error)
+	LiftRestriction(int, int) error
+
+	// The audit log is append-only. GetAuditLog returns the entries selected
+	// by the query from the newest one, all of them when the limit is 0.
+	CreateAuditEntry(*models.AuditEntry) (int, error)
+	GetAuditLog(*models.AuditQuery) ([]*models.AuditEntry, error)
+
+	// Notifications are returned from the latest activity. Aggregated
+	// notifications are gathered into the unread notification of the same
+	// type about the same hokku, CreateNotification returns its id then.
+	// CountUnreadNotifications counts unread notifications by type.
+	// ReadNotification returns ErrNoRecord when the user has no such
+	// notification. Notification preferences keep the types the user turned
+	// on or off, the other types are on.
+	CreateNotification(*models.Notification) (int, error)
+	GetNotifications(int, int, int) ([]*models.Notification, error)
+	CountUnreadNotifications(int) (map[string]int, error)
+	ReadNotification(int, int) error
+	ReadNotifications(int) error
+	GetNotificationPreferences(int) (map[string]bool, error)
+	SetNotificationPreferences(int, map[string]bool) error
+
+	// GetOutboxEvents returns at most limit events from the earliest one.
+	// Relayed events are deleted.
+	GetOutboxEvents(int) ([]*models.OutboxEvent, error)
+	DeleteOutboxEvent(int) error
+	// FailOutboxEvent counts a failed relay of the event and parks it once
+	// it failed the given number of times. It tells whether the event was
+	// parked. Parked events are not returned by GetOutboxEvents.
+	FailOutboxEvent(id, attempts int) (bool, error)
+
+	// Webhooks of the owner are returned from the newest one, all of them
+	// when the owner is 0. EnqueueWebhookDeliveries queues the payload of
+	// the event with the key for every webhook subscribed to it, due at the
+	// time, and returns their number. Webhooks get every key once.
+	// GetDueWebhookDeliveries returns at most limit pending deliveries due
+	// at the time from the earliest one. Deliveries of a webhook are
+	// returned from the newest one.
+	CreateWebhook(*models.Webhook) (int, error)
+	GetWebhook(int) (*models.Webhook, error)
+	GetWebhooks(int) ([]*models.Webhook, error)
+	DeleteWebhook(int) error
+	EnqueueWebhookDeliveries(string, string, []byte, time.Time) (int, error)
+	GetDueWebhookDeliveries(time.Time, int) ([]*models.WebhookDelivery, error)
+	UpdateWebhookDelivery(*models.WebhookDelivery) error
+	GetWebhookDeliveries(int, int, int) ([]*models.WebhookDelivery, error)
+
+	// Follow returns ErrBlocked when the followee blocked the follower.
+	Follow(int, int) error
+	Unfollow(int, int) error
+	// Blocks and mutes are kept once per pair of users and returned from
+	// the newest one. Blocking ends the following of the blocked user.
+	Block(int, int) error
+	Unblock(int, int) error
+	GetBlocks(int) ([]*models.Block, error)
+	Mute(int, int) error
+	Unmute(int, int) error
+	GetMutes(int) ([]*models.Mute, error)
+
+	// Trash methods take the earliest deletion time that is still
+	// restorable.
+	GetTrash(int, time.Time, int, int) ([]*models.Hokku, error)
+	RestoreHokku(int, int, time.Time) error
+	RestoreUser(int, time.Time) error
+	// GetDeletedUserByEmail returns the restorable user deleted with the
+	// email.
+	GetDeletedUserByEmail(string, time.Time) (*models.User, error)
+	PurgeDeleted(time.Time) (int, error)
 }
@@ -2,26 +2,96 @@ package test_store
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/EgorSkurihin/Hokku/models"
 )
 
 var (
+	ScheduledAt = time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)
+	// Mock contests take entries for a week from ContestStart and then
+	// take votes for another week
+	ContestStart = time.Date(2030, time.February, 1, 0, 0, 0, 0, time.UTC)
+
 	Users = []*models.User{
-		{Id: 1, Email: "example1@email.com", Name: "Example1", HashedPassword: "$2a$10$0UWpDXKCrmtrUAEXWkczk.hJdHusoMlaZAu8wvbNenU/mR3kF9fsy"},
-		{Id: 2, Email: "example2@email.com", Name: "Example2", HashedPassword: "$2a$10$0UWpDXKCrmtrUAEXWkczk.hJdHusoMlaZAu8wvbNenU/mR3kF9fsy"},
-		{Id: 3, Email: "example3@email.com", Name: "Example3", HashedPassword: "$2a$10$0UWpDXKCrmtrUAEXWkczk.hJdHusoMlaZAu8wvbNenU/mR3kF9fsy"},
+		{Id: 1, Email: "example1@email.com", Name: "Example1", HashedPassword: "$2a$10$0UWpDXKCrmtrUAEXWkczk.hJdHusoMlaZAu8wvbNenU/mR3kF9fsy", Role: models.RoleUser},
+		{Id: 2, Email: "example2@email.com", Name: "Example2", HashedPassword: "$2a$10$0UWpDXKCrmtrUAEXWkczk.hJdHusoMlaZAu8wvbNenU/mR3kF9fsy", Role: models.RoleUser},
+		{Id: 3, Email: "example3@email.com", Name: "Example3", HashedPassword: "$2a$10$0UWpDXKCrmtrUAEXWkczk.hJdHusoMlaZAu8wvbNenU/mR3kF9fsy", Role: models.RoleAdmin},
 	}
 	Hokkus = []*models.Hokku{
-		{Id: 1, Title: "Title1", Content: "Content", OwnerId: 1, ThemeId: 1},
-		{Id: 2, Title: "Title2", Content: "Content", OwnerId: 2, ThemeId: 2},
-		{Id: 3, Title: "Title3", Content: "Content", OwnerId: 3, ThemeId: 1},
-		{Id: 4, Title: "Title4", Content: "Content", OwnerId: 1, ThemeId: 2},
-		{Id: 5, Title: "Title5", Content: "Content", OwnerId: 2, ThemeId: 1},
+		{Id: 1, Title: "Title1", Content: "Content", Lines: []string{"Content"}, Tags: []string{"nature"}, OwnerId: 1, ThemeId: 1, Status: models.StatusPublished, Visibility: models.VisibilityPublic},
+		{Id: 2, Title: "Title2", Content: "First snow", Lines: []string{"First snow"}, Tags: []string{"nature", "season:winter"}, OwnerId: 2, ThemeId: 2, Status: models.StatusPublished, Visibility: models.VisibilityPublic},
+		{Id: 3, Title: "Title3", Content: "Content", Lines: []string{"Content"}, Tags: []string{}, OwnerId: 3, ThemeId: 1, Status: models.StatusPublished, Visibility: models.VisibilityPublic},
+		{Id: 4, Title: "Title4", Content: "Content", Lines: []string{"Content"}, Tags: []string{}, OwnerId: 1, ThemeId: 2, Status: models.StatusPublished, Visibility: models.VisibilityPublic},
+		{Id: 5, Title: "Title5", Content: "Content", Lines: []string{"Content"}, Tags: []string{}, OwnerId: 2, ThemeId: 1, Status: models.StatusPublished, Visibility: models.VisibilityPublic},
+		{Id: 6, Title: "Title6", Content: "Content", Lines: []string{"Content"}, Tags: []string{}, OwnerId: 1, ThemeId: 1, Status: models.StatusDraft, Visibility: models.VisibilityPublic},
+		{Id: 7, Title: "Title7", Content: "Content", Lines: []string{"Content"}, Tags: []string{}, OwnerId: 1, ThemeId: 2, Status: models.StatusScheduled, PublishAt: &ScheduledAt, Visibility: models.VisibilityPublic},
+		{Id: 8, Title: "Title8", Content: "Content", Lines: []string{"Content"}, Tags: []string{}, OwnerId: 2, ThemeId: 1, Status: models.StatusPublished, Visibility: models.VisibilityUnlisted},
+		{Id: 9, Title: "Title9", Content: "Content", Lines: []string{"Content"}, Tags: []string{}, OwnerId: 2, ThemeId: 2, Status: models.StatusPublished, Visibility: models.VisibilityFollowers},
+		{Id: 10, Title: "Title10", Content: "Content", Lines: []string{"Content"}, Tags: []string{}, OwnerId: 2, ThemeId: 1, Status: models.StatusPublished, Visibility: models.VisibilityPrivate},
+		{Id: 11, Title: "Title11", Content: "Content", Lines: []string{"Content"}, Tags: []string{}, OwnerId: 3, ThemeId: 4, Status: models.StatusPublished, Visibility: models.VisibilityPublic},
+		{Id: 12, Title: "Title12", Content: "Content", Lines: []string{"Content"}, Tags: []string{}, OwnerId: 1, ThemeId: 2, Status: models.StatusPublished, Visibility: models.VisibilityPublic, ChainId: 1, Position: 1},
+		{Id: 13, Title: "Title13", Content: "Content", Lines: []string{"Content"}, Tags: []string{}, OwnerId: 3, ThemeId: 2, Status: models.StatusPublished, Visibility: models.VisibilityPublic, ChainId: 1, ParentId: 12, Position: 2},
 	}
 	Themes = []*models.Theme{
-		{Id: 1, Title: "exampleTheme1"},
-		{Id: 2, Title: "exampleTheme2"},
+		{Id: 1, Title: "exampleTheme1", Slug: "example-theme-1", Description: "Description"},
+		{Id: 2, Title: "exampleTheme2", Slug: "example-theme-2"},
+		{Id: 3, Title: "exampleTheme3", Slug: "example-theme-3", Strict: true},
+		{Id: 4, Title: "exampleTheme4", Slug: "example-theme-4", ParentId: 1, Cover: "Cover"},
+		{Id: 5, Title: "exampleTheme5", Slug: "example-theme-5", PostingClosed: true},
+	}
+	Kigo = []*models.Kigo{
+		{Id: 1, Word: "first snow", Season: models.SeasonWinter},
+		{Id: 2, Word: "autumn wind", Season: models.SeasonAutumn},
+		{Id: 3, Word: "cherry blossoms", Season: models.SeasonSpring},
+		{Id: 4, Word: "первый снег", Season: models.SeasonWinter},
+	}
+	// Users 1 and 3 write the chain in turns, it's user 1's turn
+	Chains = []*models.Chain{
+		{Id: 1, Title: "Chain1", OwnerId: 1, ThemeId: 2, Participants: []int{1, 3}, Length: 2},
+	}
+	Contests = []*models.Contest{
+		{Id: 1, Title: "Contest1", ThemeId: 1, VotingMode: models.VotingSingle,
+			OpensAt: ContestStart, ClosesAt: ContestStart.AddDate(0, 0, 7),
+			VotingOpensAt: ContestStart.AddDate(0, 0, 7), VotingClosesAt: ContestStart.AddDate(0, 0, 14)},
+		{Id: 2, Title: "Contest2", ThemeId: 2, VotingMode: models.VotingRanked,
+			OpensAt: ContestStart, ClosesAt: ContestStart.AddDate(0, 0, 7),
+			VotingOpensAt: ContestStart.AddDate(0, 0, 7), VotingClosesAt: ContestStart.AddDate(0, 0, 14)},
+	}
+	ContestEntries = []*models.ContestEntry{
+		{ContestId: 1, HokkuId: 1},
+		{ContestId: 1, HokkuId: 5},
+		{ContestId: 2, HokkuId: 2},
+		{ContestId: 2, HokkuId: 4},
+	}
+	// User 3 voted in both contests
+	Ballots = []*models.Ballot{
+		{ContestId: 1, VoterId: 3, HokkuIds: []int{1}},
+		{ContestId: 2, VoterId: 3, HokkuIds: []int{4, 2}},
+	}
+	Likes = []*models.Like{
+		{UserId: 2, HokkuId: 1},
+		{UserId: 3, HokkuId: 1},
+		{UserId: 1, HokkuId: 2},
+	}
+	Bookmarks = []*models.Bookmark{
+		{UserId: 1, HokkuId: 3},
+		{UserId: 1, HokkuId: 8},
+	}
+	Comments = []*models.Comment{
+		{Id: 1, HokkuId: 1, OwnerId: 2, Content: "Beautiful"},
+		{Id: 2, HokkuId: 1, OwnerId: 3, Content: "Indeed"},
+	}
+	Views = []*models.DailyViews{
+		{HokkuId: 4, Day: "2030-01-01", Views: 10},
+		{HokkuId: 4, Day: "2030-01-02", Views: 5},
+	}
+	Classics = []*models.Classic{
+		{Id: 1, Title: "Old pond", Author: "Matsuo Basho", Content: "An old silent pond\nA frog jumps into the pond\nSplash! Silence again"},
+	}
+	// User 1 follows user 2
+	Follows = []*models.Follow{
+		{FollowerId: 1, FolloweeId: 2},
 	}
 )
 
@@ -38,7 +108,18 @@ func MockUsers() []byte {
 }
 
 func MockHokkus(left, right int) []byte {
-	res, _ := json.Marshal(Hokkus[left:right])
+	hs := make([]*models.Hokku, 0)
+	for _, h := range Hokkus {
+		if h.Listed(0, false) {
+			hs = append(hs, h)
+		}
+	}
+	var res []byte
+	if right == -1 {
+		res, _ = json.Marshal(hs[left:])
+	} else {
+		res, _ = json.Marshal(hs[left:right])
+	}
 	res = append(res, 10)
 	return res
 }
@@ -46,7 +127,7 @@ func MockHokkus(left, right int) []byte {
 func MockHokkusByTheme(themeId, left, right int) []byte {
 	hs := make([]*models.Hokku, 0)
 	for _, h := range Hokkus {
-		if h.ThemeId == themeId {
+		if h.ThemeId == themeId && h.Listed(0, false) {
 			hs = append(hs, h)
 		}
 	}
@@ -63,7 +144,7 @@ func MockHokkusByTheme(themeId, left, right int) []byte {
 func MockHokkusByUser(userId, left, right int) []byte {
 	hs := make([]*models.Hokku, 0)
 	for _, h := range Hokkus {
-		if h.OwnerId == userId {
+		if h.OwnerId == userId && h.Listed(0, false) {
 			hs = append(hs, h)
 		}
 	}
@@ -77,6 +158,48 @@ func MockHokkusByUser(userId, left, right int) []byte {
 	return res
 }
 
+func MockDrafts(userId int) []byte {
+	hs := make([]*models.Hokku, 0)
+	for _, h := range Hokkus {
+		if h.OwnerId == userId && !h.IsPublished() {
+			hs = append(hs, h)
+		}
+	}
+	res, _ := json.Marshal(hs)
+	res = append(res, 10)
+	return res
+}
+
+// MockHokkusByThemeTree returns public hokkus of the theme and its
+// descendants.
+func MockHokkusByThemeTree(themeId int) []byte {
+	themeIds := map[int]bool{}
+	for _, id := range models.DescendantIds(Themes, themeId) {
+		themeIds[id] = true
+	}
+	hs := make([]*models.Hokku, 0)
+	for _, h := range Hokkus {
+		if themeIds[h.ThemeId] && h.Listed(0, false) {
+			hs = append(hs, h)
+		}
+	}
+	res, _ := json.Marshal(hs)
+	res = append(res, 10)
+	return res
+}
+
+func MockChain(id int) []byte {
+	stanzas := make([]*models.Hokku, 0)
+	for _, h := range Hokkus {
+		if h.ChainId == id && h.DeletedAt == nil {
+			stanzas = append(stanzas, h)
+		}
+	}
+	res, _ := json.Marshal(&models.ChainDetails{Chain: Chains[id-1], Stanzas: stanzas})
+	res = append(res, 10)
+	return res
+}
+
 func MockHokku(id int) []byte {
 	res, _ := json.Marshal(Hokkus[id-1])
 	res = append(res, 10)
@@ -89,8 +212,57 @@ func MockUser(id int) []byte {
 	return res
 }
 
+func MockKigo() []byte {
+	res, _ := json.Marshal(Kigo)
+	res = append(res, 10)
+	return res
+}
+
+// MockHokkusByTag returns hokkus with the tag listed to anonymous viewers.
+func MockHokkusByTag(tag string) []byte {
+	hs := make([]*models.Hokku, 0)
+	for _, h := range Hokkus {
+		if !h.Listed(0, false) {
+			continue
+		}
+		for _, t := range h.Tags {
+			if t == tag {
+				hs = append(hs, h)
+			}
+		}
+	}
+	res, _ := json.Marshal(hs)
+	res = append(res, 10)
+	return res
+}
+
 func MockTheme(id int) []byte {
 	res, _ := json.Marshal(Themes[id-1])
 	res = append(res, 10)
 	return res
 }
+
+// MockContest returns the contest with its entries in the phase.
+func MockContest(id int, phase string) []byte {
+	entries := make([]*models.Hokku, 0)
+	for _, e := range ContestEntries {
+		if e.ContestId == id {
+			entries = append(entries, Hokkus[e.HokkuId-1])
+		}
+	}
+	res, _ := json.Marshal(&models.ContestDetails{Contest: Contests[id-1], Phase: phase, Entries: entries})
+	res = append(res, 10)
+	return res
+}
+
+func MockComments(hokkuId int) []byte {
+	cs := make([]*models.Comment, 0)
+	for _, c := range Comments {
+		if c.HokkuId == hokkuId {
+			cs = append(cs, c)
+		}
+	}
+	res, _ := json.Marshal(cs)
+	res = append(res, 10)
+	return res
+}
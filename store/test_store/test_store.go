@@ -1,22 +1,119 @@
 package test_store
 
 import (
+	"context"
+	"math/rand"
+	"reflect"
+	"sort"
+	"time"
+
 	"github.com/EgorSkurihin/Hokku/models"
 	"github.com/EgorSkurihin/Hokku/store"
 )
 
 type TestStore struct {
-	Users  []*models.User
-	Hokkus []*models.Hokku
-	Themes []*models.Theme
+	Users   []*models.User
+	Hokkus  []*models.Hokku
+	Themes  []*models.Theme
+	Follows []*models.Follow
+	Blocks  []*models.Block
+	Mutes   []*models.Mute
+	Kigo    []*models.Kigo
+	Chains  []*models.Chain
+
+	Likes     []*models.Like
+	Bookmarks []*models.Bookmark
+	Comments  []*models.Comment
+	Views     []*models.DailyViews
+	Scores    []*models.HokkuScore
+
+	Contests       []*models.Contest
+	ContestEntries []*models.ContestEntry
+	Ballots        []*models.Ballot
+
+	DailyPicks []*models.DailyPick
+
+	Classics []*models.Classic
+	Flags    []*models.Flag
+
+	Reports       []*models.Report
+	Notifications []*models.Notification
+	Restrictions  []*models.Restriction
+	AuditLog      []*models.AuditEntry
+
+	Outbox            []*models.OutboxEvent
+	Webhooks          []*models.Webhook
+	WebhookDeliveries []*models.WebhookDelivery
+
+	// NotificationPreferences keeps the preferences by user and
+	// notificationActors the distinct actors by notification.
+	NotificationPreferences map[int]map[string]bool
+	notificationActors      map[int]map[int]bool
 }
 
+// New returns a store filled with copies of the mock data, so changes made
+// through one store don't leak into the mocks or other stores.
 func New() *TestStore {
-	return &TestStore{
-		Users:  Users,
-		Hokkus: Hokkus,
-		Themes: Themes,
+	s := &TestStore{}
+	for _, u := range Users {
+		cp := *u
+		s.Users = append(s.Users, &cp)
+	}
+	for _, h := range Hokkus {
+		cp := *h
+		s.Hokkus = append(s.Hokkus, &cp)
+	}
+	for _, t := range Themes {
+		cp := *t
+		s.Themes = append(s.Themes, &cp)
+	}
+	for _, f := range Follows {
+		cp := *f
+		s.Follows = append(s.Follows, &cp)
+	}
+	for _, k := range Kigo {
+		cp := *k
+		s.Kigo = append(s.Kigo, &cp)
+	}
+	for _, c := range Chains {
+		cp := *c
+		cp.Participants = append([]int{}, c.Participants...)
+		s.Chains = append(s.Chains, &cp)
+	}
+	for _, l := range Likes {
+		cp := *l
+		s.Likes = append(s.Likes, &cp)
+	}
+	for _, b := range Bookmarks {
+		cp := *b
+		s.Bookmarks = append(s.Bookmarks, &cp)
+	}
+	for _, c := range Comments {
+		cp := *c
+		s.Comments = append(s.Comments, &cp)
 	}
+	for _, v := range Views {
+		cp := *v
+		s.Views = append(s.Views, &cp)
+	}
+	for _, c := range Classics {
+		cp := *c
+		s.Classics = append(s.Classics, &cp)
+	}
+	for _, c := range Contests {
+		cp := *c
+		s.Contests = append(s.Contests, &cp)
+	}
+	for _, e := range ContestEntries {
+		cp := *e
+		s.ContestEntries = append(s.ContestEntries, &cp)
+	}
+	for _, b := range Ballots {
+		cp := *b
+		cp.HokkuIds = append([]int{}, b.HokkuIds...)
+		s.Ballots = append(s.Ballots, &cp)
+	}
+	return s
 }
 
 func (s *TestStore) Open() error {
@@ -25,44 +122,229 @@ func (s *TestStore) Open() error {
 
 func (s *TestStore) Close() {}
 
+// WithTx runs fn on the store itself and puts back a copy of the data
+// taken before when fn returns an error or panics.
+func (s *TestStore) WithTx(ctx context.Context, fn func(store.Store) error) error {
+	saved := s.snapshot()
+	defer func() {
+		if p := recover(); p != nil {
+			*s = *saved
+			panic(p)
+		}
+	}()
+	if err := fn(s); err != nil {
+		*s = *saved
+		return err
+	}
+	return nil
+}
+
+// snapshot returns a copy of the store sharing nothing with it.
+func (s *TestStore) snapshot() *TestStore {
+	cp := deepCopy(reflect.ValueOf(s).Elem()).Addr().Interface().(*TestStore)
+	cp.notificationActors = map[int]map[int]bool{}
+	for id, actors := range s.notificationActors {
+		cp.notificationActors[id] = map[int]bool{}
+		for actor := range actors {
+			cp.notificationActors[id][actor] = true
+		}
+	}
+	return cp
+}
+
+// deepCopy copies the value with everything it points to. Unexported
+// struct fields are copied as they are.
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type().Elem())
+		cp.Elem().Set(deepCopy(v.Elem()))
+		return cp
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return cp
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			cp.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return cp
+	case reflect.Struct:
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if cp.Field(i).CanSet() {
+				cp.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return cp
+	}
+	return v
+}
+
 func (s *TestStore) GetThemes() ([]*models.Theme, error) {
-	return s.Themes, nil
+	res := append([]*models.Theme{}, s.Themes...)
+	models.SortThemes(res)
+	return res, nil
+}
+
+func (s *TestStore) GetTheme(id int) (*models.Theme, error) {
+	for _, t := range s.Themes {
+		if t.Id == id {
+			return t, nil
+		}
+	}
+	return nil, store.ErrNoRecord
+}
+
+func (s *TestStore) GetThemeBySlug(slug string) (*models.Theme, error) {
+	for _, t := range s.Themes {
+		if t.Slug == slug {
+			return t, nil
+		}
+	}
+	return nil, store.ErrNoRecord
+}
+
+// checkTheme mirrors the unique and foreign key constraints of themes.
+func (s *TestStore) checkTheme(theme *models.Theme) error {
+	for _, t := range s.Themes {
+		if t.Id != theme.Id && (t.Title == theme.Title || t.Slug == theme.Slug) {
+			return store.ErrAlreadyExist
+		}
+	}
+	if theme.ParentId != 0 {
+		if _, err := s.GetTheme(theme.ParentId); err != nil {
+			return store.ErrForeignKeyConstraint
+		}
+	}
+	return nil
 }
 
 func (s *TestStore) CreateTheme(theme *models.Theme) (int, error) {
+	theme.Id = 0
+	if err := s.checkTheme(theme); err != nil {
+		return 0, err
+	}
+	theme.Id = 1
 	for _, t := range s.Themes {
-		if t.Title == theme.Title {
-			return 0, store.ErrAlreadyExist
+		if t.Id >= theme.Id {
+			theme.Id = t.Id + 1
 		}
 	}
-	theme.Id = len(s.Users) + 1
 	s.Themes = append(s.Themes, theme)
 	return theme.Id, nil
 }
 
+func (s *TestStore) UpdateTheme(theme *models.Theme) error {
+	for i, t := range s.Themes {
+		if t.Id == theme.Id {
+			if err := s.checkTheme(theme); err != nil {
+				return err
+			}
+			s.Themes[i] = theme
+			return nil
+		}
+	}
+	return store.ErrNoRecord
+}
+
+// DeleteTheme mirrors the MySQL schema: hokkus of the theme are removed
+// and its subthemes become root themes.
+func (s *TestStore) MoveHokkus(from, to int) (int, error) {
+	if _, err := s.GetTheme(to); err != nil {
+		return 0, store.ErrForeignKeyConstraint
+	}
+	ids := s.hokkuIds(func(h *models.Hokku) bool { return h.ThemeId == from })
+	err := s.changeHokkus(models.EventHokkuUpdated, ids, func() {
+		for _, id := range ids {
+			s.Hokkus[s.hokkuIndex(id)].ThemeId = to
+		}
+		for _, c := range s.Chains {
+			if c.ThemeId == from {
+				c.ThemeId = to
+			}
+		}
+	})
+	return len(ids), err
+}
+
 func (s *TestStore) DeleteTheme(id int) error {
-	if id < 0 || id > len(s.Themes)-1 {
-		return store.ErrNoRecord
+	for i, t := range s.Themes {
+		if t.Id != id {
+			continue
+		}
+		s.Themes = append(s.Themes[:i], s.Themes[i+1:]...)
+		for _, sub := range s.Themes {
+			if sub.ParentId == id {
+				sub.ParentId = 0
+			}
+		}
+		hokkus := make([]*models.Hokku, 0, len(s.Hokkus))
+		for _, h := range s.Hokkus {
+			if h.ThemeId != id {
+				hokkus = append(hokkus, h)
+			}
+		}
+		s.Hokkus = hokkus
+		return nil
 	}
-	s.Themes[id] = s.Themes[len(s.Themes)-1]
-	s.Themes = s.Themes[:len(s.Themes)-1]
-	return nil
+	return store.ErrNoRecord
+}
+
+func (s *TestStore) CountHokkusByTheme() (map[int]int, error) {
+	counts := map[int]int{}
+	for _, h := range s.filterHokkus(func(h *models.Hokku) bool { return s.listed(0, h) }) {
+		counts[h.ThemeId]++
+	}
+	return counts, nil
+}
+
+// userIndex returns the position of the user in s.Users, deleted users
+// included, or -1.
+func (s *TestStore) userIndex(id int) int {
+	for i, u := range s.Users {
+		if u.Id == id {
+			return i
+		}
+	}
+	return -1
 }
 
 func (s *TestStore) GetUsers() ([]*models.User, error) {
-	return s.Users, nil
+	res := make([]*models.User, 0)
+	for _, u := range s.Users {
+		if u.DeletedAt == nil {
+			res = append(res, u)
+		}
+	}
+	return res, nil
 }
 
 func (s *TestStore) GetUser(id int) (*models.User, error) {
-	if id < 0 || id > len(s.Users) {
+	i := s.userIndex(id)
+	if i == -1 || s.Users[i].DeletedAt != nil {
 		return nil, store.ErrNoRecord
 	}
-	return s.Users[id-1], nil
+	return s.Users[i], nil
 }
 
 func (s *TestStore) GetUserByEmail(email string) (*models.User, error) {
 	for _, u := range s.Users {
-		if u.Email == email {
+		if u.Email == email && u.DeletedAt == nil {
 			return u, nil
 		}
 	}
@@ -75,122 +357,1731 @@ func (s *TestStore) CreateUser(user *models.User) (int, error) {
 			return 0, store.ErrAlreadyExist
 		}
 	}
-	user.Id = len(s.Users) + 1
+	user.Id = 1
+	for _, u := range s.Users {
+		if u.Id >= user.Id {
+			user.Id = u.Id + 1
+		}
+	}
 	s.Users = append(s.Users, user)
+	if err := s.writeEvent(models.EventUserCreated, user.Id, &models.UserEvent{Id: user.Id, Name: user.Name}); err != nil {
+		return 0, err
+	}
 	return user.Id, nil
 }
 
 func (s *TestStore) DeleteUser(id int) error {
-	if id < 0 || id > len(s.Users)-1 {
-		return store.ErrNoRecord
+	u, err := s.GetUser(id)
+	if err != nil {
+		return err
 	}
-	s.Users[id] = s.Users[len(s.Users)-1]
-	s.Users = s.Users[:len(s.Users)-1]
-	return nil
+	now := time.Now()
+	ids := s.hokkuIds(func(h *models.Hokku) bool { return h.OwnerId == id && h.DeletedAt == nil })
+	return s.changeHokkus(models.EventHokkuDeleted, ids, func() {
+		u.DeletedAt = &now
+		for _, hokkuId := range ids {
+			s.Hokkus[s.hokkuIndex(hokkuId)].DeletedAt = &now
+		}
+	})
 }
 
 func (s *TestStore) UpdateUser(user *models.User) error {
-	if user.Id < 0 || user.Id > len(s.Users) {
-		return store.ErrNoRecord
+	u, err := s.GetUser(user.Id)
+	if err != nil {
+		return err
 	}
-	s.Users[user.Id-1] = user
+	// Like the MySQL store, the role and the hidden flag can't be changed
+	// by an update
+	user.Role, user.Hidden = u.Role, u.Hidden
+	s.Users[s.userIndex(user.Id)] = user
 	return nil
 }
 
-func (s *TestStore) GetHokkus(limit, offset int) ([]*models.Hokku, error) {
-	var res []*models.Hokku
-	if limit != 0 {
-		if offset >= len(s.Hokkus) {
-			res = make([]*models.Hokku, 0)
-		}
-		if limit+offset >= len(s.Hokkus) {
-			res = s.Hokkus[offset:]
-		}
-		if limit+offset < len(s.Hokkus) {
-			res = s.Hokkus[offset : offset+limit]
-		}
-	} else {
-		res = s.Hokkus
+func paginate(hs []*models.Hokku, limit, offset int) []*models.Hokku {
+	if offset >= len(hs) {
+		return make([]*models.Hokku, 0)
 	}
-	return res, nil
+	if limit == 0 || limit+offset >= len(hs) {
+		return hs[offset:]
+	}
+	return hs[offset : offset+limit]
 }
 
-func (s *TestStore) GetHokkusByAuthor(authorId, limit, offset int) ([]*models.Hokku, error) {
+func (s *TestStore) filterHokkus(match func(*models.Hokku) bool) []*models.Hokku {
 	res := make([]*models.Hokku, 0)
 	for _, h := range s.Hokkus {
-		if h.OwnerId == authorId {
+		if h.DeletedAt == nil && match(h) {
 			res = append(res, h)
 		}
 	}
-	if limit != 0 {
-		if limit+offset >= len(res) {
-			if offset >= len(res) {
-				res = make([]*models.Hokku, 0)
-			} else {
-				res = res[offset:]
-			}
+	return res
+}
+
+// hokkuIndex returns the position of the hokku in s.Hokkus, deleted
+// hokkus included, or -1.
+func (s *TestStore) hokkuIndex(id int) int {
+	for i, h := range s.Hokkus {
+		if h.Id == id {
+			return i
 		}
-		if limit+offset < len(res) {
-			res = res[offset : offset+limit]
+	}
+	return -1
+}
+
+func (s *TestStore) isFollower(followerId, followeeId int) bool {
+	for _, f := range s.Follows {
+		if f.FollowerId == followerId && f.FolloweeId == followeeId {
+			return true
 		}
 	}
-	return res, nil
+	return false
 }
 
-func (s *TestStore) GetHokkusByTheme(themeId, limit, offset int) ([]*models.Hokku, error) {
-	res := make([]*models.Hokku, 0)
-	for _, h := range s.Hokkus {
-		if h.ThemeId == themeId {
-			res = append(res, h)
+func (s *TestStore) blocked(blockerId, blockedId int) bool {
+	for _, b := range s.Blocks {
+		if b.BlockerId == blockerId && b.BlockedId == blockedId {
+			return true
 		}
 	}
-	if limit != 0 {
-		if limit+offset >= len(res) {
-			if offset >= len(res) {
-				res = make([]*models.Hokku, 0)
-			} else {
-				res = res[offset:]
-			}
+	return false
+}
+
+func (s *TestStore) muted(muterId, mutedId int) bool {
+	for _, m := range s.Mutes {
+		if m.MuterId == muterId && m.MutedId == mutedId {
+			return true
 		}
-		if limit+offset < len(res) {
-			res = res[offset : offset+limit]
+	}
+	return false
+}
+
+// follows reports whether the viewer follows the owner of the hokku and
+// isn't blocked by them.
+func (s *TestStore) follows(viewerId int, h *models.Hokku) bool {
+	return s.isFollower(viewerId, h.OwnerId) && !s.blocked(h.OwnerId, viewerId)
+}
+
+// shadowBanned reports whether the hokkus of the user are shown only to
+// the user.
+func (s *TestStore) shadowBanned(userId int) bool {
+	for _, r := range s.Restrictions {
+		if r.UserId == userId && r.Kind == models.RestrictionShadowBan && r.Active(time.Now()) {
+			return true
 		}
 	}
-	return res, nil
+	return false
+}
+
+// shown reports whether the hokku is shown to users other than its owner.
+func (s *TestStore) shown(h *models.Hokku) bool {
+	if i := s.userIndex(h.OwnerId); i != -1 && s.Users[i].Hidden {
+		return false
+	}
+	return !h.Hidden && !s.shadowBanned(h.OwnerId)
 }
 
-func (s *TestStore) GetHokku(id int) (*models.Hokku, error) {
-	if id < 0 || id > len(s.Hokkus) {
+func (s *TestStore) listed(viewerId int, h *models.Hokku) bool {
+	return h.Listed(viewerId, s.follows(viewerId, h)) &&
+		(h.OwnerId == viewerId || s.shown(h) && !s.muted(viewerId, h.OwnerId))
+}
+
+func (s *TestStore) visible(viewerId int, h *models.Hokku) bool {
+	return h.VisibleTo(viewerId, s.follows(viewerId, h)) && (h.OwnerId == viewerId || s.shown(h))
+}
+
+func (s *TestStore) GetHokkus(viewerId, limit, offset int) ([]*models.Hokku, error) {
+	res := s.filterHokkus(func(h *models.Hokku) bool {
+		return s.listed(viewerId, h)
+	})
+	return paginate(res, limit, offset), nil
+}
+
+func (s *TestStore) GetHokkusByAuthor(viewerId, authorId, limit, offset int) ([]*models.Hokku, error) {
+	res := s.filterHokkus(func(h *models.Hokku) bool {
+		return h.OwnerId == authorId && s.listed(viewerId, h)
+	})
+	return paginate(res, limit, offset), nil
+}
+
+func (s *TestStore) GetHokkusByTheme(viewerId, themeId int, withDescendants bool, limit, offset int) ([]*models.Hokku, error) {
+	themeIds := map[int]bool{themeId: true}
+	if withDescendants {
+		for _, id := range models.DescendantIds(s.Themes, themeId) {
+			themeIds[id] = true
+		}
+	}
+	res := s.filterHokkus(func(h *models.Hokku) bool {
+		return themeIds[h.ThemeId] && s.listed(viewerId, h)
+	})
+	return paginate(res, limit, offset), nil
+}
+
+func (s *TestStore) GetHokkusByTag(viewerId int, tag string, limit, offset int) ([]*models.Hokku, error) {
+	res := s.filterHokkus(func(h *models.Hokku) bool {
+		for _, t := range h.Tags {
+			if t == tag {
+				return s.listed(viewerId, h)
+			}
+		}
+		return false
+	})
+	return paginate(res, limit, offset), nil
+}
+
+func (s *TestStore) GetHokku(viewerId, id int) (*models.Hokku, error) {
+	i := s.hokkuIndex(id)
+	if i == -1 || s.Hokkus[i].DeletedAt != nil {
 		return nil, store.ErrNoRecord
 	}
-	return s.Hokkus[id-1], nil
+	h := s.Hokkus[i]
+	if !s.visible(viewerId, h) {
+		return nil, store.ErrNoRecord
+	}
+	return h, nil
+}
+
+func (s *TestStore) GetRandomHokku(viewerId, themeId int) (*models.Hokku, error) {
+	res := s.filterHokkus(func(h *models.Hokku) bool {
+		return (themeId == 0 || h.ThemeId == themeId) && s.listed(viewerId, h)
+	})
+	if len(res) == 0 {
+		return nil, store.ErrNoRecord
+	}
+	return res[rand.Intn(len(res))], nil
 }
 
 func (s *TestStore) CreateHokku(hokku *models.Hokku) (int, error) {
-	if hokku.ThemeId > len(s.Themes) || hokku.ThemeId < 1 {
+	if _, err := s.GetTheme(hokku.ThemeId); err != nil {
 		return 0, store.ErrForeignKeyConstraint
 	}
-	if hokku.OwnerId > len(s.Users) || hokku.OwnerId < 1 {
+	if s.userIndex(hokku.OwnerId) == -1 {
 		return 0, store.ErrForeignKeyConstraint
 	}
-	hokku.Id = len(s.Hokkus) + 1
+	hokku.Normalize()
+	hokku.Id = 1
+	for _, h := range s.Hokkus {
+		if h.Id >= hokku.Id {
+			hokku.Id = h.Id + 1
+		}
+	}
 	s.Hokkus = append(s.Hokkus, hokku)
+	if err := s.writeEvent(models.EventHokkuCreated, hokku.Id, s.hokkuEvent(hokku)); err != nil {
+		return 0, err
+	}
 	return hokku.Id, nil
 }
 
 func (s *TestStore) DeleteHokku(id int) error {
-	if id < 0 || id > len(s.Hokkus)-1 {
+	i := s.hokkuIndex(id)
+	if i == -1 || s.Hokkus[i].DeletedAt != nil {
 		return store.ErrNoRecord
 	}
-	s.Hokkus[id] = s.Hokkus[len(s.Hokkus)-1]
-	s.Hokkus = s.Hokkus[:len(s.Hokkus)-1]
-	return nil
+	now := time.Now()
+	return s.changeHokkus(models.EventHokkuDeleted, []int{id}, func() {
+		s.Hokkus[i].DeletedAt = &now
+	})
 }
 
 func (s *TestStore) UpdateHokku(hokku *models.Hokku) error {
-	if hokku.Id < 0 || hokku.Id > len(s.Hokkus) {
+	i := s.hokkuIndex(hokku.Id)
+	if i == -1 || s.Hokkus[i].DeletedAt != nil {
+		return store.ErrNoRecord
+	}
+	hokku.Normalize()
+	old := s.Hokkus[i]
+	hokku.ChainId, hokku.ParentId, hokku.Position = old.ChainId, old.ParentId, old.Position
+	hokku.OwnerId, hokku.ThemeId, hokku.Hidden = old.OwnerId, old.ThemeId, old.Hidden
+	return s.changeHokkus(models.EventHokkuUpdated, []int{hokku.Id}, func() {
+		s.Hokkus[i] = hokku
+	})
+}
+
+// changeHokkus runs the change of the hokkus and writes the event about
+// each of them to the outbox telling how it was listed before and after
+// the change.
+func (s *TestStore) changeHokkus(typ string, ids []int, change func()) error {
+	before := make([]bool, len(ids))
+	for i, id := range ids {
+		before[i] = s.hokkuEvent(s.Hokkus[s.hokkuIndex(id)]).Listed
+	}
+	change()
+	for i, id := range ids {
+		e := s.hokkuEvent(s.Hokkus[s.hokkuIndex(id)])
+		e.WasListed = before[i]
+		if err := s.writeEvent(typ, id, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hokkuEvent returns the payload of an event about the hokku. Hokkus that
+// are not shown, like the ones of hidden or shadow-banned users, count as
+// hidden.
+func (s *TestStore) hokkuEvent(h *models.Hokku) *models.HokkuEvent {
+	cp := *h
+	cp.Hidden = !s.shown(h)
+	return models.NewHokkuEvent(&cp)
+}
+
+// hokkuIds returns the ids of the hokkus matching the filter.
+func (s *TestStore) hokkuIds(match func(*models.Hokku) bool) []int {
+	ids := []int{}
+	for _, h := range s.Hokkus {
+		if match(h) {
+			ids = append(ids, h.Id)
+		}
+	}
+	return ids
+}
+
+// writeEvent appends the event about the aggregate to the outbox.
+func (s *TestStore) writeEvent(typ string, aggregateId int, payload interface{}) error {
+	e, err := models.NewOutboxEvent(typ, aggregateId, payload)
+	if err != nil {
+		return err
+	}
+	e.Id, e.Created = 1, time.Now()
+	for _, o := range s.Outbox {
+		if o.Id >= e.Id {
+			e.Id = o.Id + 1
+		}
+	}
+	s.Outbox = append(s.Outbox, e)
+	return nil
+}
+
+func (s *TestStore) GetOutboxEvents(limit int) ([]*models.OutboxEvent, error) {
+	res := []*models.OutboxEvent{}
+	for _, e := range s.Outbox {
+		if len(res) == limit {
+			break
+		}
+		if !e.Parked {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+func (s *TestStore) FailOutboxEvent(id, attempts int) (bool, error) {
+	for _, e := range s.Outbox {
+		if e.Id == id {
+			e.Attempts++
+			e.Parked = e.Attempts >= attempts
+			return e.Parked, nil
+		}
+	}
+	return false, store.ErrNoRecord
+}
+
+func (s *TestStore) DeleteOutboxEvent(id int) error {
+	for i, e := range s.Outbox {
+		if e.Id == id {
+			s.Outbox = append(s.Outbox[:i], s.Outbox[i+1:]...)
+			return nil
+		}
+	}
+	return store.ErrNoRecord
+}
+
+func (s *TestStore) Like(userId, hokkuId int) error {
+	i := s.hokkuIndex(hokkuId)
+	if i == -1 {
+		return store.ErrForeignKeyConstraint
+	}
+	if s.blocked(s.Hokkus[i].OwnerId, userId) {
+		return store.ErrBlocked
+	}
+	for _, l := range s.Likes {
+		if l.UserId == userId && l.HokkuId == hokkuId {
+			return store.ErrAlreadyExist
+		}
+	}
+	s.Likes = append(s.Likes, &models.Like{UserId: userId, HokkuId: hokkuId, Created: time.Now()})
+	return nil
+}
+
+func (s *TestStore) Unlike(userId, hokkuId int) error {
+	for i, l := range s.Likes {
+		if l.UserId == userId && l.HokkuId == hokkuId {
+			s.Likes = append(s.Likes[:i], s.Likes[i+1:]...)
+			return nil
+		}
+	}
+	return store.ErrNoRecord
+}
+
+func (s *TestStore) Bookmark(userId, hokkuId int) error {
+	if s.hokkuIndex(hokkuId) == -1 {
+		return store.ErrForeignKeyConstraint
+	}
+	for _, b := range s.Bookmarks {
+		if b.UserId == userId && b.HokkuId == hokkuId {
+			return store.ErrAlreadyExist
+		}
+	}
+	s.Bookmarks = append(s.Bookmarks, &models.Bookmark{UserId: userId, HokkuId: hokkuId, Created: time.Now()})
+	return nil
+}
+
+func (s *TestStore) Unbookmark(userId, hokkuId int) error {
+	for i, b := range s.Bookmarks {
+		if b.UserId == userId && b.HokkuId == hokkuId {
+			s.Bookmarks = append(s.Bookmarks[:i], s.Bookmarks[i+1:]...)
+			return nil
+		}
+	}
+	return store.ErrNoRecord
+}
+
+func (s *TestStore) GetBookmarks(userId, limit, offset int) ([]*models.Hokku, error) {
+	res := make([]*models.Hokku, 0)
+	for _, b := range s.Bookmarks {
+		i := s.hokkuIndex(b.HokkuId)
+		if b.UserId != userId || i == -1 {
+			continue
+		}
+		h := s.Hokkus[i]
+		if h.DeletedAt == nil && s.visible(userId, h) {
+			res = append(res, h)
+		}
+	}
+	return paginate(res, limit, offset), nil
+}
+
+func (s *TestStore) GetComments(viewerId, hokkuId, limit, offset int) ([]*models.Comment, error) {
+	res := make([]*models.Comment, 0)
+	for _, c := range s.Comments {
+		if c.HokkuId == hokkuId && !s.muted(viewerId, c.OwnerId) {
+			res = append(res, c)
+		}
+	}
+	if offset >= len(res) {
+		return []*models.Comment{}, nil
+	}
+	res = res[offset:]
+	if limit != 0 && limit < len(res) {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (s *TestStore) commentIndex(id int) int {
+	for i, c := range s.Comments {
+		if c.Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *TestStore) GetComment(id int) (*models.Comment, error) {
+	i := s.commentIndex(id)
+	if i == -1 {
+		return nil, store.ErrNoRecord
+	}
+	return s.Comments[i], nil
+}
+
+func (s *TestStore) CreateComment(comment *models.Comment) (int, error) {
+	i := s.hokkuIndex(comment.HokkuId)
+	if i == -1 || s.userIndex(comment.OwnerId) == -1 {
+		return 0, store.ErrForeignKeyConstraint
+	}
+	if s.blocked(s.Hokkus[i].OwnerId, comment.OwnerId) {
+		return 0, store.ErrBlocked
+	}
+	comment.Id = 1
+	for _, c := range s.Comments {
+		if c.Id >= comment.Id {
+			comment.Id = c.Id + 1
+		}
+	}
+	comment.Created = time.Now()
+	s.Comments = append(s.Comments, comment)
+	return comment.Id, nil
+}
+
+func (s *TestStore) DeleteComment(id int) error {
+	i := s.commentIndex(id)
+	if i == -1 {
 		return store.ErrNoRecord
 	}
-	s.Hokkus[hokku.Id-1] = hokku
+	s.Comments = append(s.Comments[:i], s.Comments[i+1:]...)
 	return nil
 }
+
+func (s *TestStore) GetEngagement() ([]*models.Engagement, error) {
+	res := make([]*models.Engagement, 0)
+	byHokku := map[int]*models.Engagement{}
+	for _, h := range s.filterHokkus(func(h *models.Hokku) bool { return s.listed(0, h) }) {
+		e := &models.Engagement{HokkuId: h.Id, Created: h.Created}
+		byHokku[h.Id] = e
+		res = append(res, e)
+	}
+	for _, l := range s.Likes {
+		if e, ok := byHokku[l.HokkuId]; ok {
+			e.Likes++
+		}
+	}
+	for _, c := range s.Comments {
+		if e, ok := byHokku[c.HokkuId]; ok {
+			e.Comments++
+		}
+	}
+	for _, b := range s.Bookmarks {
+		if e, ok := byHokku[b.HokkuId]; ok {
+			e.Bookmarks++
+		}
+	}
+	for _, v := range s.Views {
+		if e, ok := byHokku[v.HokkuId]; ok {
+			e.Views += v.Views
+		}
+	}
+	return res, nil
+}
+
+func (s *TestStore) SaveScores(scores []*models.HokkuScore) error {
+	s.Scores = scores
+	return nil
+}
+
+// rankHokkus orders the hokkus by the score from the highest one, newer
+// hokkus go first among equal ones.
+func (s *TestStore) rankHokkus(hs []*models.Hokku, score func(*models.HokkuScore) float64) {
+	scores := map[int]float64{}
+	for _, sc := range s.Scores {
+		scores[sc.HokkuId] = score(sc)
+	}
+	sort.SliceStable(hs, func(i, j int) bool {
+		if scores[hs[i].Id] != scores[hs[j].Id] {
+			return scores[hs[i].Id] > scores[hs[j].Id]
+		}
+		return hs[i].Id > hs[j].Id
+	})
+}
+
+func (s *TestStore) AddViews(views []*models.DailyViews) error {
+	for _, v := range views {
+		if s.hokkuIndex(v.HokkuId) < 0 {
+			continue
+		}
+		added := false
+		for _, saved := range s.Views {
+			if saved.HokkuId == v.HokkuId && saved.Day == v.Day {
+				saved.Views += v.Views
+				added = true
+			}
+		}
+		if !added {
+			cp := *v
+			s.Views = append(s.Views, &cp)
+		}
+	}
+	return nil
+}
+
+func (s *TestStore) GetViews(hokkuId int) ([]*models.DailyViews, error) {
+	res := []*models.DailyViews{}
+	for _, v := range s.Views {
+		if v.HokkuId == hokkuId {
+			cp := *v
+			res = append(res, &cp)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Day < res[j].Day })
+	return res, nil
+}
+
+// authorStats counts the reactions to the published hokkus of the author
+// in the order of the hokkus.
+func (s *TestStore) authorStats(userId int) []*models.HokkuStats {
+	res := []*models.HokkuStats{}
+	byHokku := map[int]*models.HokkuStats{}
+	for _, h := range s.filterHokkus(func(h *models.Hokku) bool {
+		return h.OwnerId == userId && h.Status == models.StatusPublished
+	}) {
+		st := &models.HokkuStats{HokkuId: h.Id, Title: h.Title}
+		byHokku[h.Id] = st
+		res = append(res, st)
+	}
+	for _, l := range s.Likes {
+		if st, ok := byHokku[l.HokkuId]; ok {
+			st.Likes++
+		}
+	}
+	for _, c := range s.Comments {
+		if st, ok := byHokku[c.HokkuId]; ok {
+			st.Comments++
+		}
+	}
+	for _, v := range s.Views {
+		if st, ok := byHokku[v.HokkuId]; ok {
+			st.Views += v.Views
+		}
+	}
+	return res
+}
+
+func (s *TestStore) GetAuthorTotals(userId int) (*models.AuthorTotals, error) {
+	totals := &models.AuthorTotals{}
+	for _, st := range s.authorStats(userId) {
+		totals.Hokkus++
+		totals.Likes += st.Likes
+		totals.Comments += st.Comments
+		totals.Views += st.Views
+	}
+	for _, f := range s.Follows {
+		if f.FolloweeId != userId {
+			continue
+		}
+		if i := s.userIndex(f.FollowerId); i >= 0 && s.Users[i].DeletedAt == nil {
+			totals.Followers++
+		}
+	}
+	return totals, nil
+}
+
+func (s *TestStore) GetPopularHokkus(userId, limit int) ([]*models.HokkuStats, error) {
+	res := s.authorStats(userId)
+	sort.SliceStable(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if a.Likes != b.Likes {
+			return a.Likes > b.Likes
+		}
+		if a.Comments != b.Comments {
+			return a.Comments > b.Comments
+		}
+		if a.Views != b.Views {
+			return a.Views > b.Views
+		}
+		return a.HokkuId > b.HokkuId
+	})
+	if limit < len(res) {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (s *TestStore) GetThemeStats(userId int) ([]*models.ThemeStats, error) {
+	res := []*models.ThemeStats{}
+	byTheme := map[int]*models.ThemeStats{}
+	for _, st := range s.authorStats(userId) {
+		h := s.Hokkus[s.hokkuIndex(st.HokkuId)]
+		ts, ok := byTheme[h.ThemeId]
+		if !ok {
+			ts = &models.ThemeStats{ThemeId: h.ThemeId}
+			if th, err := s.GetTheme(h.ThemeId); err == nil {
+				ts.Title = th.Title
+			}
+			byTheme[h.ThemeId] = ts
+			res = append(res, ts)
+		}
+		ts.Hokkus++
+		ts.Likes += st.Likes
+		ts.Comments += st.Comments
+		ts.Views += st.Views
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Hokkus != res[j].Hokkus {
+			return res[i].Hokkus > res[j].Hokkus
+		}
+		return res[i].ThemeId < res[j].ThemeId
+	})
+	return res, nil
+}
+
+func (s *TestStore) GetPostingDays(userId int) ([]string, error) {
+	days := []string{}
+	seen := map[string]bool{}
+	for _, st := range s.authorStats(userId) {
+		day := s.Hokkus[s.hokkuIndex(st.HokkuId)].Created.UTC().Format(models.DayLayout)
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	sort.Strings(days)
+	return days, nil
+}
+
+func (s *TestStore) GetActivity(userId int, since time.Time) ([]*models.Activity, error) {
+	byDay := map[string]*models.Activity{}
+	activity := func(t time.Time) *models.Activity {
+		day := t.UTC().Format(models.DayLayout)
+		if _, ok := byDay[day]; !ok {
+			byDay[day] = &models.Activity{Day: day}
+		}
+		return byDay[day]
+	}
+	authored := map[int]bool{}
+	for _, st := range s.authorStats(userId) {
+		authored[st.HokkuId] = true
+		if created := s.Hokkus[s.hokkuIndex(st.HokkuId)].Created; !created.Before(since) {
+			activity(created).Hokkus++
+		}
+	}
+	for _, l := range s.Likes {
+		if authored[l.HokkuId] && !l.Created.Before(since) {
+			activity(l.Created).Likes++
+		}
+	}
+	for _, c := range s.Comments {
+		if authored[c.HokkuId] && !c.Created.Before(since) {
+			activity(c.Created).Comments++
+		}
+	}
+	first := since.UTC().Format(models.DayLayout)
+	for _, v := range s.Views {
+		if authored[v.HokkuId] && v.Day >= first {
+			day, _ := time.Parse(models.DayLayout, v.Day)
+			activity(day).Views += v.Views
+		}
+	}
+	res := []*models.Activity{}
+	for _, a := range byDay {
+		res = append(res, a)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Day < res[j].Day })
+	return res, nil
+}
+
+func (s *TestStore) GetTrendingHokkus(viewerId, limit, offset int) ([]*models.Hokku, error) {
+	res := s.filterHokkus(func(h *models.Hokku) bool {
+		return s.listed(viewerId, h)
+	})
+	s.rankHokkus(res, func(sc *models.HokkuScore) float64 { return sc.Trending })
+	return paginate(res, limit, offset), nil
+}
+
+func (s *TestStore) GetTopHokkus(viewerId int, since time.Time, limit, offset int) ([]*models.Hokku, error) {
+	res := s.filterHokkus(func(h *models.Hokku) bool {
+		return !h.Created.Before(since) && s.listed(viewerId, h)
+	})
+	s.rankHokkus(res, func(sc *models.HokkuScore) float64 { return sc.Score })
+	return paginate(res, limit, offset), nil
+}
+
+func (s *TestStore) CreateChain(chain *models.Chain, first *models.Hokku) (int, error) {
+	if _, err := s.GetTheme(chain.ThemeId); err != nil {
+		return 0, store.ErrForeignKeyConstraint
+	}
+	for _, id := range chain.Participants {
+		if s.userIndex(id) == -1 {
+			return 0, store.ErrForeignKeyConstraint
+		}
+	}
+	chain.Id = 1
+	for _, c := range s.Chains {
+		if c.Id >= chain.Id {
+			chain.Id = c.Id + 1
+		}
+	}
+	chain.Created = time.Now()
+	first.ChainId, first.ParentId, first.Position = chain.Id, 0, 1
+	firstId, err := s.CreateHokku(first)
+	if err != nil {
+		return 0, err
+	}
+	first.Id = firstId
+	chain.Length = 1
+	s.Chains = append(s.Chains, chain)
+	return chain.Id, nil
+}
+
+// chainStanzas returns the stanzas of the chain in order, deleted ones
+// included.
+func (s *TestStore) chainStanzas(chainId int) []*models.Hokku {
+	res := make([]*models.Hokku, 0)
+	for _, h := range s.Hokkus {
+		if h.ChainId == chainId {
+			res = append(res, h)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Position < res[j].Position })
+	return res
+}
+
+func (s *TestStore) GetChain(id int) (*models.Chain, error) {
+	for _, c := range s.Chains {
+		if c.Id == id {
+			cp := *c
+			cp.Length = 0
+			if stanzas := s.chainStanzas(id); len(stanzas) > 0 {
+				cp.Length = stanzas[len(stanzas)-1].Position
+			}
+			return &cp, nil
+		}
+	}
+	return nil, store.ErrNoRecord
+}
+
+func (s *TestStore) GetChainStanzas(chainId int) ([]*models.Hokku, error) {
+	res := make([]*models.Hokku, 0)
+	for _, h := range s.chainStanzas(chainId) {
+		if h.DeletedAt == nil && s.shown(h) {
+			res = append(res, h)
+		}
+	}
+	return res, nil
+}
+
+func (s *TestStore) AppendStanza(stanza *models.Hokku) (int, error) {
+	if _, err := s.GetChain(stanza.ChainId); err != nil {
+		return 0, store.ErrForeignKeyConstraint
+	}
+	stanza.ParentId = 0
+	for _, h := range s.chainStanzas(stanza.ChainId) {
+		if h.Position == stanza.Position {
+			return 0, store.ErrAlreadyExist
+		}
+		if h.Position == stanza.Position-1 {
+			stanza.ParentId = h.Id
+		}
+	}
+	return s.CreateHokku(stanza)
+}
+
+func (s *TestStore) GetContests() ([]*models.Contest, error) {
+	res := make([]*models.Contest, 0, len(s.Contests))
+	for _, c := range s.Contests {
+		cp := *c
+		cp.Results = nil
+		res = append(res, &cp)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].OpensAt.After(res[j].OpensAt)
+	})
+	return res, nil
+}
+
+func (s *TestStore) contestIndex(id int) int {
+	for i, c := range s.Contests {
+		if c.Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *TestStore) GetContest(id int) (*models.Contest, error) {
+	i := s.contestIndex(id)
+	if i == -1 {
+		return nil, store.ErrNoRecord
+	}
+	cp := *s.Contests[i]
+	return &cp, nil
+}
+
+func (s *TestStore) CreateContest(contest *models.Contest) (int, error) {
+	if _, err := s.GetTheme(contest.ThemeId); err != nil {
+		return 0, store.ErrForeignKeyConstraint
+	}
+	contest.Id = 1
+	for _, c := range s.Contests {
+		if c.Id >= contest.Id {
+			contest.Id = c.Id + 1
+		}
+	}
+	s.Contests = append(s.Contests, contest)
+	return contest.Id, nil
+}
+
+func (s *TestStore) GetContestEntries(contestId int) ([]*models.Hokku, error) {
+	res := make([]*models.Hokku, 0)
+	for _, e := range s.ContestEntries {
+		i := s.hokkuIndex(e.HokkuId)
+		if e.ContestId == contestId && i != -1 && s.contestEntry(s.Hokkus[i]) {
+			res = append(res, s.Hokkus[i])
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Id < res[j].Id
+	})
+	return res, nil
+}
+
+// contestEntry reports whether the submitted hokku still takes part in the
+// contest.
+func (s *TestStore) contestEntry(h *models.Hokku) bool {
+	return h.DeletedAt == nil && h.IsPublished() && s.shown(h) &&
+		(h.Visibility == models.VisibilityPublic || h.Visibility == models.VisibilityUnlisted)
+}
+
+func (s *TestStore) CreateContestEntry(entry *models.ContestEntry) error {
+	if s.contestIndex(entry.ContestId) == -1 || s.hokkuIndex(entry.HokkuId) == -1 {
+		return store.ErrForeignKeyConstraint
+	}
+	for _, e := range s.ContestEntries {
+		if e.ContestId == entry.ContestId && e.HokkuId == entry.HokkuId {
+			return store.ErrAlreadyExist
+		}
+	}
+	s.ContestEntries = append(s.ContestEntries, entry)
+	return nil
+}
+
+func (s *TestStore) GetBallots(contestId int) ([]*models.Ballot, error) {
+	res := make([]*models.Ballot, 0)
+	for _, b := range s.Ballots {
+		if b.ContestId == contestId {
+			res = append(res, b)
+		}
+	}
+	return res, nil
+}
+
+func (s *TestStore) CreateBallot(ballot *models.Ballot) error {
+	if s.contestIndex(ballot.ContestId) == -1 || s.userIndex(ballot.VoterId) == -1 {
+		return store.ErrForeignKeyConstraint
+	}
+	for _, b := range s.Ballots {
+		if b.ContestId == ballot.ContestId && b.VoterId == ballot.VoterId {
+			return store.ErrAlreadyExist
+		}
+	}
+	ballot.Created = time.Now()
+	s.Ballots = append(s.Ballots, ballot)
+	return nil
+}
+
+func (s *TestStore) GetUnjudgedContests(now time.Time) ([]*models.Contest, error) {
+	res := make([]*models.Contest, 0)
+	for _, c := range s.Contests {
+		if c.ResultsPublishedAt == nil && !c.VotingClosesAt.After(now) {
+			res = append(res, c)
+		}
+	}
+	return res, nil
+}
+
+func (s *TestStore) PublishContestResults(contestId int, results []*models.ContestResult, now time.Time) error {
+	i := s.contestIndex(contestId)
+	if i == -1 {
+		return store.ErrNoRecord
+	}
+	if s.Contests[i].ResultsPublishedAt != nil {
+		return store.ErrAlreadyExist
+	}
+	s.Contests[i].ResultsPublishedAt = &now
+	s.Contests[i].Results = results
+	return nil
+}
+
+func (s *TestStore) GetPublicHokkuIds() ([]int, error) {
+	ids := []int{}
+	for _, h := range s.filterHokkus(func(h *models.Hokku) bool { return s.listed(0, h) }) {
+		ids = append(ids, h.Id)
+	}
+	return ids, nil
+}
+
+func (s *TestStore) GetDailyPicks(timezone, since string) ([]*models.DailyPick, error) {
+	res := make([]*models.DailyPick, 0)
+	for _, p := range s.DailyPicks {
+		if p.Timezone == timezone && p.Day >= since {
+			res = append(res, p)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Day > res[j].Day
+	})
+	return res, nil
+}
+
+func (s *TestStore) CreateDailyPick(pick *models.DailyPick) error {
+	if s.hokkuIndex(pick.HokkuId) == -1 {
+		return store.ErrForeignKeyConstraint
+	}
+	for _, p := range s.DailyPicks {
+		if p.Day == pick.Day && p.Timezone == pick.Timezone {
+			return store.ErrAlreadyExist
+		}
+	}
+	s.DailyPicks = append(s.DailyPicks, pick)
+	return nil
+}
+
+func (s *TestStore) GetDrafts(ownerId, limit, offset int) ([]*models.Hokku, error) {
+	res := s.filterHokkus(func(h *models.Hokku) bool {
+		return h.OwnerId == ownerId && !h.IsPublished()
+	})
+	return paginate(res, limit, offset), nil
+}
+
+func (s *TestStore) PublishScheduled(now time.Time) (int, error) {
+	ids := s.hokkuIds(func(h *models.Hokku) bool {
+		return h.DeletedAt == nil && h.Status == models.StatusScheduled && h.PublishAt != nil && !h.PublishAt.After(now)
+	})
+	err := s.changeHokkus(models.EventHokkuUpdated, ids, func() {
+		for _, id := range ids {
+			h := s.Hokkus[s.hokkuIndex(id)]
+			h.Status = models.StatusPublished
+			h.Created = *h.PublishAt
+			h.PublishAt = nil
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+func (s *TestStore) GetKigo() ([]*models.Kigo, error) {
+	return s.Kigo, nil
+}
+
+func (s *TestStore) kigoIndex(id int) int {
+	for i, k := range s.Kigo {
+		if k.Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *TestStore) CreateKigo(kigo *models.Kigo) (int, error) {
+	kigo.Id = 1
+	for _, k := range s.Kigo {
+		if k.Word == kigo.Word {
+			return 0, store.ErrAlreadyExist
+		}
+		if k.Id >= kigo.Id {
+			kigo.Id = k.Id + 1
+		}
+	}
+	s.Kigo = append(s.Kigo, kigo)
+	return kigo.Id, nil
+}
+
+func (s *TestStore) UpdateKigo(kigo *models.Kigo) error {
+	i := s.kigoIndex(kigo.Id)
+	if i == -1 {
+		return store.ErrNoRecord
+	}
+	for _, k := range s.Kigo {
+		if k.Word == kigo.Word && k.Id != kigo.Id {
+			return store.ErrAlreadyExist
+		}
+	}
+	s.Kigo[i] = kigo
+	return nil
+}
+
+func (s *TestStore) DeleteKigo(id int) error {
+	i := s.kigoIndex(id)
+	if i == -1 {
+		return store.ErrNoRecord
+	}
+	s.Kigo = append(s.Kigo[:i], s.Kigo[i+1:]...)
+	return nil
+}
+
+func (s *TestStore) FindDuplicates(userId int, fingerprint uint64, maxDistance int) (*models.Duplicates, error) {
+	d := &models.Duplicates{HokkuIds: []int{}, ClassicIds: []int{}}
+	for _, h := range s.filterHokkus(func(h *models.Hokku) bool {
+		return h.OwnerId != userId && s.visible(userId, h)
+	}) {
+		if models.FingerprintDistance(models.Fingerprint(h.Content), fingerprint) <= maxDistance {
+			d.HokkuIds = append(d.HokkuIds, h.Id)
+		}
+	}
+	for _, c := range s.Classics {
+		if models.FingerprintDistance(models.Fingerprint(c.Content), fingerprint) <= maxDistance {
+			d.ClassicIds = append(d.ClassicIds, c.Id)
+		}
+	}
+	return d, nil
+}
+
+func (s *TestStore) GetClassics(limit, offset int) ([]*models.Classic, error) {
+	res := append([]*models.Classic{}, s.Classics...)
+	if offset > len(res) {
+		offset = len(res)
+	}
+	res = res[offset:]
+	if limit != 0 && limit < len(res) {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (s *TestStore) CreateClassic(classic *models.Classic) (int, error) {
+	classic.Id = 1
+	for _, c := range s.Classics {
+		if c.Id >= classic.Id {
+			classic.Id = c.Id + 1
+		}
+	}
+	s.Classics = append(s.Classics, classic)
+	return classic.Id, nil
+}
+
+func (s *TestStore) DeleteClassic(id int) error {
+	for i, c := range s.Classics {
+		if c.Id == id {
+			s.Classics = append(s.Classics[:i], s.Classics[i+1:]...)
+			return nil
+		}
+	}
+	return store.ErrNoRecord
+}
+
+func (s *TestStore) CreateFlag(flag *models.Flag) (int, error) {
+	if s.hokkuIndex(flag.HokkuId) == -1 {
+		return 0, store.ErrForeignKeyConstraint
+	}
+	flag.Id = len(s.Flags) + 1
+	s.Flags = append(s.Flags, flag)
+	return flag.Id, nil
+}
+
+func (s *TestStore) GetFlags(limit, offset int) ([]*models.Flag, error) {
+	res := []*models.Flag{}
+	for i := len(s.Flags) - 1; i >= 0; i-- {
+		res = append(res, s.Flags[i])
+	}
+	if offset > len(res) {
+		offset = len(res)
+	}
+	res = res[offset:]
+	if limit != 0 && limit < len(res) {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+// sameTarget reports whether the reports are on the same hokku, comment or
+// user.
+func sameTarget(a, b *models.Report) bool {
+	return a.HokkuId == b.HokkuId && a.CommentId == b.CommentId && a.AuthorId == b.AuthorId
+}
+
+func (s *TestStore) CreateReport(report *models.Report) (int, error) {
+	if report.ReporterId != 0 && s.userIndex(report.ReporterId) == -1 || s.userIndex(report.AuthorId) == -1 {
+		return 0, store.ErrForeignKeyConstraint
+	}
+	if report.CommentId != 0 && s.commentIndex(report.CommentId) == -1 {
+		return 0, store.ErrForeignKeyConstraint
+	}
+	if report.HokkuId != 0 && s.hokkuIndex(report.HokkuId) == -1 {
+		return 0, store.ErrForeignKeyConstraint
+	}
+	for _, r := range s.Reports {
+		if r.ReporterId == report.ReporterId && r.Status != models.ReportResolved && sameTarget(r, report) {
+			return 0, store.ErrAlreadyExist
+		}
+	}
+	report.Id = len(s.Reports) + 1
+	report.Status, report.ModeratorId, report.Resolution, report.Resolved = models.ReportOpen, 0, "", nil
+	report.Created = time.Now()
+	s.Reports = append(s.Reports, report)
+	return report.Id, nil
+}
+
+func (s *TestStore) GetReports(status string, limit, offset int) ([]*models.Report, error) {
+	res := []*models.Report{}
+	for _, r := range s.Reports {
+		if r.Status == status || status == "" && r.Status != models.ReportResolved {
+			res = append(res, r)
+		}
+	}
+	if offset > len(res) {
+		offset = len(res)
+	}
+	res = res[offset:]
+	if limit != 0 && limit < len(res) {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (s *TestStore) GetReport(id int) (*models.Report, error) {
+	for _, r := range s.Reports {
+		if r.Id == id {
+			return r, nil
+		}
+	}
+	return nil, store.ErrNoRecord
+}
+
+// claimable reports whether the moderator may claim or resolve the report.
+func claimable(r *models.Report, moderatorId int) bool {
+	return r.Status == models.ReportOpen || r.Status == models.ReportClaimed && r.ModeratorId == moderatorId
+}
+
+func (s *TestStore) ClaimReport(id, moderatorId int) error {
+	r, err := s.GetReport(id)
+	if err != nil {
+		return err
+	}
+	if !claimable(r, moderatorId) {
+		return store.ErrAlreadyExist
+	}
+	r.Status, r.ModeratorId = models.ReportClaimed, moderatorId
+	return nil
+}
+
+func (s *TestStore) ResolveReport(id, moderatorId int, resolution string) ([]*models.Report, error) {
+	report, err := s.GetReport(id)
+	if err != nil {
+		return nil, err
+	}
+	if !claimable(report, moderatorId) {
+		return nil, store.ErrAlreadyExist
+	}
+	res := []*models.Report{}
+	now := time.Now()
+	for _, r := range s.Reports {
+		if r.Status != models.ReportResolved && sameTarget(r, report) {
+			r.Status, r.ModeratorId, r.Resolution, r.Resolved = models.ReportResolved, moderatorId, resolution, &now
+			res = append(res, r)
+		}
+	}
+	return res, nil
+}
+
+func (s *TestStore) HideHokku(id int) error {
+	return s.setHokkuHidden(id, true)
+}
+
+func (s *TestStore) UnhideHokku(id int) error {
+	return s.setHokkuHidden(id, false)
+}
+
+func (s *TestStore) setHokkuHidden(id int, hidden bool) error {
+	i := s.hokkuIndex(id)
+	if i == -1 || s.Hokkus[i].DeletedAt != nil {
+		return store.ErrNoRecord
+	}
+	return s.changeHokkus(models.EventHokkuUpdated, []int{id}, func() {
+		s.Hokkus[i].Hidden = hidden
+	})
+}
+
+func (s *TestStore) HideUser(id int) error {
+	return s.setUserHidden(id, true)
+}
+
+func (s *TestStore) UnhideUser(id int) error {
+	return s.setUserHidden(id, false)
+}
+
+func (s *TestStore) setUserHidden(id int, hidden bool) error {
+	u, err := s.GetUser(id)
+	if err != nil {
+		return err
+	}
+	ids := s.hokkuIds(func(h *models.Hokku) bool { return h.OwnerId == id && h.DeletedAt == nil })
+	return s.changeHokkus(models.EventHokkuUpdated, ids, func() {
+		u.Hidden = hidden
+	})
+}
+
+func (s *TestStore) SetRole(id int, role string) error {
+	u, err := s.GetUser(id)
+	if err != nil {
+		return err
+	}
+	u.Role = role
+	return nil
+}
+
+func (s *TestStore) CreateRestriction(r *models.Restriction) (int, error) {
+	if s.userIndex(r.UserId) == -1 {
+		return 0, store.ErrForeignKeyConstraint
+	}
+	r.Id = len(s.Restrictions) + 1
+	r.Created = time.Now()
+	r.LiftedBy, r.Lifted = 0, nil
+	s.Restrictions = append(s.Restrictions, r)
+	return r.Id, nil
+}
+
+func (s *TestStore) GetRestriction(id int) (*models.Restriction, error) {
+	for _, r := range s.Restrictions {
+		if r.Id == id {
+			return r, nil
+		}
+	}
+	return nil, store.ErrNoRecord
+}
+
+func (s *TestStore) GetRestrictions(userId int) ([]*models.Restriction, error) {
+	res := []*models.Restriction{}
+	for i := len(s.Restrictions) - 1; i >= 0; i-- {
+		if s.Restrictions[i].UserId == userId {
+			res = append(res, s.Restrictions[i])
+		}
+	}
+	return res, nil
+}
+
+func (s *TestStore) GetActiveRestrictions(userId int, now time.Time) ([]*models.Restriction, error) {
+	res := []*models.Restriction{}
+	for i := len(s.Restrictions) - 1; i >= 0; i-- {
+		if r := s.Restrictions[i]; r.UserId == userId && r.Active(now) {
+			res = append(res, r)
+		}
+	}
+	return res, nil
+}
+
+func (s *TestStore) LiftRestriction(id, moderatorId int) error {
+	for _, r := range s.Restrictions {
+		if r.Id == id && r.Lifted == nil {
+			now := time.Now()
+			r.LiftedBy, r.Lifted = moderatorId, &now
+			return nil
+		}
+	}
+	return store.ErrNoRecord
+}
+
+func (s *TestStore) CreateAuditEntry(e *models.AuditEntry) (int, error) {
+	e.Id = len(s.AuditLog) + 1
+	e.Created = time.Now()
+	s.AuditLog = append(s.AuditLog, e)
+	return e.Id, nil
+}
+
+func (s *TestStore) GetAuditLog(q *models.AuditQuery) ([]*models.AuditEntry, error) {
+	res := []*models.AuditEntry{}
+	for i := len(s.AuditLog) - 1; i >= 0; i-- {
+		if q.Matches(s.AuditLog[i]) {
+			res = append(res, s.AuditLog[i])
+		}
+	}
+	offset := q.Offset
+	if offset > len(res) {
+		offset = len(res)
+	}
+	res = res[offset:]
+	if q.Limit != 0 && q.Limit < len(res) {
+		res = res[:q.Limit]
+	}
+	return res, nil
+}
+
+func (s *TestStore) CreateNotification(n *models.Notification) (int, error) {
+	if s.userIndex(n.UserId) == -1 || n.ActorId != 0 && s.userIndex(n.ActorId) == -1 {
+		return 0, store.ErrForeignKeyConstraint
+	}
+	now := time.Now()
+	if n.Aggregated() {
+		for i := len(s.Notifications) - 1; i >= 0; i-- {
+			saved := s.Notifications[i]
+			if saved.UserId == n.UserId && !saved.Read && saved.Type == n.Type && saved.HokkuId == n.HokkuId {
+				s.addNotificationActor(saved, n.ActorId)
+				saved.ActorId, saved.CommentId, saved.Updated = n.ActorId, n.CommentId, now
+				n.Id, n.Actors, n.Created, n.Updated = saved.Id, saved.Actors, saved.Created, saved.Updated
+				return n.Id, nil
+			}
+		}
+	}
+	n.Id = len(s.Notifications) + 1
+	n.Created, n.Updated = now, now
+	if n.ActorId != 0 {
+		s.addNotificationActor(n, n.ActorId)
+	}
+	s.Notifications = append(s.Notifications, n)
+	return n.Id, nil
+}
+
+func (s *TestStore) addNotificationActor(n *models.Notification, actorId int) {
+	if s.notificationActors == nil {
+		s.notificationActors = map[int]map[int]bool{}
+	}
+	if s.notificationActors[n.Id] == nil {
+		s.notificationActors[n.Id] = map[int]bool{}
+	}
+	s.notificationActors[n.Id][actorId] = true
+	n.Actors = len(s.notificationActors[n.Id])
+}
+
+func (s *TestStore) GetNotifications(userId, limit, offset int) ([]*models.Notification, error) {
+	res := []*models.Notification{}
+	for i := len(s.Notifications) - 1; i >= 0; i-- {
+		if s.Notifications[i].UserId == userId {
+			res = append(res, s.Notifications[i])
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Updated.After(res[j].Updated) })
+	if offset > len(res) {
+		offset = len(res)
+	}
+	res = res[offset:]
+	if limit != 0 && limit < len(res) {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (s *TestStore) CountUnreadNotifications(userId int) (map[string]int, error) {
+	res := map[string]int{}
+	for _, n := range s.Notifications {
+		if n.UserId == userId && !n.Read {
+			res[n.Type]++
+		}
+	}
+	return res, nil
+}
+
+func (s *TestStore) ReadNotification(userId, id int) error {
+	for _, n := range s.Notifications {
+		if n.Id == id && n.UserId == userId {
+			n.Read = true
+			return nil
+		}
+	}
+	return store.ErrNoRecord
+}
+
+func (s *TestStore) ReadNotifications(userId int) error {
+	for _, n := range s.Notifications {
+		if n.UserId == userId {
+			n.Read = true
+		}
+	}
+	return nil
+}
+
+func (s *TestStore) GetNotificationPreferences(userId int) (map[string]bool, error) {
+	res := map[string]bool{}
+	for t, enabled := range s.NotificationPreferences[userId] {
+		res[t] = enabled
+	}
+	return res, nil
+}
+
+func (s *TestStore) SetNotificationPreferences(userId int, prefs map[string]bool) error {
+	if s.userIndex(userId) == -1 {
+		return store.ErrForeignKeyConstraint
+	}
+	if s.NotificationPreferences == nil {
+		s.NotificationPreferences = map[int]map[string]bool{}
+	}
+	if s.NotificationPreferences[userId] == nil {
+		s.NotificationPreferences[userId] = map[string]bool{}
+	}
+	for t, enabled := range prefs {
+		s.NotificationPreferences[userId][t] = enabled
+	}
+	return nil
+}
+
+func (s *TestStore) CreateWebhook(w *models.Webhook) (int, error) {
+	if s.userIndex(w.OwnerId) == -1 {
+		return 0, store.ErrForeignKeyConstraint
+	}
+	w.Id = 1
+	for _, saved := range s.Webhooks {
+		if saved.Id >= w.Id {
+			w.Id = saved.Id + 1
+		}
+	}
+	w.Created = time.Now()
+	s.Webhooks = append(s.Webhooks, w)
+	return w.Id, nil
+}
+
+func (s *TestStore) GetWebhook(id int) (*models.Webhook, error) {
+	for _, w := range s.Webhooks {
+		if w.Id == id {
+			return w, nil
+		}
+	}
+	return nil, store.ErrNoRecord
+}
+
+func (s *TestStore) GetWebhooks(ownerId int) ([]*models.Webhook, error) {
+	res := []*models.Webhook{}
+	for i := len(s.Webhooks) - 1; i >= 0; i-- {
+		if ownerId == 0 || s.Webhooks[i].OwnerId == ownerId {
+			res = append(res, s.Webhooks[i])
+		}
+	}
+	return res, nil
+}
+
+func (s *TestStore) DeleteWebhook(id int) error {
+	for i, w := range s.Webhooks {
+		if w.Id == id {
+			s.Webhooks = append(s.Webhooks[:i], s.Webhooks[i+1:]...)
+			deliveries := s.WebhookDeliveries[:0]
+			for _, d := range s.WebhookDeliveries {
+				if d.WebhookId != id {
+					deliveries = append(deliveries, d)
+				}
+			}
+			s.WebhookDeliveries = deliveries
+			return nil
+		}
+	}
+	return store.ErrNoRecord
+}
+
+func (s *TestStore) EnqueueWebhookDeliveries(key, event string, payload []byte, at time.Time) (int, error) {
+	n, id := 0, 1
+	queued := map[int]bool{}
+	for _, d := range s.WebhookDeliveries {
+		if d.Id >= id {
+			id = d.Id + 1
+		}
+		if d.Key == key {
+			queued[d.WebhookId] = true
+		}
+	}
+	for _, w := range s.Webhooks {
+		if !w.Subscribed(event) || queued[w.Id] {
+			continue
+		}
+		s.WebhookDeliveries = append(s.WebhookDeliveries, &models.WebhookDelivery{
+			Id:          id + n,
+			WebhookId:   w.Id,
+			Key:         key,
+			Event:       event,
+			Payload:     payload,
+			Status:      models.DeliveryPending,
+			NextAttempt: at,
+			Created:     time.Now(),
+		})
+		n++
+	}
+	return n, nil
+}
+
+func (s *TestStore) GetDueWebhookDeliveries(now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	res := []*models.WebhookDelivery{}
+	for _, d := range s.WebhookDeliveries {
+		if d.Status == models.DeliveryPending && !d.NextAttempt.After(now) {
+			res = append(res, d)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].NextAttempt.Before(res[j].NextAttempt) })
+	if limit < len(res) {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (s *TestStore) UpdateWebhookDelivery(d *models.WebhookDelivery) error {
+	for i, saved := range s.WebhookDeliveries {
+		if saved.Id == d.Id {
+			cp := *d
+			s.WebhookDeliveries[i] = &cp
+			return nil
+		}
+	}
+	return store.ErrNoRecord
+}
+
+func (s *TestStore) GetWebhookDeliveries(webhookId, limit, offset int) ([]*models.WebhookDelivery, error) {
+	res := []*models.WebhookDelivery{}
+	for i := len(s.WebhookDeliveries) - 1; i >= 0; i-- {
+		if s.WebhookDeliveries[i].WebhookId == webhookId {
+			res = append(res, s.WebhookDeliveries[i])
+		}
+	}
+	if offset > len(res) {
+		offset = len(res)
+	}
+	res = res[offset:]
+	if limit != 0 && limit < len(res) {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (s *TestStore) Follow(followerId, followeeId int) error {
+	if s.userIndex(followeeId) == -1 {
+		return store.ErrForeignKeyConstraint
+	}
+	if s.blocked(followeeId, followerId) {
+		return store.ErrBlocked
+	}
+	if s.isFollower(followerId, followeeId) {
+		return store.ErrAlreadyExist
+	}
+	s.Follows = append(s.Follows, &models.Follow{
+		FollowerId: followerId,
+		FolloweeId: followeeId,
+		Created:    time.Now(),
+	})
+	return nil
+}
+
+func (s *TestStore) Unfollow(followerId, followeeId int) error {
+	for i, f := range s.Follows {
+		if f.FollowerId == followerId && f.FolloweeId == followeeId {
+			s.Follows = append(s.Follows[:i], s.Follows[i+1:]...)
+			return nil
+		}
+	}
+	return store.ErrNoRecord
+}
+
+func (s *TestStore) Block(blockerId, blockedId int) error {
+	if s.userIndex(blockerId) == -1 || s.userIndex(blockedId) == -1 {
+		return store.ErrForeignKeyConstraint
+	}
+	if s.blocked(blockerId, blockedId) {
+		return store.ErrAlreadyExist
+	}
+	s.Blocks = append([]*models.Block{{BlockerId: blockerId, BlockedId: blockedId, Created: time.Now()}}, s.Blocks...)
+	s.Unfollow(blockedId, blockerId)
+	return nil
+}
+
+func (s *TestStore) Unblock(blockerId, blockedId int) error {
+	for i, b := range s.Blocks {
+		if b.BlockerId == blockerId && b.BlockedId == blockedId {
+			s.Blocks = append(s.Blocks[:i], s.Blocks[i+1:]...)
+			return nil
+		}
+	}
+	return store.ErrNoRecord
+}
+
+func (s *TestStore) GetBlocks(blockerId int) ([]*models.Block, error) {
+	res := make([]*models.Block, 0)
+	for _, b := range s.Blocks {
+		if b.BlockerId == blockerId {
+			res = append(res, b)
+		}
+	}
+	return res, nil
+}
+
+func (s *TestStore) Mute(muterId, mutedId int) error {
+	if s.userIndex(muterId) == -1 || s.userIndex(mutedId) == -1 {
+		return store.ErrForeignKeyConstraint
+	}
+	if s.muted(muterId, mutedId) {
+		return store.ErrAlreadyExist
+	}
+	s.Mutes = append([]*models.Mute{{MuterId: muterId, MutedId: mutedId, Created: time.Now()}}, s.Mutes...)
+	return nil
+}
+
+func (s *TestStore) Unmute(muterId, mutedId int) error {
+	for i, m := range s.Mutes {
+		if m.MuterId == muterId && m.MutedId == mutedId {
+			s.Mutes = append(s.Mutes[:i], s.Mutes[i+1:]...)
+			return nil
+		}
+	}
+	return store.ErrNoRecord
+}
+
+func (s *TestStore) GetMutes(muterId int) ([]*models.Mute, error) {
+	res := make([]*models.Mute, 0)
+	for _, m := range s.Mutes {
+		if m.MuterId == muterId {
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+func (s *TestStore) GetTrash(ownerId int, deletedSince time.Time, limit, offset int) ([]*models.Hokku, error) {
+	res := make([]*models.Hokku, 0)
+	for _, h := range s.Hokkus {
+		if h.OwnerId == ownerId && h.DeletedAt != nil && !h.DeletedAt.Before(deletedSince) {
+			res = append(res, h)
+		}
+	}
+	return paginate(res, limit, offset), nil
+}
+
+func (s *TestStore) RestoreHokku(ownerId, id int, deletedSince time.Time) error {
+	i := s.hokkuIndex(id)
+	if i == -1 {
+		return store.ErrNoRecord
+	}
+	h := s.Hokkus[i]
+	if h.OwnerId != ownerId || h.DeletedAt == nil || h.DeletedAt.Before(deletedSince) {
+		return store.ErrNoRecord
+	}
+	return s.changeHokkus(models.EventHokkuUpdated, []int{id}, func() {
+		h.DeletedAt = nil
+	})
+}
+
+func (s *TestStore) RestoreUser(id int, deletedSince time.Time) error {
+	i := s.userIndex(id)
+	if i == -1 {
+		return store.ErrNoRecord
+	}
+	u := s.Users[i]
+	if u.DeletedAt == nil || u.DeletedAt.Before(deletedSince) {
+		return store.ErrNoRecord
+	}
+	ids := s.hokkuIds(func(h *models.Hokku) bool {
+		return h.OwnerId == id && h.DeletedAt != nil && h.DeletedAt.Equal(*u.DeletedAt)
+	})
+	return s.changeHokkus(models.EventHokkuUpdated, ids, func() {
+		for _, hokkuId := range ids {
+			s.Hokkus[s.hokkuIndex(hokkuId)].DeletedAt = nil
+		}
+		u.DeletedAt = nil
+	})
+}
+
+func (s *TestStore) GetDeletedUserByEmail(email string, deletedSince time.Time) (*models.User, error) {
+	for _, u := range s.Users {
+		if u.Email == email && u.DeletedAt != nil && !u.DeletedAt.Before(deletedSince) {
+			return u, nil
+		}
+	}
+	return nil, store.ErrNoRecord
+}
+
+// PurgeDeleted mirrors the cascading deletes of the MySQL schema: hokkus
+// and follows of purged users are removed too.
+func (s *TestStore) PurgeDeleted(before time.Time) (int, error) {
+	purged := 0
+	purgedUsers := map[int]bool{}
+	users := make([]*models.User, 0, len(s.Users))
+	for _, u := range s.Users {
+		if u.DeletedAt != nil && u.DeletedAt.Before(before) {
+			purgedUsers[u.Id] = true
+			purged++
+			continue
+		}
+		users = append(users, u)
+	}
+	hokkus := make([]*models.Hokku, 0, len(s.Hokkus))
+	for _, h := range s.Hokkus {
+		if h.DeletedAt != nil && h.DeletedAt.Before(before) {
+			purged++
+			continue
+		}
+		if !purgedUsers[h.OwnerId] {
+			hokkus = append(hokkus, h)
+		}
+	}
+	follows := make([]*models.Follow, 0, len(s.Follows))
+	for _, f := range s.Follows {
+		if !purgedUsers[f.FollowerId] && !purgedUsers[f.FolloweeId] {
+			follows = append(follows, f)
+		}
+	}
+	s.Users, s.Hokkus, s.Follows = users, hokkus, follows
+	blocks := make([]*models.Block, 0, len(s.Blocks))
+	for _, b := range s.Blocks {
+		if !purgedUsers[b.BlockerId] && !purgedUsers[b.BlockedId] {
+			blocks = append(blocks, b)
+		}
+	}
+	mutes := make([]*models.Mute, 0, len(s.Mutes))
+	for _, m := range s.Mutes {
+		if !purgedUsers[m.MuterId] && !purgedUsers[m.MutedId] {
+			mutes = append(mutes, m)
+		}
+	}
+	s.Blocks, s.Mutes = blocks, mutes
+	return purged, nil
+}
@@ -0,0 +1,183 @@
+// Package stream fans events out to the subscribers of their topics and
+// keeps the latest events, so reconnecting subscribers can catch up.
+package stream
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TopicHokkus carries the public hokkus of every theme.
+const TopicHokkus = "hokkus"
+
+// ThemeTopic carries the public hokkus of the theme.
+func ThemeTopic(themeId int) string {
+	return "theme:" + strconv.Itoa(themeId)
+}
+
+// UserTopic carries the events meant only for the user.
+func UserTopic(userId int) string {
+	return "user:" + strconv.Itoa(userId)
+}
+
+// Event is a message published to topics. Ids grow with every event
+// published to the hub.
+type Event struct {
+	Id     uint64
+	Type   string
+	Topics []string
+	Data   []byte
+}
+
+// Hub delivers events to subscribers without blocking publishers. A
+// subscriber queues at most bufferSize events, when it falls behind it is
+// dropped and has to subscribe again, catching up from the history of the
+// last historySize events.
+type Hub struct {
+	historySize int
+	bufferSize  int
+	heartbeat   time.Duration
+
+	mu      sync.Mutex
+	lastId  uint64
+	history []*Event
+	subs    map[*Subscription]struct{}
+}
+
+func NewHub(historySize, bufferSize int, heartbeat time.Duration) *Hub {
+	return &Hub{
+		historySize: historySize,
+		bufferSize:  bufferSize,
+		heartbeat:   heartbeat,
+		subs:        map[*Subscription]struct{}{},
+	}
+}
+
+// Heartbeat is how often idle subscribers should be told the connection
+// is alive.
+func (h *Hub) Heartbeat() time.Duration {
+	return h.heartbeat
+}
+
+// Publish sends the event to the subscribers of any of the topics.
+func (h *Hub) Publish(typ string, data []byte, topics ...string) *Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastId++
+	e := &Event{Id: h.lastId, Type: typ, Topics: topics, Data: data}
+	if h.historySize > 0 {
+		h.history = append(h.history, e)
+		if len(h.history) > h.historySize {
+			h.history = h.history[len(h.history)-h.historySize:]
+		}
+	}
+	for s := range h.subs {
+		if !s.wants(e) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			s.dropped = true
+			h.remove(s)
+		}
+	}
+	return e
+}
+
+// Subscribe starts delivering the events of the topics published from now
+// on. When lastId is set, the events of the topics published after it are
+// returned to be sent first. complete is false when some of them are no
+// longer kept or the hub was restarted since, then the subscriber should
+// load the current state again.
+func (h *Hub) Subscribe(lastId uint64, topics ...string) (sub *Subscription, replay []*Event, complete bool) {
+	sub = &Subscription{
+		hub:    h,
+		topics: map[string]bool{},
+		ch:     make(chan *Event, h.bufferSize),
+	}
+	for _, t := range topics {
+		sub.topics[t] = true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subs[sub] = struct{}{}
+	if lastId == 0 || lastId == h.lastId {
+		return sub, nil, true
+	}
+	oldest := h.lastId + 1
+	if len(h.history) > 0 {
+		oldest = h.history[0].Id
+	}
+	complete = lastId < h.lastId && lastId+1 >= oldest
+	for _, e := range h.history {
+		if e.Id > lastId && sub.wants(e) {
+			replay = append(replay, e)
+		}
+	}
+	return sub, replay, complete
+}
+
+// Subscribers counts the current subscriptions.
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// CloseTopic closes the subscriptions of the topic, like the ones of a user
+// who is no longer allowed to listen.
+func (h *Hub) CloseTopic(topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs {
+		if s.topics[topic] {
+			h.remove(s)
+		}
+	}
+}
+
+// remove must be called with the lock held.
+func (h *Hub) remove(s *Subscription) {
+	if _, ok := h.subs[s]; ok {
+		delete(h.subs, s)
+		close(s.ch)
+	}
+}
+
+// Subscription receives the events of its topics until it is closed.
+type Subscription struct {
+	hub     *Hub
+	topics  map[string]bool
+	ch      chan *Event
+	dropped bool
+}
+
+// Events is closed when the subscription is closed or dropped.
+func (s *Subscription) Events() <-chan *Event {
+	return s.ch
+}
+
+// Dropped reports whether the subscription was closed because it fell
+// behind.
+func (s *Subscription) Dropped() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.dropped
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+func (s *Subscription) wants(e *Event) bool {
+	for _, t := range e.Topics {
+		if s.topics[t] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package stream_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/EgorSkurihin/Hokku/stream"
+	"github.com/stretchr/testify/assert"
+)
+
+func ids(events []*stream.Event) []uint64 {
+	res := []uint64{}
+	for _, e := range events {
+		res = append(res, e.Id)
+	}
+	return res
+}
+
+func TestHubPublish(t *testing.T) {
+	hub := stream.NewHub(10, 10, time.Second)
+	feed, _, _ := hub.Subscribe(0, stream.TopicHokkus)
+	theme, _, _ := hub.Subscribe(0, stream.ThemeTopic(2), stream.UserTopic(1))
+	hub.Publish("hokku.created", []byte(`{"id":1}`), stream.TopicHokkus, stream.ThemeTopic(1))
+	hub.Publish("hokku.created", []byte(`{"id":2}`), stream.TopicHokkus, stream.ThemeTopic(2))
+	hub.Publish("notification", []byte(`{"id":1}`), stream.UserTopic(1))
+
+	got := []*stream.Event{}
+	for len(feed.Events()) > 0 {
+		got = append(got, <-feed.Events())
+	}
+	assert.Equal(t, []uint64{1, 2}, ids(got))
+	got = got[:0]
+	for len(theme.Events()) > 0 {
+		got = append(got, <-theme.Events())
+	}
+	assert.Equal(t, []uint64{2, 3}, ids(got))
+
+	assert.Equal(t, 2, hub.Subscribers())
+	feed.Close()
+	feed.Close()
+	_, open := <-feed.Events()
+	assert.False(t, open)
+	assert.False(t, feed.Dropped())
+	assert.Equal(t, 1, hub.Subscribers())
+}
+
+func TestHubBackpressure(t *testing.T) {
+	hub := stream.NewHub(10, 2, time.Second)
+	slow, _, _ := hub.Subscribe(0, stream.TopicHokkus)
+	for i := 0; i < 3; i++ {
+		hub.Publish("hokku.created", nil, stream.TopicHokkus)
+	}
+	assert.True(t, slow.Dropped())
+	assert.Equal(t, 0, hub.Subscribers())
+	got := []*stream.Event{}
+	for e := range slow.Events() {
+		got = append(got, e)
+	}
+	assert.Equal(t, []uint64{1, 2}, ids(got))
+
+	// The dropped subscriber catches up from the last event it received
+	_, replay, complete := hub.Subscribe(2, stream.TopicHokkus)
+	assert.True(t, complete)
+	assert.Equal(t, []uint64{3}, ids(replay))
+}
+
+func TestHubReplay(t *testing.T) {
+	hub := stream.NewHub(3, 10, time.Second)
+	for i := 0; i < 5; i++ {
+		hub.Publish("hokku.created", nil, stream.TopicHokkus)
+	}
+	hub.Publish("notification", nil, stream.UserTopic(1))
+	cases := []struct {
+		name     string
+		lastId   uint64
+		replay   []uint64
+		complete bool
+	}{
+		{name: "new", lastId: 0, replay: []uint64{}, complete: true},
+		{name: "up to date", lastId: 6, replay: []uint64{}, complete: true},
+		{name: "kept", lastId: 3, replay: []uint64{4, 5}, complete: true},
+		{name: "lost", lastId: 1, replay: []uint64{4, 5}, complete: false},
+		{name: "restarted", lastId: 100, replay: []uint64{}, complete: false},
+	}
+	for _, cs := range cases {
+		t.Run(cs.name, func(t *testing.T) {
+			sub, replay, complete := hub.Subscribe(cs.lastId, stream.TopicHokkus)
+			defer sub.Close()
+			assert.Equal(t, cs.replay, ids(replay))
+			assert.Equal(t, cs.complete, complete)
+		})
+	}
+}
+
+func TestHubCloseTopic(t *testing.T) {
+	hub := stream.NewHub(10, 10, time.Second)
+	user, _, _ := hub.Subscribe(0, stream.TopicHokkus, stream.UserTopic(1))
+	feed, _, _ := hub.Subscribe(0, stream.TopicHokkus)
+	hub.CloseTopic(stream.UserTopic(1))
+	_, open := <-user.Events()
+	assert.False(t, open)
+	assert.False(t, user.Dropped())
+	assert.Equal(t, 1, hub.Subscribers())
+	user.Close()
+	feed.Close()
+}